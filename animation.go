@@ -13,11 +13,14 @@ const (
 	TrackTypeScale    = "Sca"
 	TrackTypeRotation = "Rot"
 
-	InterpolationLinear = iota
-	InterpolationConstant
-	InterpolationCubic // Unimplemented
+	InterpolationLinear   = iota // Linear interpolation between keyframes
+	InterpolationConstant        // Each keyframe's value is held until the next keyframe
+	InterpolationCubic           // Cubic Hermite spline interpolation using the keyframes' in and out tangents
 )
 
+// TrackTypeMorphWeights tracks drive the MorphWeights of a Model.
+const TrackTypeMorphWeights = "Wei"
+
 type Data struct {
 	contents interface{}
 }
@@ -30,10 +33,18 @@ func (data *Data) AsQuaternion() Quaternion {
 	return data.contents.(Quaternion)
 }
 
+func (data *Data) AsWeights() []float64 {
+	return data.contents.([]float64)
+}
+
 // Keyframe represents a single keyframe in an animation for an AnimationTrack.
 type Keyframe struct {
 	Time float64
 	Data Data
+	// InTangent and OutTangent are the incoming and outgoing tangents of the Keyframe, and are only used
+	// when the AnimationTrack uses InterpolationCubic. If a tangent is unset, it's treated as zero.
+	InTangent  Data
+	OutTangent Data
 }
 
 func newKeyframe(time float64, data Data) *Keyframe {
@@ -55,6 +66,23 @@ func (track *AnimationTrack) AddKeyframe(time float64, data interface{}) {
 	track.Keyframes = append(track.Keyframes, newKeyframe(time, Data{data}))
 }
 
+// AddKeyframeCubic adds a keyframe of the necessary data type to the AnimationTrack, along with its incoming and outgoing
+// tangents for use with InterpolationCubic. The tangents should be of the same data type as the keyframe's data.
+func (track *AnimationTrack) AddKeyframeCubic(time float64, data, inTangent, outTangent interface{}) {
+	keyframe := newKeyframe(time, Data{data})
+	keyframe.InTangent = Data{inTangent}
+	keyframe.OutTangent = Data{outTangent}
+	track.Keyframes = append(track.Keyframes, keyframe)
+}
+
+// hermiteWeights returns the weights of the starting value, starting tangent, ending value, and ending tangent
+// for a cubic Hermite spline at the given percentage (0-1) between two keyframes.
+func hermiteWeights(t float64) (float64, float64, float64, float64) {
+	t2 := t * t
+	t3 := t2 * t
+	return 2*t3 - 3*t2 + 1, t3 - 2*t2 + t, -2*t3 + 3*t2, t3 - t2
+}
+
 // ValueAsVector returns a Vector associated with the current time in seconds, as well as a boolean indicating if
 // the Vector exists (i.e. if the AnimationTrack has location or scale data in the animation). The Vector will be
 // interpolated according to time between keyframes.
@@ -93,17 +121,33 @@ func (track *AnimationTrack) ValueAsVector(time float64) (Vector, bool) {
 	fd := first.Data.AsVector()
 	ld := last.Data.AsVector()
 
-	// Linear interpolation
 	t := (time - first.Time) / (last.Time - first.Time)
 
 	if track.Interpolation == InterpolationConstant {
 		return fd, true
 	}
 
-	// We still need to implement InterpolationCubic
-	// if track.Type == TrackTypePosition || track.Type == TrackTypeScale {
+	if track.Interpolation == InterpolationCubic {
+
+		// Tangents are scaled by the time between keyframes, as they're stored in units per second.
+		dt := last.Time - first.Time
+
+		var outTangent, inTangent Vector
+		if first.OutTangent.contents != nil {
+			outTangent = first.OutTangent.AsVector()
+		}
+		if last.InTangent.contents != nil {
+			inTangent = last.InTangent.AsVector()
+		}
+
+		h00, h10, h01, h11 := hermiteWeights(t)
+
+		return fd.Scale(h00).Add(outTangent.Scale(h10 * dt)).Add(ld.Scale(h01)).Add(inTangent.Scale(h11 * dt)), true
+
+	}
+
+	// Linear interpolation
 	return fd.Add(ld.Sub(fd).Scale(t)), true
-	// }
 
 }
 
@@ -144,9 +188,39 @@ func (track *AnimationTrack) ValueAsQuaternion(time float64) (Quaternion, bool)
 			fd := first.Data.AsQuaternion()
 			ld := last.Data.AsQuaternion()
 
-			// Linear interpolation
 			t := (time - first.Time) / (last.Time - first.Time)
 
+			if track.Interpolation == InterpolationConstant {
+				return fd, true
+			}
+
+			if track.Interpolation == InterpolationCubic {
+
+				dt := last.Time - first.Time
+
+				var outTangent, inTangent Quaternion
+				if first.OutTangent.contents != nil {
+					outTangent = first.OutTangent.AsQuaternion()
+				}
+				if last.InTangent.contents != nil {
+					inTangent = last.InTangent.AsQuaternion()
+				}
+
+				h00, h10, h01, h11 := hermiteWeights(t)
+				h10 *= dt
+				h11 *= dt
+
+				// The spline is evaluated per-component and then normalized, as the glTF spec dictates.
+				return NewQuaternion(
+					fd.X*h00+outTangent.X*h10+ld.X*h01+inTangent.X*h11,
+					fd.Y*h00+outTangent.Y*h10+ld.Y*h01+inTangent.Y*h11,
+					fd.Z*h00+outTangent.Z*h10+ld.Z*h01+inTangent.Z*h11,
+					fd.W*h00+outTangent.W*h10+ld.W*h01+inTangent.W*h11,
+				).Normalized(), true
+
+			}
+
+			// Linear interpolation
 			return fd.Lerp(ld, t), true
 
 		}
@@ -155,6 +229,87 @@ func (track *AnimationTrack) ValueAsQuaternion(time float64) (Quaternion, bool)
 
 }
 
+// ValueAsWeights returns the morph target weights associated with this AnimationTrack using the given time in seconds,
+// and a boolean indicating if the weights exist (i.e. if this track has morph weight animation data). The weights will be
+// interpolated according to time between keyframes. The returned slice is newly allocated.
+func (track *AnimationTrack) ValueAsWeights(time float64) ([]float64, bool) {
+
+	if len(track.Keyframes) == 0 {
+		return nil, false
+	}
+
+	if first := track.Keyframes[0]; time <= first.Time {
+		return append([]float64{}, first.Data.AsWeights()...), true
+	} else if last := track.Keyframes[len(track.Keyframes)-1]; time >= last.Time {
+		return append([]float64{}, last.Data.AsWeights()...), true
+	}
+
+	var first *Keyframe
+	var last *Keyframe
+
+	for _, k := range track.Keyframes {
+
+		if k.Time < time {
+			first = k
+		} else {
+			last = k
+			break
+		}
+
+	}
+
+	fd := first.Data.AsWeights()
+	ld := last.Data.AsWeights()
+
+	weights := make([]float64, len(fd))
+
+	if time == last.Time {
+		copy(weights, ld)
+		return weights, true
+	} else if track.Interpolation == InterpolationConstant {
+		copy(weights, fd)
+		return weights, true
+	}
+
+	t := (time - first.Time) / (last.Time - first.Time)
+
+	if track.Interpolation == InterpolationCubic {
+
+		dt := last.Time - first.Time
+
+		var outTangent, inTangent []float64
+		if first.OutTangent.contents != nil {
+			outTangent = first.OutTangent.AsWeights()
+		}
+		if last.InTangent.contents != nil {
+			inTangent = last.InTangent.AsWeights()
+		}
+
+		h00, h10, h01, h11 := hermiteWeights(t)
+
+		for i := range weights {
+			weights[i] = fd[i]*h00 + ld[i]*h01
+			if i < len(outTangent) {
+				weights[i] += outTangent[i] * h10 * dt
+			}
+			if i < len(inTangent) {
+				weights[i] += inTangent[i] * h11 * dt
+			}
+		}
+
+		return weights, true
+
+	}
+
+	// Linear interpolation
+	for i := range weights {
+		weights[i] = fd[i] + (ld[i]-fd[i])*t
+	}
+
+	return weights, true
+
+}
+
 func newAnimationTrack(trackType string) *AnimationTrack {
 	return &AnimationTrack{
 		Type:      trackType,
@@ -235,7 +390,10 @@ type AnimationValues struct {
 	ScaleExists    bool
 	Rotation       Quaternion
 	RotationExists bool
-	channel        *AnimationChannel
+	// MorphWeights are the animated weights for a Model's morph targets.
+	MorphWeights       []float64
+	MorphWeightsExists bool
+	channel            *AnimationChannel
 }
 
 // AnimationPlayer is an object that allows you to play back an animation on a Node.
@@ -276,6 +434,27 @@ type AnimationPlayer struct {
 	startingPosition Vector
 	startingScale    Vector
 	startingRotation Matrix4
+
+	// Layers are blended over the pose of the AnimationPlayer's Animation in order, each playing its own Animation or BlendSpace (see
+	// AnimationPlayer.AddLayer()). Layers are updated even when the AnimationPlayer isn't playing its Animation.
+	Layers      []*AnimationLayer
+	nodesByName map[string]INode          // The nodes in the root node's tree, used to assign layers' channels to nodes
+	restPose    map[INode]AnimationValues // The values of nodes from before layers first animated them
+	pose        map[INode]AnimationValues // The final values of the animated nodes for the current update
+
+	// If ExtractRootMotion is true, the root bone's horizontal movement and turning around the Y axis are removed from the pose of the
+	// Animation and recorded each update instead, so that gameplay code can move the character by exactly as much as the Animation does (see
+	// AnimationPlayer.RootMotionDelta(), AnimationPlayer.RootMotionYaw(), and AnimationPlayer.ApplyRootMotion()).
+	ExtractRootMotion bool
+	// RootMotionBone is the name of the bone root motion is extracted from. If it's empty, root motion is extracted from a bone animated by the
+	// Animation that isn't parented to another bone.
+	RootMotionBone string
+	// If RootMotionVertical is true, the root bone's vertical movement is extracted as well (i.e. for jumps or climbing).
+	RootMotionVertical bool
+	rootMotionDelta    Vector
+	rootMotionYaw      float64
+	rootMotionAnim     *Animation // The Animation root motion was last extracted from
+	rootMotionTime     float64    // The time in the Animation root motion was last extracted at
 }
 
 // NewAnimationPlayer returns a new AnimationPlayer for the Node.
@@ -308,6 +487,14 @@ func (ap *AnimationPlayer) Clone() *AnimationPlayer {
 	newAP.OnFinish = ap.OnFinish
 	newAP.Playing = ap.Playing
 	newAP.PlayLastFrame = ap.PlayLastFrame
+	newAP.ExtractRootMotion = ap.ExtractRootMotion
+	newAP.RootMotionBone = ap.RootMotionBone
+	newAP.RootMotionVertical = ap.RootMotionVertical
+
+	for _, layer := range ap.Layers {
+		newAP.Layers = append(newAP.Layers, layer.clone(newAP))
+	}
+
 	return newAP
 }
 
@@ -315,6 +502,12 @@ func (ap *AnimationPlayer) Clone() *AnimationPlayer {
 func (ap *AnimationPlayer) SetRoot(node INode) {
 	ap.RootNode = node
 	ap.ChannelsUpdated = false
+	ap.nodesByName = nil
+	ap.restPose = nil
+	for _, layer := range ap.Layers {
+		layer.maskNodes = nil
+		layer.referenceSet = false
+	}
 }
 
 // PlayAnim plays the specified animation back, resetting the playhead if the specified animation is not currently
@@ -474,6 +667,13 @@ func (ap *AnimationPlayer) updateValues(dt float64) {
 					}
 				}
 
+				if track, exists := channel.Tracks[TrackTypeMorphWeights]; exists {
+					if weights, exists := track.ValueAsWeights(ap.Playhead); exists {
+						n.MorphWeights = weights
+						n.MorphWeightsExists = true
+					}
+				}
+
 				ap.AnimatedProperties[node] = n
 
 			}
@@ -570,17 +770,21 @@ func (ap *AnimationPlayer) Update(dt float64) {
 
 	ap.finished = false
 	ap.touchedMarkers = []Marker{}
+	ap.rootMotionDelta = Vector{}
+	ap.rootMotionYaw = 0
 
 	if !ap.Playing && !ap.blendStart.IsZero() {
 		ap.blendStart = time.Time{}
 		ap.prevAnimatedProperties = map[INode]AnimationValues{}
 	}
 
-	if ap.Animation == nil || !ap.Playing {
+	playing := ap.Animation != nil && ap.Playing
+
+	if !playing && len(ap.Layers) == 0 {
 		return
 	}
 
-	ap.forceUpdate(dt)
+	ap.forceUpdate(dt, playing)
 
 }
 
@@ -588,14 +792,35 @@ func (ap *AnimationPlayer) Update(dt float64) {
 // also performs an update of the animated nodes.
 func (ap *AnimationPlayer) SetPlayhead(time float64) {
 	ap.Playhead = time
-	ap.forceUpdate(0)
+	ap.rootMotionAnim = nil // Jumping to another time isn't root motion
+	ap.forceUpdate(0, ap.Animation != nil)
 }
 
-func (ap *AnimationPlayer) forceUpdate(dt float64) {
+// forceUpdate updates the AnimationPlayer's layers by dt seconds and animates the nodes in the root node's tree. If animate is true, the
+// AnimationPlayer's Animation is updated and blended beneath its layers.
+func (ap *AnimationPlayer) forceUpdate(dt float64, animate bool) {
+
+	if ap.pose == nil {
+		ap.pose = map[INode]AnimationValues{}
+	}
+
+	pose := ap.pose
+
+	for node := range pose {
+		delete(pose, node)
+	}
+
+	// Without the Animation, the layers blend over the nodes' rest pose.
+	var properties map[INode]AnimationValues
 
-	ap.updateValues(dt)
+	sampleTime := ap.Playhead
+
+	if animate {
+		ap.updateValues(dt)
+		properties = ap.AnimatedProperties
+	}
 
-	for node, props := range ap.AnimatedProperties {
+	for node, props := range properties {
 
 		_, prevExists := ap.prevAnimatedProperties[node]
 
@@ -605,6 +830,8 @@ func (ap *AnimationPlayer) forceUpdate(dt float64) {
 		var scaleSet bool
 		var targetRotation Quaternion
 		var rotSet bool
+		var targetWeights []float64
+		var weightsSet bool
 
 		if !ap.blendStart.IsZero() && prevExists {
 
@@ -650,6 +877,24 @@ func (ap *AnimationPlayer) forceUpdate(dt float64) {
 				rotSet = true
 			}
 
+			if start.MorphWeightsExists && props.MorphWeightsExists {
+				targetWeights = make([]float64, len(props.MorphWeights))
+				for i := range targetWeights {
+					startWeight := 0.0
+					if i < len(start.MorphWeights) {
+						startWeight = start.MorphWeights[i]
+					}
+					targetWeights[i] = startWeight + (props.MorphWeights[i]-startWeight)*bp
+				}
+				weightsSet = true
+			} else if props.MorphWeightsExists {
+				targetWeights = props.MorphWeights
+				weightsSet = true
+			} else if start.MorphWeightsExists {
+				targetWeights = start.MorphWeights
+				weightsSet = true
+			}
+
 			if bp == 1 {
 				ap.blendStart = time.Time{}
 				ap.prevAnimatedProperties = map[INode]AnimationValues{}
@@ -669,6 +914,10 @@ func (ap *AnimationPlayer) forceUpdate(dt float64) {
 				targetRotation = props.Rotation
 				rotSet = true
 			}
+			if props.MorphWeightsExists {
+				targetWeights = props.MorphWeights
+				weightsSet = true
+			}
 
 		}
 
@@ -697,37 +946,85 @@ func (ap *AnimationPlayer) forceUpdate(dt float64) {
 			if scaleSet {
 				n.Scale = targetScale
 			}
+			n.MorphWeightsExists = weightsSet
+			if weightsSet {
+				n.MorphWeights = targetWeights
+			}
 
 			ap.currentProperties[node] = n
 
 		}
 
+		values := AnimationValues{channel: props.channel}
+
 		if posSet {
+			values.PositionExists = true
 			if ap.RelativeMotion {
-				node.SetLocalPositionVec(ap.startingPosition.Add(targetPosition.Sub(props.channel.startingPosition)))
+				values.Position = ap.startingPosition.Add(targetPosition.Sub(props.channel.startingPosition))
 			} else {
-				node.SetLocalPositionVec(targetPosition)
+				values.Position = targetPosition
 			}
 		}
 
 		if scaleSet {
+			values.ScaleExists = true
 			if ap.RelativeMotion {
-				node.SetLocalScaleVec(ap.startingScale.Mult(targetScale))
+				values.Scale = ap.startingScale.Mult(targetScale)
 			} else {
-				node.SetLocalScaleVec(targetScale)
+				values.Scale = targetScale
 			}
 		}
 
 		if rotSet {
+			values.RotationExists = true
 			if ap.RelativeMotion {
-				node.SetLocalRotation(ap.startingRotation.Mult(targetRotation.ToMatrix4()))
+				values.Rotation = ap.startingRotation.Mult(targetRotation.ToMatrix4()).ToQuaternion()
 			} else {
-				node.SetLocalRotation(targetRotation.ToMatrix4())
+				values.Rotation = targetRotation
+			}
+		}
+
+		if weightsSet {
+			values.MorphWeights = targetWeights
+			values.MorphWeightsExists = true
+		}
+
+		pose[node] = values
+
+	}
+
+	ap.updateLayers(dt, pose)
+
+	ap.extractRootMotion(animate, sampleTime, pose)
+
+	for node, values := range pose {
+
+		if values.PositionExists {
+			node.SetLocalPositionVec(values.Position)
+		}
+
+		if values.ScaleExists {
+			node.SetLocalScaleVec(values.Scale)
+		}
+
+		if values.RotationExists {
+			node.SetLocalRotation(values.Rotation.ToMatrix4())
+		}
+
+		if values.MorphWeightsExists {
+			if model, ok := node.(*Model); ok {
+				for len(model.MorphWeights) < len(values.MorphWeights) {
+					model.MorphWeights = append(model.MorphWeights, 0)
+				}
+				copy(model.MorphWeights, values.MorphWeights)
 			}
 		}
 
 	}
 
+	// Constraints are applied after animation, so they work from the animated pose.
+	ap.RootNode.baseNode().ApplyConstraints(true)
+
 }
 
 // Finished returns whether the AnimationPlayer is finished playing its current animation.
@@ -0,0 +1,698 @@
+package tetra3d
+
+import (
+	"math"
+	"sort"
+)
+
+// AnimationLayer is a layer of animation that an AnimationPlayer blends over the pose of its Animation and the layers beneath it. A layer plays
+// either an Animation or a BlendSpace, so a character can, for example, run using its AnimationPlayer's Animation while an upper-body layer,
+// masked to the character's spine, plays an attack.
+type AnimationLayer struct {
+	Name string
+
+	// Weight is how much the layer contributes to the pose, ranging from 0 (not at all) to 1 (fully replacing the pose beneath it).
+	// Defaults to 1.
+	Weight float64
+
+	// If Additive is true, the layer adds the difference between its current pose and its first frame (its reference pose) onto the pose
+	// beneath it, rather than replacing it - this is useful for things like breathing or leaning on top of other animations.
+	Additive bool
+
+	Animation  *Animation  // The Animation the layer plays.
+	BlendSpace *BlendSpace // The BlendSpace the layer plays; if set, this is played instead of the Animation.
+
+	// Playhead is the layer's playhead in seconds. When playing a BlendSpace, the Playhead instead ranges from 0 to 1 through the BlendSpace's
+	// Animations, as they're synchronized to play through at the same time.
+	Playhead   float64
+	PlaySpeed  float64    // Playback speed in percentage - defaults to 1 (100%)
+	Playing    bool       // Whether the layer's playhead advances; a layer that isn't playing holds its current pose.
+	FinishMode FinishMode // What to do when the layer finishes playback. Defaults to looping.
+
+	player    *AnimationPlayer
+	mask      []string
+	maskNodes map[INode]bool
+
+	// The pose buffers the layer samples into, which are reused from update to update
+	pose        map[INode]AnimationValues
+	pointPose   map[INode]AnimationValues // The pose of each of a BlendSpace's Animations as they're blended together
+	pointTotals map[INode]float64         // The total weight of a BlendSpace's Animations blended into each node so far
+
+	// The additive layer's reference pose (the first frame of what it's playing), which is only sampled again when that changes
+	reference          map[INode]AnimationValues
+	referenceSet       bool
+	referenceParameter Vector // The BlendSpace Parameter the reference pose was sampled with
+}
+
+// AddLayer adds a new AnimationLayer with the given name over the AnimationPlayer's existing layers, returning it.
+// Layers are blended in the order they were added.
+func (ap *AnimationPlayer) AddLayer(name string) *AnimationLayer {
+	layer := &AnimationLayer{
+		Name:       name,
+		Weight:     1,
+		PlaySpeed:  1,
+		FinishMode: FinishModeLoop,
+		player:     ap,
+	}
+	ap.Layers = append(ap.Layers, layer)
+	return layer
+}
+
+// Layer returns the AnimationLayer with the given name, or nil if the AnimationPlayer has no layer by that name.
+func (ap *AnimationPlayer) Layer(name string) *AnimationLayer {
+	for _, layer := range ap.Layers {
+		if layer.Name == name {
+			return layer
+		}
+	}
+	return nil
+}
+
+// RemoveLayer removes the AnimationLayer with the given name from the AnimationPlayer.
+func (ap *AnimationPlayer) RemoveLayer(name string) {
+	for i, layer := range ap.Layers {
+		if layer.Name == name {
+			ap.Layers = append(ap.Layers[:i], ap.Layers[i+1:]...)
+			return
+		}
+	}
+}
+
+// clone returns a clone of the AnimationLayer for the AnimationPlayer given.
+func (layer *AnimationLayer) clone(player *AnimationPlayer) *AnimationLayer {
+	newLayer := *layer
+	newLayer.player = player
+	newLayer.mask = append([]string{}, layer.mask...)
+	newLayer.maskNodes = nil
+	newLayer.pose = nil
+	newLayer.pointPose = nil
+	newLayer.pointTotals = nil
+	newLayer.reference = nil
+	newLayer.referenceSet = false
+	return &newLayer
+}
+
+// PlayAnim plays the Animation given on the layer, restarting its playhead if the Animation isn't already playing on it. Playing a nil
+// Animation leaves the layer without anything to play, so it no longer affects the pose.
+func (layer *AnimationLayer) PlayAnim(animation *Animation) {
+
+	if layer.Animation == animation && layer.BlendSpace == nil && layer.Playing {
+		return
+	}
+
+	layer.Animation = animation
+	layer.BlendSpace = nil
+
+	length := 0.0
+	if animation != nil {
+		length = animation.Length
+	}
+
+	layer.restart(length)
+
+}
+
+// PlayBlendSpace plays the BlendSpace given on the layer, restarting its playhead if the BlendSpace isn't already playing on it.
+func (layer *AnimationLayer) PlayBlendSpace(blendSpace *BlendSpace) {
+
+	if layer.BlendSpace == blendSpace && layer.Playing {
+		return
+	}
+
+	layer.BlendSpace = blendSpace
+	layer.restart(1)
+
+}
+
+func (layer *AnimationLayer) restart(length float64) {
+	layer.Playing = true
+	if layer.PlaySpeed > 0 {
+		layer.Playhead = 0
+	} else {
+		layer.Playhead = length
+	}
+	layer.player.nodesByName = nil
+	layer.referenceSet = false
+}
+
+// Stop stops the layer's playback, holding its current pose.
+func (layer *AnimationLayer) Stop() {
+	layer.Playing = false
+}
+
+// SetMask masks the layer to the given nodes and the nodes underneath them in the scenegraph, so the layer only animates those nodes (for
+// example, passing a character's spine bone masks the layer to the character's upper body). Masked nodes are matched by name, so masks
+// carry over to clones of the AnimationPlayer. Calling SetMask without any nodes removes the mask, so the layer animates every node.
+func (layer *AnimationLayer) SetMask(roots ...INode) {
+	layer.mask = layer.mask[:0]
+	for _, root := range roots {
+		layer.mask = append(layer.mask, root.Name())
+	}
+	layer.maskNodes = nil
+}
+
+// masked returns if the layer's mask allows it to animate the node given.
+func (layer *AnimationLayer) masked(node INode) bool {
+
+	if len(layer.mask) == 0 {
+		return true
+	}
+
+	if layer.maskNodes == nil {
+
+		layer.maskNodes = map[INode]bool{}
+
+		root := layer.player.RootNode
+		nodes := append([]INode{root}, root.SearchTree().INodes()...)
+
+		for _, name := range layer.mask {
+			for _, n := range nodes {
+				if n.Name() == name {
+					layer.maskNodes[n] = true
+					for _, child := range n.SearchTree().INodes() {
+						layer.maskNodes[child] = true
+					}
+				}
+			}
+		}
+
+	}
+
+	return layer.maskNodes[node]
+
+}
+
+// length returns the length of the layer's playback - the length of its Animation, or 1 for its BlendSpace.
+func (layer *AnimationLayer) length() float64 {
+	if layer.BlendSpace != nil {
+		return 1
+	}
+	if layer.Animation != nil {
+		return layer.Animation.Length
+	}
+	return 0
+}
+
+// sample samples the layer's pose at the given playhead into the pose given.
+func (layer *AnimationLayer) sample(playhead float64, pose map[INode]AnimationValues) {
+
+	if layer.BlendSpace != nil {
+
+		if layer.pointPose == nil {
+			layer.pointPose = map[INode]AnimationValues{}
+			layer.pointTotals = map[INode]float64{}
+		}
+
+		layer.player.sampleBlendSpace(layer.BlendSpace, playhead, pose, layer.pointPose, layer.pointTotals)
+		return
+
+	}
+
+	layer.player.sampleAnimation(layer.Animation, playhead, pose)
+
+}
+
+// referencePose returns the additive layer's reference pose, sampling it if what the layer plays has changed since it was last sampled.
+func (layer *AnimationLayer) referencePose() map[INode]AnimationValues {
+
+	if layer.reference == nil {
+		layer.reference = map[INode]AnimationValues{}
+	}
+
+	if !layer.referenceSet || (layer.BlendSpace != nil && layer.BlendSpace.Parameter != layer.referenceParameter) {
+		layer.sample(0, layer.reference)
+		layer.referenceSet = true
+		if layer.BlendSpace != nil {
+			layer.referenceParameter = layer.BlendSpace.Parameter
+		}
+	}
+
+	return layer.reference
+
+}
+
+// advance moves the layer's playhead forward by dt seconds according to its PlaySpeed and FinishMode.
+func (layer *AnimationLayer) advance(dt float64) {
+
+	length := layer.length()
+
+	if !layer.Playing || length <= 0 {
+		return
+	}
+
+	step := dt * layer.PlaySpeed
+
+	// BlendSpaces play through their Animations over their blended length.
+	if layer.BlendSpace != nil {
+		step /= layer.BlendSpace.length()
+	}
+
+	layer.Playhead += step
+
+	switch layer.FinishMode {
+
+	case FinishModeLoop:
+		layer.Playhead = math.Mod(layer.Playhead, length)
+		if layer.Playhead < 0 {
+			layer.Playhead += length
+		}
+
+	case FinishModePingPong:
+		if layer.Playhead > length {
+			layer.Playhead = length - (layer.Playhead - length)
+			layer.PlaySpeed *= -1
+		} else if layer.Playhead < 0 {
+			layer.Playhead *= -1
+			layer.PlaySpeed *= -1
+		}
+
+	case FinishModeStop:
+		if layer.Playhead > length || layer.Playhead < 0 {
+			layer.Playhead = clamp(layer.Playhead, 0, length)
+			layer.Playing = false
+		}
+
+	}
+
+}
+
+// updateLayers blends the AnimationPlayer's layers over the given pose, advancing their playheads by dt seconds.
+func (ap *AnimationPlayer) updateLayers(dt float64, pose map[INode]AnimationValues) {
+
+	for _, layer := range ap.Layers {
+
+		if layer.length() <= 0 {
+			continue
+		}
+
+		if layer.Weight > 0 {
+
+			if layer.pose == nil {
+				layer.pose = map[INode]AnimationValues{}
+			}
+
+			layer.sample(layer.Playhead, layer.pose)
+
+			var reference map[INode]AnimationValues
+			if layer.Additive {
+				reference = layer.referencePose()
+			}
+
+			for node, values := range layer.pose {
+
+				if !layer.masked(node) {
+					continue
+				}
+
+				below, exists := pose[node]
+				if !exists {
+					below = ap.restValues(node)
+				}
+
+				if layer.Additive {
+					pose[node] = addAnimationValues(below, values, reference[node], layer.Weight)
+				} else {
+					pose[node] = blendAnimationValues(below, values, layer.Weight)
+				}
+
+			}
+
+		}
+
+		layer.advance(dt)
+
+	}
+
+}
+
+// restValues returns the rest values of the node given; this is the pose layers blend over for nodes that aren't animated beneath them.
+// A node rests at its original local transform (from when it was instantiated in the Scene or cloned); nodes without an original transform
+// rest where they were when the AnimationPlayer's layers first animated them.
+func (ap *AnimationPlayer) restValues(node INode) AnimationValues {
+
+	if ap.restPose == nil {
+		ap.restPose = map[INode]AnimationValues{}
+	}
+
+	rest, exists := ap.restPose[node]
+
+	if !exists {
+
+		position, scale, rotation, original := node.originalLocalTransform()
+
+		if !original {
+			position, scale, rotation = node.LocalPosition(), node.LocalScale(), node.LocalRotation()
+		}
+
+		rest = AnimationValues{
+			Position:       position,
+			PositionExists: true,
+			Scale:          scale,
+			ScaleExists:    true,
+			Rotation:       rotation.ToQuaternion(),
+			RotationExists: true,
+		}
+
+		if model, ok := node.(*Model); ok && len(model.MorphWeights) > 0 {
+			rest.MorphWeights = append([]float64{}, model.MorphWeights...)
+			rest.MorphWeightsExists = true
+		}
+
+		ap.restPose[node] = rest
+
+	}
+
+	return rest
+
+}
+
+// nodeForChannel returns the node in the AnimationPlayer's tree that the channel with the given name animates; like the AnimationPlayer's
+// Animation, channels that don't match any node animate the root node.
+func (ap *AnimationPlayer) nodeForChannel(name string) INode {
+
+	if ap.nodesByName == nil {
+		ap.nodesByName = map[string]INode{}
+		nodes := ap.RootNode.SearchTree().INodes()
+		// Nodes are added in reverse so that the first node with a given name is used, as with the AnimationPlayer's Animation.
+		for i := len(nodes) - 1; i >= 0; i-- {
+			ap.nodesByName[nodes[i].Name()] = nodes[i]
+		}
+		ap.nodesByName[ap.RootNode.Name()] = ap.RootNode
+	}
+
+	if node, exists := ap.nodesByName[name]; exists {
+		return node
+	}
+
+	return ap.RootNode
+
+}
+
+// sampleAnimation fills the pose given with the values of the nodes animated by the Animation at the given time, emptying it first.
+func (ap *AnimationPlayer) sampleAnimation(animation *Animation, time float64, pose map[INode]AnimationValues) {
+
+	clearAnimationPose(pose)
+
+	for _, channel := range animation.Channels {
+
+		node := ap.nodeForChannel(channel.Name)
+		values := pose[node]
+		values.channel = channel
+
+		if track, exists := channel.Tracks[TrackTypePosition]; exists {
+			values.Position, values.PositionExists = track.ValueAsVector(time)
+		}
+
+		if track, exists := channel.Tracks[TrackTypeScale]; exists {
+			values.Scale, values.ScaleExists = track.ValueAsVector(time)
+		}
+
+		if track, exists := channel.Tracks[TrackTypeRotation]; exists {
+			values.Rotation, values.RotationExists = track.ValueAsQuaternion(time)
+		}
+
+		if track, exists := channel.Tracks[TrackTypeMorphWeights]; exists {
+			values.MorphWeights, values.MorphWeightsExists = track.ValueAsWeights(time)
+		}
+
+		pose[node] = values
+
+	}
+
+}
+
+// sampleBlendSpace fills the pose given with the values of the nodes animated by the BlendSpace's Animations, blended according to the
+// BlendSpace's Parameter, at the given playhead (ranging from 0 to 1 through each Animation). pointPose and totals are emptied and used
+// as scratch space.
+func (ap *AnimationPlayer) sampleBlendSpace(blendSpace *BlendSpace, playhead float64, pose, pointPose map[INode]AnimationValues, totals map[INode]float64) {
+
+	weights := blendSpace.Weights()
+
+	clearAnimationPose(pose)
+
+	for node := range totals {
+		delete(totals, node)
+	}
+
+	for i, point := range blendSpace.Points {
+
+		if weights[i] <= 0 || point.Animation == nil {
+			continue
+		}
+
+		ap.sampleAnimation(point.Animation, playhead*point.Animation.Length, pointPose)
+
+		for node, values := range pointPose {
+
+			total := totals[node] + weights[i]
+			totals[node] = total
+
+			// Each Animation's values are blended in according to their share of the total weight so far, giving a weighted average.
+			if existing, exists := pose[node]; exists {
+				pose[node] = blendAnimationValues(existing, values, weights[i]/total)
+			} else {
+				pose[node] = values
+			}
+
+		}
+
+	}
+
+}
+
+// clearAnimationPose empties the pose given, so it can be reused.
+func clearAnimationPose(pose map[INode]AnimationValues) {
+	for node := range pose {
+		delete(pose, node)
+	}
+}
+
+// blendAnimationValues blends from the values given to the target values by the percentage given. Values that only exist on one side are
+// taken from that side.
+func blendAnimationValues(from, to AnimationValues, percent float64) AnimationValues {
+
+	result := from
+
+	if to.PositionExists {
+		if from.PositionExists {
+			result.Position = from.Position.Add(to.Position.Sub(from.Position).Scale(percent))
+		} else {
+			result.Position = to.Position
+		}
+		result.PositionExists = true
+	}
+
+	if to.ScaleExists {
+		if from.ScaleExists {
+			result.Scale = from.Scale.Add(to.Scale.Sub(from.Scale).Scale(percent))
+		} else {
+			result.Scale = to.Scale
+		}
+		result.ScaleExists = true
+	}
+
+	if to.RotationExists {
+		if from.RotationExists {
+			result.Rotation = from.Rotation.Lerp(to.Rotation, percent).Normalized()
+		} else {
+			result.Rotation = to.Rotation
+		}
+		result.RotationExists = true
+	}
+
+	if to.MorphWeightsExists {
+		weights := make([]float64, len(to.MorphWeights))
+		for i := range weights {
+			start := to.MorphWeights[i]
+			if from.MorphWeightsExists && i < len(from.MorphWeights) {
+				start = from.MorphWeights[i]
+			}
+			weights[i] = start + (to.MorphWeights[i]-start)*percent
+		}
+		result.MorphWeights = weights
+		result.MorphWeightsExists = true
+	}
+
+	return result
+
+}
+
+// addAnimationValues adds the difference between the values given and the reference values onto the base values, scaled by the weight given.
+func addAnimationValues(base, values, reference AnimationValues, weight float64) AnimationValues {
+
+	result := base
+
+	if base.PositionExists && values.PositionExists && reference.PositionExists {
+		result.Position = base.Position.Add(values.Position.Sub(reference.Position).Scale(weight))
+	}
+
+	if base.ScaleExists && values.ScaleExists && reference.ScaleExists {
+		ratio := Vector{1, 1, 1, 0}
+		if reference.Scale.X != 0 {
+			ratio.X = values.Scale.X / reference.Scale.X
+		}
+		if reference.Scale.Y != 0 {
+			ratio.Y = values.Scale.Y / reference.Scale.Y
+		}
+		if reference.Scale.Z != 0 {
+			ratio.Z = values.Scale.Z / reference.Scale.Z
+		}
+		ratio = Vector{1, 1, 1, 0}.Add(ratio.Sub(Vector{1, 1, 1, 0}).Scale(weight))
+		result.Scale = base.Scale.Mult(ratio)
+	}
+
+	if base.RotationExists && values.RotationExists && reference.RotationExists {
+		delta := reference.Rotation.Inverted().Mult(values.Rotation)
+		delta = NewQuaternion(0, 0, 0, 1).Lerp(delta, weight).Normalized()
+		result.Rotation = base.Rotation.Mult(delta).Normalized()
+	}
+
+	if base.MorphWeightsExists && values.MorphWeightsExists && reference.MorphWeightsExists {
+		weights := append([]float64{}, base.MorphWeights...)
+		for i := range weights {
+			if i < len(values.MorphWeights) && i < len(reference.MorphWeights) {
+				weights[i] += (values.MorphWeights[i] - reference.MorphWeights[i]) * weight
+			}
+		}
+		result.MorphWeights = weights
+	}
+
+	return result
+
+}
+
+// BlendSpacePoint is an Animation placed in a BlendSpace.
+type BlendSpacePoint struct {
+	Animation *Animation
+	Position  Vector // The position of the Animation in the BlendSpace; only X is used for one-dimensional BlendSpaces.
+}
+
+// BlendSpace mixes Animations placed at points in a one- or two-dimensional space according to a parameter - for example, a one-dimensional
+// BlendSpace could mix walking and running Animations according to a character's speed, while a two-dimensional BlendSpace could mix
+// Animations for strafing in each direction according to the character's movement. A BlendSpace is played on an AnimationLayer using
+// AnimationLayer.PlayBlendSpace(). Its Animations play through together, so each Animation should be a cycle of the same motion (for example,
+// a walk and a run should both start on the same foot).
+type BlendSpace struct {
+	Points    []BlendSpacePoint
+	Parameter Vector // The parameter the Animations are mixed by; only X is used for one-dimensional BlendSpaces.
+	twoD      bool
+}
+
+// NewBlendSpace1D creates a new, empty one-dimensional BlendSpace. Animations are mixed according to the X value of the parameter.
+func NewBlendSpace1D() *BlendSpace {
+	return &BlendSpace{}
+}
+
+// NewBlendSpace2D creates a new, empty two-dimensional BlendSpace. Animations are mixed according to the X and Y values of the parameter.
+func NewBlendSpace2D() *BlendSpace {
+	return &BlendSpace{twoD: true}
+}
+
+// AddPoint places the Animation given at the given position in the BlendSpace (y is ignored for one-dimensional BlendSpaces).
+func (bs *BlendSpace) AddPoint(animation *Animation, x, y float64) {
+	bs.Points = append(bs.Points, BlendSpacePoint{Animation: animation, Position: Vector{x, y, 0, 0}})
+}
+
+// SetParameter sets the parameter that the BlendSpace mixes its Animations by (y is ignored for one-dimensional BlendSpaces).
+func (bs *BlendSpace) SetParameter(x, y float64) {
+	bs.Parameter.X = x
+	bs.Parameter.Y = y
+}
+
+// Weights returns the weights of each of the BlendSpace's Points according to its Parameter, adding up to 1.
+// One-dimensional BlendSpaces mix the two Points to either side of the parameter, holding the nearest Point past the ends.
+// Two-dimensional BlendSpaces mix their Points using gradient band interpolation, so each Point fully plays at its position, and Points
+// are mixed by how close the parameter is to each of them relative to the others.
+func (bs *BlendSpace) Weights() []float64 {
+
+	weights := make([]float64, len(bs.Points))
+
+	if len(bs.Points) == 0 {
+		return weights
+	}
+
+	if !bs.twoD {
+
+		order := make([]int, len(bs.Points))
+		for i := range order {
+			order[i] = i
+		}
+		sort.SliceStable(order, func(i, j int) bool { return bs.Points[order[i]].Position.X < bs.Points[order[j]].Position.X })
+
+		x := bs.Parameter.X
+
+		if x <= bs.Points[order[0]].Position.X {
+			weights[order[0]] = 1
+			return weights
+		}
+
+		for i := 0; i < len(order)-1; i++ {
+			start, end := bs.Points[order[i]].Position.X, bs.Points[order[i+1]].Position.X
+			if x <= end {
+				t := 1.0
+				if end > start {
+					t = (x - start) / (end - start)
+				}
+				weights[order[i]] = 1 - t
+				weights[order[i+1]] = t
+				return weights
+			}
+		}
+
+		weights[order[len(order)-1]] = 1
+		return weights
+
+	}
+
+	param := Vector{bs.Parameter.X, bs.Parameter.Y, 0, 0}
+	total := 0.0
+
+	for i, point := range bs.Points {
+
+		weight := 1.0
+		pi := Vector{point.Position.X, point.Position.Y, 0, 0}
+
+		for j, other := range bs.Points {
+			if i == j {
+				continue
+			}
+			pj := Vector{other.Position.X, other.Position.Y, 0, 0}
+			diff := pj.Sub(pi)
+			if lengthSquared := diff.Dot(diff); lengthSquared > 0 {
+				weight = math.Min(weight, 1-param.Sub(pi).Dot(diff)/lengthSquared)
+			}
+		}
+
+		weights[i] = math.Max(weight, 0)
+		total += weights[i]
+
+	}
+
+	if total > 0 {
+		for i := range weights {
+			weights[i] /= total
+		}
+	}
+
+	return weights
+
+}
+
+// length returns the length of the BlendSpace's Animations, weighted by how much each contributes.
+func (bs *BlendSpace) length() float64 {
+
+	length := 0.0
+
+	for i, weight := range bs.Weights() {
+		if anim := bs.Points[i].Animation; anim != nil {
+			length += anim.Length * weight
+		}
+	}
+
+	if length <= 0 {
+		return 1
+	}
+
+	return length
+
+}
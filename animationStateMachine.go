@@ -0,0 +1,564 @@
+package tetra3d
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// AnimationState is a state of an AnimationStateMachine, playing an Animation while the state machine is in it.
+type AnimationState struct {
+	Name        string
+	Animation   *Animation
+	PlaySpeed   float64    // Playback speed of the Animation in percentage - defaults to 1 (100%)
+	FinishMode  FinishMode // What to do when the Animation finishes playback. Defaults to looping.
+	Transitions []*AnimationTransition
+}
+
+// AddTransition adds a transition from the AnimationState to the state with the given name, crossfading between their Animations over the
+// duration given in seconds. Without any conditions or an exit time, the transition is taken immediately.
+func (state *AnimationState) AddTransition(to string, duration float64) *AnimationTransition {
+	transition := &AnimationTransition{To: to, Duration: duration}
+	state.Transitions = append(state.Transitions, transition)
+	return transition
+}
+
+// Condition comparisons for AnimationConditions.
+const (
+	ConditionEqual        = "=="
+	ConditionNotEqual     = "!="
+	ConditionGreater      = ">"
+	ConditionGreaterEqual = ">="
+	ConditionLess         = "<"
+	ConditionLessEqual    = "<="
+	ConditionMarker       = "marker"  // The condition passes when the Animation playing touches the Marker named by the condition's Name
+	ConditionTrigger      = "trigger" // The condition passes when the trigger named by the condition's Name is set; taking the transition resets it
+)
+
+// AnimationCondition is a condition that must pass for an AnimationTransition to be taken. Conditions compare a parameter of the
+// AnimationStateMachine against a value, or check for touched Markers or set triggers.
+type AnimationCondition struct {
+	Name       string  // The name of the parameter, Marker, or trigger checked.
+	Comparison string  // How the parameter is compared against the Value (i.e. ConditionGreater), or ConditionMarker or ConditionTrigger.
+	Value      float64 // The value the parameter is compared against.
+}
+
+// AnimationTransition is a transition from one AnimationState to another, taken once all of its conditions pass.
+type AnimationTransition struct {
+	To         string  // The name of the state transitioned to.
+	Duration   float64 // How long the Animations crossfade for in seconds.
+	Conditions []AnimationCondition
+
+	// If HasExitTime is true, the transition can only be taken once the Animation playing has passed its ExitTime, ranging from 0 (the start
+	// of the Animation) to 1 (the end of the Animation, or when it loops). A transition with an exit time and no conditions is taken as soon as
+	// the exit time passes.
+	HasExitTime bool
+	ExitTime    float64
+}
+
+// When adds a condition to the transition comparing the parameter with the given name against a value, returning the transition.
+// For example, transition.When("speed", ConditionGreater, 0.1).
+func (transition *AnimationTransition) When(param, comparison string, value float64) *AnimationTransition {
+	transition.Conditions = append(transition.Conditions, AnimationCondition{Name: param, Comparison: comparison, Value: value})
+	return transition
+}
+
+// WhenMarker adds a condition to the transition that passes when the Animation playing touches the Marker with the given name, returning the
+// transition.
+func (transition *AnimationTransition) WhenMarker(marker string) *AnimationTransition {
+	transition.Conditions = append(transition.Conditions, AnimationCondition{Name: marker, Comparison: ConditionMarker})
+	return transition
+}
+
+// WhenTrigger adds a condition to the transition that passes when the trigger with the given name is set (see
+// AnimationStateMachine.SetTrigger()), returning the transition.
+func (transition *AnimationTransition) WhenTrigger(trigger string) *AnimationTransition {
+	transition.Conditions = append(transition.Conditions, AnimationCondition{Name: trigger, Comparison: ConditionTrigger})
+	return transition
+}
+
+// AtExitTime sets the exit time of the transition (ranging from 0 to 1 through the Animation playing), returning the transition.
+func (transition *AnimationTransition) AtExitTime(exitTime float64) *AnimationTransition {
+	transition.HasExitTime = true
+	transition.ExitTime = exitTime
+	return transition
+}
+
+// AnimationStateMachine plays Animations on an AnimationPlayer according to states, transitioning between them as their conditions pass.
+// Conditions check parameters that are set on the state machine (like a character's speed or if they're on the ground), triggers (like
+// attacking), Markers touched in the Animation playing, and how far the Animation has played. Transitions crossfade using the
+// AnimationPlayer's BlendTime, so the state machine sets the BlendTime as it transitions.
+type AnimationStateMachine struct {
+	Player *AnimationPlayer
+	States map[string]*AnimationState
+
+	// AnyTransitions are transitions that can be taken from any state (other than the state they transition to), before the current state's
+	// transitions.
+	AnyTransitions []*AnimationTransition
+
+	StartState string          // The name of the state the state machine starts in; defaults to the first state added.
+	Current    *AnimationState // The current state.
+	Previous   *AnimationState // The state before the current state.
+	StateTime  float64         // How long the state machine has been in the current state, in seconds.
+
+	OnEnter func(state *AnimationState) // Callback indicating the state machine has entered a state
+	OnLeave func(state *AnimationState) // Callback indicating the state machine has left a state
+
+	params   map[string]float64
+	triggers map[string]bool
+	entered  []string
+	left     []string
+}
+
+// NewAnimationStateMachine creates a new AnimationStateMachine without any states, playing Animations on the given AnimationPlayer.
+func NewAnimationStateMachine(player *AnimationPlayer) *AnimationStateMachine {
+	return &AnimationStateMachine{
+		Player:   player,
+		States:   map[string]*AnimationState{},
+		params:   map[string]float64{},
+		triggers: map[string]bool{},
+	}
+}
+
+// AddState adds a state with the given name that plays the Animation given, returning it.
+func (sm *AnimationStateMachine) AddState(name string, animation *Animation) *AnimationState {
+
+	state := &AnimationState{
+		Name:       name,
+		Animation:  animation,
+		PlaySpeed:  1,
+		FinishMode: FinishModeLoop,
+	}
+
+	if len(sm.States) == 0 && sm.StartState == "" {
+		sm.StartState = name
+	}
+
+	sm.States[name] = state
+
+	return state
+
+}
+
+// State returns the state with the given name, or nil if the state machine has no state by that name.
+func (sm *AnimationStateMachine) State(name string) *AnimationState {
+	return sm.States[name]
+}
+
+// AddAnyTransition adds a transition from any state to the state with the given name, crossfading between their Animations over the
+// duration given in seconds.
+func (sm *AnimationStateMachine) AddAnyTransition(to string, duration float64) *AnimationTransition {
+	transition := &AnimationTransition{To: to, Duration: duration}
+	sm.AnyTransitions = append(sm.AnyTransitions, transition)
+	return transition
+}
+
+// SetParam sets the parameter with the given name to the value given.
+func (sm *AnimationStateMachine) SetParam(name string, value float64) {
+	sm.params[name] = value
+}
+
+// SetBool sets the parameter with the given name to 1 if the value is true, or 0 otherwise.
+func (sm *AnimationStateMachine) SetBool(name string, value bool) {
+	if value {
+		sm.params[name] = 1
+	} else {
+		sm.params[name] = 0
+	}
+}
+
+// Param returns the value of the parameter with the given name; parameters that haven't been set are 0.
+func (sm *AnimationStateMachine) Param(name string) float64 {
+	return sm.params[name]
+}
+
+// SetTrigger sets the trigger with the given name. The trigger stays set until a transition with a condition checking it is taken.
+func (sm *AnimationStateMachine) SetTrigger(name string) {
+	sm.triggers[name] = true
+}
+
+// ResetTrigger resets the trigger with the given name.
+func (sm *AnimationStateMachine) ResetTrigger(name string) {
+	delete(sm.triggers, name)
+}
+
+// SetState immediately enters the state with the given name without crossfading, returning an error if the state doesn't exist.
+func (sm *AnimationStateMachine) SetState(name string) error {
+	state, exists := sm.States[name]
+	if !exists {
+		return errors.New("Animation state named {" + name + "} not found in state machine")
+	}
+	sm.enter(state, 0)
+	return nil
+}
+
+// Update checks the transitions of the current state (entering the start state if the state machine isn't in one yet), and then updates the
+// AnimationPlayer by the delta specified in seconds (usually 1/FPS or 1/TARGET FPS). At most one transition is taken per update.
+func (sm *AnimationStateMachine) Update(dt float64) {
+
+	sm.entered = sm.entered[:0]
+	sm.left = sm.left[:0]
+
+	if sm.Current == nil {
+		if start, exists := sm.States[sm.StartState]; exists {
+			sm.enter(start, 0)
+		}
+	} else {
+
+		var taken *AnimationTransition
+
+		for _, transition := range sm.AnyTransitions {
+			if transition.To != sm.Current.Name && sm.passes(transition) {
+				taken = transition
+				break
+			}
+		}
+
+		if taken == nil {
+			for _, transition := range sm.Current.Transitions {
+				if sm.passes(transition) {
+					taken = transition
+					break
+				}
+			}
+		}
+
+		if taken != nil {
+
+			for _, condition := range taken.Conditions {
+				if condition.Comparison == ConditionTrigger {
+					delete(sm.triggers, condition.Name)
+				}
+			}
+
+			if next, exists := sm.States[taken.To]; exists {
+				sm.enter(next, taken.Duration)
+			}
+
+		}
+
+	}
+
+	sm.StateTime += dt
+	sm.Player.Update(dt)
+
+}
+
+// enter leaves the current state and enters the state given, crossfading to its Animation over the duration given.
+func (sm *AnimationStateMachine) enter(state *AnimationState, duration float64) {
+
+	if sm.Current != nil {
+		sm.left = append(sm.left, sm.Current.Name)
+		if sm.OnLeave != nil {
+			sm.OnLeave(sm.Current)
+		}
+	}
+
+	sm.Previous = sm.Current
+	sm.Current = state
+	sm.StateTime = 0
+
+	player := sm.Player
+
+	// The AnimationPlayer only keeps track of the pose it blends from while its BlendTime is above 0, so when the state being left was
+	// entered without crossfading, the player is given the pose it last animated to crossfade from.
+	if duration > 0 && len(player.currentProperties) == 0 {
+		for node, values := range player.AnimatedProperties {
+			player.currentProperties[node] = values
+		}
+	}
+
+	player.BlendTime = duration
+	player.PlaySpeed = state.PlaySpeed
+	player.FinishMode = state.FinishMode
+
+	if state.Animation != nil {
+		// Re-entering a state restarts its Animation.
+		if player.Animation == state.Animation {
+			player.Playing = false
+		}
+		player.PlayAnim(state.Animation)
+	} else {
+		player.Stop()
+	}
+
+	sm.entered = append(sm.entered, state.Name)
+	if sm.OnEnter != nil {
+		sm.OnEnter(state)
+	}
+
+}
+
+// passes returns if the transition can be taken.
+func (sm *AnimationStateMachine) passes(transition *AnimationTransition) bool {
+
+	if transition.HasExitTime {
+
+		anim := sm.Current.Animation
+		progress := 1.0
+
+		if anim != nil && anim.Length > 0 {
+			progress = sm.Player.Playhead / anim.Length
+			if sm.Player.PlaySpeed < 0 {
+				progress = 1 - progress
+			}
+		}
+
+		// Finishing the Animation (including when it loops) passes any exit time.
+		if progress < transition.ExitTime && !(sm.StateTime > 0 && sm.Player.Finished()) {
+			return false
+		}
+
+	}
+
+	for _, condition := range transition.Conditions {
+		if !sm.conditionPasses(condition) {
+			return false
+		}
+	}
+
+	return true
+
+}
+
+func (sm *AnimationStateMachine) conditionPasses(condition AnimationCondition) bool {
+
+	value := sm.params[condition.Name]
+
+	switch condition.Comparison {
+	case ConditionEqual:
+		return value == condition.Value
+	case ConditionNotEqual:
+		return value != condition.Value
+	case ConditionGreater:
+		return value > condition.Value
+	case ConditionGreaterEqual:
+		return value >= condition.Value
+	case ConditionLess:
+		return value < condition.Value
+	case ConditionLessEqual:
+		return value <= condition.Value
+	case ConditionMarker:
+		return sm.StateTime > 0 && sm.Player.TouchedMarker(condition.Name)
+	case ConditionTrigger:
+		return sm.triggers[condition.Name]
+	}
+
+	return false
+
+}
+
+// EnteredState returns if the state machine entered the state with the given name in the last update.
+func (sm *AnimationStateMachine) EnteredState(name string) bool {
+	for _, n := range sm.entered {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// LeftState returns if the state machine left the state with the given name in the last update.
+func (sm *AnimationStateMachine) LeftState(name string) bool {
+	for _, n := range sm.left {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// InState returns if the state machine's current state has the given name.
+func (sm *AnimationStateMachine) InState(name string) bool {
+	return sm.Current != nil && sm.Current.Name == name
+}
+
+// LoadAnimationStateMachine creates an AnimationStateMachine for the node's AnimationPlayer from the node's Properties (i.e. the game
+// properties of an armature exported from Blender), using the Animations in the node's Library:
+//
+// "anim_state:<State>" properties add states, with a value of the name of the Animation to play, optionally followed by options
+// separated by semicolons - "once" (to stop at the end of the Animation), "pingpong", or "speed <percentage>". For example,
+// "anim_state:Attack" = "Slash; once; speed 1.5".
+//
+// "anim_transition:<From>><To>" properties add transitions, with a value of the transition's duration, exit time, and conditions separated by
+// semicolons - "duration <seconds>", "exit <0 to 1>", "marker <name>", "trigger <name>", "<param> <comparison> <value>", "<param>" (true),
+// or "!<param>" (false). For example, "anim_transition:Idle>Run" = "duration 0.2; speed > 0.1; grounded". A <From> state of "*" adds a transition
+// from any state.
+//
+// An "anim_start" property sets the start state; otherwise, the start state is the first state alphabetically.
+func LoadAnimationStateMachine(node INode) (*AnimationStateMachine, error) {
+
+	sm := NewAnimationStateMachine(node.AnimationPlayer())
+	props := node.Properties()
+
+	var animations map[string]*Animation
+	if lib := node.Library(); lib != nil {
+		animations = lib.Animations
+	}
+
+	names := make([]string, 0, len(props.props))
+	for name := range props.props {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+
+		if !strings.HasPrefix(name, "anim_state:") {
+			continue
+		}
+
+		if !props.Get(name).IsString() {
+			return nil, errors.New("State property {" + name + "} should be the name of an Animation")
+		}
+
+		options := strings.Split(props.Get(name).AsString(), ";")
+
+		animName := strings.TrimSpace(options[0])
+		anim, exists := animations[animName]
+		if !exists {
+			return nil, errors.New("Animation named {" + animName + "} for state property {" + name + "} not found in node's owning Library")
+		}
+
+		state := sm.AddState(strings.TrimPrefix(name, "anim_state:"), anim)
+
+		for _, option := range options[1:] {
+
+			fields := strings.Fields(option)
+
+			switch {
+			case len(fields) == 0:
+			case fields[0] == "once":
+				state.FinishMode = FinishModeStop
+			case fields[0] == "pingpong":
+				state.FinishMode = FinishModePingPong
+			case fields[0] == "speed" && len(fields) == 2:
+				speed, err := strconv.ParseFloat(fields[1], 64)
+				if err != nil {
+					return nil, errors.New("Invalid speed in state property {" + name + "}: " + err.Error())
+				}
+				state.PlaySpeed = speed
+			default:
+				return nil, errors.New("Unknown option {" + strings.TrimSpace(option) + "} in state property {" + name + "}")
+			}
+
+		}
+
+	}
+
+	if props.Has("anim_start") && props.Get("anim_start").IsString() {
+		sm.StartState = props.Get("anim_start").AsString()
+		if sm.State(sm.StartState) == nil {
+			return nil, errors.New("Start state {" + sm.StartState + "} not found in state machine")
+		}
+	}
+
+	for _, name := range names {
+
+		if !strings.HasPrefix(name, "anim_transition:") {
+			continue
+		}
+
+		ends := strings.SplitN(strings.TrimPrefix(name, "anim_transition:"), ">", 2)
+		if len(ends) != 2 {
+			return nil, errors.New("Transition property {" + name + "} should be named \"anim_transition:<From>><To>\"")
+		}
+
+		from, to := ends[0], ends[1]
+
+		if sm.State(to) == nil {
+			return nil, errors.New("State {" + to + "} for transition property {" + name + "} not found in state machine")
+		}
+
+		var transition *AnimationTransition
+
+		if from == "*" {
+			transition = sm.AddAnyTransition(to, 0)
+		} else if state := sm.State(from); state != nil {
+			transition = state.AddTransition(to, 0)
+		} else {
+			return nil, errors.New("State {" + from + "} for transition property {" + name + "} not found in state machine")
+		}
+
+		if props.Get(name).IsString() {
+			if err := parseAnimationTransition(transition, props.Get(name).AsString()); err != nil {
+				return nil, errors.New("Invalid transition property {" + name + "}: " + err.Error())
+			}
+		}
+
+	}
+
+	return sm, nil
+
+}
+
+// parseAnimationTransition parses the semicolon-separated duration, exit time, and conditions given onto the transition.
+func parseAnimationTransition(transition *AnimationTransition, definition string) error {
+
+	for _, clause := range strings.Split(definition, ";") {
+
+		fields := strings.Fields(clause)
+
+		if len(fields) == 0 {
+			continue
+		}
+
+		number := func(str string) (float64, error) {
+			value, err := strconv.ParseFloat(str, 64)
+			if err != nil {
+				return 0, errors.New("invalid number {" + str + "}")
+			}
+			return value, nil
+		}
+
+		switch {
+
+		case len(fields) == 2 && fields[0] == "duration":
+			duration, err := number(fields[1])
+			if err != nil {
+				return err
+			}
+			transition.Duration = duration
+
+		case len(fields) == 2 && fields[0] == "exit":
+			exitTime, err := number(fields[1])
+			if err != nil {
+				return err
+			}
+			transition.AtExitTime(exitTime)
+
+		case len(fields) == 2 && fields[0] == "marker":
+			transition.WhenMarker(fields[1])
+
+		case len(fields) == 2 && fields[0] == "trigger":
+			transition.WhenTrigger(fields[1])
+
+		case len(fields) == 3:
+			switch fields[1] {
+			case ConditionEqual, ConditionNotEqual, ConditionGreater, ConditionGreaterEqual, ConditionLess, ConditionLessEqual:
+			default:
+				return errors.New("unknown comparison {" + fields[1] + "}")
+			}
+			value, err := number(fields[2])
+			if err != nil {
+				return err
+			}
+			transition.When(fields[0], fields[1], value)
+
+		case len(fields) == 1 && strings.HasPrefix(fields[0], "!"):
+			transition.When(strings.TrimPrefix(fields[0], "!"), ConditionEqual, 0)
+
+		case len(fields) == 1:
+			transition.When(fields[0], ConditionNotEqual, 0)
+
+		default:
+			return errors.New("unknown clause {" + strings.TrimSpace(clause) + "}")
+
+		}
+
+	}
+
+	return nil
+
+}
@@ -0,0 +1,172 @@
+package tetra3d
+
+import (
+	"testing"
+)
+
+func TestAnimationStateMachine(t *testing.T) {
+
+	body := NewNode("Body")
+	arm := NewNode("Arm")
+	body.AddChildren(arm)
+
+	idle := constantPositionAnimation("Idle", 1, map[string]Vector{"Arm": {0, 0, 0, 0}})
+	run := constantPositionAnimation("Run", 1, map[string]Vector{"Arm": {1, 0, 0, 0}})
+	attack := constantPositionAnimation("Attack", 1, map[string]Vector{"Arm": {0, 1, 0, 0}})
+
+	sm := NewAnimationStateMachine(NewAnimationPlayer(body))
+	sm.AddState("Idle", idle).AddTransition("Run", 0).When("speed", ConditionGreater, 0.1)
+	sm.AddState("Run", run).AddTransition("Idle", 0).When("speed", ConditionLessEqual, 0.1)
+	sm.AddState("Attack", attack).FinishMode = FinishModeStop
+	sm.State("Attack").AddTransition("Idle", 0).AtExitTime(1)
+	sm.AddAnyTransition("Attack", 0).WhenTrigger("attack")
+
+	entered := []string{}
+	sm.OnEnter = func(state *AnimationState) { entered = append(entered, state.Name) }
+
+	sm.Update(0.1)
+
+	if !sm.InState("Idle") || !sm.EnteredState("Idle") || sm.Player.Animation != idle {
+		t.Fatalf("state machine should start in the first state added")
+	}
+
+	sm.SetParam("speed", 1)
+	sm.Update(0.1)
+
+	if !sm.InState("Run") || !sm.LeftState("Idle") || !sm.EnteredState("Run") {
+		t.Fatalf("state machine should transition to Run when speed is set")
+	}
+
+	if !arm.LocalPosition().Equals(Vector{1, 0, 0, 0}) {
+		t.Fatalf("arm should be posed by the Run animation, but is at %s", arm.LocalPosition())
+	}
+
+	sm.Update(0.1)
+
+	if sm.EnteredState("Run") {
+		t.Fatalf("entered states should only be reported for the update the state was entered in")
+	}
+
+	sm.SetTrigger("attack")
+	sm.Update(0.1)
+
+	if !sm.InState("Attack") || sm.Previous.Name != "Run" {
+		t.Fatalf("trigger should transition to Attack from any state")
+	}
+
+	if sm.triggers["attack"] {
+		t.Fatalf("taking the transition should reset its trigger")
+	}
+
+	// The Attack state shouldn't be left until its Animation finishes, even though speed would pass the condition to go to Run from Idle.
+	sm.SetParam("speed", 0)
+	sm.Update(0.5)
+
+	if !sm.InState("Attack") {
+		t.Fatalf("Attack state shouldn't be left before its exit time")
+	}
+
+	sm.Update(0.6)
+	sm.Update(0.1)
+
+	if !sm.InState("Idle") {
+		t.Fatalf("Attack state should transition to Idle after finishing, but is in %s", sm.Current.Name)
+	}
+
+	expected := []string{"Idle", "Run", "Attack", "Idle"}
+	if len(entered) != len(expected) {
+		t.Fatalf("expected OnEnter for %v, got %v", expected, entered)
+	}
+	for i := range expected {
+		if entered[i] != expected[i] {
+			t.Fatalf("expected OnEnter for %v, got %v", expected, entered)
+		}
+	}
+
+	if err := sm.SetState("Swim"); err == nil {
+		t.Fatalf("setting a state that doesn't exist should return an error")
+	}
+
+}
+
+func TestAnimationStateMachineCrossfade(t *testing.T) {
+
+	body := NewNode("Body")
+	arm := NewNode("Arm")
+	body.AddChildren(arm)
+
+	idle := constantPositionAnimation("Idle", 1, map[string]Vector{"Arm": {0, 0, 0, 0}})
+	run := constantPositionAnimation("Run", 1, map[string]Vector{"Arm": {1, 0, 0, 0}})
+
+	// Idle is entered without crossfading, so the AnimationPlayer doesn't track the pose it blends from until the transition to Run.
+	sm := NewAnimationStateMachine(NewAnimationPlayer(body))
+	sm.AddState("Idle", idle).AddTransition("Run", 100).When("speed", ConditionGreater, 0.1)
+	sm.AddState("Run", run)
+
+	sm.Update(0.1)
+	sm.SetParam("speed", 1)
+	sm.Update(0.1)
+
+	if !sm.InState("Run") {
+		t.Fatalf("state machine should transition to Run when speed is set")
+	}
+
+	if pos := arm.LocalPosition(); pos.X > 0.01 {
+		t.Fatalf("arm should start crossfading from the Idle pose, but is at %s", pos)
+	}
+
+}
+
+func TestLoadAnimationStateMachine(t *testing.T) {
+
+	lib := NewLibrary()
+	lib.Animations["Idle"] = constantPositionAnimation("Idle", 1, map[string]Vector{"Arm": {0, 0, 0, 0}})
+	lib.Animations["Jump"] = constantPositionAnimation("Jump", 1, map[string]Vector{"Arm": {0, 1, 0, 0}})
+
+	armature := NewNode("Armature")
+	armature.setLibrary(lib)
+
+	props := armature.Properties()
+	props.Get("anim_state:Idle").Set("Idle")
+	props.Get("anim_state:Jump").Set("Jump; once; speed 2")
+	props.Get("anim_start").Set("Idle")
+	props.Get("anim_transition:Idle>Jump").Set("duration 0.25; !grounded; vy > 1")
+	props.Get("anim_transition:*>Idle").Set("exit 0.9; grounded")
+
+	sm, err := LoadAnimationStateMachine(armature)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jump := sm.State("Jump")
+	if jump == nil || jump.FinishMode != FinishModeStop || jump.PlaySpeed != 2 {
+		t.Fatalf("Jump state wasn't loaded properly")
+	}
+
+	transitions := sm.State("Idle").Transitions
+	if len(transitions) != 1 || transitions[0].To != "Jump" || transitions[0].Duration != 0.25 || len(transitions[0].Conditions) != 2 {
+		t.Fatalf("Idle > Jump transition wasn't loaded properly")
+	}
+
+	if c := transitions[0].Conditions[0]; c.Name != "grounded" || c.Comparison != ConditionEqual || c.Value != 0 {
+		t.Fatalf("\"!grounded\" should be loaded as a condition that grounded is 0, got %v", c)
+	}
+
+	if len(sm.AnyTransitions) != 1 || !sm.AnyTransitions[0].HasExitTime || sm.AnyTransitions[0].ExitTime != 0.9 {
+		t.Fatalf("any state transition wasn't loaded properly")
+	}
+
+	props.Get("anim_transition:Idle>Swim").Set("")
+
+	if _, err := LoadAnimationStateMachine(armature); err == nil {
+		t.Fatalf("loading a transition to a state that doesn't exist should return an error")
+	}
+
+	props.Remove("anim_transition:Idle>Swim")
+	props.Get("anim_transition:Idle>Jump").Set("vy ~ 1")
+
+	if _, err := LoadAnimationStateMachine(armature); err == nil {
+		t.Fatalf("loading a transition with an unknown comparison should return an error")
+	}
+
+}
@@ -0,0 +1,394 @@
+package tetra3d
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestCubicInterpolation(t *testing.T) {
+
+	track := newAnimationTrack(TrackTypePosition)
+	track.Interpolation = InterpolationCubic
+	track.AddKeyframeCubic(0, Vector{0, 0, 0, 0}, Vector{}, Vector{1, 0, 0, 0})
+	track.AddKeyframeCubic(1, Vector{1, 0, 0, 0}, Vector{}, Vector{})
+
+	tests := []struct {
+		time     float64
+		expected float64
+	}{
+		{0, 0},
+		{0.5, 0.625},
+		{1, 1},
+	}
+
+	for _, test := range tests {
+		v, _ := track.ValueAsVector(test.time)
+		if math.Abs(v.X-test.expected) > 0.0001 {
+			t.Fatalf("cubic interpolation at %f gave %f, expected %f", test.time, v.X, test.expected)
+		}
+	}
+
+	// Keyframes without tangents should ease in and out
+	track = newAnimationTrack(TrackTypePosition)
+	track.Interpolation = InterpolationCubic
+	track.AddKeyframe(0, Vector{0, 0, 0, 0})
+	track.AddKeyframe(1, Vector{1, 0, 0, 0})
+
+	if v, _ := track.ValueAsVector(0.25); math.Abs(v.X-0.15625) > 0.0001 {
+		t.Fatalf("cubic interpolation without tangents gave %f, expected %f", v.X, 0.15625)
+	}
+
+	rotTrack := newAnimationTrack(TrackTypeRotation)
+	rotTrack.Interpolation = InterpolationCubic
+	rotTrack.AddKeyframe(0, NewQuaternionFromAxisAngle(Vector{0, 1, 0, 0}, 0))
+	rotTrack.AddKeyframe(1, NewQuaternionFromAxisAngle(Vector{0, 1, 0, 0}, math.Pi/2))
+
+	if q, _ := rotTrack.ValueAsQuaternion(0.5); math.Abs(q.Magnitude()-1) > 0.0001 {
+		t.Fatalf("cubic quaternion interpolation isn't normalized; magnitude is %f", q.Magnitude())
+	}
+
+}
+
+func TestMorphWeightAnimation(t *testing.T) {
+
+	mesh := NewCubeMesh()
+	target := mesh.AddMorphTarget("Stretch")
+	for i := range target.PositionDeltas {
+		target.PositionDeltas[i] = Vector{0, 1, 0, 0}
+	}
+
+	model := NewModel(mesh, "Cube")
+
+	anim := NewAnimation("Stretching")
+	track := anim.AddChannel("Cube").AddTrack(TrackTypeMorphWeights)
+	track.AddKeyframe(0, []float64{0})
+	track.AddKeyframe(1, []float64{1})
+	anim.Length = 1
+
+	player := NewAnimationPlayer(model)
+	player.PlayAnim(anim)
+	player.SetPlayhead(0.5)
+
+	if weight := model.MorphWeight("Stretch"); math.Abs(weight-0.5) > 0.0001 {
+		t.Fatalf("morph weight animated to %f, expected 0.5", weight)
+	}
+
+	model.updateMorphState()
+	pos, _ := model.morphVertex(0)
+	if expected := mesh.VertexPositions[0].Y + 0.5; math.Abs(pos.Y-expected) > 0.0001 {
+		t.Fatalf("morphed vertex Y is %f, expected %f", pos.Y, expected)
+	}
+
+}
+
+// constantPositionAnimation creates an Animation that holds each named channel at the given position.
+func constantPositionAnimation(name string, length float64, positions map[string]Vector) *Animation {
+	anim := NewAnimation(name)
+	for channel, pos := range positions {
+		track := anim.AddChannel(channel).AddTrack(TrackTypePosition)
+		track.AddKeyframe(0, pos)
+		track.AddKeyframe(length, pos)
+	}
+	anim.Length = length
+	return anim
+}
+
+func TestAnimationLayers(t *testing.T) {
+
+	body := NewNode("Body")
+	spine := NewNode("Spine")
+	arm := NewNode("Arm")
+	leg := NewNode("Leg")
+	body.AddChildren(spine, leg)
+	spine.AddChildren(arm)
+
+	run := constantPositionAnimation("Run", 1, map[string]Vector{"Arm": {1, 0, 0, 0}, "Leg": {1, 0, 0, 0}})
+	attack := constantPositionAnimation("Attack", 1, map[string]Vector{"Arm": {0, 3, 0, 0}, "Leg": {0, 5, 0, 0}})
+
+	player := NewAnimationPlayer(body)
+	player.PlayAnim(run)
+
+	upper := player.AddLayer("Upper Body")
+	upper.SetMask(spine)
+	upper.Weight = 0.5
+	upper.PlayAnim(attack)
+
+	player.Update(0.1)
+
+	if pos := arm.LocalPosition(); !pos.Equals(Vector{0.5, 1.5, 0, 0}) {
+		t.Fatalf("arm is at %s, expected it halfway between running and attacking", pos)
+	}
+
+	if pos := leg.LocalPosition(); !pos.Equals(Vector{1, 0, 0, 0}) {
+		t.Fatalf("leg is at %s, expected the mask to leave it running", pos)
+	}
+
+	// An additive layer adds its motion since its first frame onto the pose beneath it.
+	breathe := NewAnimation("Breathe")
+	track := breathe.AddChannel("Arm").AddTrack(TrackTypePosition)
+	track.AddKeyframe(0, Vector{0, 0, 0, 0})
+	track.AddKeyframe(1, Vector{0, 2, 0, 0})
+	turn := breathe.AddChannel("Leg").AddTrack(TrackTypeRotation)
+	turn.AddKeyframe(0, NewQuaternionFromAxisAngle(WorldUp, 0))
+	turn.AddKeyframe(1, NewQuaternionFromAxisAngle(WorldUp, math.Pi/2))
+	breathe.Length = 1
+
+	upper.Weight = 0
+	additive := player.AddLayer("Breathing")
+	additive.Additive = true
+	additive.PlayAnim(breathe)
+	additive.Playhead = 0.5
+
+	leg.SetLocalRotation(NewMatrix4Rotate(0, 1, 0, math.Pi/4))
+	run.Channels["Leg"].AddTrack(TrackTypeRotation).AddKeyframe(0, NewQuaternionFromAxisAngle(WorldUp, math.Pi/4))
+
+	player.Update(0.1)
+
+	if pos := arm.LocalPosition(); !pos.Equals(Vector{1, 1, 0, 0}) {
+		t.Fatalf("arm is at %s, expected the additive layer to raise it by 1", pos)
+	}
+
+	if !leg.LocalRotation().Equals(NewMatrix4Rotate(0, 1, 0, math.Pi/2)) {
+		t.Fatalf("leg's rotation is %s, expected the additive layer to turn it a further 45 degrees", leg.LocalRotation())
+	}
+
+	// Layers play even without the AnimationPlayer's Animation, blending over the nodes' rest pose.
+	player.RemoveLayer("Breathing")
+	player.Stop()
+	upper.Weight = 1
+	upper.SetMask()
+
+	player.Update(0.1)
+
+	if pos := leg.LocalPosition(); !pos.Equals(Vector{0, 5, 0, 0}) {
+		t.Fatalf("leg is at %s, expected the unmasked layer to animate it", pos)
+	}
+
+	clone := player.Clone()
+	if len(clone.Layers) != 1 || clone.Layers[0].Animation != attack || clone.Layers[0].player != clone {
+		t.Fatalf("layers weren't cloned with the AnimationPlayer")
+	}
+
+}
+
+func TestAnimationLayerRestPose(t *testing.T) {
+
+	body := NewNode("Body")
+	arm := NewNode("Arm")
+	arm.SetLocalPosition(0, 1, 0)
+	body.AddChildren(arm)
+
+	// Cloning sets the nodes' original transforms, which they rest at.
+	body = body.Clone().(*Node)
+	arm = body.Get("Arm").(*Node)
+	arm.SetLocalPosition(5, 0, 0)
+
+	player := NewAnimationPlayer(body)
+
+	layer := player.AddLayer("Wave")
+	layer.Weight = 0.5
+	layer.PlayAnim(constantPositionAnimation("Wave", 1, map[string]Vector{"Arm": {2, 1, 0, 0}}))
+
+	player.Update(0.1)
+
+	if pos := arm.LocalPosition(); !pos.Equals(Vector{1, 1, 0, 0}) {
+		t.Fatalf("arm is at %s, expected it halfway between its rest position and the layer's", pos)
+	}
+
+	// Playing nothing on a layer leaves the pose alone.
+	layer.PlayAnim(nil)
+	arm.SetLocalPosition(3, 0, 0)
+	player.Update(0.1)
+
+	if pos := arm.LocalPosition(); !pos.Equals(Vector{3, 0, 0, 0}) {
+		t.Fatalf("arm is at %s, expected a layer without an animation not to move it", pos)
+	}
+
+	// Additive layers sample their reference pose once, and reuse their pose buffers.
+	breathe := NewAnimation("Breathe")
+	track := breathe.AddChannel("Arm").AddTrack(TrackTypePosition)
+	track.AddKeyframe(0, Vector{0, 0, 0, 0})
+	track.AddKeyframe(1, Vector{0, 2, 0, 0})
+	breathe.Length = 1
+
+	layer.Additive = true
+	layer.Weight = 1
+	layer.PlayAnim(breathe)
+	player.Update(0.1)
+
+	pose := reflect.ValueOf(layer.pose).Pointer()
+	reference := reflect.ValueOf(layer.reference).Pointer()
+
+	track.Keyframes[0].Data = Data{Vector{0, 10, 0, 0}}
+	player.Update(0.1)
+
+	if reflect.ValueOf(layer.pose).Pointer() != pose || reflect.ValueOf(layer.reference).Pointer() != reference {
+		t.Fatalf("layer should reuse its pose buffers between updates")
+	}
+
+	if !layer.reference[arm].Position.Equals(Vector{0, 0, 0, 0}) {
+		t.Fatalf("layer should only sample its reference pose again when what it plays changes")
+	}
+
+}
+
+func TestBlendSpace(t *testing.T) {
+
+	root := NewNode("Root")
+	leg := NewNode("Leg")
+	root.AddChildren(leg)
+
+	walk := constantPositionAnimation("Walk", 1, map[string]Vector{"Leg": {1, 0, 0, 0}})
+	run := constantPositionAnimation("Run", 2, map[string]Vector{"Leg": {3, 0, 0, 0}})
+
+	locomotion := NewBlendSpace1D()
+	locomotion.AddPoint(run, 1, 0)
+	locomotion.AddPoint(walk, 0, 0)
+	locomotion.SetParameter(0.25, 0)
+
+	player := NewAnimationPlayer(root)
+	layer := player.AddLayer("Locomotion")
+	layer.PlayBlendSpace(locomotion)
+
+	// The Animations play through together over their blended length of 1.25 seconds.
+	player.Update(0.625)
+
+	if pos := leg.LocalPosition(); !pos.Equals(Vector{1.5, 0, 0, 0}) {
+		t.Fatalf("leg is at %s, expected a quarter of the way from walking to running", pos)
+	}
+
+	if math.Abs(layer.Playhead-0.5) > 0.0001 {
+		t.Fatalf("blend space playhead is %f, expected it halfway through", layer.Playhead)
+	}
+
+	for _, test := range []struct {
+		param    float64
+		expected []float64
+	}{
+		{-1, []float64{0, 1}},
+		{0.5, []float64{0.5, 0.5}},
+		{2, []float64{1, 0}},
+	} {
+		locomotion.SetParameter(test.param, 0)
+		for i, weight := range locomotion.Weights() {
+			if math.Abs(weight-test.expected[i]) > 0.0001 {
+				t.Fatalf("1D blend space weights at %f are %v, expected %v", test.param, locomotion.Weights(), test.expected)
+			}
+		}
+	}
+
+	strafe := NewBlendSpace2D()
+	strafe.AddPoint(walk, 0, 0)
+	strafe.AddPoint(run, 1, 0)
+	strafe.AddPoint(walk, 0, 1)
+
+	strafe.SetParameter(1, 0)
+	if weights := strafe.Weights(); math.Abs(weights[1]-1) > 0.0001 {
+		t.Fatalf("2D blend space weights at a point are %v, expected only that point", weights)
+	}
+
+	strafe.SetParameter(0.3, 0.3)
+	total := 0.0
+	for _, weight := range strafe.Weights() {
+		if weight <= 0 {
+			t.Fatalf("2D blend space weights within the points are %v, expected each point to contribute", strafe.Weights())
+		}
+		total += weight
+	}
+	if math.Abs(total-1) > 0.0001 {
+		t.Fatalf("2D blend space weights add up to %f, expected 1", total)
+	}
+
+}
+
+func TestRootMotion(t *testing.T) {
+
+	armature := NewNode("Armature")
+	root := NewNode("Root")
+	root.isBone = true
+	armature.AddChildren(root)
+
+	// Walk 4 units forward each second, bobbing up and down, while turning a quarter turn.
+	walk := NewAnimation("Walk")
+	walk.Length = 1
+	pos := walk.AddChannel("Root").AddTrack(TrackTypePosition)
+	pos.AddKeyframe(0, Vector{0, 1, 0, 0})
+	pos.AddKeyframe(0.5, Vector{0, 1.5, 2, 0})
+	pos.AddKeyframe(1, Vector{0, 1, 4, 0})
+	rot := walk.Channels["Root"].AddTrack(TrackTypeRotation)
+	rot.AddKeyframe(0, NewQuaternionFromAxisAngle(WorldUp, 0))
+	rot.AddKeyframe(1, NewQuaternionFromAxisAngle(WorldUp, math.Pi/2))
+
+	player := NewAnimationPlayer(armature)
+	player.ExtractRootMotion = true
+	player.PlayAnim(walk)
+
+	character := NewNode("Character")
+
+	player.Update(0.25)
+
+	if !player.RootMotionDelta().IsZero() || player.RootMotionYaw() != 0 {
+		t.Fatalf("there shouldn't be any root motion on the first update")
+	}
+
+	turned := 0.0
+
+	for i := 0; i < 8; i++ {
+
+		player.Update(0.25)
+
+		delta := player.RootMotionDelta()
+
+		if delta.Y != 0 || player.RootMotionYaw() <= 0 {
+			t.Fatalf("update %d: root motion should turn without moving vertically, but moved %s and turned %f", i, delta, player.RootMotionYaw())
+		}
+
+		turned += player.RootMotionYaw()
+
+		if math.Abs(delta.Magnitude()-1) > 0.0001 {
+			t.Fatalf("update %d: root motion should move 1 unit, but moved %s", i, delta)
+		}
+
+		if p := root.LocalPosition(); p.X != 0 || p.Z != 0 {
+			t.Fatalf("update %d: root bone should stay in place, but is at %s", i, p)
+		}
+
+		if forward := root.LocalRotation().Forward(); !forward.Equals(Vector{0, 0, 1, 0}) {
+			t.Fatalf("update %d: root bone should keep facing forward, but faces %s", i, forward)
+		}
+
+		if y := root.LocalPosition().Y; i == 0 && math.Abs(y-1.25) > 0.0001 {
+			t.Fatalf("root bone should keep bobbing up and down, but is at a height of %f", y)
+		}
+
+		player.ApplyRootMotion(character)
+
+	}
+
+	// Two loops of the Animation turn the character around.
+	if math.Abs(turned-math.Pi) > 0.0001 {
+		t.Fatalf("root motion should turn pi radians over two loops, but turned %f", turned)
+	}
+
+	if forward := character.LocalRotation().Forward(); !forward.Equals(Vector{0, 0, -1, 0}) {
+		t.Fatalf("character should have turned around, but faces %s", forward)
+	}
+
+	player.ExtractRootMotion = false
+	player.Update(0.25)
+
+	if !player.RootMotionDelta().IsZero() || root.LocalPosition().Z == 0 {
+		t.Fatalf("root motion shouldn't be extracted when ExtractRootMotion is false")
+	}
+
+}
+
+func TestInterpolationValues(t *testing.T) {
+
+	// The interpolation constants share a const block with the track types, and their values shouldn't change.
+	if InterpolationLinear != 3 || InterpolationConstant != 4 || InterpolationCubic != 5 {
+		t.Fatalf("interpolation constants changed values to %d, %d, and %d", InterpolationLinear, InterpolationConstant, InterpolationCubic)
+	}
+
+}
@@ -68,6 +68,11 @@ func (bp *Broadphase) Resize(gridSize int) {
 	bp.GridSize = gridSize
 
 	if gridSize <= 0 {
+		// Without a grid, every triangle is checked.
+		bp.allTriSet = make(map[uint16]bool, len(bp.BoundingTriangles.Mesh.Triangles))
+		for _, tri := range bp.BoundingTriangles.Mesh.Triangles {
+			bp.allTriSet[tri.ID] = true
+		}
 		return
 	}
 
@@ -0,0 +1,35 @@
+package tetra3d
+
+import "testing"
+
+func TestBroadphaseDisabled(t *testing.T) {
+
+	ground := NewBoundingTriangles("Ground", NewPlaneMesh(), 0)
+
+	sphere := NewBoundingSphere("Sphere", 1)
+	sphere.SetLocalPosition(0.5, 0.5, 0.5)
+
+	// Without a broadphase grid, every triangle is checked for collisions.
+	if triangles := ground.Broadphase.TrianglesFromBounding(sphere); len(triangles) != len(ground.Mesh.Triangles) {
+		t.Fatalf("broadphase without a grid should return all %d triangles, but returned %d", len(ground.Mesh.Triangles), len(triangles))
+	}
+
+	if !sphere.Colliding(ground) {
+		t.Fatalf("sphere touching the ground should collide with it without a broadphase grid")
+	}
+
+	// Disabling the broadphase after it had a grid still checks every triangle.
+	ground = NewBoundingTriangles("Ground", NewPlaneMesh(), 0.5)
+	ground.DisableBroadphase()
+
+	if !sphere.Colliding(ground) {
+		t.Fatalf("sphere touching the ground should collide with it after disabling the broadphase")
+	}
+
+	sphere.SetLocalPosition(0.5, 3, 0.5)
+
+	if sphere.Colliding(ground) {
+		t.Fatalf("sphere above the ground shouldn't collide with it")
+	}
+
+}
@@ -45,13 +45,19 @@ type Camera struct {
 
 	RenderDepth       bool // If the Camera should attempt to render a depth texture; if this is true, then DepthTexture() will hold the depth texture render results. Defaults to true.
 	RenderNormals     bool // If the Camera should attempt to render a normal texture; if this is true, then NormalTexture() will hold the normal texture render results. Defaults to false.
+	RenderIDs         bool // If the Camera should render an ID texture, allowing rendered Models and triangles to be picked by pixel using Camera.PickAt(). Defaults to false.
 	SectorRendering   bool // If the Camera should render using sectors or not; if no sectors are present, then it won't attempt to render with them. Defaults to false.
-	SectorRenderDepth int  // How far out the Camera renders other sectors. Defaults to 1 (so the current sector and its immediate neighbors).
-	currentSector     *Sector
+	SectorRenderDepth int  // How far out the Camera renders other sectors. Defaults to 1 (so the current sector and its immediate neighbors). Unused if the current sector has Portals.
+
+	// CullMask is the set of render layers the Camera renders; Models that aren't on any of these layers aren't rendered. Defaults to RenderLayerAll.
+	CullMask RenderLayer
+
+	currentSector *Sector
 
 	resultColorTexture    *ebiten.Image // ColorTexture holds the color results of rendering any models.
 	resultDepthTexture    *ebiten.Image // DepthTexture holds the depth results of rendering any models, if Camera.RenderDepth is on.
 	resultNormalTexture   *ebiten.Image // NormalTexture holds a texture indicating the normal render
+	resultIDTexture       *ebiten.Image // IDTexture holds the pick IDs of rendered triangles, if Camera.RenderIDs is on.
 	colorIntermediate     *ebiten.Image
 	depthIntermediate     *ebiten.Image
 	clipAlphaIntermediate *ebiten.Image
@@ -68,6 +74,16 @@ type Camera struct {
 
 	VertexSnapping float64 // When set to a value > 0, it will snap all rendered models' vertices to a grid of the provided size (so VertexSnapping of 0.1 will snap all rendered positions to 0.1 intervals). Defaults to 0 (off).
 
+	// VertexWorkers is the number of goroutines used to process (transform, skin, and light) the vertices of rendered Models. When it's greater
+	// than 1, Models are processed in parallel, though they're still drawn in the same order. Note that Models' VertexTransformFunctions are then
+	// called from multiple goroutines at once (including for different Models at the same time), so they must be safe for concurrent use; a
+	// VertexTransformFunction that writes to shared state should guard it with a mutex. VertexClipFunctions are always called from the rendering
+	// goroutine, as they run while drawing. Defaults to 1 (so vertices are processed on the rendering goroutine).
+	VertexWorkers int
+
+	// PostProcessing is the stack of post-processing passes applied to the Camera's render results when calling Camera.PostProcess(). Empty by default.
+	PostProcessing *PostProcessStack
+
 	DebugInfo DebugInfo
 
 	depthShader              *ebiten.Shader
@@ -75,6 +91,19 @@ type Camera struct {
 	clipAlphaRenderShader    *ebiten.Shader
 	colorShader              *ebiten.Shader
 	sprite3DShader           *ebiten.Shader
+	idShader                 *ebiten.Shader
+
+	pickEntries     []PickResult   // The Models and triangles rendered to the ID texture, indexed by their pick ID - 1
+	lodRenderModels []*Model       // The Models to render after choosing levels of detail
+	vertexJobs      vertexJobQueue // The MeshParts queued to be processed by vertex workers
+
+	viewportX, viewportY int           // The position of the Camera's viewport on its render target
+	renderingTexture     bool          // If the Camera is currently being rendered for Materials' textures
+	renderingBound       bool          // If the Camera is currently rendering the Cameras bound to Materials' textures
+	frame                uint64        // The frame the Camera is rendering (see renderFrame), so that Cameras bound to Materials' textures are rendered once for it
+	frameRendered        bool          // If the Camera has rendered anything in its current frame
+	textureFrame         uint64        // The frame the Camera was last rendered for Materials' textures
+	boundTexture         *ebiten.Image // A copy of the Camera's render results from the last time it was rendered for Materials' textures
 
 	// Visibility check variables
 	cameraForward          Vector
@@ -105,6 +134,10 @@ func NewCamera(w, h int) *Camera {
 
 		SectorRendering:   false,
 		SectorRenderDepth: 1,
+		CullMask:          RenderLayerAll,
+		VertexWorkers:     1,
+
+		PostProcessing: NewPostProcessStack(),
 	}
 
 	depthShaderText := []byte(
@@ -121,8 +154,16 @@ func NewCamera(w, h int) *Camera {
 			return rgba.r + (rgba.g / 255) + (rgba.b / 65025)
 		}
 
+		var DitherRange vec2
+		var BayerMatrix [16]float
+
 		func Fragment(position vec4, texCoord vec2, color vec4) vec4 {
 
+			dither := BayerMatrix[(int(position.y)%4)*4 + int(position.x)%4]
+			if dither < DitherRange.x || dither >= DitherRange.y {
+				discard()
+			}
+
 			existingDepth := imageSrc0At(position.xy / imageSrcTextureSize())
 
 			if existingDepth.a == 0 || decodeDepth(existingDepth) > color.r {
@@ -180,8 +221,16 @@ func NewCamera(w, h int) *Camera {
 			return rgba.r + (rgba.g / 255) + (rgba.b / 65025)
 		}
 
+		var DitherRange vec2
+		var BayerMatrix [16]float
+
 		func Fragment(position vec4, texCoord vec2, color vec4) vec4 {
 
+			dither := BayerMatrix[(int(position.y)%4)*4 + int(position.x)%4]
+			if dither < DitherRange.x || dither >= DitherRange.y {
+				discard()
+			}
+
 			depthValue := imageSrc0UnsafeAt(texCoord)
 			texture := imageSrc1UnsafeAt(texCoord)
 
@@ -256,10 +305,9 @@ func NewCamera(w, h int) *Camera {
 						} else if Fog.a == 1 {
 							colorTex.rgb -= Fog.rgb * fogMult * colorTex.a
 						} else if Fog.a == 2 {
-							colorTex.rgb = mix(colorTex.rgb, Fog.rgb, fogMult) * colorTex.a
+							colorTex.rgb = mix(colorTex.rgb, Fog.rgb * colorTex.a, fogMult)
 						} else if Fog.a == 3 {
-							colorTex.a *= abs(1-d) * step(0, abs(1-d) - BayerMatrix[(yc*4) + xc])
-							colorTex.rgb = mix(vec3(0, 0, 0), colorTex.rgb, colorTex.a)
+							colorTex *= abs(1-d) * step(0, abs(1-d) - BayerMatrix[(yc*4) + xc])
 						}
 
 					} else {
@@ -269,10 +317,9 @@ func NewCamera(w, h int) *Camera {
 						} else if Fog.a == 1 {
 							colorTex.rgb -= Fog.rgb * d * colorTex.a
 						} else if Fog.a == 2 {
-							colorTex.rgb = mix(colorTex.rgb, Fog.rgb, d) * colorTex.a
+							colorTex.rgb = mix(colorTex.rgb, Fog.rgb * colorTex.a, d)
 						} else if Fog.a == 3 {
-							colorTex.a *= abs(1-d)
-							colorTex.rgb = mix(vec3(0, 0, 0), colorTex.rgb, colorTex.a)
+							colorTex *= abs(1-d)
 						}
 
 					}
@@ -324,6 +371,12 @@ func NewCamera(w, h int) *Camera {
 		panic(err)
 	}
 
+	cam.idShader, err = ebiten.NewShader(idShaderText)
+
+	if err != nil {
+		panic(err)
+	}
+
 	if w != 0 && h != 0 {
 		cam.Resize(w, h)
 	}
@@ -340,6 +393,8 @@ func (camera *Camera) Clone() INode {
 	clone := NewCamera(w, h)
 
 	clone.RenderDepth = camera.RenderDepth
+	clone.RenderNormals = camera.RenderNormals
+	clone.RenderIDs = camera.RenderIDs
 	clone.near = camera.near
 	clone.far = camera.far
 	clone.perspective = camera.perspective
@@ -347,9 +402,14 @@ func (camera *Camera) Clone() INode {
 	clone.orthoScale = camera.orthoScale
 	clone.SectorRendering = camera.SectorRendering
 	clone.SectorRenderDepth = camera.SectorRenderDepth
+	clone.CullMask = camera.CullMask
+	clone.VertexWorkers = camera.VertexWorkers
+	clone.viewportX = camera.viewportX
+	clone.viewportY = camera.viewportY
 
 	clone.AccumulateColorMode = camera.AccumulateColorMode
 	clone.AccumulateDrawOptions = camera.AccumulateDrawOptions
+	clone.PostProcessing = camera.PostProcessing.Clone()
 
 	clone.Node = camera.Node.Clone().(*Node)
 	for _, child := range camera.children {
@@ -372,6 +432,7 @@ func (camera *Camera) Resize(w, h int) {
 		camera.resultColorTexture.Dispose()
 		camera.resultAccumulatedColorTexture.Dispose()
 		camera.resultNormalTexture.Dispose()
+		camera.resultIDTexture.Dispose()
 		camera.accumulatedBackBuffer.Dispose()
 		camera.resultDepthTexture.Dispose()
 		camera.colorIntermediate.Dispose()
@@ -388,6 +449,7 @@ func (camera *Camera) Resize(w, h int) {
 	camera.resultColorTexture = ebiten.NewImageWithOptions(bounds, opt)
 	camera.resultDepthTexture = ebiten.NewImageWithOptions(bounds, opt)
 	camera.resultNormalTexture = ebiten.NewImageWithOptions(bounds, opt)
+	camera.resultIDTexture = ebiten.NewImageWithOptions(bounds, opt)
 	camera.colorIntermediate = ebiten.NewImageWithOptions(bounds, opt)
 	camera.depthIntermediate = ebiten.NewImageWithOptions(bounds, opt)
 	camera.clipAlphaIntermediate = ebiten.NewImageWithOptions(bounds, opt)
@@ -782,6 +844,23 @@ func (camera *Camera) Clear() {
 		camera.resultNormalTexture.Clear()
 	}
 
+	if camera.RenderIDs {
+		camera.resultIDTexture.Clear()
+	}
+
+	camera.pickEntries = camera.pickEntries[:0]
+
+	if !camera.renderingTexture {
+		camera.startFrame()
+	}
+
+	camera.beginFrame()
+
+}
+
+// beginFrame resets the Camera's debug values and caches its orientation for frustum culling at the start of a rendered frame.
+func (camera *Camera) beginFrame() {
+
 	if camera.DebugInfo.frameCount > 0 && time.Since(camera.DebugInfo.tickTime).Milliseconds() >= 100 {
 
 		if !camera.DebugInfo.tickTime.IsZero() {
@@ -827,11 +906,17 @@ func (camera *Camera) RenderScene(scene *Scene) {
 // is false, scenes rendered one after another in multiple RenderScene() calls will be rendered on top of each other in the Camera's texture buffers.
 // Note that each MeshPart of a Model has a maximum renderable triangle count of 21845.
 func (camera *Camera) RenderNodes(scene *Scene, rootNode INode) {
+	models, lights := camera.renderables(rootNode)
+	camera.Render(scene, lights, models...)
+}
+
+// renderables returns the Models and lights under the rootNode that should be rendered, taking sector rendering into account.
+func (camera *Camera) renderables(rootNode INode) ([]*Model, []ILight) {
 
 	meshes := []*Model{}
 	lights := []ILight{}
 
-	if model, isModel := rootNode.(*Model); isModel {
+	if model, isModel := nodeModel(rootNode); isModel {
 		meshes = append(meshes, model)
 	}
 
@@ -873,10 +958,20 @@ func (camera *Camera) RenderNodes(scene *Scene, rootNode INode) {
 			}
 		}
 
+		w, h := camera.Size()
+		screen := screenRect{0, 0, float64(w), float64(h)}
+		throughPortals := insideSector != nil && len(insideSector.Portals) > 0
+
 		if insideSector != nil {
 
-			for r := range insideSector.NeighborsWithinRange(camera.SectorRenderDepth) {
-				r.sectorVisible = true
+			insideSector.visibleRect = screen
+
+			if throughPortals {
+				camera.markPortalSectors(insideSector, screen, nil)
+			} else {
+				for r := range insideSector.NeighborsWithinRange(camera.SectorRenderDepth) {
+					r.sectorVisible = true
+				}
 			}
 
 		}
@@ -886,10 +981,17 @@ func (camera *Camera) RenderNodes(scene *Scene, rootNode INode) {
 
 			if s.sector.sectorVisible {
 
+				// Objects in Sectors seen through Portals are only visible if they overlap the region of the screen the Sector is seen through.
+				rect := s.sector.visibleRect
+				cull := throughPortals && rect != screen
+
 				search := s.SearchTree().INodes()
 				meshes = append(meshes, s)
 				for _, n := range search {
-					if model, ok := n.(*Model); ok {
+					if model, ok := nodeModel(n); ok {
+						if cull && camera.modelScreenRect(model).intersect(rect).empty() {
+							continue
+						}
 						meshes = append(meshes, model)
 					}
 					if light, ok := n.(ILight); ok && light.IsOn() {
@@ -904,7 +1006,7 @@ func (camera *Camera) RenderNodes(scene *Scene, rootNode INode) {
 		// Now search for non-Sectors
 
 		for _, i := range nonSectorSearch.INodes() {
-			if model, ok := i.(*Model); ok {
+			if model, ok := nodeModel(i); ok {
 				meshes = append(meshes, model)
 			}
 			if light, ok := i.(ILight); ok && light.IsOn() {
@@ -924,10 +1026,15 @@ func (camera *Camera) RenderNodes(scene *Scene, rootNode INode) {
 		}
 	}
 
-	camera.Render(scene, lights, meshes...)
+	return meshes, lights
 
 }
 
+// onCullMask returns if the Model is on any of the render layers in the Camera's CullMask.
+func (camera *Camera) onCullMask(model *Model) bool {
+	return model.layers&camera.CullMask != 0
+}
+
 // CurrentSector returns the current sector the Camera is in, if sector-based rendering is enabled.
 func (camera *Camera) CurrentSector() *Sector { return camera.currentSector }
 
@@ -946,56 +1053,25 @@ var bayerMatrix = []float32{
 
 // Render renders all of the models passed using the provided Scene's properties (fog, for example). Note that if Camera.RenderDepth
 // is false, scenes rendered one after another in multiple Render() calls will be rendered on top of each other in the Camera's texture buffers.
-// Note that each MeshPart of a Model has a maximum renderable triangle count of 21845.
+// Note that each MeshPart of a Model has a maximum renderable triangle count of 21845. Before drawing, any Cameras bound to the Materials
+// being drawn (through Material.TextureCamera) are rendered, so the Materials' textures are up to date.
 func (camera *Camera) Render(scene *Scene, lights []ILight, models ...*Model) {
 
 	scene.HandleAutobatch()
 
-	frametimeStart := time.Now()
-
-	sceneLights := make([]ILight, 0, len(lights))
-
-	if scene.World != nil && scene.World.LightingOn {
-
-		lights = append(lights, scene.World.AmbientLight)
-
-		for _, light := range lights {
-			if light.IsOn() {
-				camera.DebugInfo.ActiveLightCount++
-				light.beginRender()
-				sceneLights = append(sceneLights, light)
-			}
-		}
-
-	}
-
-	camera.DebugInfo.LightCount = len(lights)
-
-	// if scene.World == nil || scene.World.LightingOn {
+	// By multiplying the camera's position against the view matrix (which contains the negated camera position), we're left with just the rotation
+	// matrix, which we feed into model.TransformedVertices() to draw vertices in order of distance. This also updates the projection factors
+	// used for frustum culling, so it's done before sorting.
+	vpMatrix := camera.ViewMatrix().Mult(camera.Projection())
 
-	// 	for _, l := range scene.Root.SearchTree().INodes() {
-	// 		if light, isLight := l.(ILight); isLight {
-	// 			camera.DebugInfo.LightCount++
-	// 			if light.IsOn() {
-	// 				sceneLights = append(sceneLights, light)
-	// 				light.beginRender()
-	// 				camera.DebugInfo.ActiveLightCount++
-	// 			}
-	// 		}
-	// 	}
+	solids, transparents := camera.sortRenderPairs(camera.lodModels(models))
 
-	// 	if scene.World != nil && scene.World.AmbientLight != nil && scene.World.AmbientLight.IsOn() {
-	// 		sceneLights = append(sceneLights, scene.World.AmbientLight)
-	// 		scene.World.AmbientLight.beginRender()
-	// 		camera.DebugInfo.LightCount++
-	// 		camera.DebugInfo.ActiveLightCount++
-	// 	}
+	// Cameras bound to the textures of Materials about to be drawn are rendered before anything else, as they use the same vertex buffers.
+	camera.renderTextureCameras(scene, solids, transparents)
 
-	// }
+	frametimeStart := time.Now()
 
-	// By multiplying the camera's position against the view matrix (which contains the negated camera position), we're left with just the rotation
-	// matrix, which we feed into model.TransformedVertices() to draw vertices in order of distance.
-	vpMatrix := camera.ViewMatrix().Mult(camera.Projection())
+	sceneLights := camera.beginLights(scene, lights)
 
 	rectShaderOptions := &ebiten.DrawRectShaderOptions{}
 	rectShaderOptions.Images[0] = camera.colorIntermediate
@@ -1020,151 +1096,32 @@ func (camera *Camera) Render(scene *Scene, lights []ILight, models ...*Model) {
 
 	}
 
-	// Reusing vectors rather than reallocating for all triangles for all models
-	solids := []renderPair{}
-	transparents := []renderPair{}
-
-	depths := map[*Model]float64{}
-
-	cameraPos := camera.WorldPosition()
-
-	camSpread := camera.far - camera.near
-
-	for _, model := range models {
-
-		if !model.visible {
-			continue
-		}
-
-		if model.FrustumCulling {
-
-			model.Transform()
-
-			if !camera.SphereInFrustum(model.BoundingSphere) {
-				continue
-			}
-			model.refreshVertexVisibility()
-
-		}
-
-		if len(model.DynamicBatchModels) > 0 {
-
-			dynamicDepths := map[*Model]float64{}
-
-			transparent := false
-
-			for meshPart, modelSlice := range model.DynamicBatchModels {
-
-				for _, child := range modelSlice {
-
-					if !child.visible {
-						continue
-					}
-
-					dynamicDepths[child] = cameraPos.DistanceSquared(child.WorldPosition())
-
-					if !transparent {
-
-						for _, mp := range child.Mesh.MeshParts {
-
-							if child.isTransparent(mp) {
-								transparent = true
-								break
-							}
-
-						}
-
-					}
-
-				}
-
-				sort.Slice(modelSlice, func(i, j int) bool {
-					return dynamicDepths[modelSlice[i]] > dynamicDepths[modelSlice[j]]
-				})
-
-				if transparent {
-					transparents = append(transparents, renderPair{model, meshPart})
-					depths[model] = cameraPos.DistanceSquared(model.WorldPosition())
-				} else {
-					solids = append(solids, renderPair{model, meshPart})
-					if !camera.RenderDepth {
-						depths[model] = cameraPos.DistanceSquared(model.WorldPosition())
-					}
-				}
-
-				camera.DebugInfo.TotalParts += len(modelSlice)
-
-			}
-
-		} else if model.Mesh != nil {
-
-			modelIsTransparent := false
-
-			for _, mp := range model.Mesh.MeshParts {
-				if model.isTransparent(mp) {
-					transparents = append(transparents, renderPair{model, mp})
-					modelIsTransparent = true
-				} else {
-					solids = append(solids, renderPair{model, mp})
-				}
-			}
-
-			if !camera.RenderDepth || modelIsTransparent {
-				depths[model] = cameraPos.DistanceSquared(model.WorldPosition())
-				// depths[model] = camera.WorldToScreen(model.WorldPosition()).Z
-			}
-
-			camera.DebugInfo.TotalParts++
-
-		}
-
-	}
-
-	// If the camera isn't rendering depth, then we should sort models by distance to ensure things draw in something like the correct order
-	if !camera.RenderDepth {
-
-		sort.SliceStable(solids, func(i, j int) bool {
-			return depths[solids[i].Model] > depths[solids[j].Model]
-		})
-
-	}
-
 	camWidth, camHeight := camera.resultColorTexture.Size()
 
 	colorVertex := ebiten.Vertex{}
 	depthVertex := colorVertex
 	normalVertex := colorVertex
 
-	render := func(rp renderPair) {
-
-		// startingVertexListIndex := vertexListIndex
+	var batch renderPair // The pair being rendered, which is flushed if the vertices drawn for it don't fit in the vertex buffers
+	var flushBuffers func(rp renderPair)
 
-		model := rp.Model
+	// draw adds the processed vertices of the MeshPart to the vertex buffers. mesh holds the processed vertices, and color is the Model's
+	// color as of when they were processed.
+	draw := func(model *Model, meshPart *MeshPart, mesh *Mesh, color *Color, sortingTris []sortingTriangle, lighting bool, flushPair renderPair) {
 
-		// Models without Meshes are essentially just "nodes" that just have a position. They aren't counted for rendering.
-		if model.Mesh == nil {
-			return
-		}
+		// startingVertexListIndex := vertexListIndex
 
-		meshPart := rp.MeshPart
 		mat := meshPart.Material
 
-		lighting := false
-		if scene.World != nil {
-			if mat != nil {
-				lighting = scene.World.LightingOn && !mat.Shadeless
-			} else {
-				lighting = scene.World.LightingOn
-			}
-		}
-
 		camera.DebugInfo.TotalTris += meshPart.TriangleCount()
 
 		if model.DynamicBatchOwner != nil {
 			camera.DebugInfo.BatchedParts++
 		}
 
-		sortingTris := model.ProcessVertices(vpMatrix, camera, meshPart, scene)
+		if vertexListIndex+meshPart.VertexIndexCount() > MaxTriangleCount*3 || indexListIndex+meshPart.TriangleCount()*3 > len(indexList) {
+			flushBuffers(flushPair)
+		}
 
 		srcW := 0.0
 		srcH := 0.0
@@ -1174,69 +1131,14 @@ func (camera *Camera) Render(scene *Scene, lights []ILight, models ...*Model) {
 			srcH = float64(mat.Texture.Bounds().Dy())
 		}
 
-		if lighting {
-
-			t := time.Now()
-
-			if model.LightGroup != nil && model.LightGroup.Active {
-				sceneLights = model.LightGroup.Lights
-				for _, l := range model.LightGroup.Lights {
-					l.beginRender() // Call this because it's relatively cheap and necessary if a light doesn't exist in the Scene
-				}
-			}
-
-			for _, light := range sceneLights {
-				light.beginModel(model)
-			}
-
-			camera.DebugInfo.lightTime += time.Since(t)
-
-		}
-
-		mesh := model.Mesh
-
-		maxSpan := model.Mesh.Dimensions.MaxSpan()
-		modelPos := model.WorldPosition()
-
 		// Here we do all vertex transforms first because of data locality (it's faster to access all vertex transformations, then go back and do all UV values, etc)
 
-		mpColor := model.Color.Clone()
+		mpColor := color.Clone()
 
 		if meshPart.Material != nil {
 			mpColor.MultiplyRGBA(meshPart.Material.Color.ToFloat32s())
 		}
 
-		if lighting && len(sortingTris) > 0 {
-
-			t := time.Now()
-
-			meshPart.ForEachVertexIndex(func(vertIndex int) {
-				mesh.vertexLights[vertIndex].Set(0, 0, 0, 1)
-			}, true)
-
-			for _, light := range sceneLights {
-
-				// Skip calculating lighting for objects that are too far away from light sources.
-				if point, ok := light.(*PointLight); ok && point.Distance > 0 {
-					dist := maxSpan + point.Distance
-					if modelPos.DistanceSquared(point.WorldPosition()) > dist*dist {
-						continue
-					}
-				} else if cube, ok := light.(*CubeLight); ok && cube.Distance > 0 {
-					dist := maxSpan + cube.Distance
-					if modelPos.DistanceSquared(cube.WorldPosition()) > dist*dist {
-						continue
-					}
-				}
-
-				light.Light(meshPart, model, mesh.vertexLights, true)
-
-			}
-
-			camera.DebugInfo.lightTime += time.Since(t)
-
-		}
-
 		// TODO: Implement PS1-style automatic tesselation
 
 		meshPart.ForEachVertexIndex(
@@ -1287,79 +1189,14 @@ func (camera *Camera) Render(scene *Scene, lights []ILight, models ...*Model) {
 					normalVertexList[vertexListIndex] = normalVertex
 				}
 
-				// Vertex colors
-
-				if activeChannel := mesh.VertexActiveColorChannel[vertIndex]; activeChannel >= 0 {
-					colorVertex.ColorR = mesh.VertexColors[vertIndex][activeChannel].R * mpColor.R
-					colorVertex.ColorG = mesh.VertexColors[vertIndex][activeChannel].G * mpColor.G
-					colorVertex.ColorB = mesh.VertexColors[vertIndex][activeChannel].B * mpColor.B
-					colorVertex.ColorA = mesh.VertexColors[vertIndex][activeChannel].A * mpColor.A
-				} else {
-					colorVertex.ColorR = mpColor.R
-					colorVertex.ColorG = mpColor.G
-					colorVertex.ColorB = mpColor.B
-					colorVertex.ColorA = mpColor.A
-				}
-
-				if lighting {
-					colorVertex.ColorR *= mesh.vertexLights[vertIndex].R
-					colorVertex.ColorG *= mesh.vertexLights[vertIndex].G
-					colorVertex.ColorB *= mesh.vertexLights[vertIndex].B
-				}
+				colorVertex.ColorR, colorVertex.ColorG, colorVertex.ColorB, colorVertex.ColorA = camera.vertexColor(scene, mesh, vertIndex, mpColor, lighting)
 
 				if camera.RenderDepth {
-
-					// 3/28/23, TODO: We currently use the transformed vertex positions for rendering the depth texture. Note
-					// that this makes fog shift aggressively as you turn the camera, more noticeably in first-person games
-					// (as points closer to the corners of the screen are mathematically closer to the camera because of projection).
-					// In an attempt to fix this, I used the below, now commented-out depth function. This attempt did fix the fog,
-					// but it also made depth sorting buggier, which is unacceptable. For now, I've reverted this change to have
-					// better depth sorting. This is currently fine, though the fog issue should be resolved at some point in the future.
-
-					// See this Discord conversation for the visualization of the issue:
-					// https://discord.com/channels/842049801528016967/844522898126536725/1090223569247674488
-
-					// p, s, r := model.Transform().Inverted().Decompose()
-					// invertedCameraPos := r.MultVec(camera.WorldPosition()).Add(p.Mult(Vector{1 / s.X, 1 / s.Y, 1 / s.Z, s.W}))
-					// depth := (invertedCameraPos.Distance(mesh.VertexPositions[vertIndex]) - camera.near) / (camera.far - camera.near)
-
-					depth := mesh.vertexTransforms[vertIndex].Z / camSpread
-
-					if depth < 0 {
-						depth = 0
-					} else if depth > 1 {
-						depth = 1
-					}
-
-					depthVertex.ColorR = float32(depth)
-					depthVertex.ColorG = float32(depth)
-					depthVertex.ColorB = float32(depth)
+					depth := float32(camera.vertexDepth(mesh, vertIndex))
+					depthVertex.ColorR = depth
+					depthVertex.ColorG = depth
+					depthVertex.ColorB = depth
 					depthVertex.ColorA = 1
-
-				} else if scene.World != nil && scene.World.FogOn {
-
-					depth := mesh.vertexTransforms[vertIndex].Z / camSpread
-
-					if depth < 0 {
-						depth = 0
-					} else if depth > 1 {
-						depth = 1
-					}
-
-					// depth = 1 - depth
-
-					depth = float64(scene.World.FogRange[0] + ((scene.World.FogRange[1]-scene.World.FogRange[0])*1 - float32(depth)))
-
-					if scene.World.FogMode == FogAdd {
-						colorVertex.ColorR += scene.World.FogColor.R * float32(depth)
-						colorVertex.ColorG += scene.World.FogColor.G * float32(depth)
-						colorVertex.ColorB += scene.World.FogColor.B * float32(depth)
-					} else if scene.World.FogMode == FogSub {
-						colorVertex.ColorR *= scene.World.FogColor.R * float32(depth)
-						colorVertex.ColorG *= scene.World.FogColor.G * float32(depth)
-						colorVertex.ColorB *= scene.World.FogColor.B * float32(depth)
-					}
-
 				}
 
 				colorVertexList[vertexListIndex] = colorVertex
@@ -1377,8 +1214,20 @@ func (camera *Camera) Render(scene *Scene, lights []ILight, models ...*Model) {
 
 		for _, sortingTri := range sortingTris {
 
+			if camera.RenderIDs {
+				pickModel := model
+				if model.renderOwner != nil {
+					pickModel = model.renderOwner
+				}
+				camera.pickEntries = append(camera.pickEntries, PickResult{Model: pickModel, Triangle: sortingTri.Triangle})
+			}
+
 			for _, index := range sortingTri.Triangle.VertexIndices {
-				indexList[indexListIndex] = uint16(index - sortingTri.Triangle.MeshPart.VertexIndexStart + indexListStart)
+				listIndex := uint16(index - sortingTri.Triangle.MeshPart.VertexIndexStart + indexListStart)
+				indexList[indexListIndex] = listIndex
+				if camera.RenderIDs {
+					idVertexList[indexListIndex] = camera.idVertex(colorVertexList[listIndex], len(camera.pickEntries), camera.vertexDepth(mesh, index))
+				}
 				indexListIndex++
 			}
 
@@ -1392,20 +1241,48 @@ func (camera *Camera) Render(scene *Scene, lights []ILight, models ...*Model) {
 
 	}
 
-	flush := func(rp renderPair) {
+	render := func(rp renderPair) {
 
-		if vertexListIndex == 0 {
+		model := rp.Model
+
+		// Models without Meshes are essentially just "nodes" that just have a position. They aren't counted for rendering.
+		if model.Mesh == nil {
 			return
 		}
 
-		model := rp.Model
 		meshPart := rp.MeshPart
-		mat := meshPart.Material
 
-		var img *ebiten.Image
+		lighting := camera.lightingOn(scene, meshPart.Material)
 
-		if mat != nil {
-			img = mat.Texture
+		if camera.VertexWorkers > 1 {
+			sceneLights = camera.queueVertexJob(rp, batch, sceneLights, lighting)
+			return
+		}
+
+		sortingTris := model.ProcessVertices(vpMatrix, camera, meshPart, scene)
+
+		if lighting {
+			sceneLights = camera.lightMeshPart(model, meshPart, sceneLights, len(sortingTris) > 0)
+		}
+
+		draw(model, meshPart, model.Mesh, model.Color, sortingTris, lighting, batch)
+
+	}
+
+	flushBuffers = func(rp renderPair) {
+
+		if vertexListIndex == 0 {
+			return
+		}
+
+		model := rp.Model
+		meshPart := rp.MeshPart
+		mat := meshPart.Material
+
+		var img *ebiten.Image
+
+		if mat != nil {
+			img = mat.Texture
 		}
 
 		if img == nil {
@@ -1441,6 +1318,13 @@ func (camera *Camera) Render(scene *Scene, lights []ILight, models ...*Model) {
 
 			camera.depthIntermediate.Clear()
 
+			// Models dithering in or out while cross-fading between levels of detail only draw depth (and so color) to some pixels.
+			ditherMin, ditherMax := model.ditherRange()
+			ditherUniforms := map[string]interface{}{
+				"DitherRange": []float32{ditherMin, ditherMax},
+				"BayerMatrix": bayerMatrix,
+			}
+
 			if transparencyMode == TransparencyModeAlphaClip {
 
 				camera.clipAlphaIntermediate.Clear()
@@ -1449,11 +1333,12 @@ func (camera *Camera) Render(scene *Scene, lights []ILight, models ...*Model) {
 
 				w, h := camera.depthIntermediate.Size()
 
-				camera.depthIntermediate.DrawRectShader(w, h, camera.clipAlphaCompositeShader, &ebiten.DrawRectShaderOptions{Images: [4]*ebiten.Image{camera.resultDepthTexture, camera.clipAlphaIntermediate}})
+				camera.depthIntermediate.DrawRectShader(w, h, camera.clipAlphaCompositeShader, &ebiten.DrawRectShaderOptions{Images: [4]*ebiten.Image{camera.resultDepthTexture, camera.clipAlphaIntermediate}, Uniforms: ditherUniforms})
 
 			} else {
 				shaderOpt := &ebiten.DrawTrianglesShaderOptions{
-					Images: [4]*ebiten.Image{camera.resultDepthTexture},
+					Images:   [4]*ebiten.Image{camera.resultDepthTexture},
+					Uniforms: ditherUniforms,
 				}
 
 				camera.depthIntermediate.DrawTrianglesShader(depthVertexList[:vertexListIndex], indexList[:indexListIndex], camera.depthShader, shaderOpt)
@@ -1528,6 +1413,20 @@ func (camera *Camera) Render(scene *Scene, lights []ILight, models ...*Model) {
 
 		}
 
+		if camera.RenderIDs {
+
+			depthTest := float32(0)
+			if camera.RenderDepth {
+				depthTest = 1
+			}
+
+			camera.resultIDTexture.DrawTrianglesShader(idVertexList[:indexListIndex], idIndexList[:indexListIndex], camera.idShader, &ebiten.DrawTrianglesShaderOptions{
+				Images:   [4]*ebiten.Image{camera.resultDepthTexture},
+				Uniforms: map[string]interface{}{"DepthTest": depthTest},
+			})
+
+		}
+
 		camera.DebugInfo.DrawnTris += indexListIndex / 3
 		camera.DebugInfo.DrawnParts++
 
@@ -1537,6 +1436,41 @@ func (camera *Camera) Render(scene *Scene, lights []ILight, models ...*Model) {
 
 	}
 
+	// With multiple vertex workers, MeshParts are queued up to be processed in parallel, and then drawn and flushed in the order they were queued.
+	flush := func(rp renderPair) {
+		if camera.VertexWorkers > 1 {
+			camera.queueVertexFlush(rp)
+		} else {
+			flushBuffers(rp)
+		}
+	}
+
+	camera.vertexJobs.begin(vpMatrix, scene, func(job *vertexJob) {
+		draw(job.pair.Model, job.pair.MeshPart, &job.mesh, &job.color, job.sortingTris, job.lighting, job.batch)
+	}, flushBuffers)
+
+	// The Sky is drawn before anything else. It isn't drawn to the ID or normal textures, as it isn't something in the Scene to pick or light.
+	if scene.World != nil && scene.World.Sky != nil {
+
+		if sky := scene.World.Sky.model(camera); sky != nil {
+
+			renderIDs, renderNormals := camera.RenderIDs, camera.RenderNormals
+			camera.RenderIDs, camera.RenderNormals = false, false
+
+			for _, part := range sky.Mesh.MeshParts {
+				batch = renderPair{Model: sky, MeshPart: part}
+				render(batch)
+				flush(batch)
+			}
+
+			camera.runVertexJobs()
+
+			camera.RenderIDs, camera.RenderNormals = renderIDs, renderNormals
+
+		}
+
+	}
+
 	for _, pair := range solids {
 
 		// Automatically statically batched models can't render
@@ -1544,6 +1478,8 @@ func (camera *Camera) Render(scene *Scene, lights []ILight, models ...*Model) {
 			continue
 		}
 
+		batch = pair
+
 		// Internally, the idea behind dynamic batching is that we simply hold off on flushing until the
 		// end - this saves a lot of time if we're rendering singular low-poly objects, at the cost of each
 		// object sharing the same material / object-level properties (color / material blending mode, for
@@ -1555,7 +1491,7 @@ func (camera *Camera) Render(scene *Scene, lights []ILight, models ...*Model) {
 
 			for _, merged := range modelSlice {
 
-				if !merged.visible {
+				if !merged.visible || !camera.onCullMask(merged) {
 					continue
 				}
 
@@ -1573,6 +1509,9 @@ func (camera *Camera) Render(scene *Scene, lights []ILight, models ...*Model) {
 
 			flush(pair)
 
+		} else if pair.Model.instancer != nil {
+			pair.Model.instancer.renderInstances(camera, vpMatrix, pair.MeshPart, render)
+			flush(pair)
 		} else {
 			render(pair)
 			flush(pair)
@@ -1580,16 +1519,14 @@ func (camera *Camera) Render(scene *Scene, lights []ILight, models ...*Model) {
 
 	}
 
-	sort.SliceStable(transparents, func(i, j int) bool {
-		return depths[transparents[i].Model] > depths[transparents[j].Model]
-	})
-
 	for _, pair := range transparents {
 
 		if !pair.Model.visible {
 			continue
 		}
 
+		batch = pair
+
 		if pair.Model.dynamicBatcher {
 
 			if dyn := pair.Model.DynamicBatchModels; len(dyn) > 0 {
@@ -1598,7 +1535,7 @@ func (camera *Camera) Render(scene *Scene, lights []ILight, models ...*Model) {
 
 				for _, merged := range modelSlice {
 
-					if !merged.visible {
+					if !merged.visible || !camera.onCullMask(merged) {
 						continue
 					}
 
@@ -1618,6 +1555,9 @@ func (camera *Camera) Render(scene *Scene, lights []ILight, models ...*Model) {
 
 			}
 
+		} else if pair.Model.instancer != nil {
+			pair.Model.instancer.renderInstances(camera, vpMatrix, pair.MeshPart, render)
+			flush(pair)
 		} else {
 			// Autobatch models don't need to render if they don't have anything
 			render(pair)
@@ -1626,12 +1566,320 @@ func (camera *Camera) Render(scene *Scene, lights []ILight, models ...*Model) {
 
 	}
 
+	camera.runVertexJobs()
+
 	camera.DebugInfo.frameTime += time.Since(frametimeStart)
 
 	camera.DebugInfo.frameCount++
 
 }
 
+// beginLights readies the lights given for rendering the Scene, returning the ones that are active (including the World's ambient light).
+func (camera *Camera) beginLights(scene *Scene, lights []ILight) []ILight {
+
+	sceneLights := make([]ILight, 0, len(lights))
+
+	if scene.World != nil && scene.World.LightingOn {
+
+		lights = append(lights, scene.World.AmbientLight)
+
+		for _, light := range lights {
+			if light.IsOn() {
+				camera.DebugInfo.ActiveLightCount++
+				light.beginRender()
+				sceneLights = append(sceneLights, light)
+			}
+		}
+
+	}
+
+	camera.DebugInfo.LightCount = len(lights)
+
+	return sceneLights
+
+}
+
+// sortRenderPairs culls the models given against the Camera's frustum and splits their MeshParts into solid and transparent render pairs,
+// sorted in the order they should be drawn.
+func (camera *Camera) sortRenderPairs(models []*Model) (solids, transparents []renderPair) {
+
+	depths := map[*Model]float64{}
+
+	cameraPos := camera.WorldPosition()
+
+	for _, model := range models {
+
+		if !model.visible || !camera.onCullMask(model) {
+			continue
+		}
+
+		// InstancedModels cull their Instances individually instead.
+		if model.FrustumCulling && model.instancer == nil {
+
+			model.Transform()
+
+			if !camera.SphereInFrustum(model.BoundingSphere) {
+				continue
+			}
+			model.refreshVertexVisibility()
+
+		}
+
+		if len(model.DynamicBatchModels) > 0 {
+
+			dynamicDepths := map[*Model]float64{}
+
+			transparent := false
+
+			for meshPart, modelSlice := range model.DynamicBatchModels {
+
+				for _, child := range modelSlice {
+
+					if !child.visible || !camera.onCullMask(child) {
+						continue
+					}
+
+					dynamicDepths[child] = cameraPos.DistanceSquared(child.WorldPosition())
+
+					if !transparent {
+
+						for _, mp := range child.Mesh.MeshParts {
+
+							if child.isTransparent(mp) {
+								transparent = true
+								break
+							}
+
+						}
+
+					}
+
+				}
+
+				sort.Slice(modelSlice, func(i, j int) bool {
+					return dynamicDepths[modelSlice[i]] > dynamicDepths[modelSlice[j]]
+				})
+
+				if transparent {
+					transparents = append(transparents, renderPair{model, meshPart})
+					depths[model] = cameraPos.DistanceSquared(model.WorldPosition())
+				} else {
+					solids = append(solids, renderPair{model, meshPart})
+					if !camera.RenderDepth {
+						depths[model] = cameraPos.DistanceSquared(model.WorldPosition())
+					}
+				}
+
+				camera.DebugInfo.TotalParts += len(modelSlice)
+
+			}
+
+		} else if model.Mesh != nil {
+
+			modelIsTransparent := false
+
+			for _, mp := range model.Mesh.MeshParts {
+				if model.isTransparent(mp) {
+					transparents = append(transparents, renderPair{model, mp})
+					modelIsTransparent = true
+				} else {
+					solids = append(solids, renderPair{model, mp})
+				}
+			}
+
+			if !camera.RenderDepth || modelIsTransparent {
+				depths[model] = cameraPos.DistanceSquared(model.WorldPosition())
+				// depths[model] = camera.WorldToScreen(model.WorldPosition()).Z
+			}
+
+			camera.DebugInfo.TotalParts++
+
+		}
+
+	}
+
+	// If the camera isn't rendering depth, then we should sort models by distance to ensure things draw in something like the correct order
+	if !camera.RenderDepth {
+
+		sort.SliceStable(solids, func(i, j int) bool {
+			return depths[solids[i].Model] > depths[solids[j].Model]
+		})
+
+	}
+
+	sort.SliceStable(transparents, func(i, j int) bool {
+		return depths[transparents[i].Model] > depths[transparents[j].Model]
+	})
+
+	return solids, transparents
+
+}
+
+// lightingOn returns if lighting should be calculated for a MeshPart using the Material given.
+func (camera *Camera) lightingOn(scene *Scene, mat *Material) bool {
+	if scene.World == nil {
+		return false
+	}
+	if mat != nil {
+		return scene.World.LightingOn && !mat.Shadeless
+	}
+	return scene.World.LightingOn
+}
+
+// lightMeshPart calculates the lighting for the MeshPart's vertices from the lights given, storing the result in the Mesh's vertex lights.
+// If the Model has an active LightGroup, its lights are used instead; the lights used are returned.
+func (camera *Camera) lightMeshPart(model *Model, meshPart *MeshPart, sceneLights []ILight, visible bool) []ILight {
+
+	t := time.Now()
+
+	sceneLights = modelLights(model, sceneLights)
+
+	lightVertices(model, meshPart, sceneLights, visible)
+
+	camera.DebugInfo.lightTime += time.Since(t)
+
+	return sceneLights
+
+}
+
+// modelLights returns the lights that light the Model - the lights of its LightGroup if it has an active one, or the scene lights given otherwise.
+func modelLights(model *Model, sceneLights []ILight) []ILight {
+
+	if model.LightGroup != nil && model.LightGroup.Active {
+		sceneLights = model.LightGroup.Lights
+		for _, l := range model.LightGroup.Lights {
+			l.beginRender() // Call this because it's relatively cheap and necessary if a light doesn't exist in the Scene
+		}
+	}
+
+	return sceneLights
+
+}
+
+// lightVertices lights the MeshPart's vertices with the lights given, storing the result in the Model's Mesh's vertex lights. If visible is false,
+// the lights are readied for the Model, but nothing is lit.
+func lightVertices(model *Model, meshPart *MeshPart, lights []ILight, visible bool) {
+
+	for _, light := range lights {
+		light.beginModel(model)
+	}
+
+	if visible {
+
+		mesh := model.Mesh
+
+		maxSpan := model.Mesh.Dimensions.MaxSpan()
+		modelPos := model.WorldPosition()
+
+		meshPart.ForEachVertexIndex(func(vertIndex int) {
+			mesh.vertexLights[vertIndex].Set(0, 0, 0, 1)
+		}, true)
+
+		for _, light := range lights {
+
+			// Lights only light Models that share a render layer with them.
+			if light.Layers()&model.layers == 0 {
+				continue
+			}
+
+			// Skip calculating lighting for objects that are too far away from light sources.
+			if point, ok := light.(*PointLight); ok && point.Distance > 0 {
+				dist := maxSpan + point.Distance
+				if modelPos.DistanceSquared(point.WorldPosition()) > dist*dist {
+					continue
+				}
+			} else if spot, ok := light.(*SpotLight); ok && spot.Distance > 0 {
+				dist := maxSpan + spot.Distance
+				if modelPos.DistanceSquared(spot.WorldPosition()) > dist*dist {
+					continue
+				}
+			} else if cube, ok := light.(*CubeLight); ok && cube.Distance > 0 {
+				dist := maxSpan + cube.Distance
+				if modelPos.DistanceSquared(cube.WorldPosition()) > dist*dist {
+					continue
+				}
+			}
+
+			light.Light(meshPart, model, mesh.vertexLights, true)
+
+		}
+
+	}
+
+}
+
+// vertexColor returns the color of a processed vertex, combining the MeshPart's color (mpColor) with its vertex color and lighting.
+// If the Camera isn't rendering depth, fog is applied to the vertex color as well.
+func (camera *Camera) vertexColor(scene *Scene, mesh *Mesh, vertIndex int, mpColor *Color, lighting bool) (r, g, b, a float32) {
+
+	if activeChannel := mesh.VertexActiveColorChannel[vertIndex]; activeChannel >= 0 {
+		r = mesh.VertexColors[vertIndex][activeChannel].R * mpColor.R
+		g = mesh.VertexColors[vertIndex][activeChannel].G * mpColor.G
+		b = mesh.VertexColors[vertIndex][activeChannel].B * mpColor.B
+		a = mesh.VertexColors[vertIndex][activeChannel].A * mpColor.A
+	} else {
+		r, g, b, a = mpColor.ToFloat32s()
+	}
+
+	if lighting {
+		r *= mesh.vertexLights[vertIndex].R
+		g *= mesh.vertexLights[vertIndex].G
+		b *= mesh.vertexLights[vertIndex].B
+	}
+
+	if !camera.RenderDepth && scene.World != nil && scene.World.FogOn {
+
+		depth := camera.vertexDepth(mesh, vertIndex)
+
+		// depth = 1 - depth
+
+		depth = float64(scene.World.FogRange[0] + ((scene.World.FogRange[1]-scene.World.FogRange[0])*1 - float32(depth)))
+
+		if scene.World.FogMode == FogAdd {
+			r += scene.World.FogColor.R * float32(depth)
+			g += scene.World.FogColor.G * float32(depth)
+			b += scene.World.FogColor.B * float32(depth)
+		} else if scene.World.FogMode == FogSub {
+			r *= scene.World.FogColor.R * float32(depth)
+			g *= scene.World.FogColor.G * float32(depth)
+			b *= scene.World.FogColor.B * float32(depth)
+		}
+
+	}
+
+	return r, g, b, a
+
+}
+
+// vertexDepth returns the depth of a processed vertex, ranging from 0 (close to the Camera) to 1 (at the Camera's far plane).
+func (camera *Camera) vertexDepth(mesh *Mesh, vertIndex int) float64 {
+
+	// 3/28/23, TODO: We currently use the transformed vertex positions for rendering the depth texture. Note
+	// that this makes fog shift aggressively as you turn the camera, more noticeably in first-person games
+	// (as points closer to the corners of the screen are mathematically closer to the camera because of projection).
+	// In an attempt to fix this, I used the below, now commented-out depth function. This attempt did fix the fog,
+	// but it also made depth sorting buggier, which is unacceptable. For now, I've reverted this change to have
+	// better depth sorting. This is currently fine, though the fog issue should be resolved at some point in the future.
+
+	// See this Discord conversation for the visualization of the issue:
+	// https://discord.com/channels/842049801528016967/844522898126536725/1090223569247674488
+
+	// p, s, r := model.Transform().Inverted().Decompose()
+	// invertedCameraPos := r.MultVec(camera.WorldPosition()).Add(p.Mult(Vector{1 / s.X, 1 / s.Y, 1 / s.Z, s.W}))
+	// depth := (invertedCameraPos.Distance(mesh.VertexPositions[vertIndex]) - camera.near) / (camera.far - camera.near)
+
+	depth := mesh.vertexTransforms[vertIndex].Z / (camera.far - camera.near)
+
+	if depth < 0 {
+		depth = 0
+	} else if depth > 1 {
+		depth = 1
+	}
+
+	return depth
+
+}
+
 // func encodeDepth(depth float64) *Color {
 
 // 	r := math.Floor(depth*255) / 255
@@ -2026,6 +2274,21 @@ func (camera *Camera) NormalTexture() *ebiten.Image {
 	return camera.resultNormalTexture
 }
 
+// PostProcess applies the Camera's PostProcessing stack to the results of any previous Render() or RenderNodes() calls, returning
+// the post-processed image. If the stack has no active passes, this is the Camera's ColorTexture.
+func (camera *Camera) PostProcess() *ebiten.Image {
+	return camera.PostProcessing.Apply(camera)
+}
+
+// IDTexture returns the camera's final result ID texture from any previous Render() or RenderNodes() calls, which encodes the
+// triangle rendered to each pixel for picking. If Camera.RenderIDs is set to false, the function will return nil instead.
+func (camera *Camera) IDTexture() *ebiten.Image {
+	if !camera.RenderIDs {
+		return nil
+	}
+	return camera.resultIDTexture
+}
+
 // AccumulationColorTexture returns the camera's final result accumulation color texture from previous renders. If the Camera's AccumulateColorMode
 // property is set to AccumulateColorModeNone, the function will return nil instead.
 func (camera *Camera) AccumulationColorTexture() *ebiten.Image {
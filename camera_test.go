@@ -0,0 +1,286 @@
+package tetra3d
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+func TestCameraCullMask(t *testing.T) {
+
+	const viewModelLayer RenderLayer = 1 << 1
+
+	scene := NewScene("Test")
+
+	world := NewModel(NewCubeMesh(), "World")
+	world.Mesh.MeshParts[0].Material.Shadeless = true
+	world.Color.Set(1, 0, 0, 1)
+
+	viewModel := NewModel(NewCubeMesh(), "View Model")
+	viewModel.Mesh.MeshParts[0].Material.Shadeless = true
+	viewModel.Color.Set(0, 1, 0, 1)
+	viewModel.SetLocalPosition(0, 0, 2)
+	viewModel.SetLocalScale(0.5, 0.5, 0.5)
+	viewModel.SetLayers(viewModelLayer, true)
+
+	scene.Root.AddChildren(world, viewModel)
+
+	camera := NewCamera(64, 64)
+	camera.SetLocalPosition(0, 0, 5)
+	scene.Root.AddChildren(camera)
+
+	sr := NewSoftwareRenderer(camera)
+
+	sr.Clear()
+	sr.RenderScene(scene)
+
+	if c := sr.ColorBuffer().RGBAAt(32, 32); c != (color.RGBA{0, 255, 0, 255}) {
+		t.Fatalf("center pixel is %v, expected the view model's green", c)
+	}
+
+	camera.CullMask = RenderLayerDefault
+
+	sr.Clear()
+	sr.RenderScene(scene)
+
+	if c := sr.ColorBuffer().RGBAAt(32, 32); c != (color.RGBA{255, 0, 0, 255}) {
+		t.Fatalf("center pixel is %v, expected the world's red with the view model culled", c)
+	}
+
+	camera.CullMask = viewModelLayer
+
+	sr.Clear()
+	sr.RenderScene(scene)
+
+	if c := sr.ColorBuffer().RGBAAt(20, 32); c.A != 0 {
+		t.Fatalf("pixel beside the view model is %v, expected the world to be culled", c)
+	}
+
+	if clone := camera.Clone().(*Camera); clone.CullMask != viewModelLayer {
+		t.Fatalf("cloned camera has cull mask %d, expected %d", clone.CullMask, viewModelLayer)
+	}
+
+	if clone := viewModel.Clone(); clone.Layers() != viewModelLayer {
+		t.Fatalf("cloned model has layers %d, expected %d", clone.Layers(), viewModelLayer)
+	}
+
+}
+
+func TestLightLayers(t *testing.T) {
+
+	scene := NewScene("Test")
+	scene.World.AmbientLight.On = false
+
+	cube := NewModel(NewCubeMesh(), "Cube")
+	scene.Root.AddChildren(cube)
+
+	light := NewPointLight("Light", 1, 1, 1, 1)
+	light.SetLocalPosition(0, 0, 3)
+	scene.Root.AddChildren(light)
+
+	if light.Layers() != RenderLayerAll {
+		t.Fatalf("lights should be on every layer by default")
+	}
+
+	camera := NewCamera(64, 64)
+	camera.SetLocalPosition(0, 0, 5)
+	scene.Root.AddChildren(camera)
+
+	sr := NewSoftwareRenderer(camera)
+
+	sr.Clear()
+	sr.RenderScene(scene)
+
+	if c := sr.ColorBuffer().RGBAAt(32, 32); c.R == 0 {
+		t.Fatalf("center pixel is %v, expected the cube to be lit", c)
+	}
+
+	light.SetLayers(1<<2, false)
+
+	sr.Clear()
+	sr.RenderScene(scene)
+
+	if c := sr.ColorBuffer().RGBAAt(32, 32); c.R != 0 || c.A != 255 {
+		t.Fatalf("center pixel is %v, expected the cube to be unlit by a light on another layer", c)
+	}
+
+}
+
+func TestSplitViewports(t *testing.T) {
+
+	screen := image.Rect(0, 0, 640, 360)
+
+	for count, expected := range map[int][]image.Rectangle{
+		1: {screen},
+		2: {image.Rect(0, 0, 320, 360), image.Rect(320, 0, 640, 360)},
+		3: {image.Rect(0, 0, 320, 180), image.Rect(320, 0, 640, 180), image.Rect(0, 180, 640, 360)},
+		4: {image.Rect(0, 0, 320, 180), image.Rect(320, 0, 640, 180), image.Rect(0, 180, 320, 360), image.Rect(320, 180, 640, 360)},
+	} {
+
+		viewports := SplitViewports(screen, count)
+
+		if len(viewports) != len(expected) {
+			t.Fatalf("expected %d viewports, got %v", count, viewports)
+		}
+
+		for i := range viewports {
+			if viewports[i] != expected[i] {
+				t.Fatalf("viewport %d of %d is %v, expected %v", i, count, viewports[i], expected[i])
+			}
+		}
+
+	}
+
+	if tall := SplitViewports(image.Rect(0, 0, 360, 640), 2); tall[0] != image.Rect(0, 0, 360, 320) || tall[1] != image.Rect(0, 320, 360, 640) {
+		t.Fatalf("expected two viewports to be stacked in a tall area, got %v", tall)
+	}
+
+	if SplitViewports(screen, 0) != nil {
+		t.Fatalf("expected no viewports for no players")
+	}
+
+}
+
+func TestCameraViewport(t *testing.T) {
+
+	camera := NewCamera(64, 64)
+	camera.SetViewport(image.Rect(320, 0, 640, 180))
+
+	if w, h := camera.Size(); w != 320 || h != 180 {
+		t.Fatalf("camera wasn't resized to its viewport, got %dx%d", w, h)
+	}
+
+	if x, y, inside := camera.ToViewport(330, 20); x != 10 || y != 20 || !inside {
+		t.Fatalf("position within the viewport converted to %d, %d (inside: %t)", x, y, inside)
+	}
+
+	if _, _, inside := camera.ToViewport(10, 20); inside {
+		t.Fatalf("position outside of the viewport was reported to be inside")
+	}
+
+	camera.Resize(100, 50)
+
+	if vp := camera.Viewport(); vp != image.Rect(320, 0, 420, 50) {
+		t.Fatalf("viewport should follow the camera's size, got %v", vp)
+	}
+
+	if clone := camera.Clone().(*Camera); clone.Viewport() != camera.Viewport() {
+		t.Fatalf("cloned camera's viewport is %v, expected %v", clone.Viewport(), camera.Viewport())
+	}
+
+}
+
+func TestCameraBindTexture(t *testing.T) {
+
+	scene := NewScene("Test")
+
+	monitorScreen := NewModel(NewPlaneMesh(), "Monitor")
+	monitorScreen.SetLocalRotation(NewMatrix4Rotate(1, 0, 0, 1.5708))
+	scene.Root.AddChildren(monitorScreen)
+
+	security := NewCamera(32, 32)
+	security.SetLocalPosition(0, 5, 10)
+	scene.Root.AddChildren(security)
+
+	screenMat := monitorScreen.Mesh.MeshParts[0].Material
+	security.BindTexture(screenMat)
+
+	camera := NewCamera(64, 64)
+	camera.SetLocalPosition(0, 0, 5)
+	scene.Root.AddChildren(camera)
+
+	camera.Clear()
+	camera.RenderScene(scene)
+
+	if security.textureFrame == 0 || screenMat.Texture == nil || screenMat.Texture != security.boundTexture {
+		t.Fatalf("the bound camera wasn't rendered for the material's texture")
+	}
+
+	// The bound camera sees the monitor, which shows what it saw last.
+	if security.DebugInfo.DrawnParts == 0 {
+		t.Fatalf("the bound camera should see the monitor showing its own texture")
+	}
+
+	// Rendering again without clearing starts a new frame, so the bound camera keeps updating.
+	security.DebugInfo.DrawnParts = -1
+	camera.RenderScene(scene)
+
+	if security.DebugInfo.DrawnParts == -1 {
+		t.Fatalf("the bound camera should be rendered again when the camera renders again without being cleared")
+	}
+
+	// Cameras seeing each other's textures shouldn't render each other endlessly.
+	other := NewModel(NewPlaneMesh(), "Other Monitor")
+	other.SetLocalRotation(NewMatrix4Rotate(1, 0, 0, 1.5708))
+	other.SetLocalPosition(0, 0, 20)
+	scene.Root.AddChildren(other)
+	camera.BindTexture(other.Mesh.MeshParts[0].Material)
+	security.SetLocalRotation(NewMatrix4Rotate(0, 1, 0, 3.1416))
+
+	security.DebugInfo.DrawnParts = -1
+	camera.Clear()
+	camera.RenderScene(scene)
+
+	if security.DebugInfo.DrawnParts == -1 {
+		t.Fatalf("the bound camera should be rendered again after the camera is cleared")
+	}
+
+	// The camera was partway through rendering when the bound camera saw its texture, so it wasn't rendered again.
+	if camera.textureFrame != 0 || other.Mesh.MeshParts[0].Material.Texture != camera.boundTexture {
+		t.Fatalf("the camera was rendered for its texture while it was rendering")
+	}
+
+}
+
+func TestCameraUnbindTexture(t *testing.T) {
+
+	scene := NewScene("Test")
+
+	monitorScreen := NewModel(NewPlaneMesh(), "Monitor")
+	monitorScreen.SetLocalRotation(NewMatrix4Rotate(1, 0, 0, 1.5708))
+	scene.Root.AddChildren(monitorScreen)
+
+	original := ebiten.NewImage(4, 4)
+	screenMat := monitorScreen.Mesh.MeshParts[0].Material
+	screenMat.Texture = original
+
+	security := NewCamera(32, 32)
+	security.BindTexture(screenMat)
+
+	// The bound camera renders for the first camera to see it, even if that camera was never cleared.
+	camera := NewCamera(64, 64)
+	camera.SetLocalPosition(0, 0, 5)
+	scene.Root.AddChildren(camera)
+	camera.RenderScene(scene)
+
+	if security.textureFrame == 0 || screenMat.Texture != security.boundTexture {
+		t.Fatalf("the bound camera wasn't rendered for the material's texture")
+	}
+
+	// Clearing every camera before rendering any of them renders the bound camera once.
+	other := NewCamera(64, 64)
+	other.SetLocalPosition(0, 0, 5)
+	scene.Root.AddChildren(other)
+
+	camera.Clear()
+	other.Clear()
+	camera.RenderScene(scene)
+	rendered := security.textureFrame
+	other.RenderScene(scene)
+
+	if security.textureFrame != rendered {
+		t.Fatalf("the bound camera was rendered more than once for split-screen cameras")
+	}
+
+	screenMat.TextureCamera = nil
+
+	camera.Clear()
+	camera.RenderScene(scene)
+
+	if screenMat.Texture != original {
+		t.Fatalf("unbinding the camera should give the material its original texture back")
+	}
+
+}
@@ -161,6 +161,30 @@ func (color *Color) ConvertTosRGB() {
 
 }
 
+// ConvertToLinear() converts the color's R, G, and B components from the sRGB color space to linear space; it's the
+// inverse of ConvertTosRGB(), and is used to convert colors back to how they should be stored in GLTF files.
+func (color *Color) ConvertToLinear() {
+
+	if color.R <= 0.04045 {
+		color.R /= 12.92
+	} else {
+		color.R = float32(math.Pow((float64(color.R)+0.055)/1.055, 2.4))
+	}
+
+	if color.G <= 0.04045 {
+		color.G /= 12.92
+	} else {
+		color.G = float32(math.Pow((float64(color.G)+0.055)/1.055, 2.4))
+	}
+
+	if color.B <= 0.04045 {
+		color.B /= 12.92
+	} else {
+		color.B = float32(math.Pow((float64(color.B)+0.055)/1.055, 2.4))
+	}
+
+}
+
 func (color *Color) String() string {
 	if ReadableReferences {
 		return fmt.Sprintf("<%0.2f, %0.2f, %0.2f, %0.2f>", color.R, color.G, color.B, color.A)
@@ -0,0 +1,496 @@
+package tetra3d
+
+import (
+	"errors"
+	"math"
+	"strings"
+)
+
+const (
+	ConstraintTrackTo       = iota // Rotates the node so that its TrackAxis points at the Target, with its UpAxis pointing up as much as possible.
+	ConstraintDampedTrack          // Rotates the node as little as possible for its TrackAxis to point at the Target.
+	ConstraintCopyLocation         // Copies the Target's world position on the constraint's Axes.
+	ConstraintCopyRotation         // Copies the Target's world rotation on the constraint's Axes (as euler angles).
+	ConstraintCopyScale            // Copies the Target's world scale on the constraint's Axes.
+	ConstraintLimitDistance        // Limits the node's distance from the Target (see Constraint.DistanceMode).
+	ConstraintChildOf              // Transforms the node by the Target as though the node were parented to it.
+)
+
+const (
+	LimitDistanceInside    = iota // The node is kept within the Distance of the Target.
+	LimitDistanceOutside          // The node is kept outside of the Distance of the Target.
+	LimitDistanceOnSurface        // The node is kept at exactly the Distance from the Target.
+)
+
+// Constraint is a rule that transforms a Node relative to a Target node after the Node's been animated, like Blender's object constraints -
+// for example, to have a Node look at another, or to follow it. Constraints are added to Nodes with Node.AddConstraints(), and applied in
+// order with Node.ApplyConstraints(). An AnimationPlayer applies the constraints of the nodes in its RootNode's tree each update, after
+// animating them.
+//
+// Constraints are applied over the transform the Node had before its constraints were last applied (unless the Node's been moved or
+// animated since), so they don't build up when they're applied every frame.
+type Constraint struct {
+	Type   int   // The type of the Constraint (i.e. ConstraintTrackTo).
+	Target INode // The node the Constraint is relative to. A Constraint without a Target does nothing.
+
+	// Influence is how much the Constraint affects the node, ranging from 0 (the node is unaffected) to 1 (the node is fully constrained).
+	// Defaults to 1.
+	Influence float64
+
+	TrackAxis Vector // The local axis of the node that points at the Target for track-to and damped track Constraints. Defaults to +Z.
+	UpAxis    Vector // The local axis of the node that points up for track-to Constraints. Defaults to +Y.
+
+	// Axes is the axes copy Constraints copy, with 1 for an axis that's copied, -1 for an axis that's copied inverted (for location and
+	// rotation), and 0 for an axis that isn't copied. Defaults to {1, 1, 1}, copying every axis.
+	Axes Vector
+	// If Offset is true, copy Constraints add the Target's values to the node's own (or multiply them, for scale) rather than replacing them.
+	Offset bool
+
+	// Distance is the distance from the Target limit distance Constraints keep the node at. If it's 0 when the Constraint is first applied,
+	// it's set to the distance the node is from the Target.
+	Distance     float64
+	DistanceMode int // How limit distance Constraints keep the node at the Distance (i.e. LimitDistanceInside).
+
+	// ChildOfInverse is the transform that child-of Constraints transform the node by before the Target's transform; by default, it's the
+	// inverse of the Target's transform when the Constraint was created, so the node stays where it is until the Target moves.
+	ChildOfInverse Matrix4
+}
+
+// NewConstraint creates a new Constraint of the given type (i.e. ConstraintCopyLocation) relative to the Target node given.
+func NewConstraint(constraintType int, target INode) *Constraint {
+
+	constraint := &Constraint{
+		Type:           constraintType,
+		Target:         target,
+		Influence:      1,
+		TrackAxis:      Vector{0, 0, 1, 0},
+		UpAxis:         Vector{0, 1, 0, 0},
+		Axes:           Vector{1, 1, 1, 0},
+		ChildOfInverse: NewMatrix4(),
+	}
+
+	if constraintType == ConstraintChildOf && target != nil {
+		constraint.ChildOfInverse = target.Transform().Inverted()
+	}
+
+	return constraint
+
+}
+
+// NewTrackToConstraint creates a new track-to Constraint that points the node's local track axis at the target, with its local up axis
+// pointing up as much as possible. For example, a Camera looks down -Z, so a Camera could look at a target with a track axis of -Z and an
+// up axis of +Y.
+func NewTrackToConstraint(target INode, trackAxis, upAxis Vector) *Constraint {
+	constraint := NewConstraint(ConstraintTrackTo, target)
+	constraint.TrackAxis = trackAxis
+	constraint.UpAxis = upAxis
+	return constraint
+}
+
+// NewLimitDistanceConstraint creates a new limit distance Constraint that keeps the node at the distance given from the target, using
+// the mode given (i.e. LimitDistanceInside).
+func NewLimitDistanceConstraint(target INode, distance float64, mode int) *Constraint {
+	constraint := NewConstraint(ConstraintLimitDistance, target)
+	constraint.Distance = distance
+	constraint.DistanceMode = mode
+	return constraint
+}
+
+// Clone returns a clone of the Constraint, with the same Target. (Cloning a Node points the cloned Constraints of its tree at the clones
+// of any Targets in the tree.)
+func (constraint *Constraint) Clone() *Constraint {
+	clone := *constraint
+	return &clone
+}
+
+// retargetClonedConstraints points the Constraints of the cloned nodes given at the clones of their Targets, for Targets that are among the
+// original nodes given; both slices should list the nodes in the same order. ChildOfInverse is left as is, as the cloned Targets are
+// transformed like the originals relative to the rest of the clones.
+func retargetClonedConstraints(originals, clones []INode) {
+
+	nodeClones := map[*Node]INode{}
+	for i, n := range originals {
+		if i < len(clones) {
+			nodeClones[n.baseNode()] = clones[i]
+		}
+	}
+
+	for _, n := range clones {
+		for _, constraint := range n.baseNode().constraints {
+			if constraint.Target == nil {
+				continue
+			}
+			if target, exists := nodeClones[constraint.Target.baseNode()]; exists {
+				constraint.Target = target
+			}
+		}
+	}
+
+}
+
+// apply applies the Constraint to the node.
+func (constraint *Constraint) apply(node *Node) {
+
+	if constraint.Target == nil || constraint.Influence <= 0 {
+		return
+	}
+
+	transform := node.Transform()
+	position, scale, rotation := transform.Decompose()
+
+	targetTransform := constraint.Target.Transform()
+	targetPosition, targetScale, targetRotation := targetTransform.Decompose()
+
+	newPosition, newScale, newRotation := position, scale, rotation
+
+	switch constraint.Type {
+
+	case ConstraintTrackTo:
+
+		if toTarget := targetPosition.Sub(position); toTarget.MagnitudeSquared() > 1e-12 {
+			newRotation = constraintTrackRotation(constraint.TrackAxis, constraint.UpAxis, toTarget, rotation)
+		}
+
+	case ConstraintDampedTrack:
+
+		current := rotation.MultVec(constraint.TrackAxis)
+		toTarget := targetPosition.Sub(position)
+
+		if axis := current.Cross(toTarget); axis.MagnitudeSquared() > 1e-12 {
+			newRotation = rotation.Mult(NewMatrix4Rotate(axis.X, axis.Y, axis.Z, current.Angle(toTarget)))
+		} else if current.Dot(toTarget) < 0 {
+			// Pointing directly away from the Target; any perpendicular axis turns the node around.
+			axis = constraintPerpendicular(current)
+			newRotation = rotation.Mult(NewMatrix4Rotate(axis.X, axis.Y, axis.Z, math.Pi))
+		}
+
+	case ConstraintCopyLocation:
+
+		newPosition = constraint.copyAxes(position, targetPosition, false)
+
+	case ConstraintCopyRotation:
+
+		if constraint.Axes == (Vector{1, 1, 1, 0}) && !constraint.Offset {
+			newRotation = targetRotation
+		} else {
+			newRotation = NewMatrix4RotateFromEuler(constraint.copyAxes(matrixToEuler(rotation), matrixToEuler(targetRotation), false))
+		}
+
+	case ConstraintCopyScale:
+
+		newScale = constraint.copyAxes(scale, targetScale, true)
+
+	case ConstraintLimitDistance:
+
+		diff := position.Sub(targetPosition)
+		dist := diff.Magnitude()
+
+		if constraint.Distance <= 0 {
+			constraint.Distance = dist
+		}
+
+		if dist < 1e-8 {
+			diff = WorldUp
+		}
+
+		if (constraint.DistanceMode == LimitDistanceInside && dist > constraint.Distance) ||
+			(constraint.DistanceMode == LimitDistanceOutside && dist < constraint.Distance) ||
+			constraint.DistanceMode == LimitDistanceOnSurface {
+			newPosition = targetPosition.Add(diff.Unit().Scale(constraint.Distance))
+		}
+
+	case ConstraintChildOf:
+
+		newPosition, newScale, newRotation = transform.Mult(constraint.ChildOfInverse).Mult(targetTransform).Decompose()
+
+	}
+
+	if constraint.Influence < 1 {
+		newPosition = position.Add(newPosition.Sub(position).Scale(constraint.Influence))
+		newScale = scale.Add(newScale.Sub(scale).Scale(constraint.Influence))
+		newRotation = rotation.ToQuaternion().Lerp(newRotation.ToQuaternion(), constraint.Influence).Normalized().ToMatrix4()
+	}
+
+	node.SetWorldRotation(newRotation)
+	node.SetWorldScaleVec(newScale)
+	node.SetWorldPositionVec(newPosition)
+
+}
+
+// copyAxes returns the values, with the target values copied over them on the Constraint's Axes.
+func (constraint *Constraint) copyAxes(values, target Vector, scale bool) Vector {
+
+	axes := [3]float64{constraint.Axes.X, constraint.Axes.Y, constraint.Axes.Z}
+	in := [3]float64{values.X, values.Y, values.Z}
+	from := [3]float64{target.X, target.Y, target.Z}
+
+	for i, axis := range axes {
+
+		if axis == 0 {
+			continue
+		}
+
+		if scale {
+			if constraint.Offset {
+				in[i] *= from[i]
+			} else {
+				in[i] = from[i]
+			}
+		} else {
+			if constraint.Offset {
+				in[i] += from[i] * axis
+			} else {
+				in[i] = from[i] * axis
+			}
+		}
+
+	}
+
+	return Vector{in[0], in[1], in[2], values.W}
+
+}
+
+// constraintTrackRotation returns a rotation that points the local track axis in the direction given, with the local up axis pointing
+// as close to WorldUp as it can. If the direction is straight up or down, the up axis points as close as it can to where it pointed in the
+// current rotation.
+func constraintTrackRotation(trackAxis, upAxis, direction Vector, current Matrix4) Matrix4 {
+
+	track := trackAxis.Unit()
+	up := upAxis.Sub(track.Scale(upAxis.Dot(track)))
+	if up.MagnitudeSquared() < 1e-12 {
+		up = constraintPerpendicular(track)
+	}
+	up = up.Unit()
+
+	forward := direction.Unit()
+	worldUp := WorldUp.Sub(forward.Scale(WorldUp.Dot(forward)))
+	if worldUp.MagnitudeSquared() < 1e-12 {
+		worldUp = current.MultVec(up)
+		worldUp = worldUp.Sub(forward.Scale(worldUp.Dot(forward)))
+	}
+	if worldUp.MagnitudeSquared() < 1e-12 {
+		worldUp = constraintPerpendicular(forward)
+	}
+	worldUp = worldUp.Unit()
+
+	local := NewMatrix4()
+	local.SetRow(0, track)
+	local.SetRow(1, up)
+	local.SetRow(2, track.Cross(up))
+
+	world := NewMatrix4()
+	world.SetRow(0, forward)
+	world.SetRow(1, worldUp)
+	world.SetRow(2, forward.Cross(worldUp))
+
+	// The local axes are orthonormal, so transposing them inverts them.
+	return local.Transposed().Mult(world)
+
+}
+
+// constraintPerpendicular returns a vector perpendicular to the one given.
+func constraintPerpendicular(vec Vector) Vector {
+	perp := vec.Cross(WorldRight)
+	if perp.MagnitudeSquared() < 1e-12 {
+		perp = vec.Cross(WorldUp)
+	}
+	return perp.Unit()
+}
+
+// matrixToEuler returns the euler angles for the rotation Matrix4 given, such that NewMatrix4RotateFromEuler() returns the same rotation.
+func matrixToEuler(matrix Matrix4) Vector {
+
+	euler := Vector{}
+
+	euler.Z = math.Asin(clamp(matrix[1][0], -1, 1))
+
+	if math.Abs(matrix[1][0]) < 0.9999 {
+		euler.Y = math.Atan2(-matrix[2][0], matrix[0][0])
+		euler.X = math.Atan2(-matrix[1][2], matrix[1][1])
+	} else {
+		// Gimbal lock; the Y and X rotations are around the same axis, so the rotation's put entirely on the X axis.
+		euler.X = math.Atan2(matrix[2][1], matrix[2][2])
+	}
+
+	return euler
+
+}
+
+// constraintAxis returns the axis Vector for an axis name exported from Blender (i.e. "x" or "-z").
+func constraintAxis(name string) (Vector, error) {
+
+	axis := Vector{}
+	sign := 1.0
+
+	if strings.HasPrefix(name, "-") {
+		sign = -1
+		name = strings.TrimPrefix(name, "-")
+	}
+
+	switch name {
+	case "x":
+		axis.X = sign
+	case "y":
+		axis.Y = sign
+	case "z":
+		axis.Z = sign
+	default:
+		return axis, errors.New("unknown axis {" + name + "}")
+	}
+
+	return axis, nil
+
+}
+
+// newConstraintFromExport creates a Constraint from the data the Blender exporter stores for an object's constraint, finding its target
+// using the function given.
+func newConstraintFromExport(data map[string]interface{}, findNode func(name string) INode) (*Constraint, error) {
+
+	constraintTypes := map[string]int{
+		"TRACK_TO":       ConstraintTrackTo,
+		"DAMPED_TRACK":   ConstraintDampedTrack,
+		"COPY_LOCATION":  ConstraintCopyLocation,
+		"COPY_ROTATION":  ConstraintCopyRotation,
+		"COPY_SCALE":     ConstraintCopyScale,
+		"LIMIT_DISTANCE": ConstraintLimitDistance,
+		"CHILD_OF":       ConstraintChildOf,
+	}
+
+	typeName, _ := data["type"].(string)
+	constraintType, exists := constraintTypes[typeName]
+	if !exists {
+		return nil, errors.New("unsupported constraint type {" + typeName + "}")
+	}
+
+	targetName, _ := data["target"].(string)
+	target := findNode(targetName)
+	if target == nil {
+		return nil, errors.New("constraint target {" + targetName + "} not found")
+	}
+
+	constraint := NewConstraint(constraintType, target)
+
+	if influence, ok := data["influence"].(float64); ok {
+		constraint.Influence = influence
+	}
+
+	if track, ok := data["track"].(string); ok {
+		axis, err := constraintAxis(track)
+		if err != nil {
+			return nil, err
+		}
+		constraint.TrackAxis = axis
+	}
+
+	if up, ok := data["up"].(string); ok {
+		axis, err := constraintAxis(up)
+		if err != nil {
+			return nil, err
+		}
+		constraint.UpAxis = axis
+	}
+
+	if axes, ok := data["axes"].([]interface{}); ok && len(axes) == 3 {
+		values := [3]float64{}
+		for i, a := range axes {
+			values[i], _ = a.(float64)
+		}
+		constraint.Axes = Vector{values[0], values[1], values[2], 0}
+	}
+
+	if offset, ok := data["offset"].(bool); ok {
+		constraint.Offset = offset
+	}
+
+	if distance, ok := data["distance"].(float64); ok {
+		constraint.Distance = distance
+	}
+
+	switch data["mode"] {
+	case "OUTSIDE":
+		constraint.DistanceMode = LimitDistanceOutside
+	case "ONSURFACE":
+		constraint.DistanceMode = LimitDistanceOnSurface
+	}
+
+	if inverse, ok := data["inverse"].([]interface{}); ok && len(inverse) == 16 {
+		for i, v := range inverse {
+			constraint.ChildOfInverse[i/4][i%4], _ = v.(float64)
+		}
+	}
+
+	return constraint, nil
+
+}
+
+/////
+
+// AddConstraints adds the Constraints given to the Node, to be applied in order after any Constraints it already has.
+func (node *Node) AddConstraints(constraints ...*Constraint) {
+	node.constraints = append(node.constraints, constraints...)
+}
+
+// RemoveConstraints removes the Constraints given from the Node.
+func (node *Node) RemoveConstraints(constraints ...*Constraint) {
+	for _, constraint := range constraints {
+		for i, c := range node.constraints {
+			if c == constraint {
+				node.constraints = append(node.constraints[:i], node.constraints[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// Constraints returns the Constraints added to the Node.
+func (node *Node) Constraints() []*Constraint {
+	return node.constraints
+}
+
+// ApplyConstraints applies the Node's Constraints to it in order. If recursive is true, the Constraints of the Node's children (and their
+// children, and so on) are applied afterwards.
+func (node *Node) ApplyConstraints(recursive bool) {
+
+	if len(node.constraints) > 0 {
+
+		// If the Node hasn't been moved since its constraints were last applied, it goes back to where it was before so that the constraints
+		// don't build on their last results.
+		state := &node.constraintState
+		if state.applied && node.position == state.position && node.scale == state.scale && node.rotation == state.rotation {
+			node.position = state.basePosition
+			node.scale = state.baseScale
+			node.rotation = state.baseRotation
+			node.dirtyTransform()
+		}
+
+		state.basePosition = node.position
+		state.baseScale = node.scale
+		state.baseRotation = node.rotation
+
+		for _, constraint := range node.constraints {
+			constraint.apply(node)
+		}
+
+		state.position = node.position
+		state.scale = node.scale
+		state.rotation = node.rotation
+		state.applied = true
+
+	}
+
+	if recursive {
+		for _, child := range node.children {
+			child.baseNode().ApplyConstraints(true)
+		}
+	}
+
+}
+
+// nodeConstraintState is the local transform of a Node before and after its Constraints were last applied.
+type nodeConstraintState struct {
+	basePosition, baseScale Vector
+	baseRotation            Matrix4
+	position, scale         Vector
+	rotation                Matrix4
+	applied                 bool
+}
@@ -0,0 +1,346 @@
+package tetra3d
+
+import (
+	"math"
+	"testing"
+)
+
+func TestConstraintTracking(t *testing.T) {
+
+	scene := NewScene("Test")
+
+	target := NewNode("Target")
+	target.SetLocalPosition(5, 0, 0)
+
+	camera := NewNode("Camera")
+	camera.AddConstraints(NewTrackToConstraint(target, Vector{0, 0, -1, 0}, Vector{0, 1, 0, 0}))
+
+	scene.Root.AddChildren(target, camera)
+	scene.Root.(*Node).ApplyConstraints(true)
+
+	rotation := camera.WorldRotation()
+
+	if looking := rotation.MultVec(Vector{0, 0, -1, 0}); !looking.Equals(Vector{1, 0, 0, 0}) {
+		t.Fatalf("track-to constraint should point -Z at the target, but it points towards %s", looking)
+	}
+
+	if up := rotation.MultVec(Vector{0, 1, 0, 0}); !up.Equals(Vector{0, 1, 0, 0}) {
+		t.Fatalf("track-to constraint should keep +Y pointing up, but it points towards %s", up)
+	}
+
+	// Damped tracking rotates as little as it can, so a node rolled around its track axis stays rolled.
+	spotlight := NewNode("Spotlight")
+	spotlight.SetLocalRotation(NewMatrix4Rotate(0, 0, 1, 0.5))
+	spotlight.SetLocalPosition(5, 5, 0)
+	spotlight.AddConstraints(NewConstraint(ConstraintDampedTrack, target))
+	scene.Root.AddChildren(spotlight)
+
+	spotlight.ApplyConstraints(false)
+
+	if looking := spotlight.WorldRotation().Forward(); !looking.Equals(Vector{0, -1, 0, 0}) {
+		t.Fatalf("damped track constraint should point +Z at the target, but it points towards %s", looking)
+	}
+
+}
+
+func TestConstraintCopyTransforms(t *testing.T) {
+
+	target := NewNode("Target")
+	target.SetLocalPosition(4, 5, 6)
+	target.SetLocalScale(2, 3, 4)
+	target.SetLocalRotation(NewMatrix4RotateFromEuler(Vector{0.3, -0.4, 0.5, 0}))
+
+	node := NewNode("Node")
+	node.SetLocalPosition(1, 2, 3)
+
+	copyLocation := NewConstraint(ConstraintCopyLocation, target)
+	copyLocation.Axes = Vector{1, 0, -1, 0}
+	node.AddConstraints(copyLocation)
+	node.ApplyConstraints(false)
+
+	if pos := node.WorldPosition(); !pos.Equals(Vector{4, 2, -6, 0}) {
+		t.Fatalf("copy location constraint should copy X and inverted Z, but node is at %s", pos)
+	}
+
+	copyLocation.Influence = 0.5
+	node.ApplyConstraints(false)
+
+	if pos := node.WorldPosition(); !pos.Equals(Vector{2.5, 2, -1.5, 0}) {
+		t.Fatalf("copy location constraint with an influence of 0.5 should move the node halfway, but node is at %s", pos)
+	}
+
+	node.RemoveConstraints(copyLocation)
+
+	copyScale := NewConstraint(ConstraintCopyScale, target)
+	copyScale.Axes = Vector{0, 1, 1, 0}
+	node.AddConstraints(copyScale)
+
+	copyRotation := NewConstraint(ConstraintCopyRotation, target)
+	copyRotation.Axes = Vector{1, 0, 1, 0}
+	node.AddConstraints(copyRotation)
+
+	node.ApplyConstraints(false)
+
+	if scale := node.WorldScale(); !scale.Equals(Vector{1, 3, 4, 0}) {
+		t.Fatalf("copy scale constraint should copy Y and Z, but node is scaled %s", scale)
+	}
+
+	if euler := matrixToEuler(node.WorldRotation()); !euler.Equals(Vector{0.3, 0, 0.5, 0}) {
+		t.Fatalf("copy rotation constraint should copy X and Z, but node is rotated %s", euler)
+	}
+
+	// Offsetting adds to the node's own values, but applying the constraint again doesn't add to them again.
+	node.RemoveConstraints(copyScale, copyRotation)
+
+	if len(node.Constraints()) != 0 {
+		t.Fatalf("node should have no constraints left, but has %d", len(node.Constraints()))
+	}
+
+	copyLocation = NewConstraint(ConstraintCopyLocation, target)
+	copyLocation.Offset = true
+	node.AddConstraints(copyLocation)
+
+	node.SetLocalPosition(1, 1, 1)
+	node.ApplyConstraints(false)
+	node.ApplyConstraints(false)
+
+	if pos := node.WorldPosition(); !pos.Equals(Vector{5, 6, 7, 0}) {
+		t.Fatalf("copy location constraint with offset should add the target's position once, but node is at %s", pos)
+	}
+
+	node.SetLocalPosition(0, 0, 0)
+	node.ApplyConstraints(false)
+
+	if pos := node.WorldPosition(); !pos.Equals(Vector{4, 5, 6, 0}) {
+		t.Fatalf("moving a constrained node should constrain it from where it was moved to, but node is at %s", pos)
+	}
+
+}
+
+func TestConstraintLimitDistance(t *testing.T) {
+
+	target := NewNode("Target")
+	target.SetLocalPosition(0, 1, 0)
+
+	node := NewNode("Node")
+	node.SetLocalPosition(10, 1, 0)
+
+	limit := NewLimitDistanceConstraint(target, 2, LimitDistanceInside)
+	node.AddConstraints(limit)
+	node.ApplyConstraints(false)
+
+	if pos := node.WorldPosition(); !pos.Equals(Vector{2, 1, 0, 0}) {
+		t.Fatalf("limit distance constraint should keep the node within 2 units, but node is at %s", pos)
+	}
+
+	limit.DistanceMode = LimitDistanceOutside
+	limit.Distance = 20
+	node.ApplyConstraints(false)
+
+	if pos := node.WorldPosition(); !pos.Equals(Vector{20, 1, 0, 0}) {
+		t.Fatalf("limit distance constraint should keep the node outside of 20 units, but node is at %s", pos)
+	}
+
+	// A limit distance constraint without a distance keeps the distance the node started at.
+	other := NewNode("Other")
+	other.SetLocalPosition(0, 4, 0)
+	other.AddConstraints(NewLimitDistanceConstraint(target, 0, LimitDistanceOnSurface))
+	other.ApplyConstraints(false)
+
+	target.SetLocalPosition(0, 0, 0)
+	other.SetLocalPosition(0, 10, 0)
+	other.ApplyConstraints(false)
+
+	if pos := other.WorldPosition(); !pos.Equals(Vector{0, 3, 0, 0}) {
+		t.Fatalf("limit distance constraint should keep the node 3 units away, but node is at %s", pos)
+	}
+
+}
+
+func TestConstraintChildOf(t *testing.T) {
+
+	target := NewNode("Target")
+
+	node := NewNode("Node")
+	node.SetLocalPosition(0, 0, 1)
+	node.AddConstraints(NewConstraint(ConstraintChildOf, target))
+
+	node.ApplyConstraints(false)
+
+	if pos := node.WorldPosition(); !pos.Equals(Vector{0, 0, 1, 0}) {
+		t.Fatalf("child-of constraint shouldn't move the node until the target moves, but node is at %s", pos)
+	}
+
+	target.SetLocalPosition(5, 0, 0)
+	target.SetLocalRotation(NewMatrix4Rotate(0, 1, 0, math.Pi/2))
+
+	node.ApplyConstraints(false)
+	node.ApplyConstraints(false)
+
+	if pos := node.WorldPosition(); !pos.Equals(Vector{6, 0, 0, 0}) {
+		t.Fatalf("child-of constraint should move and turn the node with the target, but node is at %s", pos)
+	}
+
+	if forward := node.WorldRotation().Forward(); !forward.Equals(Vector{1, 0, 0, 0}) {
+		t.Fatalf("child-of constraint should turn the node with the target, but node faces %s", forward)
+	}
+
+}
+
+func TestConstraintSceneClone(t *testing.T) {
+
+	scene := NewScene("Test")
+
+	target := NewNode("Target")
+	target.SetLocalPosition(5, 0, 0)
+
+	node := NewNode("Node")
+	node.SetLocalPosition(0, 0, 1)
+	node.AddConstraints(NewConstraint(ConstraintChildOf, target))
+
+	follower := NewNode("Follower")
+	follower.AddConstraints(NewConstraint(ConstraintCopyLocation, target))
+
+	scene.Root.AddChildren(target, node, follower)
+
+	clone := scene.Clone()
+	cloneTarget := clone.Root.Get("Target")
+	cloneNode := clone.Root.Get("Node").(*Node)
+
+	if cloneNode.Constraints()[0].Target != cloneTarget || clone.Root.Get("Follower").(*Node).Constraints()[0].Target != cloneTarget {
+		t.Fatalf("cloning a scene should point cloned constraints at the cloned targets")
+	}
+
+	cloneTarget.SetLocalPosition(5, 2, 0)
+	clone.Root.(*Node).ApplyConstraints(true)
+	scene.Root.(*Node).ApplyConstraints(true)
+
+	if pos := cloneNode.WorldPosition(); !pos.Equals(Vector{0, 2, 1, 0}) {
+		t.Fatalf("cloned child-of constraint should move the node with the cloned target, but node is at %s", pos)
+	}
+
+	if pos := clone.Root.Get("Follower").WorldPosition(); !pos.Equals(Vector{5, 2, 0, 0}) {
+		t.Fatalf("cloned copy location constraint should follow the cloned target, but node is at %s", pos)
+	}
+
+	if pos := node.WorldPosition(); !pos.Equals(Vector{0, 0, 1, 0}) {
+		t.Fatalf("moving the cloned target shouldn't move the original node, but it's at %s", pos)
+	}
+
+	if pos := follower.WorldPosition(); !pos.Equals(Vector{5, 0, 0, 0}) {
+		t.Fatalf("moving the cloned target shouldn't move the original follower, but it's at %s", pos)
+	}
+
+}
+
+func TestConstraintAfterAnimation(t *testing.T) {
+
+	armature := NewNode("Armature")
+	bone := NewNode("Bone")
+	target := NewNode("Target")
+	target.SetLocalPosition(0, 3, 0)
+	armature.AddChildren(bone, target)
+
+	copyLocation := NewConstraint(ConstraintCopyLocation, target)
+	copyLocation.Axes = Vector{0, 1, 0, 0}
+	bone.AddConstraints(copyLocation)
+
+	player := armature.AnimationPlayer()
+	player.PlayAnim(constantPositionAnimation("Move", 1, map[string]Vector{"Bone": {2, 0, 0, 0}}))
+	player.Update(0.1)
+
+	if pos := bone.WorldPosition(); !pos.Equals(Vector{2, 3, 0, 0}) {
+		t.Fatalf("constraints should be applied after animation, but bone is at %s", pos)
+	}
+
+	clone := armature.Clone()
+	if constraints := clone.Get("Bone").(*Node).Constraints(); len(constraints) != 1 || constraints[0] == copyLocation {
+		t.Fatalf("cloning a node should clone its constraints")
+	}
+
+	if constraints := clone.Get("Bone").(*Node).Constraints(); constraints[0].Target != clone.Get("Target") {
+		t.Fatalf("cloning a node should point its constraints at the clones of targets in the cloned tree")
+	}
+
+	// Targets outside of the cloned tree are kept.
+	if constraints := bone.Clone().(*Node).Constraints(); constraints[0].Target != target {
+		t.Fatalf("cloning a node should keep constraint targets that are outside of the cloned tree")
+	}
+
+}
+
+func TestConstraintFromExport(t *testing.T) {
+
+	target := NewNode("Target")
+	findNode := func(name string) INode {
+		if name == target.Name() {
+			return target
+		}
+		return nil
+	}
+
+	constraint, err := newConstraintFromExport(map[string]interface{}{
+		"type":      "TRACK_TO",
+		"target":    "Target",
+		"influence": 0.5,
+		"track":     "-z",
+		"up":        "y",
+	}, findNode)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if constraint.Type != ConstraintTrackTo || constraint.Target != target || constraint.Influence != 0.5 ||
+		!constraint.TrackAxis.Equals(Vector{0, 0, -1, 0}) || !constraint.UpAxis.Equals(Vector{0, 1, 0, 0}) {
+		t.Fatalf("track-to constraint wasn't loaded properly")
+	}
+
+	constraint, err = newConstraintFromExport(map[string]interface{}{
+		"type":   "COPY_LOCATION",
+		"target": "Target",
+		"axes":   []interface{}{1.0, 0.0, -1.0},
+		"offset": true,
+	}, findNode)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !constraint.Axes.Equals(Vector{1, 0, -1, 0}) || !constraint.Offset {
+		t.Fatalf("copy location constraint wasn't loaded properly")
+	}
+
+	constraint, err = newConstraintFromExport(map[string]interface{}{
+		"type":    "CHILD_OF",
+		"target":  "Target",
+		"inverse": []interface{}{1.0, 0.0, 0.0, 0.0, 0.0, 1.0, 0.0, 0.0, 0.0, 0.0, 1.0, 0.0, -2.0, -3.0, -4.0, 1.0},
+	}, findNode)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if inverse := NewMatrix4Translate(-2, -3, -4); !constraint.ChildOfInverse.Equals(inverse) {
+		t.Fatalf("child-of constraint's inverse matrix wasn't loaded properly")
+	}
+
+	if _, err := newConstraintFromExport(map[string]interface{}{"type": "CHILD_OF", "target": "Missing"}, findNode); err == nil {
+		t.Fatalf("loading a constraint with a missing target should return an error")
+	}
+
+	if _, err := newConstraintFromExport(map[string]interface{}{"type": "SHRINKWRAP", "target": "Target"}, findNode); err == nil {
+		t.Fatalf("loading an unsupported constraint should return an error")
+	}
+
+}
+
+func TestMatrixToEuler(t *testing.T) {
+
+	for _, euler := range []Vector{{0.3, -0.4, 0.5, 0}, {-1, 2, 0.1, 0}, {0, 0, 0, 0}} {
+		if result := matrixToEuler(NewMatrix4RotateFromEuler(euler)); !result.Equals(euler) {
+			t.Fatalf("euler angles %s became %s", euler, result)
+		}
+	}
+
+}
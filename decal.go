@@ -0,0 +1,307 @@
+package tetra3d
+
+import (
+	"math"
+	"sort"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Decal is a Model that projects a texture onto the triangles of the Models and BoundingTriangles it overlaps, for things like bullet holes,
+// footprints, or blood splats that follow the shape of the surfaces underneath them. A Decal projects a box, centered on the Decal, along
+// its forward vector (+Z); point it into a surface to project onto it - for example, for a RayHit, the Decal's rotation could be
+// NewLookAtMatrix(hit.Position, hit.Position.Sub(hit.Normal), WorldUp). The Decal's up vector (+Y) is the top of the texture.
+//
+// Call Decal.Project() to generate the Decal's geometry, clipped to the box. The geometry is generated relative to the Decal, so parenting
+// a Decal to a moving Model keeps it in place on the Model without projecting it again. Note that Decals are projected onto Models' Meshes
+// as they are at rest, without skinning or morphing.
+type Decal struct {
+	*Model
+	Material *Material // The Material the Decal is drawn with; its Texture is the texture projected.
+
+	Width, Height, Depth float64 // The size of the box the Decal projects, relative to the Decal. The texture spans the Width and Height of the box.
+
+	// MaxAngle is the maximum angle (in radians) between the direction the Decal projects in and the direction into a triangle for the triangle
+	// to receive the Decal. Steep triangles stretch the texture across them, so lowering this helps to keep the Decal on the surfaces it faces.
+	// Defaults to pi / 2, so only triangles that face the Decal receive it.
+	MaxAngle float64
+
+	Offset float64 // How far the Decal is pushed off of the surfaces it's projected onto to avoid z-fighting; defaults to 0.01.
+
+	// Lifetime is how long the Decal lasts in seconds as it's updated with Decal.Update(); once it expires, the Decal unparents itself.
+	// If Lifetime is 0 (the default), the Decal lasts forever.
+	Lifetime float64
+	FadeTime float64 // How long the Decal takes to fade out before its Lifetime expires, in seconds.
+	Age      float64 // How long the Decal has existed in seconds, as it's updated with Decal.Update().
+
+	fadeAlpha float32 // The alpha of the Decal's Color when it started fading out
+	fading    bool
+	aabb      *BoundingAABB
+}
+
+// NewDecal creates a new Decal that projects the texture given in a box of the given size. The Decal doesn't have any geometry until
+// Decal.Project() is called.
+func NewDecal(name string, texture *ebiten.Image, width, height, depth float64) *Decal {
+
+	mat := NewMaterial(name)
+	mat.Texture = texture
+	mat.TransparencyMode = TransparencyModeTransparent
+
+	decal := &Decal{
+		Model:    NewModel(NewMesh(name), name),
+		Material: mat,
+		Width:    width,
+		Height:   height,
+		Depth:    depth,
+		MaxAngle: math.Pi / 2,
+		Offset:   0.01,
+		aabb:     NewBoundingAABB("decal aabb", 1, 1, 1),
+	}
+
+	return decal
+
+}
+
+// Clone returns a clone of the Decal. The clone shares the Decal's Material and geometry until it's projected.
+func (decal *Decal) Clone() INode {
+
+	clone := NewDecal(decal.name, decal.Material.Texture, decal.Width, decal.Height, decal.Depth)
+	clone.Model = decal.Model.Clone().(*Model)
+
+	for _, child := range clone.children {
+		child.setParent(clone)
+	}
+
+	clone.Material = decal.Material
+	clone.MaxAngle = decal.MaxAngle
+	clone.Offset = decal.Offset
+	clone.Lifetime = decal.Lifetime
+	clone.FadeTime = decal.FadeTime
+	clone.Age = decal.Age
+	clone.fadeAlpha = decal.fadeAlpha
+	clone.fading = decal.fading
+
+	return clone
+
+}
+
+// Project generates the Decal's geometry, projecting it onto the triangles of the Models and BoundingTriangles of the given nodes and the nodes
+// underneath them in the scenegraph (so projecting onto a Scene's Root projects the Decal onto everything in the Scene). Other Decals and
+// InstancedModels aren't projected onto. If the Decal covers more triangles than a single MeshPart can render (see MaxTriangleCount),
+// its geometry is split across multiple MeshParts.
+func (decal *Decal) Project(targets ...INode) {
+
+	decal.Mesh = NewMesh(decal.name)
+
+	if decal.Width <= 0 || decal.Height <= 0 || decal.Depth <= 0 {
+		return
+	}
+
+	transform := decal.Transform()
+	inverse := transform.Inverted()
+	forward := decal.WorldRotation().Forward()
+	minFacing := math.Cos(decal.MaxAngle)
+
+	half := Vector{decal.Width / 2, decal.Height / 2, decal.Depth / 2, 0}
+
+	decal.updateAABB(transform, half)
+
+	verts := []VertexInfo{}
+
+	// project clips the triangle (in world space) to the Decal's box, adding the clipped geometry.
+	project := func(p0, p1, p2 Vector) {
+
+		normal := calculateNormal(p0, p1, p2)
+
+		if normal.Dot(forward.Invert()) <= minFacing {
+			return
+		}
+
+		offset := normal.Scale(decal.Offset)
+
+		polygon := []clipVertex{
+			{Position: inverse.MultVec(p0.Add(offset))},
+			{Position: inverse.MultVec(p1.Add(offset))},
+			{Position: inverse.MultVec(p2.Add(offset))},
+		}
+
+		for _, axis := range []Vector{{1, 0, 0, 0}, {0, 1, 0, 0}, {0, 0, 1, 0}} {
+			limit := axis.Dot(half)
+			polygon = clipPolygon(polygon, axis, -limit)
+			polygon = clipPolygon(polygon, axis.Invert(), -limit)
+		}
+
+		for i := 1; i < len(polygon)-1; i++ {
+			for _, v := range []clipVertex{polygon[0], polygon[i], polygon[i+1]} {
+				// Looking along the Decal's forward vector, +X is to the left, so U runs the other way.
+				texU := 0.5 - v.Position.X/decal.Width
+				texV := 0.5 + v.Position.Y/decal.Height
+				verts = append(verts, NewVertex(v.Position.X, v.Position.Y, v.Position.Z, texU, texV))
+			}
+		}
+
+	}
+
+	for _, target := range targets {
+
+		nodes := append([]INode{target}, target.SearchTree().INodes()...)
+
+		for _, node := range nodes {
+
+			switch n := node.(type) {
+
+			case *Model:
+
+				if n.Mesh == nil || !n.visible {
+					continue
+				}
+
+				modelTransform := n.Transform()
+
+				if !decal.aabb.Colliding(n.BoundingSphere) {
+					continue
+				}
+
+				for _, tri := range n.Mesh.Triangles {
+					project(
+						modelTransform.MultVec(n.Mesh.VertexPositions[tri.VertexIndices[0]]),
+						modelTransform.MultVec(n.Mesh.VertexPositions[tri.VertexIndices[1]]),
+						modelTransform.MultVec(n.Mesh.VertexPositions[tri.VertexIndices[2]]),
+					)
+				}
+
+			case *BoundingTriangles:
+
+				btTransform := n.Transform() // This also updates the BoundingTriangles' BoundingAABB
+
+				if !decal.aabb.Colliding(n.BoundingAABB) {
+					continue
+				}
+
+				// Triangles are added in order so that the Decal is generated the same way each time.
+				triIDs := make([]int, 0, 16)
+				for triID := range n.Broadphase.TrianglesFromBounding(decal.aabb) {
+					triIDs = append(triIDs, int(triID))
+				}
+				sort.Ints(triIDs)
+
+				for _, triID := range triIDs {
+					tri := n.Mesh.Triangles[triID]
+					project(
+						btTransform.MultVec(n.Mesh.VertexPositions[tri.VertexIndices[0]]),
+						btTransform.MultVec(n.Mesh.VertexPositions[tri.VertexIndices[1]]),
+						btTransform.MultVec(n.Mesh.VertexPositions[tri.VertexIndices[2]]),
+					)
+				}
+
+			}
+
+		}
+
+	}
+
+	addVertexMeshParts(decal.Mesh, decal.Material, verts)
+
+	decal.Mesh.UpdateBounds()
+	decal.Mesh.AutoNormal()
+
+	decal.BoundingSphere.Radius = half.Magnitude()
+
+}
+
+// updateAABB fits the Decal's BoundingAABB around its box in world space.
+func (decal *Decal) updateAABB(transform Matrix4, half Vector) {
+
+	center := transform.MultVec(Vector{})
+	extents := Vector{}
+
+	for i := 0; i < 8; i++ {
+		corner := Vector{half.X, half.Y, half.Z, 0}
+		if i&1 > 0 {
+			corner.X *= -1
+		}
+		if i&2 > 0 {
+			corner.Y *= -1
+		}
+		if i&4 > 0 {
+			corner.Z *= -1
+		}
+		diff := transform.MultVec(corner).Sub(center)
+		extents.X = math.Max(extents.X, math.Abs(diff.X))
+		extents.Y = math.Max(extents.Y, math.Abs(diff.Y))
+		extents.Z = math.Max(extents.Z, math.Abs(diff.Z))
+	}
+
+	decal.aabb.SetDimensions(extents.X*2, extents.Y*2, extents.Z*2)
+	decal.aabb.SetWorldPositionVec(center)
+	decal.aabb.Transform()
+
+}
+
+// Update ages the Decal by dt seconds (i.e. 1.0 / 60.0), fading it out by lowering the alpha of its Color over the last FadeTime seconds of
+// its Lifetime. Once its Lifetime expires, the Decal unparents itself, removing it from the scenegraph. If the Decal's Lifetime is 0, Update
+// does nothing.
+func (decal *Decal) Update(dt float64) {
+
+	if decal.Lifetime <= 0 {
+		return
+	}
+
+	decal.Age += dt
+
+	if decal.Expired() {
+		decal.Color.A = 0
+		decal.Unparent()
+		return
+	}
+
+	if remaining := decal.Lifetime - decal.Age; remaining < decal.FadeTime {
+
+		if !decal.fading {
+			decal.fadeAlpha = decal.Color.A
+			decal.fading = true
+		}
+
+		decal.Color.A = decal.fadeAlpha * float32(remaining/decal.FadeTime)
+
+	}
+
+}
+
+// Expired returns if the Decal has existed for longer than its Lifetime.
+func (decal *Decal) Expired() bool {
+	return decal.Lifetime > 0 && decal.Age >= decal.Lifetime
+}
+
+/////
+
+// AddChildren parents the provided children Nodes to the passed parent Node, inheriting its transformations and being under it in the scenegraph
+// hierarchy. If the children are already parented to other Nodes, they are unparented before doing so.
+func (decal *Decal) AddChildren(children ...INode) {
+	decal.addChildren(decal, children...)
+}
+
+// Unparent unparents the Decal from its parent, removing it from the scenegraph.
+func (decal *Decal) Unparent() {
+	if decal.parent != nil {
+		decal.parent.RemoveChildren(decal)
+	}
+}
+
+// Type returns the NodeType for this object.
+func (decal *Decal) Type() NodeType {
+	return NodeTypeDecal
+}
+
+// Index returns the index of the Node in its parent's children list.
+// If the node doesn't have a parent, its index will be -1.
+func (decal *Decal) Index() int {
+	if decal.parent != nil {
+		for i, c := range decal.parent.Children() {
+			if c == decal {
+				return i
+			}
+		}
+	}
+	return -1
+}
@@ -0,0 +1,201 @@
+package tetra3d
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+func TestDecalProject(t *testing.T) {
+
+	scene := NewScene("Test")
+
+	ground := NewBoundingTriangles("Ground", NewPlaneMesh(), 1)
+	ground.SetLocalScale(10, 1, 10)
+
+	step := NewModel(NewCubeMesh(), "Step")
+	step.SetLocalPosition(1, 0, 0)
+	step.SetLocalScale(0.5, 0.5, 0.5)
+
+	scene.Root.AddChildren(ground, step)
+
+	decal := NewDecal("Decal", nil, 2, 2, 2)
+	decal.SetLocalRotation(NewLookAtMatrix(Vector{}, WorldDown, WorldForward))
+	scene.Root.AddChildren(decal)
+
+	decal.Project(scene.Root)
+
+	mesh := decal.Mesh
+
+	if len(mesh.MeshParts) != 1 || mesh.MeshParts[0].Material != decal.Material {
+		t.Fatalf("expected a single decal mesh part, got %d parts", len(mesh.MeshParts))
+	}
+
+	transform := decal.Transform()
+	onStep := false
+
+	for i, local := range mesh.VertexPositions {
+
+		pos := transform.MultVec(local)
+
+		if math.Abs(pos.Y-decal.Offset) > 0.0001 && math.Abs(pos.Y-0.5-decal.Offset) > 0.0001 {
+			t.Fatalf("decal vertex %d at %s isn't on the ground or the top of the step", i, pos)
+		}
+
+		if math.Abs(pos.Y-0.5-decal.Offset) <= 0.0001 {
+			onStep = true
+			if pos.X < 0.5-0.0001 {
+				t.Fatalf("decal vertex %d at %s is on the step, but off of its top", i, pos)
+			}
+		}
+
+		if math.Abs(pos.X) > 1.0001 || math.Abs(pos.Z) > 1.0001 {
+			t.Fatalf("decal vertex %d at %s is outside of the decal's box", i, pos)
+		}
+
+		uv := mesh.VertexUVs[i]
+		if uv.X < -0.0001 || uv.X > 1.0001 || uv.Y < -0.0001 || uv.Y > 1.0001 {
+			t.Fatalf("decal vertex %d has UV %s outside of the decal texture", i, uv)
+		}
+
+		// The top of the texture faces -Z, and its left side faces -X.
+		if expected := (Vector{(pos.X + 1) / 2, (1 - pos.Z) / 2, 0, 0}); math.Abs(uv.X-expected.X) > 0.0001 || math.Abs(uv.Y-expected.Y) > 0.0001 {
+			t.Fatalf("decal vertex %d at %s has UV %s, expected %s", i, pos, uv, expected)
+		}
+
+	}
+
+	if !onStep {
+		t.Fatalf("expected the decal to be projected onto the top of the step")
+	}
+
+	// Projecting onto nodes that are out of the box doesn't generate anything.
+	decal.SetLocalPosition(0, 10, 0)
+	decal.Project(scene.Root)
+
+	if len(decal.Mesh.VertexPositions) != 0 {
+		t.Fatalf("expected no decal above the ground, got %d vertices", len(decal.Mesh.VertexPositions))
+	}
+
+	clone := decal.Clone().(*Decal)
+
+	if clone.Type() != NodeTypeDecal || !clone.Type().Is(NodeTypeModel) || clone.Material != decal.Material {
+		t.Fatalf("decal wasn't cloned properly")
+	}
+
+}
+
+func TestDecalRender(t *testing.T) {
+
+	scene := NewScene("Test")
+
+	ground := NewModel(NewPlaneMesh(), "Ground")
+	ground.SetLocalScale(10, 1, 10)
+	ground.Mesh.MeshParts[0].Material.Shadeless = true
+	scene.Root.AddChildren(ground)
+
+	camera := NewCamera(64, 64)
+	camera.SetLocalPosition(0, 5, 0)
+	camera.SetLocalRotation(NewMatrix4Rotate(1, 0, 0, -math.Pi/2))
+	scene.Root.AddChildren(camera)
+
+	sr := NewSoftwareRenderer(camera)
+
+	corners := []color.RGBA{{255, 0, 0, 255}, {0, 255, 0, 255}, {0, 0, 255, 255}, {255, 255, 0, 255}}
+
+	texture := ebiten.NewImage(2, 2)
+	img := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	for i, c := range corners {
+		img.SetRGBA(i%2, i/2, c)
+	}
+	sr.TextureImages[texture] = img
+
+	decal := NewDecal("Decal", texture, 2, 2, 1)
+	decal.Material.Shadeless = true
+	decal.SetLocalRotation(NewLookAtMatrix(Vector{}, WorldDown, WorldForward))
+	scene.Root.AddChildren(decal)
+
+	decal.Project(ground)
+
+	sr.Clear()
+	sr.RenderScene(scene)
+
+	// The camera's looking down with -Z at the top of the screen, so the decal's texture appears upright.
+	for i, c := range corners {
+		x, y := 26+(i%2)*12, 26+(i/2)*12
+		if rendered := sr.ColorBuffer().RGBAAt(x, y); rendered != c {
+			t.Fatalf("pixel %d, %d is %v, expected the decal's texture color %v", x, y, rendered, c)
+		}
+	}
+
+	if c := sr.ColorBuffer().RGBAAt(4, 4); c != (color.RGBA{255, 255, 255, 255}) {
+		t.Fatalf("pixel outside of the decal is %v, expected the ground", c)
+	}
+
+}
+
+func TestDecalLifetime(t *testing.T) {
+
+	scene := NewScene("Test")
+
+	decal := NewDecal("Decal", nil, 1, 1, 1)
+	decal.Lifetime = 2
+	decal.FadeTime = 1
+	decal.Color.A = 0.8
+	scene.Root.AddChildren(decal)
+
+	decal.Update(0.5)
+
+	if decal.Color.A != 0.8 {
+		t.Fatalf("decal shouldn't fade before its fade time, alpha is %f", decal.Color.A)
+	}
+
+	decal.Update(1)
+
+	if math.Abs(float64(decal.Color.A)-0.4) > 0.0001 {
+		t.Fatalf("decal halfway through fading has alpha %f, expected 0.4", decal.Color.A)
+	}
+
+	if decal.Expired() || decal.Parent() == nil {
+		t.Fatalf("decal expired early")
+	}
+
+	decal.Update(1)
+
+	if !decal.Expired() || decal.Parent() != nil {
+		t.Fatalf("decal should be removed from the scene once it expires")
+	}
+
+}
+
+func TestDecalProjectTriangleLimit(t *testing.T) {
+
+	// A finely divided floor, with a few more triangles than a single MeshPart can render.
+	cells := 105
+	floor := newTestGridMesh("Floor", cells)
+
+	if len(floor.Triangles) != cells*cells*2 || len(floor.Triangles) < MaxTriangleCount {
+		t.Fatalf("expected the floor to have %d triangles, got %d", cells*cells*2, len(floor.Triangles))
+	}
+
+	decal := NewDecal("Decal", nil, 4, 4, 2)
+	decal.SetLocalRotation(NewLookAtMatrix(Vector{}, WorldDown, WorldForward))
+	decal.Project(NewModel(floor, "Floor"))
+
+	total := 0
+
+	for _, part := range decal.Mesh.MeshParts {
+		if part.TriangleCount() >= MaxTriangleCount {
+			t.Fatalf("decal mesh part has %d triangles, which is more than can be rendered", part.TriangleCount())
+		}
+		total += part.TriangleCount()
+	}
+
+	if len(decal.Mesh.MeshParts) != 2 || total != len(floor.Triangles) {
+		t.Fatalf("expected the decal's %d triangles to be split across 2 mesh parts, got %d triangles in %d parts", len(floor.Triangles), total, len(decal.Mesh.MeshParts))
+	}
+
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"image"
 	"image/color"
+	"runtime"
 	"time"
 
 	_ "embed"
@@ -96,6 +97,8 @@ func (g *Game) Init() {
 	}
 
 	g.Camera = examples.NewBasicFreeCam(g.Scene)
+	// Process the cubes' vertices across all of the CPU's cores.
+	g.Camera.VertexWorkers = runtime.NumCPU()
 	g.System = examples.NewBasicSystemHandler(g)
 
 	ebiten.SetFPSMode(ebiten.FPSModeVsyncOffMaximum)
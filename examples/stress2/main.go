@@ -5,6 +5,7 @@ import (
 	"image"
 	"image/color"
 	"math"
+	"runtime"
 
 	_ "embed"
 
@@ -128,6 +129,8 @@ func (g *Game) Init() {
 	g.Scene.Root.AddChildren(batched)
 
 	g.Camera = examples.NewBasicFreeCam(g.Scene)
+	// Process the cubes' vertices across all of the CPU's cores.
+	g.Camera.VertexWorkers = runtime.NumCPU()
 	g.Camera.SetFar(120)
 	g.Camera.SetLocalPosition(0, 0, 15)
 
@@ -3,10 +3,16 @@ package tetra3d
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"image"
+	"io/fs"
 	"log"
 	"math"
+	"net/url"
 	"os"
+	"path"
+	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -32,10 +38,29 @@ type GLTFLoadOptions struct {
 	// blend file, known as "../assets.blend".
 	// You could then simply load the assets library first and then code the DependentLibraryResolver function to take the assets library, or code the
 	// function to use the path to load the library on demand. You could then store the loaded result as necessary if multiple levels use this assets Library.
+	// If DependentLibraryResolver is nil and a FileSystem is set, dependent Libraries are loaded automatically from GLTF or GLB files
+	// of the same name as the blend file through the FileSystem.
 	DependentLibraryResolver func(blendPath string) *Library
 
 	//If top-level objects in collections should be renamed according to their instance objects.
 	RenameCollectionObjects bool
+
+	// FileSystem is used to load the external resources that a GLTF file refers to - separate .bin buffers, texture images that
+	// weren't packed into the file, and dependent Libraries. Paths in the GLTF file are resolved relative to BasePath within the FileSystem.
+	// If FileSystem is nil, external resources aren't loaded (though Materials will still have their TexturePath set).
+	// LoadGLTFFile sets this automatically to load resources from the GLTF file's directory if it's nil.
+	FileSystem fs.FS
+	BasePath   string // The directory within the FileSystem that paths in the GLTF file are relative to; defaults to the root of the FileSystem.
+
+	// TextureCache holds the textures loaded from the FileSystem, keyed by their path within it, so that Materials that use the
+	// same image share a texture. If the same TextureCache is used for multiple loads, textures are shared between those Libraries
+	// as well. If TextureCache is nil, a new one is made for each load.
+	TextureCache map[string]*ebiten.Image
+
+	libraryCache map[string]*Library // Dependent Libraries loaded through the FileSystem
+	loading      map[string]bool     // Paths of the Libraries currently being loaded, to avoid loading dependent Libraries in a cycle
+	filePath     string              // The path of the file being loaded, if known
+	fileDir      string              // The directory the file is in on disk, if it was loaded through LoadGLTFFile
 }
 
 // DefaultGLTFLoadOptions creates an instance of GLTFLoadOptions with some sensible defaults.
@@ -51,28 +76,114 @@ func DefaultGLTFLoadOptions() *GLTFLoadOptions {
 
 // LoadGLTFFile loads a .gltf or .glb file from the filepath given, using a provided GLTFLoadOptions struct to alter how the file is loaded.
 // Passing nil for loadOptions will load the file using default load options. Unlike with DAE files, Animations (including armature-based
-// animations) and Cameras (assuming they are exported in the GLTF file) will be parsed properly.
+// animations) and Cameras (assuming they are exported in the GLTF file) will be parsed properly. If the GLTFLoadOptions don't have a
+// FileSystem set, external resources (textures and .bin buffers) are loaded from the file's directory, and dependent Libraries are
+// loaded from disk relative to the file's directory (so they can be in parent directories).
 // LoadGLTFFile will return a Library, and an error if the process fails.
-func LoadGLTFFile(path string, loadOptions *GLTFLoadOptions) (*Library, error) {
+func LoadGLTFFile(filePath string, loadOptions *GLTFLoadOptions) (*Library, error) {
 
-	fileData, err := os.ReadFile(path)
+	fileData, err := os.ReadFile(filePath)
 
 	if err != nil {
 		return nil, err
 	}
 
+	if loadOptions == nil {
+		loadOptions = DefaultGLTFLoadOptions()
+	}
+
+	if loadOptions.FileSystem == nil {
+
+		absPath, err := filepath.Abs(filePath)
+		if err != nil {
+			return nil, err
+		}
+
+		fileOptions := *loadOptions
+		fileOptions.FileSystem = os.DirFS(filepath.Dir(absPath))
+		fileOptions.BasePath = "."
+		fileOptions.filePath = absPath
+		fileOptions.fileDir = filepath.Dir(absPath)
+		loadOptions = &fileOptions
+
+	}
+
 	return LoadGLTFData(fileData, loadOptions)
 
 }
 
+// LoadGLTFFileFS loads a .gltf or .glb file from the path given within the fs.FS provided (like an embed.FS, or the result of os.DirFS()),
+// using a provided GLTFLoadOptions struct to alter how the file is loaded. External resources (textures, .bin buffers, and dependent Libraries)
+// are loaded from the same fs.FS, relative to the file's directory. Passing nil for loadOptions will load the file using default load options.
+// LoadGLTFFileFS will return a Library, and an error if the process fails.
+func LoadGLTFFileFS(fileSystem fs.FS, filePath string, loadOptions *GLTFLoadOptions) (*Library, error) {
+
+	fileData, err := fs.ReadFile(fileSystem, filePath)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if loadOptions == nil {
+		loadOptions = DefaultGLTFLoadOptions()
+	}
+
+	fileOptions := *loadOptions
+	fileOptions.FileSystem = fileSystem
+	fileOptions.BasePath = path.Dir(filePath)
+	fileOptions.filePath = path.Clean(filePath)
+
+	return LoadGLTFData(fileData, &fileOptions)
+
+}
+
 // LoadGLTFData loads a .gltf or .glb file from the byte data given, using a provided GLTFLoadOptions struct to alter how the file is loaded.
 // Passing nil for loadOptions will load the file using default load options. Unlike with DAE files, Animations (including armature-based
 // animations) and Cameras (assuming they are exported in the GLTF file) will be parsed properly.
 // LoadGLTFFile will return a Library, and an error if the process fails.
 func LoadGLTFData(data []byte, gltfLoadOptions *GLTFLoadOptions) (*Library, error) {
 
+	if gltfLoadOptions == nil {
+		gltfLoadOptions = DefaultGLTFLoadOptions()
+	}
+
 	decoder := gltf.NewDecoder(bytes.NewReader(data))
 
+	if gltfLoadOptions.FileSystem != nil {
+
+		// Copy the options so the caches can be filled in without altering the caller's options.
+		fsOptions := *gltfLoadOptions
+		gltfLoadOptions = &fsOptions
+
+		if gltfLoadOptions.BasePath == "" {
+			gltfLoadOptions.BasePath = "."
+		}
+
+		if gltfLoadOptions.TextureCache == nil {
+			gltfLoadOptions.TextureCache = map[string]*ebiten.Image{}
+		}
+
+		if gltfLoadOptions.libraryCache == nil {
+			gltfLoadOptions.libraryCache = map[string]*Library{}
+		}
+
+		if gltfLoadOptions.loading == nil {
+			gltfLoadOptions.loading = map[string]bool{}
+		}
+
+		if gltfLoadOptions.filePath != "" {
+			gltfLoadOptions.loading[gltfLoadOptions.filePath] = true
+		}
+
+		bufferFS, err := fs.Sub(gltfLoadOptions.FileSystem, gltfLoadOptions.BasePath)
+		if err != nil {
+			return nil, err
+		}
+
+		decoder = gltf.NewDecoderFS(bytes.NewReader(data), bufferFS)
+
+	}
+
 	doc := gltf.NewDocument()
 
 	err := decoder.Decode(doc)
@@ -81,10 +192,6 @@ func LoadGLTFData(data []byte, gltfLoadOptions *GLTFLoadOptions) (*Library, erro
 		return nil, err
 	}
 
-	if gltfLoadOptions == nil {
-		gltfLoadOptions = DefaultGLTFLoadOptions()
-	}
-
 	library := NewLibrary()
 
 	var images []*ebiten.Image
@@ -203,7 +310,21 @@ func LoadGLTFData(data []byte, gltfLoadOptions *GLTFLoadOptions) (*Library, erro
 			if exportedTextures {
 				newMat.Texture = images[*doc.Textures[texture.Index].Source]
 			} else {
-				newMat.TexturePath = doc.Images[*doc.Textures[texture.Index].Source].URI
+				gltfImage := doc.Images[*doc.Textures[texture.Index].Source]
+				newMat.TexturePath = gltfImage.URI
+
+				if gltfImage.BufferView != nil || gltfImage.IsEmbeddedResource() {
+					// Images can still be stored in the file itself if it wasn't exported through the Tetra3D add-on.
+					if newMat.Texture, err = decodeGLTFImage(doc, gltfImage); err != nil {
+						return nil, err
+					}
+				} else if gltfLoadOptions.FileSystem != nil && newMat.TexturePath != "" {
+					if tex, err := gltfLoadOptions.loadTexture(newMat.TexturePath); err != nil {
+						log.Println("Warning: couldn't load texture " + newMat.TexturePath + " for material " + newMat.Name + ": " + err.Error())
+					} else {
+						newMat.Texture = tex
+					}
+				}
 			}
 		}
 
@@ -322,6 +443,7 @@ func LoadGLTFData(data []byte, gltfLoadOptions *GLTFLoadOptions) (*Library, erro
 		newMesh.library = library
 
 		colorChannelNames := []string{}
+		morphTargetNames := []string{}
 
 		if mesh.Extras != nil {
 
@@ -334,9 +456,16 @@ func LoadGLTFData(data []byte, gltfLoadOptions *GLTFLoadOptions) (*Library, erro
 					}
 				}
 
+				// Blender's GLTF exporter stores shape key names here
+				if targetNames, exists := dataMap["targetNames"]; exists {
+					for _, name := range targetNames.([]interface{}) {
+						morphTargetNames = append(morphTargetNames, name.(string))
+					}
+				}
+
 				// Non-Tetra3D custom data
 				for tagName, data := range dataMap {
-					if !strings.HasPrefix(tagName, "t3d") || !strings.HasSuffix(tagName, "__") {
+					if (!strings.HasPrefix(tagName, "t3d") || !strings.HasSuffix(tagName, "__")) && tagName != "targetNames" {
 						newMesh.Properties.Get(tagName).Set(data)
 					}
 				}
@@ -483,6 +612,50 @@ func LoadGLTFData(data []byte, gltfLoadOptions *GLTFLoadOptions) (*Library, erro
 
 			newMesh.AddVertices(vertexData...)
 
+			// Morph targets are stored as deltas from the base vertex positions and normals; each primitive
+			// of a mesh has the same number of targets.
+			for targetIndex, target := range v.Targets {
+
+				if targetIndex >= len(newMesh.MorphTargets) {
+					name := "Target" + strconv.Itoa(targetIndex)
+					if targetIndex < len(morphTargetNames) {
+						name = morphTargetNames[targetIndex]
+					}
+					newMesh.AddMorphTarget(name)
+				}
+
+				morphTarget := newMesh.MorphTargets[targetIndex]
+
+				if posAccessor, exists := target[gltf.POSITION]; exists {
+
+					deltas, err := modeler.ReadPosition(doc, doc.Accessors[posAccessor], nil)
+
+					if err != nil {
+						return nil, err
+					}
+
+					for i, d := range deltas {
+						morphTarget.PositionDeltas[newMesh.vertsAddStart+i] = Vector{float64(d[0]), float64(d[1]), float64(d[2]), 0}
+					}
+
+				}
+
+				if normalAccessor, exists := target[gltf.NORMAL]; exists {
+
+					deltas, err := modeler.ReadNormal(doc, doc.Accessors[normalAccessor], nil)
+
+					if err != nil {
+						return nil, err
+					}
+
+					for i, d := range deltas {
+						morphTarget.NormalDeltas[newMesh.vertsAddStart+i] = Vector{float64(d[0]), float64(d[1]), float64(d[2]), 0}
+					}
+
+				}
+
+			}
+
 			indexBuffer := []uint32{}
 
 			indices, err := modeler.ReadIndices(doc, doc.Accessors[*v.Indices], indexBuffer)
@@ -510,6 +683,12 @@ func LoadGLTFData(data []byte, gltfLoadOptions *GLTFLoadOptions) (*Library, erro
 
 		}
 
+		for i, weight := range mesh.Weights {
+			if i < len(newMesh.MorphTargets) {
+				newMesh.MorphTargets[i].DefaultWeight = float64(weight)
+			}
+		}
+
 	}
 
 	for _, gltfAnim := range doc.Animations {
@@ -552,11 +731,21 @@ func LoadGLTFData(data []byte, gltfLoadOptions *GLTFLoadOptions) (*Library, erro
 				outputData := od.([][3]float32)
 
 				track := animChannel.AddTrack(TrackTypePosition)
-				track.Interpolation = int(sampler.Interpolation)
+				track.Interpolation = gltfInterpolation(sampler.Interpolation)
 				for i := 0; i < len(inputData); i++ {
 					t := inputData[i]
-					p := outputData[i]
-					track.AddKeyframe(float64(t), Vector{float64(p[0]), float64(p[1]), float64(p[2]), 0})
+					if track.Interpolation == InterpolationCubic {
+						// Cubic spline outputs are stored as in-tangent, value, out-tangent triplets
+						in, p, out := outputData[i*3], outputData[i*3+1], outputData[i*3+2]
+						track.AddKeyframeCubic(float64(t),
+							Vector{float64(p[0]), float64(p[1]), float64(p[2]), 0},
+							Vector{float64(in[0]), float64(in[1]), float64(in[2]), 0},
+							Vector{float64(out[0]), float64(out[1]), float64(out[2]), 0},
+						)
+					} else {
+						p := outputData[i]
+						track.AddKeyframe(float64(t), Vector{float64(p[0]), float64(p[1]), float64(p[2]), 0})
+					}
 					if float64(t) > animLength {
 						animLength = float64(t)
 					}
@@ -581,11 +770,74 @@ func LoadGLTFData(data []byte, gltfLoadOptions *GLTFLoadOptions) (*Library, erro
 				outputData := od.([][3]float32)
 
 				track := animChannel.AddTrack(TrackTypeScale)
-				track.Interpolation = int(sampler.Interpolation)
+				track.Interpolation = gltfInterpolation(sampler.Interpolation)
+				for i := 0; i < len(inputData); i++ {
+					t := inputData[i]
+					if track.Interpolation == InterpolationCubic {
+						in, p, out := outputData[i*3], outputData[i*3+1], outputData[i*3+2]
+						track.AddKeyframeCubic(float64(t),
+							Vector{float64(p[0]), float64(p[1]), float64(p[2]), 0},
+							Vector{float64(in[0]), float64(in[1]), float64(in[2]), 0},
+							Vector{float64(out[0]), float64(out[1]), float64(out[2]), 0},
+						)
+					} else {
+						p := outputData[i]
+						track.AddKeyframe(float64(t), Vector{float64(p[0]), float64(p[1]), float64(p[2]), 0})
+					}
+					if float64(t) > animLength {
+						animLength = float64(t)
+					}
+				}
+
+			} else if channel.Target.Path == gltf.TRSWeights {
+
+				if channel.Target.Node == nil || doc.Nodes[*channel.Target.Node].Mesh == nil {
+					continue
+				}
+
+				// The output accessor holds one weight for every morph target of the mesh for every keyframe.
+				targetMesh := doc.Meshes[*doc.Nodes[*channel.Target.Node].Mesh]
+				targetCount := len(targetMesh.Weights)
+				if len(targetMesh.Primitives) > 0 && len(targetMesh.Primitives[0].Targets) > targetCount {
+					targetCount = len(targetMesh.Primitives[0].Targets)
+				}
+
+				if targetCount == 0 {
+					continue
+				}
+
+				id, err := modeler.ReadAccessor(doc, doc.Accessors[sampler.Input], nil)
+
+				if err != nil {
+					return nil, err
+				}
+
+				inputData := id.([]float32)
+
+				outputData, err := gltfAccessorFloats(doc, doc.Accessors[sampler.Output])
+
+				if err != nil {
+					return nil, err
+				}
+
+				weightsAt := func(index int) []float64 {
+					weights := make([]float64, targetCount)
+					for w := range weights {
+						weights[w] = float64(outputData[index*targetCount+w])
+					}
+					return weights
+				}
+
+				track := animChannel.AddTrack(TrackTypeMorphWeights)
+				track.Interpolation = gltfInterpolation(sampler.Interpolation)
+
 				for i := 0; i < len(inputData); i++ {
 					t := inputData[i]
-					p := outputData[i]
-					track.AddKeyframe(float64(t), Vector{float64(p[0]), float64(p[1]), float64(p[2]), 0})
+					if track.Interpolation == InterpolationCubic {
+						track.AddKeyframeCubic(float64(t), weightsAt(i*3+1), weightsAt(i*3), weightsAt(i*3+2))
+					} else {
+						track.AddKeyframe(float64(t), weightsAt(i))
+					}
 					if float64(t) > animLength {
 						animLength = float64(t)
 					}
@@ -610,12 +862,21 @@ func LoadGLTFData(data []byte, gltfLoadOptions *GLTFLoadOptions) (*Library, erro
 				outputData := od.([][4]float32)
 
 				track := animChannel.AddTrack(TrackTypeRotation)
-				track.Interpolation = int(sampler.Interpolation)
+				track.Interpolation = gltfInterpolation(sampler.Interpolation)
 
 				for i := 0; i < len(inputData); i++ {
 					t := inputData[i]
-					p := outputData[i]
-					track.AddKeyframe(float64(t), NewQuaternion(float64(p[0]), float64(p[1]), float64(p[2]), float64(p[3])))
+					if track.Interpolation == InterpolationCubic {
+						in, p, out := outputData[i*3], outputData[i*3+1], outputData[i*3+2]
+						track.AddKeyframeCubic(float64(t),
+							NewQuaternion(float64(p[0]), float64(p[1]), float64(p[2]), float64(p[3])),
+							NewQuaternion(float64(in[0]), float64(in[1]), float64(in[2]), float64(in[3])),
+							NewQuaternion(float64(out[0]), float64(out[1]), float64(out[2]), float64(out[3])),
+						)
+					} else {
+						p := outputData[i]
+						track.AddKeyframe(float64(t), NewQuaternion(float64(p[0]), float64(p[1]), float64(p[2]), float64(p[3])))
+					}
 					if float64(t) > animLength {
 						animLength = float64(t)
 					}
@@ -696,6 +957,12 @@ func LoadGLTFData(data []byte, gltfLoadOptions *GLTFLoadOptions) (*Library, erro
 		if mesh != nil {
 			obj = NewModel(mesh, node.Name)
 
+			for i, weight := range node.Weights {
+				if i < len(obj.(*Model).MorphWeights) {
+					obj.(*Model).MorphWeights[i] = float64(weight)
+				}
+			}
+
 			if node.Extras != nil && nodeHasProp(node, "t3dAutoBatch__") {
 				s := node.Extras.(map[string]interface{})["t3dAutoBatch__"].(float64)
 				obj.(*Model).AutoBatchMode = int(s)
@@ -740,12 +1007,41 @@ func LoadGLTFData(data []byte, gltfLoadOptions *GLTFLoadOptions) (*Library, erro
 					pointLight.Distance = float64(*lightData.Range)
 				}
 				obj = pointLight
+			} else if lightData.Type == lightspuntual.TypeSpot {
+				inner, outer := 0.0, math.Pi/4
+				if lightData.Spot != nil {
+					inner = float64(lightData.Spot.InnerConeAngle)
+					outer = float64(lightData.Spot.OuterConeAngleOrDefault())
+				}
+				spotLight := NewSpotLight(node.Name, lightData.Color[0], lightData.Color[1], lightData.Color[2], *lightData.Intensity/80, inner, outer) // Spot lights also have wattage energy
+				if !math.IsInf(float64(*lightData.Range), 0) {
+					spotLight.Distance = float64(*lightData.Range)
+				}
+				obj = spotLight
 			} else {
 				// Any unsupported light type just gets turned into an ambient light
 				pointLight := NewAmbientLight(node.Name, lightData.Color[0], lightData.Color[1], lightData.Color[2], *lightData.Intensity/80)
 				obj = pointLight
 			}
 
+		} else if node.Extras != nil && nodeHasProp(node, "t3dAmbientLight__") {
+
+			lightData := nodeGetProp(node, "t3dAmbientLight__").(map[string]interface{})
+			color := gltfExtrasVector(lightData["color"])
+			obj = NewAmbientLight(node.Name, float32(color.X), float32(color.Y), float32(color.Z), float32(lightData["energy"].(float64)))
+
+		} else if node.Extras != nil && nodeHasProp(node, "t3dCubeLight__") {
+
+			lightData := nodeGetProp(node, "t3dCubeLight__").(map[string]interface{})
+			cubeLight := NewCubeLight(node.Name, Dimensions{gltfExtrasVector(lightData["min"]), gltfExtrasVector(lightData["max"])})
+			color := gltfExtrasVector(lightData["color"])
+			cubeLight.Color.Set(float32(color.X), float32(color.Y), float32(color.Z), 1)
+			cubeLight.Energy = float32(lightData["energy"].(float64))
+			cubeLight.Distance = lightData["distance"].(float64)
+			cubeLight.Bleed = lightData["bleed"].(float64)
+			cubeLight.LightingAngle = gltfExtrasVector(lightData["angle"])
+			obj = cubeLight
+
 		} else if node.Extras != nil && nodeHasProp(node, "t3dPathPoints__") {
 
 			points := []Vector{}
@@ -1112,6 +1408,24 @@ func LoadGLTFData(data []byte, gltfLoadOptions *GLTFLoadOptions) (*Library, erro
 					}
 				}
 
+				if constraints, exists := dataMap["t3dConstraints__"]; exists {
+
+					for _, c := range constraints.([]interface{}) {
+
+						data, _ := c.(map[string]interface{})
+
+						constraint, err := newConstraintFromExport(data, findNode)
+						if err != nil {
+							log.Println("Warning: Couldn't load constraint on " + obj.Name() + ": " + err.Error())
+							continue
+						}
+
+						obj.baseNode().AddConstraints(constraint)
+
+					}
+
+				}
+
 			}
 
 		}
@@ -1134,6 +1448,10 @@ func LoadGLTFData(data []byte, gltfLoadOptions *GLTFLoadOptions) (*Library, erro
 
 	}
 
+	buildGLTFPortals(objects)
+
+	lodModels := buildGLTFLODs(objects)
+
 	for obj, node := range objToNode {
 
 		if node.Extras != nil {
@@ -1158,11 +1476,16 @@ func LoadGLTFData(data []byte, gltfLoadOptions *GLTFLoadOptions) (*Library, erro
 							clone = findNode(cloneName).Clone()
 						} else {
 							path = strings.ReplaceAll(path, "//", "") // Blender relative paths have double-slashes; we don't need them to
-							if gltfLoadOptions.DependentLibraryResolver == nil {
+							resolver := gltfLoadOptions.DependentLibraryResolver
+							if resolver == nil && gltfLoadOptions.FileSystem != nil {
+								resolver = gltfLoadOptions.loadDependentLibrary
+							}
+
+							if resolver == nil {
 								log.Printf("Warning: No dependent library resolver defined to resolve dependent library %s for object %s.\n", path, cloneName)
 							} else {
 
-								if library := gltfLoadOptions.DependentLibraryResolver(path); library != nil {
+								if library := resolver(path); library != nil {
 									if foundNode := library.FindNode(cloneName); foundNode != nil {
 										clone = foundNode.Clone()
 									} else {
@@ -1284,6 +1607,65 @@ func LoadGLTFData(data []byte, gltfLoadOptions *GLTFLoadOptions) (*Library, erro
 					world.FogRange[1] = float32(fogEnd)
 				}
 
+				if v, exists := props["sky mode"]; exists {
+
+					switch v.(string) {
+					case "GRADIENT":
+						world.Sky = newSky(SkyModeGradient)
+					case "CUBEMAP":
+						world.Sky = newSky(SkyModeCubemap)
+					case "DOME":
+						world.Sky = newSky(SkyModeDome)
+					}
+
+				}
+
+				if sky := world.Sky; sky != nil {
+
+					for key, skyColor := range map[string]*Color{
+						"sky horizon color": sky.HorizonColor,
+						"sky zenith color":  sky.ZenithColor,
+						"sky ground color":  sky.GroundColor,
+					} {
+						if v, exists := props[key]; exists {
+							wcc := v.([]interface{})
+							skyColor.Set(float32(wcc[0].(float64)), float32(wcc[1].(float64)), float32(wcc[2].(float64)), float32(wcc[3].(float64)))
+							skyColor.ConvertTosRGB()
+						}
+					}
+
+					if v, exists := props["sky faces"]; exists {
+
+						for i, p := range v.([]interface{}) {
+
+							if i >= len(sky.FacePaths) {
+								break
+							}
+
+							sky.FacePaths[i] = p.(string)
+
+							if gltfLoadOptions.FileSystem != nil && sky.FacePaths[i] != "" {
+								if tex, err := gltfLoadOptions.loadTexture(sky.FacePaths[i]); err != nil {
+									log.Println("Warning: couldn't load sky texture " + sky.FacePaths[i] + " for world " + world.Name + ": " + err.Error())
+								} else {
+									sky.Faces[i] = tex
+								}
+							}
+
+						}
+
+					}
+
+					if v, exists := props["sky dome"]; exists {
+						if mesh, exists := library.Meshes[v.(string)]; exists {
+							sky.Dome = mesh
+						} else {
+							log.Println("Warning: couldn't find sky dome mesh " + v.(string) + " for world " + world.Name + "; was its object exported?")
+						}
+					}
+
+				}
+
 				library.Worlds[world.Name] = world
 
 			}
@@ -1300,7 +1682,9 @@ func LoadGLTFData(data []byte, gltfLoadOptions *GLTFLoadOptions) (*Library, erro
 
 		// Parent all parentless objects to the scene root to be visible.
 		for _, n := range s.Nodes {
-			scene.Root.AddChildren(objects[n])
+			if !lodModels[objects[n]] {
+				scene.Root.AddChildren(objects[n])
+			}
 		}
 
 		if s.Extras != nil {
@@ -1416,6 +1800,209 @@ func LoadGLTFData(data []byte, gltfLoadOptions *GLTFLoadOptions) (*Library, erro
 
 }
 
+// resolvePath returns the path within the GLTFLoadOptions' FileSystem of a file referred to by the GLTF file being loaded.
+func (options *GLTFLoadOptions) resolvePath(uri string) string {
+	// URIs in GLTF files are URL-encoded.
+	if unescaped, err := url.PathUnescape(uri); err == nil {
+		uri = unescaped
+	}
+	return path.Join(options.BasePath, strings.ReplaceAll(uri, "\\", "/"))
+}
+
+// loadTexture loads a texture from the GLTFLoadOptions' FileSystem, reusing it from the TextureCache if it's already been loaded.
+func (options *GLTFLoadOptions) loadTexture(uri string) (*ebiten.Image, error) {
+
+	texturePath := options.resolvePath(uri)
+
+	// Files loaded through LoadGLTFFile each have a FileSystem rooted at their own directory, so textures are cached by their path on disk.
+	cachePath := texturePath
+	if options.fileDir != "" {
+		cachePath = filepath.Join(options.fileDir, filepath.FromSlash(texturePath))
+	}
+
+	if texture, exists := options.TextureCache[cachePath]; exists {
+		return texture, nil
+	}
+
+	file, err := options.FileSystem.Open(texturePath)
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return nil, err
+	}
+
+	texture := ebiten.NewImageFromImage(img)
+	options.TextureCache[cachePath] = texture
+
+	return texture, nil
+
+}
+
+// loadDependentLibrary is the default DependentLibraryResolver when loading through a FileSystem. It loads the GLTF or GLB file
+// that was exported from the blend file given, looking for it in the same directory as the blend file. The dependent Library is
+// loaded with the same options as the loading Library.
+func (options *GLTFLoadOptions) loadDependentLibrary(blendPath string) *Library {
+
+	libraryPath := options.resolvePath(strings.TrimSuffix(blendPath, path.Ext(blendPath)))
+
+	for _, ext := range []string{".glb", ".gltf"} {
+
+		libraryOptions := *options
+		libraryOptions.BasePath = path.Dir(libraryPath)
+		libraryOptions.filePath = libraryPath + ext
+
+		if options.fileDir != "" {
+			// Dependent Libraries are often in parent directories, outside of the FileSystem that LoadGLTFFile roots at the file's
+			// directory, so they're read from disk instead.
+			libraryOptions.filePath = filepath.Join(options.fileDir, filepath.FromSlash(libraryPath+ext))
+			libraryOptions.fileDir = filepath.Dir(libraryOptions.filePath)
+			libraryOptions.FileSystem = os.DirFS(libraryOptions.fileDir)
+			libraryOptions.BasePath = "."
+		}
+
+		if library, exists := options.libraryCache[libraryOptions.filePath]; exists {
+			return library
+		}
+
+		if options.loading[libraryOptions.filePath] {
+			log.Println("Warning: dependent library " + libraryOptions.filePath + " depends on itself; skipping")
+			return nil
+		}
+
+		var data []byte
+		var err error
+
+		if options.fileDir != "" {
+			data, err = os.ReadFile(libraryOptions.filePath)
+		} else {
+			data, err = fs.ReadFile(options.FileSystem, libraryOptions.filePath)
+		}
+
+		if err != nil {
+			continue
+		}
+
+		library, err := LoadGLTFData(data, &libraryOptions)
+
+		delete(options.loading, libraryOptions.filePath)
+
+		if err != nil {
+			log.Println("Warning: couldn't load dependent library " + libraryOptions.filePath + ": " + err.Error())
+			return nil
+		}
+
+		options.libraryCache[libraryOptions.filePath] = library
+
+		return library
+
+	}
+
+	return nil
+
+}
+
+// decodeGLTFImage decodes an image stored within a GLTF file, either in a buffer view or as an embedded data URI.
+func decodeGLTFImage(doc *gltf.Document, gltfImage *gltf.Image) (*ebiten.Image, error) {
+
+	var imageData []byte
+	var err error
+
+	if gltfImage.BufferView != nil {
+		imageData, err = modeler.ReadBufferView(doc, doc.BufferViews[*gltfImage.BufferView])
+	} else {
+		imageData, err = gltfImage.MarshalData()
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(imageData))
+	if err != nil {
+		return nil, err
+	}
+
+	return ebiten.NewImageFromImage(img), nil
+
+}
+
+// gltfInterpolation converts a glTF sampler interpolation mode to a Tetra3D interpolation constant.
+func gltfInterpolation(interpolation gltf.Interpolation) int {
+	switch interpolation {
+	case gltf.InterpolationStep:
+		return InterpolationConstant
+	case gltf.InterpolationCubicSpline:
+		return InterpolationCubic
+	}
+	return InterpolationLinear
+}
+
+// gltfExtrasVector returns a Vector from an array of numbers stored in a glTF file's extras.
+func gltfExtrasVector(value interface{}) Vector {
+	values := [3]float64{}
+	if array, ok := value.([]interface{}); ok {
+		for i := 0; i < len(array) && i < len(values); i++ {
+			values[i], _ = array[i].(float64)
+		}
+	}
+	return Vector{values[0], values[1], values[2], 0}
+}
+
+// gltfAccessorFloats reads a scalar accessor as floats, converting normalized integer components (as morph target weights may be stored) to
+// floats as described in the glTF specification.
+func gltfAccessorFloats(doc *gltf.Document, accessor *gltf.Accessor) ([]float32, error) {
+
+	data, err := modeler.ReadAccessor(doc, accessor, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	scale := func(value, max float32) float32 {
+		if !accessor.Normalized {
+			return value
+		}
+		return float32(math.Max(float64(value/max), -1))
+	}
+
+	switch values := data.(type) {
+	case []float32:
+		return values, nil
+	case []int8:
+		floats := make([]float32, len(values))
+		for i, v := range values {
+			floats[i] = scale(float32(v), 127)
+		}
+		return floats, nil
+	case []uint8:
+		floats := make([]float32, len(values))
+		for i, v := range values {
+			floats[i] = scale(float32(v), 255)
+		}
+		return floats, nil
+	case []int16:
+		floats := make([]float32, len(values))
+		for i, v := range values {
+			floats[i] = scale(float32(v), 32767)
+		}
+		return floats, nil
+	case []uint16:
+		floats := make([]float32, len(values))
+		for i, v := range values {
+			floats[i] = scale(float32(v), 65535)
+		}
+		return floats, nil
+	}
+
+	return nil, errors.New("unsupported accessor type for " + accessor.Name + "; expected scalar floats or normalized integers")
+
+}
+
 func handleGameProperties(p interface{}) (string, interface{}) {
 
 	getOrDefaultInt := func(propMap map[string]interface{}, key string, defaultValue int) int {
@@ -1505,3 +2092,217 @@ func handleGameProperties(p interface{}) (string, interface{}) {
 	return name, value
 
 }
+
+// gltfPropertyNumber returns the value of a numeric Property, which could be loaded as either an int or a float64.
+func gltfPropertyNumber(prop *Property) float64 {
+	if prop.IsInt() {
+		return float64(prop.AsInt())
+	}
+	if prop.IsFloat64() {
+		return prop.AsFloat64()
+	}
+	return 0
+}
+
+// buildGLTFLODs gathers Models that are reduced levels of detail of other Models into LOD sets on those Models, returning the level of detail
+// Models, which are removed from the scene tree. A Model is a level of detail of another if it's named after it with an "_LOD" suffix and level
+// number (so "Rock_LOD1" for "Rock" or "Rock_LOD0"), or if it has an "lod_of" property naming the other Model (along with an optional "lod_level"
+// property). Levels are used past the distance in their "lod_distance" property (10 units per level by default) or below the screen size in their
+// "lod_screen_size" property; the base Model's "lod_hysteresis" and "lod_crossfade" properties set its LOD's Hysteresis and CrossFade.
+// Levels of detail are rendered in the base Model's place, so their own transforms are dropped; any children they have are moved to the base
+// Model, keeping their local transforms, so that they stay where they were relative to the level's mesh.
+func buildGLTFLODs(objects []INode) map[INode]bool {
+
+	type lodEntry struct {
+		model *Model
+		level int
+	}
+
+	models := map[string]*Model{}
+
+	for _, obj := range objects {
+		if model, isModel := obj.(*Model); isModel {
+			models[model.name] = model
+		}
+	}
+
+	bases := []*Model{}
+	entries := map[*Model][]lodEntry{}
+	lodModels := map[INode]bool{}
+
+	for _, obj := range objects {
+
+		model, isModel := obj.(*Model)
+		if !isModel || model.Mesh == nil {
+			continue
+		}
+
+		var base *Model
+		level := 0
+		props := model.Properties()
+
+		if props.Has("lod_of") {
+
+			baseName := props.Get("lod_of").AsString()
+			if base = models[baseName]; base == nil {
+				log.Println("Warning: Model " + model.name + " is a level of detail of " + baseName + ", but there's no Model with that name.")
+				continue
+			}
+
+			level = 1
+			if props.Has("lod_level") {
+				level = int(gltfPropertyNumber(props.Get("lod_level")))
+			}
+
+		} else if i := strings.LastIndex(model.name, "_LOD"); i > 0 {
+
+			n, err := strconv.Atoi(model.name[i+4:])
+			if err != nil || n <= 0 {
+				continue
+			}
+
+			if base = models[model.name[:i]]; base == nil {
+				base = models[model.name[:i]+"_LOD0"]
+			}
+
+			level = n
+
+		}
+
+		if base == nil || base == model {
+			continue
+		}
+
+		if _, exists := entries[base]; !exists {
+			bases = append(bases, base)
+		}
+
+		entries[base] = append(entries[base], lodEntry{model, level})
+		lodModels[model] = true
+
+	}
+
+	for _, base := range bases {
+
+		levels := entries[base]
+
+		sort.SliceStable(levels, func(i, j int) bool { return levels[i].level < levels[j].level })
+
+		lod := NewLOD(LODModeDistance)
+
+		for _, l := range levels {
+			if l.model.Properties().Has("lod_screen_size") {
+				lod.Mode = LODModeScreenSize
+			}
+		}
+
+		for _, l := range levels {
+
+			props := l.model.Properties()
+
+			var threshold float64
+
+			if lod.Mode == LODModeScreenSize {
+				threshold = 1 / float64(l.level+1)
+				if props.Has("lod_screen_size") {
+					threshold = gltfPropertyNumber(props.Get("lod_screen_size"))
+				}
+			} else {
+				threshold = float64(l.level) * 10
+				if props.Has("lod_distance") {
+					threshold = gltfPropertyNumber(props.Get("lod_distance"))
+				}
+			}
+
+			lod.AddLevel(l.model.Mesh, threshold)
+
+			base.AddChildren(l.model.Children()...)
+			l.model.Unparent()
+
+		}
+
+		props := base.Properties()
+
+		if props.Has("lod_hysteresis") {
+			lod.Hysteresis = gltfPropertyNumber(props.Get("lod_hysteresis"))
+		}
+
+		if props.Has("lod_crossfade") {
+			lod.CrossFade = gltfPropertyNumber(props.Get("lod_crossfade"))
+		}
+
+		base.LOD = lod
+
+	}
+
+	return lodModels
+
+}
+
+// buildGLTFPortals replaces Models that have a "portal" property with Portals shaped like their Meshes, linking them to the Sectors named
+// in their "portal_sector_a" and "portal_sector_b" properties, or otherwise to the Sectors whose AABBs contain their centers.
+func buildGLTFPortals(objects []INode) {
+
+	sectorModels := []*Model{}
+
+	for _, obj := range objects {
+		if model, isModel := obj.(*Model); isModel && model.sector != nil {
+			sectorModels = append(sectorModels, model)
+		}
+	}
+
+	findSector := func(name string) *Sector {
+		for _, model := range sectorModels {
+			if model.name == name {
+				return model.sector
+			}
+		}
+		return nil
+	}
+
+	for i, obj := range objects {
+
+		model, isModel := obj.(*Model)
+		if !isModel || model.Mesh == nil || !model.Properties().Has("portal") {
+			continue
+		}
+
+		if prop := model.Properties().Get("portal"); (prop.IsBool() && !prop.AsBool()) || (!prop.IsBool() && gltfPropertyNumber(prop) == 0) {
+			continue
+		}
+
+		portal := newPortalFromMesh(model.name, model.Mesh)
+		portal.SetLocalPositionVec(model.LocalPosition())
+		portal.SetLocalScaleVec(model.LocalScale())
+		portal.SetLocalRotation(model.LocalRotation())
+		portal.visible = model.visible
+		portal.props = model.props
+
+		if parent := model.Parent(); parent != nil {
+			parent.AddChildren(portal)
+			model.Unparent()
+		}
+
+		for _, child := range append([]INode{}, model.Children()...) {
+			portal.AddChildren(child)
+		}
+
+		objects[i] = portal
+
+		props := portal.Properties()
+
+		if props.Has("portal_sector_a", "portal_sector_b") {
+			a, b := findSector(props.Get("portal_sector_a").AsString()), findSector(props.Get("portal_sector_b").AsString())
+			if a != nil && b != nil {
+				portal.Link(a, b)
+				continue
+			}
+		}
+
+		if !portal.UpdateSectors(sectorModels...) {
+			log.Println("Warning: Portal " + portal.name + " couldn't be linked to two Sectors.")
+		}
+
+	}
+
+}
@@ -0,0 +1,1206 @@
+package tetra3d
+
+import (
+	"bytes"
+	"errors"
+	"image/png"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/qmuntal/gltf"
+	"github.com/qmuntal/gltf/ext/lightspuntual"
+	"github.com/qmuntal/gltf/modeler"
+)
+
+type GLTFSaveOptions struct {
+	// PackTextures indicates if Material textures should be encoded as PNG images and packed into the saved file. If false,
+	// each Material's TexturePath is written out as the URI of its texture instead.
+	// Note that packing textures reads back their pixels, which Ebitengine only allows once the game has started running.
+	PackTextures bool
+	// Indent indicates if the JSON of a .gltf file should be indented for readability. It has no effect when saving .glb files.
+	Indent bool
+}
+
+// DefaultGLTFSaveOptions creates an instance of GLTFSaveOptions with some sensible defaults.
+func DefaultGLTFSaveOptions() *GLTFSaveOptions {
+	return &GLTFSaveOptions{
+		PackTextures: true,
+	}
+}
+
+// SaveGLTF saves the Library given to a .gltf file at the filepath given, using a provided GLTFSaveOptions struct to alter how the file
+// is saved. Passing nil for saveOptions will save the file using default save options. The file is written in the same layout as
+// the Tetra3D Blender add-on exports, so it can be loaded back with LoadGLTFFile. Buffers are embedded in the .gltf file.
+// SaveGLTF will return an error if the process fails.
+func SaveGLTF(path string, library *Library, saveOptions *GLTFSaveOptions) error {
+	return saveGLTFFile(path, library, false, saveOptions)
+}
+
+// SaveGLB saves the Library given to a binary .glb file at the filepath given, using a provided GLTFSaveOptions struct to alter how the
+// file is saved. Passing nil for saveOptions will save the file using default save options.
+// SaveGLB will return an error if the process fails.
+func SaveGLB(path string, library *Library, saveOptions *GLTFSaveOptions) error {
+	return saveGLTFFile(path, library, true, saveOptions)
+}
+
+// SaveSceneGLTF saves a single Scene to a .gltf file at the filepath given. The Meshes, Materials, and World used by the Scene are
+// saved alongside it, as are the Animations from the Scene's Library (if it has one).
+// SaveSceneGLTF will return an error if the process fails.
+func SaveSceneGLTF(path string, scene *Scene, saveOptions *GLTFSaveOptions) error {
+	return saveGLTFFile(path, sceneAsLibrary(scene), false, saveOptions)
+}
+
+// SaveSceneGLB saves a single Scene to a binary .glb file at the filepath given. See SaveSceneGLTF for more information.
+// SaveSceneGLB will return an error if the process fails.
+func SaveSceneGLB(path string, scene *Scene, saveOptions *GLTFSaveOptions) error {
+	return saveGLTFFile(path, sceneAsLibrary(scene), true, saveOptions)
+}
+
+// SaveGLTFData encodes the Library given as .gltf (or .glb, if binary is true) data and returns it, along with an error if the
+// process fails. Passing nil for saveOptions will encode the data using default save options.
+func SaveGLTFData(library *Library, binary bool, saveOptions *GLTFSaveOptions) ([]byte, error) {
+
+	if library == nil {
+		return nil, errors.New("can't save a nil Library")
+	}
+
+	if saveOptions == nil {
+		saveOptions = DefaultGLTFSaveOptions()
+	}
+
+	doc, err := newGLTFExporter(saveOptions).export(library)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !binary && len(doc.Buffers) > 0 {
+		doc.Buffers[0].EmbeddedResource()
+	}
+
+	out := &bytes.Buffer{}
+
+	encoder := gltf.NewEncoder(out)
+	encoder.AsBinary = binary
+	if saveOptions.Indent {
+		encoder.SetJSONIndent("", "  ")
+	}
+
+	if err := encoder.Encode(doc); err != nil {
+		return nil, err
+	}
+
+	return out.Bytes(), nil
+
+}
+
+// SaveSceneGLTFData encodes a single Scene as .gltf (or .glb, if binary is true) data and returns it, along with an error if the
+// process fails. See SaveSceneGLTF for more information.
+func SaveSceneGLTFData(scene *Scene, binary bool, saveOptions *GLTFSaveOptions) ([]byte, error) {
+	if scene == nil {
+		return nil, errors.New("can't save a nil Scene")
+	}
+	return SaveGLTFData(sceneAsLibrary(scene), binary, saveOptions)
+}
+
+func saveGLTFFile(path string, library *Library, binary bool, saveOptions *GLTFSaveOptions) error {
+
+	data, err := SaveGLTFData(library, binary, saveOptions)
+
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0644)
+
+}
+
+// sceneAsLibrary returns a Library containing just the Scene given, along with its World and the Animations of its
+// original Library. The Scene's Library isn't altered.
+func sceneAsLibrary(scene *Scene) *Library {
+
+	if scene == nil {
+		return nil
+	}
+
+	library := NewLibrary()
+	library.Scenes = append(library.Scenes, scene)
+	library.ExportedScene = scene
+
+	if scene.library != nil {
+		for name, anim := range scene.library.Animations {
+			library.Animations[name] = anim
+		}
+	}
+
+	return library
+
+}
+
+// gltfExporter holds the state used to build a glTF document out of a Library.
+type gltfExporter struct {
+	doc     *gltf.Document
+	options *GLTFSaveOptions
+
+	nodeIndices     map[string]uint32
+	meshIndices     map[*Mesh]uint32
+	materialIndices map[*Material]uint32
+	imageIndices    map[*ebiten.Image]uint32
+	lights          lightspuntual.Lights
+	firstCamera     *Camera
+	sectorRendering bool
+	sectorDepth     int
+}
+
+func newGLTFExporter(options *GLTFSaveOptions) *gltfExporter {
+
+	doc := gltf.NewDocument()
+	doc.Scenes = []*gltf.Scene{}
+	doc.Asset.Generator = "Tetra3D"
+
+	return &gltfExporter{
+		doc:             doc,
+		options:         options,
+		nodeIndices:     map[string]uint32{},
+		meshIndices:     map[*Mesh]uint32{},
+		materialIndices: map[*Material]uint32{},
+		imageIndices:    map[*ebiten.Image]uint32{},
+	}
+
+}
+
+func (exporter *gltfExporter) export(library *Library) (*gltf.Document, error) {
+
+	doc := exporter.doc
+
+	// Materials and Meshes that aren't used by any Model still get saved, so they're sorted for a stable output.
+	materialNames := []string{}
+	for name := range library.Materials {
+		materialNames = append(materialNames, name)
+	}
+
+	for _, name := range sortedNames(materialNames) {
+		if _, err := exporter.materialIndex(library.Materials[name]); err != nil {
+			return nil, err
+		}
+	}
+
+	meshNames := []string{}
+	for name := range library.Meshes {
+		meshNames = append(meshNames, name)
+	}
+
+	for _, name := range sortedNames(meshNames) {
+		if _, err := exporter.meshIndex(library.Meshes[name]); err != nil {
+			return nil, err
+		}
+	}
+
+	worlds := map[string]*World{}
+
+	for name, world := range library.Worlds {
+		worlds[name] = world
+	}
+
+	for _, scene := range library.Scenes {
+
+		gltfScene := &gltf.Scene{Name: scene.Name}
+
+		for _, child := range scene.Root.Children() {
+			index, err := exporter.writeNode(child)
+			if err != nil {
+				return nil, err
+			}
+			gltfScene.Nodes = append(gltfScene.Nodes, index)
+		}
+
+		extras := map[string]interface{}{}
+
+		if scene.World != nil {
+			extras["t3dCurrentWorld__"] = scene.World.Name
+			if _, exists := worlds[scene.World.Name]; !exists {
+				worlds[scene.World.Name] = scene.World
+			}
+		}
+
+		exportProperties(scene.Properties(), extras)
+
+		gltfScene.Extras = extras
+
+		doc.Scenes = append(doc.Scenes, gltfScene)
+
+		if scene == library.ExportedScene {
+			doc.Scene = gltf.Index(uint32(len(doc.Scenes) - 1))
+		}
+
+	}
+
+	if len(doc.Scenes) == 0 {
+		return nil, errors.New("can't save a Library without any Scenes")
+	}
+
+	animNames := []string{}
+	for name := range library.Animations {
+		animNames = append(animNames, name)
+	}
+
+	for _, name := range sortedNames(animNames) {
+		if err := exporter.writeAnimation(library.Animations[name]); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(exporter.lights) > 0 {
+		doc.ExtensionsUsed = append(doc.ExtensionsUsed, lightspuntual.ExtensionName)
+		doc.Extensions = gltf.Extensions{lightspuntual.ExtensionName: map[string]interface{}{"lights": exporter.lights}}
+	}
+
+	// Exporter-wide settings are stored on the first scene, just like the Tetra3D Blender add-on does.
+	globalSettings := doc.Scenes[0].Extras.(map[string]interface{})
+
+	if exporter.options.PackTextures {
+		globalSettings["t3dPackTextures__"] = 1
+	} else {
+		globalSettings["t3dPackTextures__"] = 0
+	}
+
+	if exporter.firstCamera != nil {
+		w, h := exporter.firstCamera.Size()
+		globalSettings["t3dRenderResolutionW__"] = w
+		globalSettings["t3dRenderResolutionH__"] = h
+	}
+
+	if exporter.sectorRendering {
+		globalSettings["t3dSectorRendering__"] = 1
+		globalSettings["t3dSectorRenderDepth__"] = exporter.sectorDepth
+	}
+
+	worldNames := []string{}
+	for name := range worlds {
+		worldNames = append(worldNames, name)
+	}
+
+	worldData := map[string]interface{}{}
+	for _, name := range sortedNames(worldNames) {
+		world := worlds[name]
+		// Dome Skies refer to their Meshes by name, so the Meshes are saved even if nothing else uses them.
+		if world.Sky != nil && world.Sky.Dome != nil {
+			if _, err := exporter.meshIndex(world.Sky.Dome); err != nil {
+				return nil, err
+			}
+		}
+		worldData[name] = exportWorld(world)
+	}
+	globalSettings["t3dWorlds__"] = worldData
+
+	return doc, nil
+
+}
+
+// writeNode writes the node given, along with its children, to the document, returning the node's index. Children are written
+// before their parents, as the loader expects.
+func (exporter *gltfExporter) writeNode(node INode) (uint32, error) {
+
+	doc := exporter.doc
+
+	gltfNode := &gltf.Node{
+		Name:   node.Name(),
+		Matrix: gltf.DefaultMatrix,
+	}
+
+	extras := map[string]interface{}{}
+
+	var bounds INode
+
+	_, isPath := node.(*Path)
+	_, isGrid := node.(*Grid)
+
+	for _, child := range node.Children() {
+
+		// Paths and Grids store their points in their extras, rather than as child nodes.
+		if isPath || (isGrid && child.Type() == NodeTypeGridPoint) {
+			continue
+		}
+
+		if child.Type().Is(NodeTypeBoundingObject) {
+			if bounds == nil {
+				bounds = child
+				continue
+			}
+			log.Println("Warning: node " + node.Name() + " has more than one bounding object; only the first, " + bounds.Name() + ", will be loaded back as bounds")
+		}
+
+		childIndex, err := exporter.writeNode(child)
+		if err != nil {
+			return 0, err
+		}
+		gltfNode.Children = append(gltfNode.Children, childIndex)
+
+	}
+
+	pos := node.LocalPosition()
+	scale := node.LocalScale()
+	rot := node.LocalRotation().ToQuaternion()
+
+	gltfNode.Translation = [3]float32{float32(pos.X), float32(pos.Y), float32(pos.Z)}
+	gltfNode.Scale = [3]float32{float32(scale.X), float32(scale.Y), float32(scale.Z)}
+	gltfNode.Rotation = [4]float32{float32(rot.X), float32(rot.Y), float32(rot.Z), float32(rot.W)}
+
+	if node.Visible() {
+		extras["t3dVisible__"] = 1
+	} else {
+		extras["t3dVisible__"] = 0
+	}
+
+	switch n := node.(type) {
+
+	case *Model:
+
+		if n.Mesh != nil {
+
+			meshIndex, err := exporter.meshIndex(n.Mesh)
+			if err != nil {
+				return 0, err
+			}
+
+			gltfNode.Mesh = gltf.Index(meshIndex)
+
+			for _, weight := range n.MorphWeights {
+				gltfNode.Weights = append(gltfNode.Weights, float32(weight))
+			}
+
+		}
+
+		extras["t3dAutoBatch__"] = n.AutoBatchMode
+
+		if n.sector != nil {
+			extras["t3dSector__"] = 1
+		}
+
+	case *Portal:
+
+		// Portals are exported as meshes of their polygons, which are loaded back as Portals through their "portal" properties.
+		if len(n.Points) >= 3 {
+
+			verts := make([]VertexInfo, 0, len(n.Points))
+			indices := []int{}
+
+			for i, p := range n.Points {
+				verts = append(verts, NewVertex(p.X, p.Y, p.Z, 0, 0))
+				if i >= 2 {
+					indices = append(indices, 0, i-1, i)
+				}
+			}
+
+			mesh := NewMesh(n.Name(), verts...)
+			mesh.AddMeshPart(nil, indices...)
+			mesh.UpdateBounds()
+			mesh.AutoNormal()
+
+			meshIndex, err := exporter.meshIndex(mesh)
+			if err != nil {
+				return 0, err
+			}
+
+			gltfNode.Mesh = gltf.Index(meshIndex)
+
+		}
+
+		extras["portal"] = 1
+
+		if n.Sectors[0] != nil && n.Sectors[1] != nil {
+			extras["portal_sector_a"] = n.Sectors[0].Model.Name()
+			extras["portal_sector_b"] = n.Sectors[1].Model.Name()
+		}
+
+	case *Camera:
+
+		gltfCam := &gltf.Camera{Name: n.Name()}
+
+		if n.Perspective() {
+			gltfCam.Perspective = &gltf.Perspective{
+				Yfov:  float32(ToRadians(n.FieldOfView())),
+				Znear: float32(n.Near()),
+				Zfar:  gltf.Float(float32(n.Far())),
+			}
+		} else {
+			w, h := n.Size()
+			gltfCam.Orthographic = &gltf.Orthographic{
+				Xmag:  float32(n.OrthoScale() / 2),
+				Ymag:  float32(n.OrthoScale() / 2 * float64(h) / float64(w)),
+				Znear: float32(n.Near()),
+				Zfar:  float32(n.Far()),
+			}
+		}
+
+		doc.Cameras = append(doc.Cameras, gltfCam)
+		gltfNode.Camera = gltf.Index(uint32(len(doc.Cameras) - 1))
+
+		if exporter.firstCamera == nil {
+			exporter.firstCamera = n
+		}
+
+		if n.SectorRendering {
+			exporter.sectorRendering = true
+			exporter.sectorDepth = n.SectorRenderDepth
+		}
+
+	case *DirectionalLight:
+		exporter.addLight(gltfNode, &lightspuntual.Light{
+			Type:      lightspuntual.TypeDirectional,
+			Name:      n.Name(),
+			Color:     &[3]float32{n.Color.R, n.Color.G, n.Color.B},
+			Intensity: gltf.Float(n.Energy),
+		})
+
+	case *PointLight:
+		light := &lightspuntual.Light{
+			Type:      lightspuntual.TypePoint,
+			Name:      n.Name(),
+			Color:     &[3]float32{n.Color.R, n.Color.G, n.Color.B},
+			Intensity: gltf.Float(n.Energy * 80), // Point lights have wattage energy
+		}
+		if n.Distance > 0 {
+			light.Range = gltf.Float(float32(n.Distance))
+		}
+		exporter.addLight(gltfNode, light)
+
+	case *SpotLight:
+		light := &lightspuntual.Light{
+			Type:      lightspuntual.TypeSpot,
+			Name:      n.Name(),
+			Color:     &[3]float32{n.Color.R, n.Color.G, n.Color.B},
+			Intensity: gltf.Float(n.Energy * 80),
+			Spot: &lightspuntual.Spot{
+				InnerConeAngle: float32(n.InnerConeAngle),
+				OuterConeAngle: gltf.Float(float32(n.OuterConeAngle)),
+			},
+		}
+		if n.Distance > 0 {
+			light.Range = gltf.Float(float32(n.Distance))
+		}
+		exporter.addLight(gltfNode, light)
+
+	case *AmbientLight:
+
+		// Ambient and cube lights aren't punctual lights, so they're saved in the node's extras instead.
+		extras["t3dAmbientLight__"] = map[string]interface{}{
+			"color":  []float32{n.Color.R, n.Color.G, n.Color.B},
+			"energy": n.Energy,
+		}
+
+	case *CubeLight:
+
+		extras["t3dCubeLight__"] = map[string]interface{}{
+			"color":    []float32{n.Color.R, n.Color.G, n.Color.B},
+			"energy":   n.Energy,
+			"distance": n.Distance,
+			"bleed":    n.Bleed,
+			"angle":    []float64{n.LightingAngle.X, n.LightingAngle.Y, n.LightingAngle.Z},
+			"min":      []float64{n.Dimensions.Min.X, n.Dimensions.Min.Y, n.Dimensions.Min.Z},
+			"max":      []float64{n.Dimensions.Max.X, n.Dimensions.Max.Y, n.Dimensions.Max.Z},
+		}
+
+	case *Path:
+
+		// Path points are stored in Blender's coordinate space (Z-up).
+		points := []interface{}{}
+		for _, child := range n.Children() {
+			p := child.LocalPosition()
+			points = append(points, []float64{p.X, -p.Z, p.Y})
+		}
+		extras["t3dPathPoints__"] = points
+
+		if n.Closed {
+			extras["t3dPathCyclic__"] = 1
+		} else {
+			extras["t3dPathCyclic__"] = 0
+		}
+
+	case *Grid:
+
+		gridPoints := n.Points()
+		pointIndices := map[*GridPoint]int{}
+
+		entries := []string{}
+		for i, gp := range gridPoints {
+			p := gp.LocalPosition()
+			entries = append(entries, "("+strconv.FormatFloat(p.X, 'f', -1, 64)+", "+strconv.FormatFloat(-p.Z, 'f', -1, 64)+", "+strconv.FormatFloat(p.Y, 'f', -1, 64)+")")
+			pointIndices[gp] = i
+		}
+
+		connections := map[string][]string{}
+		for i, gp := range gridPoints {
+			connected := []string{}
+			for _, c := range gp.Connections {
+				if index, exists := pointIndices[c]; exists {
+					connected = append(connected, strconv.Itoa(index))
+				}
+			}
+			connections[strconv.Itoa(i)] = connected
+		}
+
+		extras["t3dGridEntries__"] = entries
+		extras["t3dGridConnections__"] = connections
+
+	}
+
+	if bounds != nil {
+		exportBounds(bounds, extras)
+	}
+
+	exportProperties(node.Properties(), extras)
+
+	gltfNode.Extras = extras
+
+	doc.Nodes = append(doc.Nodes, gltfNode)
+	index := uint32(len(doc.Nodes) - 1)
+
+	// Animation channels refer to nodes by name, so the first node with a given name is the one that's animated.
+	if _, exists := exporter.nodeIndices[node.Name()]; !exists {
+		exporter.nodeIndices[node.Name()] = index
+	}
+
+	return index, nil
+
+}
+
+func (exporter *gltfExporter) addLight(gltfNode *gltf.Node, light *lightspuntual.Light) {
+	exporter.lights = append(exporter.lights, light)
+	gltfNode.Extensions = gltf.Extensions{lightspuntual.ExtensionName: map[string]interface{}{"light": len(exporter.lights) - 1}}
+}
+
+// exportBounds stores the size of the bounding object given in the extras of its parent, so that the loader recreates it.
+func exportBounds(bounds INode, extras map[string]interface{}) {
+
+	switch b := bounds.(type) {
+	case *BoundingAABB:
+		extras["t3dBoundsType__"] = 1
+		extras["t3dAABBCustomEnabled__"] = 1
+		extras["t3dAABBCustomSize__"] = []float64{b.internalSize.X, b.internalSize.Y, b.internalSize.Z}
+	case *BoundingCapsule:
+		extras["t3dBoundsType__"] = 2
+		extras["t3dCapsuleCustomEnabled__"] = 1
+		extras["t3dCapsuleCustomHeight__"] = b.Height
+		extras["t3dCapsuleCustomRadius__"] = b.Radius
+	case *BoundingSphere:
+		extras["t3dBoundsType__"] = 3
+		extras["t3dSphereCustomEnabled__"] = 1
+		extras["t3dSphereCustomRadius__"] = b.Radius
+	case *BoundingTriangles:
+		extras["t3dBoundsType__"] = 4
+		gridSize := 0.0
+		if b.Broadphase != nil {
+			gridSize = b.Broadphase.cellSize - 1 // The broadphase adds a unit of room to each cell
+		}
+		extras["t3dTrianglesCustomBroadphaseEnabled__"] = 1
+		extras["t3dTrianglesCustomBroadphaseGridSize__"] = gridSize
+	}
+
+}
+
+func (exporter *gltfExporter) materialIndex(material *Material) (uint32, error) {
+
+	if index, exists := exporter.materialIndices[material]; exists {
+		return index, nil
+	}
+
+	doc := exporter.doc
+
+	color := material.Color.Clone()
+	color.ConvertToLinear()
+
+	gltfMat := &gltf.Material{
+		Name:        material.Name,
+		DoubleSided: !material.BackfaceCulling,
+		PBRMetallicRoughness: &gltf.PBRMetallicRoughness{
+			BaseColorFactor: &[4]float32{color.R, color.G, color.B, color.A},
+		},
+	}
+
+	switch material.TransparencyMode {
+	case TransparencyModeTransparent:
+		gltfMat.AlphaMode = gltf.AlphaBlend
+	case TransparencyModeAlphaClip:
+		gltfMat.AlphaMode = gltf.AlphaMask
+	default:
+		gltfMat.AlphaMode = gltf.AlphaOpaque
+	}
+
+	imageIndex := -1
+
+	if exporter.options.PackTextures {
+
+		if material.Texture != nil {
+
+			if index, exists := exporter.imageIndices[material.Texture]; exists {
+				imageIndex = int(index)
+			} else {
+
+				imageData := &bytes.Buffer{}
+
+				if err := png.Encode(imageData, material.Texture); err != nil {
+					return 0, err
+				}
+
+				index, err := modeler.WriteImage(doc, material.Name, "image/png", imageData)
+				if err != nil {
+					return 0, err
+				}
+
+				exporter.imageIndices[material.Texture] = index
+				imageIndex = int(index)
+
+			}
+
+		}
+
+	} else if material.TexturePath != "" {
+		doc.Images = append(doc.Images, &gltf.Image{URI: material.TexturePath})
+		imageIndex = len(doc.Images) - 1
+	}
+
+	if imageIndex >= 0 {
+		doc.Textures = append(doc.Textures, &gltf.Texture{Source: gltf.Index(uint32(imageIndex))})
+		gltfMat.PBRMetallicRoughness.BaseColorTexture = &gltf.TextureInfo{Index: uint32(len(doc.Textures) - 1)}
+	}
+
+	extras := map[string]interface{}{
+		"t3dMaterialColor__": []float32{color.R, color.G, color.B, color.A},
+	}
+
+	if material.Shadeless {
+		extras["t3dMaterialShadeless__"] = 1
+	}
+
+	if material.Fogless {
+		extras["t3dMaterialFogless__"] = 1
+	}
+
+	switch material.CompositeMode {
+	case ebiten.CompositeModeLighter:
+		extras["t3dCompositeMode__"] = 1
+	case ebiten.CompositeModeDestinationOut:
+		extras["t3dCompositeMode__"] = 3
+	default:
+		extras["t3dCompositeMode__"] = 0
+	}
+
+	switch material.BillboardMode {
+	case BillboardModeXZ:
+		extras["t3dBillboardMode__"] = 1
+	case BillboardModeAll:
+		extras["t3dBillboardMode__"] = 2
+	default:
+		extras["t3dBillboardMode__"] = 0
+	}
+
+	exportProperties(material.Properties(), extras)
+
+	gltfMat.Extras = extras
+
+	doc.Materials = append(doc.Materials, gltfMat)
+	index := uint32(len(doc.Materials) - 1)
+	exporter.materialIndices[material] = index
+
+	return index, nil
+
+}
+
+func (exporter *gltfExporter) meshIndex(mesh *Mesh) (uint32, error) {
+
+	if index, exists := exporter.meshIndices[mesh]; exists {
+		return index, nil
+	}
+
+	doc := exporter.doc
+
+	gltfMesh := &gltf.Mesh{Name: mesh.Name}
+
+	extras := map[string]interface{}{}
+
+	// Color channels are saved as attributes named after the channels, like Blender's GLTF exporter does.
+	channelCount := len(mesh.VertexColorChannelNames)
+	for _, colors := range mesh.VertexColors {
+		if len(colors) > channelCount {
+			channelCount = len(colors)
+		}
+	}
+
+	channelNames := make([]string, channelCount)
+	for i := range channelNames {
+		channelNames[i] = "Color" + strconv.Itoa(i)
+	}
+	for name, index := range mesh.VertexColorChannelNames {
+		if index < channelCount {
+			channelNames[index] = name
+		}
+	}
+
+	if channelCount > 0 {
+		extras["t3dVertexColorNames__"] = channelNames
+		activeChannel := 0
+		if len(mesh.VertexActiveColorChannel) > 0 {
+			activeChannel = mesh.VertexActiveColorChannel[0]
+		}
+		extras["t3dActiveVertexColorIndex__"] = activeChannel
+	}
+
+	if len(mesh.MorphTargets) > 0 {
+		targetNames := []string{}
+		for _, target := range mesh.MorphTargets {
+			targetNames = append(targetNames, target.Name)
+			gltfMesh.Weights = append(gltfMesh.Weights, float32(target.DefaultWeight))
+		}
+		extras["targetNames"] = targetNames
+	}
+
+	for name, prop := range mesh.Properties.props {
+		extras[name] = exportRawProperty(prop.Value)
+	}
+
+	gltfMesh.Extras = extras
+
+	for _, part := range mesh.MeshParts {
+
+		if part.TriangleEnd < part.TriangleStart {
+			continue
+		}
+
+		// Each MeshPart becomes a primitive containing just the vertices its triangles use.
+		start, end := math.MaxInt, 0
+		part.ForEachTri(func(tri *Triangle) {
+			for _, index := range tri.VertexIndices {
+				if index < start {
+					start = index
+				}
+				if index+1 > end {
+					end = index + 1
+				}
+			}
+		})
+
+		vertexCount := end - start
+
+		positions := make([][3]float32, vertexCount)
+		normals := make([][3]float32, vertexCount)
+		uvs := make([][2]float32, vertexCount)
+
+		for i := 0; i < vertexCount; i++ {
+			p := mesh.VertexPositions[start+i]
+			n := mesh.VertexNormals[start+i]
+			uv := mesh.VertexUVs[start+i]
+			positions[i] = [3]float32{float32(p.X), float32(p.Y), float32(p.Z)}
+			normals[i] = [3]float32{float32(n.X), float32(n.Y), float32(n.Z)}
+			uvs[i] = [2]float32{float32(uv.X), float32(1 - uv.Y)}
+		}
+
+		primitive := &gltf.Primitive{
+			Mode: gltf.PrimitiveTriangles,
+			Attributes: gltf.Attribute{
+				gltf.POSITION:   modeler.WritePosition(doc, positions),
+				gltf.NORMAL:     modeler.WriteNormal(doc, normals),
+				gltf.TEXCOORD_0: modeler.WriteTextureCoord(doc, uvs),
+			},
+		}
+
+		for channel, name := range channelNames {
+
+			colors := make([][4]uint16, vertexCount)
+
+			for i := 0; i < vertexCount; i++ {
+				vertexColors := mesh.VertexColors[start+i]
+				if channel >= len(vertexColors) || vertexColors[channel] == nil {
+					colors[i] = [4]uint16{math.MaxUint16, math.MaxUint16, math.MaxUint16, math.MaxUint16}
+					continue
+				}
+				// Colors are stored as linear values in GLTF files
+				color := vertexColors[channel].Clone()
+				color.ConvertToLinear()
+				colors[i] = [4]uint16{colorChannelToUint16(color.R), colorChannelToUint16(color.G), colorChannelToUint16(color.B), colorChannelToUint16(color.A)}
+			}
+
+			primitive.Attributes["_"+strings.ToUpper(name)] = modeler.WriteColor(doc, colors)
+
+		}
+
+		for _, target := range mesh.MorphTargets {
+
+			positionDeltas := make([][3]float32, vertexCount)
+			normalDeltas := make([][3]float32, vertexCount)
+
+			for i := 0; i < vertexCount; i++ {
+				p := target.PositionDeltas[start+i]
+				n := target.NormalDeltas[start+i]
+				positionDeltas[i] = [3]float32{float32(p.X), float32(p.Y), float32(p.Z)}
+				normalDeltas[i] = [3]float32{float32(n.X), float32(n.Y), float32(n.Z)}
+			}
+
+			primitive.Targets = append(primitive.Targets, gltf.Attribute{
+				gltf.POSITION: modeler.WritePosition(doc, positionDeltas),
+				gltf.NORMAL:   modeler.WriteNormal(doc, normalDeltas),
+			})
+
+		}
+
+		indices := []uint32{}
+		part.ForEachTri(func(tri *Triangle) {
+			for _, index := range tri.VertexIndices {
+				indices = append(indices, uint32(index-start))
+			}
+		})
+
+		primitive.Indices = gltf.Index(modeler.WriteIndices(doc, indices))
+
+		if part.Material != nil {
+			materialIndex, err := exporter.materialIndex(part.Material)
+			if err != nil {
+				return 0, err
+			}
+			primitive.Material = gltf.Index(materialIndex)
+		}
+
+		gltfMesh.Primitives = append(gltfMesh.Primitives, primitive)
+
+	}
+
+	if len(gltfMesh.Primitives) == 0 {
+		return 0, errors.New("can't save mesh " + mesh.Name + " as it has no triangles")
+	}
+
+	doc.Meshes = append(doc.Meshes, gltfMesh)
+	index := uint32(len(doc.Meshes) - 1)
+	exporter.meshIndices[mesh] = index
+
+	return index, nil
+
+}
+
+func (exporter *gltfExporter) writeAnimation(anim *Animation) error {
+
+	doc := exporter.doc
+
+	gltfAnim := &gltf.Animation{Name: anim.Name}
+
+	channelNames := []string{}
+	for name := range anim.Channels {
+		channelNames = append(channelNames, name)
+	}
+
+	for _, channelName := range sortedNames(channelNames) {
+
+		channel := anim.Channels[channelName]
+
+		var target *uint32
+
+		if index, exists := exporter.nodeIndices[channelName]; exists {
+			target = gltf.Index(index)
+		} else if channelName != "root" {
+			log.Println("Warning: animation " + anim.Name + " has a channel for node " + channelName + ", which isn't being saved; skipping channel")
+			continue
+		}
+
+		for _, trackType := range []string{TrackTypePosition, TrackTypeScale, TrackTypeRotation, TrackTypeMorphWeights} {
+
+			track, exists := channel.Tracks[trackType]
+
+			if !exists || len(track.Keyframes) == 0 {
+				continue
+			}
+
+			cubic := track.Interpolation == InterpolationCubic
+
+			times := make([]float32, 0, len(track.Keyframes))
+			for _, key := range track.Keyframes {
+				times = append(times, float32(key.Time))
+			}
+
+			input := modeler.WriteAccessor(doc, gltf.TargetNone, times)
+			doc.Accessors[input].Min = []float32{times[0]}
+			doc.Accessors[input].Max = []float32{times[len(times)-1]}
+
+			var output uint32
+			var path gltf.TRSProperty
+
+			switch trackType {
+
+			case TrackTypePosition, TrackTypeScale:
+
+				values := [][3]float32{}
+				toArray := func(data Data) [3]float32 {
+					if data.contents == nil {
+						return [3]float32{}
+					}
+					v := data.AsVector()
+					return [3]float32{float32(v.X), float32(v.Y), float32(v.Z)}
+				}
+
+				for _, key := range track.Keyframes {
+					if cubic {
+						// Cubic spline outputs are stored as in-tangent, value, out-tangent triplets
+						values = append(values, toArray(key.InTangent), toArray(key.Data), toArray(key.OutTangent))
+					} else {
+						values = append(values, toArray(key.Data))
+					}
+				}
+
+				output = modeler.WriteAccessor(doc, gltf.TargetNone, values)
+
+				path = gltf.TRSTranslation
+				if trackType == TrackTypeScale {
+					path = gltf.TRSScale
+				}
+
+			case TrackTypeRotation:
+
+				values := [][4]float32{}
+				toArray := func(data Data) [4]float32 {
+					if data.contents == nil {
+						return [4]float32{}
+					}
+					q := data.AsQuaternion()
+					return [4]float32{float32(q.X), float32(q.Y), float32(q.Z), float32(q.W)}
+				}
+
+				for _, key := range track.Keyframes {
+					if cubic {
+						values = append(values, toArray(key.InTangent), toArray(key.Data), toArray(key.OutTangent))
+					} else {
+						values = append(values, toArray(key.Data))
+					}
+				}
+
+				output = modeler.WriteAccessor(doc, gltf.TargetNone, values)
+				path = gltf.TRSRotation
+
+			case TrackTypeMorphWeights:
+
+				// Weights are written as a flat list holding one weight for every morph target for every keyframe.
+				targetCount := 0
+				for _, key := range track.Keyframes {
+					if len(key.Data.AsWeights()) > targetCount {
+						targetCount = len(key.Data.AsWeights())
+					}
+				}
+
+				values := []float32{}
+				appendWeights := func(data Data) {
+					var weights []float64
+					if data.contents != nil {
+						weights = data.AsWeights()
+					}
+					for i := 0; i < targetCount; i++ {
+						if i < len(weights) {
+							values = append(values, float32(weights[i]))
+						} else {
+							values = append(values, 0)
+						}
+					}
+				}
+
+				for _, key := range track.Keyframes {
+					if cubic {
+						appendWeights(key.InTangent)
+						appendWeights(key.Data)
+						appendWeights(key.OutTangent)
+					} else {
+						appendWeights(key.Data)
+					}
+				}
+
+				output = modeler.WriteAccessor(doc, gltf.TargetNone, values)
+				path = gltf.TRSWeights
+
+			}
+
+			interpolation := gltf.InterpolationLinear
+			switch track.Interpolation {
+			case InterpolationConstant:
+				interpolation = gltf.InterpolationStep
+			case InterpolationCubic:
+				interpolation = gltf.InterpolationCubicSpline
+			}
+
+			gltfAnim.Samplers = append(gltfAnim.Samplers, &gltf.AnimationSampler{
+				Input:         input,
+				Output:        output,
+				Interpolation: interpolation,
+			})
+
+			gltfAnim.Channels = append(gltfAnim.Channels, &gltf.Channel{
+				Sampler: gltf.Index(uint32(len(gltfAnim.Samplers) - 1)),
+				Target: gltf.ChannelTarget{
+					Node: target,
+					Path: path,
+				},
+			})
+
+		}
+
+	}
+
+	if len(gltfAnim.Channels) == 0 {
+		log.Println("Warning: animation " + anim.Name + " has no channels to save; skipping animation")
+		return nil
+	}
+
+	markers := []interface{}{}
+	for _, marker := range anim.Markers {
+		markers = append(markers, map[string]interface{}{
+			"name": marker.Name,
+			"time": marker.Time,
+		})
+	}
+
+	gltfAnim.Extras = map[string]interface{}{"t3dMarkers__": markers}
+
+	doc.Animations = append(doc.Animations, gltfAnim)
+
+	return nil
+
+}
+
+// exportWorld returns the World given as a map of settings in the form the Tetra3D Blender add-on exports.
+func exportWorld(world *World) map[string]interface{} {
+
+	linear := func(color *Color) []float32 {
+		c := color.Clone()
+		c.ConvertToLinear()
+		return []float32{c.R, c.G, c.B, c.A}
+	}
+
+	settings := map[string]interface{}{
+		"clear color":           linear(world.ClearColor),
+		"fog color":             linear(world.FogColor),
+		"fog range start":       world.FogRange[0],
+		"fog range end":         world.FogRange[1],
+		"dithered transparency": world.DitheredFogSize,
+	}
+
+	if world.AmbientLight != nil {
+		settings["ambient color"] = linear(world.AmbientLight.Color)[:3]
+		settings["ambient energy"] = world.AmbientLight.Energy
+	}
+
+	fogMode := "OFF"
+	if world.FogOn {
+		switch world.FogMode {
+		case FogAdd:
+			fogMode = "ADDITIVE"
+		case FogSub:
+			fogMode = "SUBTRACT"
+		case FogOverwrite:
+			fogMode = "OVERWRITE"
+		case FogTransparent:
+			fogMode = "TRANSPARENT"
+		}
+	}
+	settings["fog mode"] = fogMode
+
+	switch world.FogCurve {
+	case FogCurveOutCirc:
+		settings["fog curve"] = "OUTCIRC"
+	case FogCurveInCirc:
+		settings["fog curve"] = "INCIRC"
+	default:
+		settings["fog curve"] = "LINEAR"
+	}
+
+	// Sky faces are saved as their FacePaths, as the add-on exports them.
+	if sky := world.Sky; sky != nil {
+
+		switch sky.Mode {
+		case SkyModeGradient:
+			settings["sky mode"] = "GRADIENT"
+		case SkyModeCubemap:
+			settings["sky mode"] = "CUBEMAP"
+		case SkyModeDome:
+			settings["sky mode"] = "DOME"
+		}
+
+		settings["sky horizon color"] = linear(sky.HorizonColor)
+		settings["sky zenith color"] = linear(sky.ZenithColor)
+		settings["sky ground color"] = linear(sky.GroundColor)
+		settings["sky faces"] = sky.FacePaths[:]
+
+		if sky.Dome != nil {
+			settings["sky dome"] = sky.Dome.Name
+		}
+
+	}
+
+	return settings
+
+}
+
+// exportProperties writes the Properties given into the extras map as game properties. Values that game properties can't
+// represent are written as plain custom data instead.
+func exportProperties(props *Properties, extras map[string]interface{}) {
+
+	gameProps := []interface{}{}
+
+	propNames := []string{}
+	for name := range props.props {
+		propNames = append(propNames, name)
+	}
+
+	for _, name := range sortedNames(propNames) {
+
+		value := props.props[name].Value
+
+		switch v := value.(type) {
+		case bool:
+			boolValue := 0
+			if v {
+				boolValue = 1
+			}
+			gameProps = append(gameProps, map[string]interface{}{"name": name, "valueType": 0, "valueBool": boolValue})
+		case int:
+			gameProps = append(gameProps, map[string]interface{}{"name": name, "valueType": 1, "valueInt": v})
+		case float64:
+			gameProps = append(gameProps, map[string]interface{}{"name": name, "valueType": 2, "valueFloat": v})
+		case string:
+			gameProps = append(gameProps, map[string]interface{}{"name": name, "valueType": 3, "valueString": v})
+		case *Color:
+			color := v.Clone()
+			color.ConvertToLinear()
+			gameProps = append(gameProps, map[string]interface{}{"name": name, "valueType": 5, "valueColor": []float32{color.R, color.G, color.B, color.A}})
+		case Vector:
+			gameProps = append(gameProps, map[string]interface{}{"name": name, "valueType": 6, "valueVector3D": []float64{v.X, -v.Z, v.Y}})
+		default:
+			extras[name] = exportRawProperty(value)
+		}
+
+	}
+
+	if len(gameProps) > 0 {
+		extras["t3dGameProperties__"] = gameProps
+	}
+
+}
+
+// exportRawProperty converts a property value into a value that can be written as JSON custom data.
+func exportRawProperty(value interface{}) interface{} {
+	switch v := value.(type) {
+	case *Color:
+		return []float32{v.R, v.G, v.B, v.A}
+	case Vector:
+		return []float64{v.X, v.Y, v.Z}
+	}
+	return value
+}
+
+func colorChannelToUint16(value float32) uint16 {
+	return uint16(math.Round(clamp(float64(value), 0, 1) * math.MaxUint16))
+}
+
+// sortedNames returns the names given in alphabetical order, so that saved files are stable between saves.
+func sortedNames(names []string) []string {
+	sort.Strings(names)
+	return names
+}
@@ -1,8 +1,21 @@
 package tetra3d
 
 import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"image"
+	pngenc "image/png"
+	"math"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"testing/fstest"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/qmuntal/gltf"
+	"github.com/qmuntal/gltf/modeler"
 )
 
 func BenchmarkLoadGLTFData(b *testing.B) {
@@ -21,3 +34,655 @@ func BenchmarkLoadGLTFData(b *testing.B) {
 		}
 	}
 }
+
+func TestSaveGLTFRoundTrip(t *testing.T) {
+
+	library := NewLibrary()
+	scene := library.AddScene("Level")
+	library.ExportedScene = scene
+
+	mat := NewMaterial("Red")
+	mat.Color.Set(1, 0, 0, 1)
+	mat.Shadeless = true
+	mat.TransparencyMode = TransparencyModeAlphaClip
+
+	mesh := NewCubeMesh()
+	mesh.Name = "Crate"
+	mesh.MeshParts[0].Material = mat
+	target := mesh.AddMorphTarget("Squash")
+	target.DefaultWeight = 0.25
+
+	crate := NewModel(mesh, "Crate")
+	crate.SetLocalPosition(1, 2, 3)
+	crate.SetLocalRotation(NewMatrix4Rotate(0, 1, 0, math.Pi/2))
+	crate.Properties().Get("health").Set(10)
+	crate.Properties().Get("solid").Set(true)
+	crate.Properties().Get("tag").Set("box")
+	crate.Properties().Get("spawn").Set(Vector{1, 2, 3, 0})
+	crate.AddChildren(NewBoundingSphere("BoundingSphere", 2))
+
+	light := NewPointLight("Lamp", 1, 0.5, 0.25, 2)
+	light.Distance = 10
+	crate.AddChildren(light)
+
+	path := NewPath("Route", Vector{0, 0, 0, 0}, Vector{0, 0, -4, 0})
+	path.Closed = true
+
+	scene.Root.AddChildren(crate, path)
+
+	anim := NewAnimation("Bob")
+	track := anim.AddChannel("Crate").AddTrack(TrackTypePosition)
+	track.AddKeyframe(0, Vector{1, 2, 3, 0})
+	track.AddKeyframe(2, Vector{1, 4, 3, 0})
+	anim.Markers = append(anim.Markers, Marker{Name: "top", Time: 1})
+	library.Animations[anim.Name] = anim
+
+	for _, binary := range []bool{false, true} {
+
+		data, err := SaveGLTFData(library, binary, &GLTFSaveOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		loaded, err := LoadGLTFData(data, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if loaded.ExportedScene == nil || loaded.ExportedScene.Name != "Level" {
+			t.Fatalf("exported scene wasn't loaded back")
+		}
+
+		loadedCrate, ok := loaded.ExportedScene.Root.Get("Crate").(*Model)
+		if !ok {
+			t.Fatalf("crate model wasn't loaded back")
+		}
+
+		if !loadedCrate.LocalPosition().Equals(Vector{1, 2, 3, 0}) {
+			t.Errorf("crate position is %s", loadedCrate.LocalPosition())
+		}
+
+		if !loadedCrate.LocalRotation().Equals(crate.LocalRotation()) {
+			t.Errorf("crate rotation wasn't kept")
+		}
+
+		if props := loadedCrate.Properties(); props.Get("health").AsInt() != 10 || !props.Get("solid").AsBool() || props.Get("tag").AsString() != "box" || !props.Get("spawn").AsVector().Equals(Vector{1, 2, 3, 0}) {
+			t.Errorf("crate properties weren't kept")
+		}
+
+		if sphere, ok := loadedCrate.Get("BoundingSphere").(*BoundingSphere); !ok || sphere.Radius != 2 {
+			t.Errorf("crate bounding sphere wasn't kept")
+		}
+
+		if len(loadedCrate.Mesh.Triangles) != len(mesh.Triangles) || len(loadedCrate.Mesh.MorphTargets) != 1 || loadedCrate.Mesh.MorphTargets[0].DefaultWeight != 0.25 {
+			t.Errorf("crate mesh wasn't kept")
+		}
+
+		loadedMat := loaded.Materials["Red"]
+		if loadedMat == nil || !loadedMat.Shadeless || loadedMat.TransparencyMode != TransparencyModeAlphaClip || math.Abs(float64(loadedMat.Color.R-1)) > 0.001 {
+			t.Errorf("material wasn't kept")
+		}
+
+		if lamp, ok := loadedCrate.Get("Lamp").(*PointLight); !ok || lamp.Distance != 10 || math.Abs(float64(lamp.Energy-2)) > 0.001 {
+			t.Errorf("point light wasn't kept")
+		}
+
+		loadedPath, ok := loaded.ExportedScene.Root.Get("Route").(*Path)
+		if !ok || !loadedPath.Closed || len(loadedPath.Children()) != 2 || !loadedPath.Children()[1].LocalPosition().Equals(Vector{0, 0, -4, 0}) {
+			t.Errorf("path wasn't kept")
+		}
+
+		loadedAnim := loaded.Animations["Bob"]
+		if loadedAnim == nil || loadedAnim.Length != 2 || len(loadedAnim.Markers) != 1 || loadedAnim.Channels["Crate"] == nil {
+			t.Errorf("animation wasn't kept")
+		}
+
+	}
+
+}
+
+func TestLoadGLTFFileFS(t *testing.T) {
+
+	library := NewLibrary()
+	scene := library.AddScene("Level")
+	library.ExportedScene = scene
+
+	for _, name := range []string{"Grass", "Dirt"} {
+		mat := NewMaterial(name)
+		mat.TexturePath = "textures/tile.png"
+		mesh := NewCubeMesh()
+		mesh.Name = name
+		mesh.MeshParts[0].Material = mat
+		scene.Root.AddChildren(NewModel(mesh, name))
+	}
+
+	data, err := SaveGLTFData(library, false, &GLTFSaveOptions{PackTextures: false})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Move the embedded buffer out into a separate .bin file.
+	doc := map[string]interface{}{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	buffer := doc["buffers"].([]interface{})[0].(map[string]interface{})
+	uri := buffer["uri"].(string)
+	bin, err := base64.StdEncoding.DecodeString(uri[strings.Index(uri, ",")+1:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	buffer["uri"] = "level.bin"
+
+	if data, err = json.Marshal(doc); err != nil {
+		t.Fatal(err)
+	}
+
+	png := &bytes.Buffer{}
+	texture := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	if err := pngenc.Encode(png, texture); err != nil {
+		t.Fatal(err)
+	}
+
+	fileSystem := fstest.MapFS{
+		"assets/level.gltf":             {Data: data},
+		"assets/level.bin":              {Data: bin},
+		"assets/textures/tile.png":      {Data: png.Bytes()},
+		"assets/textures/unrelated.png": {Data: png.Bytes()},
+	}
+
+	loaded, err := LoadGLTFFileFS(fileSystem, "assets/level.gltf", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	grass := loaded.Materials["Grass"]
+	dirt := loaded.Materials["Dirt"]
+
+	if grass == nil || grass.Texture == nil || grass.TexturePath != "textures/tile.png" {
+		t.Fatalf("external texture wasn't loaded")
+	}
+
+	if grass.Texture != dirt.Texture {
+		t.Fatalf("materials using the same image don't share a texture")
+	}
+
+	if model, ok := loaded.ExportedScene.Root.Get("Grass").(*Model); !ok || len(model.Mesh.VertexPositions) == 0 {
+		t.Fatalf("mesh data wasn't loaded from the external buffer")
+	}
+
+	// Textures should be shared across loads that use the same cache.
+	options := DefaultGLTFLoadOptions()
+	options.TextureCache = map[string]*ebiten.Image{}
+
+	first, err := LoadGLTFFileFS(fileSystem, "assets/level.gltf", options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := LoadGLTFFileFS(fileSystem, "assets/level.gltf", options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(options.TextureCache) != 1 || first.Materials["Grass"].Texture != second.Materials["Grass"].Texture {
+		t.Fatalf("texture cache wasn't shared between loads")
+	}
+
+}
+
+func TestLoadGLTFLODs(t *testing.T) {
+
+	library := NewLibrary()
+	scene := library.AddScene("Level")
+	library.ExportedScene = scene
+
+	newMesh := func(name string) *Mesh {
+		mesh := NewCubeMesh()
+		mesh.Name = name
+		return mesh
+	}
+
+	rock := NewModel(newMesh("Rock"), "Rock")
+	rock.Properties().Get("lod_crossfade").Set(2.0)
+
+	rockLOD1 := NewModel(newMesh("RockLow"), "Rock_LOD1")
+	rockLOD1.Properties().Get("lod_distance").Set(25.0)
+	rock.AddChildren(rockLOD1)
+
+	moss := NewNode("Moss")
+	moss.SetLocalPosition(0, 1, 0)
+	rockLOD1.AddChildren(moss)
+
+	rockLOD2 := NewModel(newMesh("RockLowest"), "Rock_LOD2")
+
+	scene.Root.AddChildren(rock, rockLOD2)
+
+	data, err := SaveGLTFData(library, false, &GLTFSaveOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadGLTFData(data, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root := loaded.ExportedScene.Root
+
+	loadedRock, ok := root.Get("Rock").(*Model)
+	if !ok || loadedRock.LOD == nil {
+		t.Fatalf("rock wasn't loaded with an LOD")
+	}
+
+	if root.SearchTree().ByName("Rock_LOD1").First() != nil || root.SearchTree().ByName("Rock_LOD2").First() != nil {
+		t.Errorf("levels of detail should be removed from the scene tree")
+	}
+
+	if loadedMoss := loadedRock.Get("Moss"); loadedMoss == nil || !loadedMoss.LocalPosition().Equals(Vector{0, 1, 0, 0}) {
+		t.Errorf("children of levels of detail should be moved to the base model, keeping their local transforms")
+	}
+
+	lod := loadedRock.LOD
+
+	if len(lod.Levels) != 2 || lod.Levels[0].Threshold != 20 || lod.Levels[1].Threshold != 25 {
+		t.Fatalf("expected the LOD_2 level at the default distance before the LOD_1 level with a custom distance, got %d levels", len(lod.Levels))
+	}
+
+	if lod.Levels[0].Mesh.Name != "RockLowest" || lod.CrossFade != 2 {
+		t.Errorf("rock LOD wasn't set up from its levels' properties")
+	}
+
+}
+
+func TestLoadGLTFPortals(t *testing.T) {
+
+	scene, _, _ := newPortalTestLevel()
+
+	library := NewLibrary()
+	library.Scenes = append(library.Scenes, scene)
+	library.ExportedScene = scene
+
+	data, err := SaveGLTFData(library, false, &GLTFSaveOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadGLTFData(data, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root := loaded.ExportedScene.Root
+
+	portal, ok := root.Get("AB").(*Portal)
+	if !ok {
+		t.Fatalf("portal wasn't loaded back as a Portal")
+	}
+
+	if a, b := root.Get("A").(*Model), root.Get("B").(*Model); portal.Sectors[0] != a.sector || portal.Sectors[1] != b.sector {
+		t.Fatalf("portal wasn't linked to its sectors")
+	}
+
+	if len(portal.Points) != 4 || !portal.WorldCenter().Equals(Vector{0, 0, -5, 0}) {
+		t.Fatalf("portal shape wasn't kept: %v", portal.Points)
+	}
+
+}
+
+func TestLoadGLTFSky(t *testing.T) {
+
+	library := NewLibrary()
+	scene := library.AddScene("Level")
+	library.ExportedScene = scene
+
+	dome := NewIcosphereMesh(1)
+	dome.Name = "Dome"
+	scene.World = NewWorld("Night")
+	scene.World.Sky = NewSkyDome(dome)
+
+	sunset := NewWorld("Sunset")
+	sunset.Sky = NewSkyGradient(NewColor(1, 0.5, 0, 1), NewColor(0.25, 0, 0.5, 1), NewColor(0, 0, 0, 1))
+	library.Worlds[sunset.Name] = sunset
+
+	space := NewWorld("Space")
+	space.Sky = NewSkyCubemap([6]*ebiten.Image{})
+	for i := range space.Sky.FacePaths {
+		space.Sky.FacePaths[i] = "sky/space.png"
+	}
+	library.Worlds[space.Name] = space
+
+	library.Worlds["Plain"] = NewWorld("Plain")
+
+	data, err := SaveGLTFData(library, false, &GLTFSaveOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	png := &bytes.Buffer{}
+	if err := pngenc.Encode(png, image.NewRGBA(image.Rect(0, 0, 2, 2))); err != nil {
+		t.Fatal(err)
+	}
+
+	fileSystem := fstest.MapFS{
+		"level.gltf":    {Data: data},
+		"sky/space.png": {Data: png.Bytes()},
+	}
+
+	loaded, err := LoadGLTFFileFS(fileSystem, "level.gltf", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if sky := loaded.Worlds["Night"].Sky; sky == nil || sky.Mode != SkyModeDome || sky.Dome != loaded.Meshes["Dome"] || sky.Dome == nil {
+		t.Fatalf("dome sky wasn't loaded with its mesh")
+	}
+
+	sky := loaded.Worlds["Sunset"].Sky
+	if sky == nil || sky.Mode != SkyModeGradient {
+		t.Fatalf("gradient sky wasn't loaded")
+	}
+
+	for _, pair := range [][2]*Color{{sky.HorizonColor, sunset.Sky.HorizonColor}, {sky.ZenithColor, sunset.Sky.ZenithColor}, {sky.GroundColor, sunset.Sky.GroundColor}} {
+		if math.Abs(float64(pair[0].R-pair[1].R)) > 0.01 || math.Abs(float64(pair[0].G-pair[1].G)) > 0.01 || math.Abs(float64(pair[0].B-pair[1].B)) > 0.01 {
+			t.Fatalf("gradient sky color %v wasn't kept, expected %v", pair[0], pair[1])
+		}
+	}
+
+	sky = loaded.Worlds["Space"].Sky
+	if sky == nil || sky.Mode != SkyModeCubemap {
+		t.Fatalf("cubemap sky wasn't loaded")
+	}
+
+	for i := range sky.Faces {
+		if sky.FacePaths[i] != "sky/space.png" || sky.Faces[i] == nil || sky.Faces[i] != sky.Faces[0] {
+			t.Fatalf("cubemap sky face %d wasn't loaded from its path", i)
+		}
+	}
+
+	if loaded.Worlds["Plain"].Sky != nil {
+		t.Fatalf("worlds without a sky shouldn't have one")
+	}
+
+}
+
+func TestLoadGLTFMorphWeights(t *testing.T) {
+
+	library := NewLibrary()
+	scene := library.AddScene("Level")
+	library.ExportedScene = scene
+
+	mesh := NewCubeMesh()
+	mesh.Name = "Blob"
+	mesh.AddMorphTarget("Squash")
+	mesh.AddMorphTarget("Stretch").DefaultWeight = 0.5
+
+	scene.Root.AddChildren(NewModel(mesh, "Blob"))
+
+	anim := NewAnimation("Wobble")
+	track := anim.AddChannel("Blob").AddTrack(TrackTypeMorphWeights)
+	track.AddKeyframe(0, []float64{0, 1})
+	track.AddKeyframe(1, []float64{1, 0})
+	library.Animations[anim.Name] = anim
+
+	data, err := SaveGLTFData(library, false, &GLTFSaveOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Weights may also be stored as normalized integers, so the weight animation's output is rewritten as such.
+	normalized := func(componentType gltf.ComponentType) []byte {
+
+		doc := &gltf.Document{}
+		if err := gltf.NewDecoder(bytes.NewReader(data)).Decode(doc); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, gltfAnim := range doc.Animations {
+			for _, channel := range gltfAnim.Channels {
+				if channel.Target.Path != gltf.TRSWeights {
+					continue
+				}
+				sampler := gltfAnim.Samplers[*channel.Sampler]
+				if componentType == gltf.ComponentUbyte {
+					sampler.Output = modeler.WriteAccessor(doc, gltf.TargetNone, []uint8{0, 255, 255, 0})
+				} else {
+					sampler.Output = modeler.WriteAccessor(doc, gltf.TargetNone, []uint16{0, 65535, 65535, 0})
+				}
+				doc.Accessors[sampler.Output].Normalized = true
+			}
+		}
+
+		doc.Buffers[0].EmbeddedResource()
+
+		out := &bytes.Buffer{}
+		if err := gltf.NewEncoder(out).Encode(doc); err != nil {
+			t.Fatal(err)
+		}
+		return out.Bytes()
+
+	}
+
+	for _, gltfData := range [][]byte{data, normalized(gltf.ComponentUbyte), normalized(gltf.ComponentUshort)} {
+
+		loaded, err := LoadGLTFData(gltfData, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		blob := loaded.ExportedScene.Root.Get("Blob").(*Model)
+
+		if len(blob.Mesh.MorphTargets) != 2 || blob.Mesh.MorphTargets[1].DefaultWeight != 0.5 {
+			t.Fatalf("morph targets weren't loaded")
+		}
+
+		wobble := loaded.Animations["Wobble"]
+		if wobble == nil || wobble.Channels["Blob"].Tracks[TrackTypeMorphWeights] == nil {
+			t.Fatalf("morph weight animation wasn't loaded")
+		}
+
+		player := NewAnimationPlayer(blob)
+		player.PlayAnim(wobble)
+		player.SetPlayhead(0.5)
+
+		if squash, stretch := blob.MorphWeight("Squash"), blob.MorphWeight("Stretch"); math.Abs(squash-0.5) > 0.01 || math.Abs(stretch-0.5) > 0.01 {
+			t.Fatalf("morph weights should be 0.5 halfway through the animation, but are %f and %f", squash, stretch)
+		}
+
+		player.SetPlayhead(0.99)
+
+		if squash := blob.MorphWeight("Squash"); math.Abs(squash-0.99) > 0.01 {
+			t.Fatalf("squash morph weight should be almost 1 near the end of the animation, but is %f", squash)
+		}
+
+	}
+
+}
+
+func TestSaveGLTFAmbientAndCubeLights(t *testing.T) {
+
+	library := NewLibrary()
+	scene := library.AddScene("Level")
+	library.ExportedScene = scene
+
+	ambient := NewAmbientLight("Ambient", 0.25, 0.5, 1, 0.75)
+
+	cube := NewCubeLight("Cube", Dimensions{Vector{-1, -2, -3, 0}, Vector{1, 2, 3, 0}})
+	cube.Color.Set(1, 0.5, 0, 1)
+	cube.Energy = 2
+	cube.Distance = 4
+	cube.Bleed = 0.5
+	cube.LightingAngle = Vector{1, 0, 0, 0}
+	cube.SetLocalPosition(5, 0, 0)
+
+	scene.Root.AddChildren(ambient, cube)
+
+	data, err := SaveGLTFData(library, false, &GLTFSaveOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadGLTFData(data, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root := loaded.ExportedScene.Root
+
+	loadedAmbient, ok := root.Get("Ambient").(*AmbientLight)
+	if !ok {
+		t.Fatalf("ambient light wasn't loaded back as an AmbientLight")
+	}
+
+	if *loadedAmbient.Color != *ambient.Color || loadedAmbient.Energy != ambient.Energy {
+		t.Fatalf("ambient light settings weren't kept")
+	}
+
+	loadedCube, ok := root.Get("Cube").(*CubeLight)
+	if !ok {
+		t.Fatalf("cube light wasn't loaded back as a CubeLight")
+	}
+
+	if *loadedCube.Color != *cube.Color || loadedCube.Energy != 2 || loadedCube.Distance != 4 || loadedCube.Bleed != 0.5 ||
+		!loadedCube.LightingAngle.Equals(cube.LightingAngle) || !loadedCube.Dimensions.Min.Equals(cube.Dimensions.Min) ||
+		!loadedCube.Dimensions.Max.Equals(cube.Dimensions.Max) || !loadedCube.WorldPosition().Equals(Vector{5, 0, 0, 0}) {
+		t.Fatalf("cube light settings weren't kept")
+	}
+
+	if loadedCube.Properties().Has("t3dCubeLight__") {
+		t.Fatalf("cube light settings shouldn't be loaded as game properties")
+	}
+
+}
+
+// newDependentGLTF returns a GLTF file with a Node named after the collection given, instancing that collection from the blend file
+// given, along with a Camera.
+func newDependentGLTF(t *testing.T, objectName, collection, blendPath string) []byte {
+
+	library := NewLibrary()
+	scene := library.AddScene("Scene")
+	library.ExportedScene = scene
+
+	mesh := NewCubeMesh()
+	mesh.Name = objectName
+	scene.Root.AddChildren(NewModel(mesh, objectName), NewCamera(16, 16))
+
+	if collection != "" {
+		scene.Root.AddChildren(NewNode(collection))
+	}
+
+	data, err := SaveGLTFData(library, false, &GLTFSaveOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if collection == "" {
+		return data
+	}
+
+	doc := map[string]interface{}{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	gltfScene := doc["scenes"].([]interface{})[0].(map[string]interface{})
+	extras, _ := gltfScene["extras"].(map[string]interface{})
+	if extras == nil {
+		extras = map[string]interface{}{}
+		gltfScene["extras"] = extras
+	}
+	extras["t3dCollections__"] = map[string]interface{}{
+		collection: map[string]interface{}{"Objects": []string{collection + "Object"}, "Offset": []float64{0, 0, 0}, "Path": blendPath},
+	}
+
+	for _, n := range doc["nodes"].([]interface{}) {
+		node := n.(map[string]interface{})
+		if node["name"] == collection {
+			nodeExtras, _ := node["extras"].(map[string]interface{})
+			if nodeExtras == nil {
+				nodeExtras = map[string]interface{}{}
+				node["extras"] = nodeExtras
+			}
+			nodeExtras["t3dInstanceCollection__"] = collection
+		}
+	}
+
+	if data, err = json.Marshal(doc); err != nil {
+		t.Fatal(err)
+	}
+
+	return data
+
+}
+
+func TestLoadGLTFDependentLibraries(t *testing.T) {
+
+	// The level instances a prop from a Library in another directory, which in turn instances an object from the level.
+	level := newDependentGLTF(t, "LevelObject", "Prop", "//../props/props.blend")
+	props := newDependentGLTF(t, "PropObject", "Level", "//../levels/level.blend")
+
+	check := func(library *Library, err error) {
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// The instanced object takes the place of the collection instance.
+		if prop, ok := library.ExportedScene.Root.Get("Prop").(*Model); !ok || prop.Mesh.Name != "PropObject" {
+			t.Fatalf("object from dependent library wasn't instantiated")
+		}
+
+		camera := library.ExportedScene.Root.SearchTree().ByType(NodeTypeCamera).First().(*Camera)
+		if w, _ := camera.Size(); w != 123 {
+			t.Fatalf("camera should be loaded with the width set in the load options, but is %d wide", w)
+		}
+
+	}
+
+	options := DefaultGLTFLoadOptions()
+	options.CameraWidth = 123
+	options.CameraHeight = 45
+
+	fileSystem := fstest.MapFS{
+		"game/levels/level.gltf": {Data: level},
+		"game/props/props.gltf":  {Data: props},
+	}
+
+	check(LoadGLTFFileFS(fileSystem, "game/levels/level.gltf", options))
+
+	// LoadGLTFFile loads dependent libraries from disk, even when they're in a parent directory.
+	dir := t.TempDir()
+
+	for name, file := range fileSystem {
+		filePath := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filePath, file.Data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	check(LoadGLTFFile(filepath.Join(dir, "game", "levels", "level.gltf"), options))
+
+	// A custom resolver is used instead of loading dependent libraries automatically.
+	resolved := []string{}
+	options.DependentLibraryResolver = func(blendPath string) *Library {
+		resolved = append(resolved, blendPath)
+		library, err := LoadGLTFData(props, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return library
+	}
+
+	if _, err := LoadGLTFFileFS(fileSystem, "game/levels/level.gltf", options); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(resolved) != 1 || resolved[0] != "../props/props.blend" {
+		t.Fatalf("custom dependent library resolver wasn't used, resolved %v", resolved)
+	}
+
+}
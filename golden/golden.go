@@ -0,0 +1,260 @@
+// Package golden renders Tetra3D scenes from fixed views with a tetra3d.SoftwareRenderer and compares the results against
+// reference PNG images, so that rendering regressions can be caught in tests.
+package golden
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/solarlune/tetra3d"
+)
+
+// View is a fixed view of a Scene to render and check against a reference image.
+type View struct {
+	Name string // The name of the View; this is also the name of the reference image (with a ".png" extension).
+
+	// Camera is the path (or name) of a Camera in the Scene to render the View from. The Camera's transform and projection settings
+	// are used, while Width and Height still set the size of the render.
+	// If Camera is empty, the View is rendered from Position looking towards Target instead, using the projection settings below.
+	Camera string
+
+	Position tetra3d.Vector // The position to render from.
+	Target   tetra3d.Vector // The position the View looks towards.
+
+	Width, Height int // The size of the rendered image. Defaults to 160x120.
+
+	FieldOfView  float64 // The vertical field of view of a perspective View in degrees. Defaults to 60.
+	Orthographic bool    // If the View has an orthographic projection rather than a perspective one.
+	OrthoScale   float64 // The horizontal scale of an orthographic View in units. Defaults to 20.
+}
+
+// Options controls how rendered images are compared against their reference images.
+type Options struct {
+	ReferenceDir string // The directory containing the reference images. Defaults to "testdata".
+
+	// DiffDir is the directory that the rendered image and a diff image are written to when a check fails. Defaults to a
+	// "tetra3d-golden" directory in the system's temporary directory.
+	DiffDir string
+
+	Tolerance           uint8 // The amount each color channel of a pixel may differ from the reference image before the pixel counts as mismatched.
+	MaxMismatchedPixels int   // The number of mismatched pixels allowed before a check fails.
+
+	// Update makes checks write the rendered images out as the new reference images instead of comparing against them.
+	// This is usually set from a test flag (e.g. "-update") after an intended change to rendering.
+	Update bool
+}
+
+// DefaultOptions returns a new Options struct that compares against reference images in "testdata", with a small tolerance
+// for floating-point differences between machines.
+func DefaultOptions() *Options {
+	return &Options{
+		ReferenceDir: "testdata",
+		DiffDir:      filepath.Join(os.TempDir(), "tetra3d-golden"),
+		Tolerance:    2,
+	}
+}
+
+// Render renders the Scene from the View given into a new image.
+func Render(scene *tetra3d.Scene, view View) (*image.RGBA, error) {
+
+	w, h := view.Width, view.Height
+	if w <= 0 || h <= 0 {
+		w, h = 160, 120
+	}
+
+	camera := tetra3d.NewCamera(w, h)
+
+	if view.Camera != "" {
+
+		sceneCamera, ok := scene.Root.Get(view.Camera).(*tetra3d.Camera)
+		if !ok {
+			sceneCamera, ok = scene.Root.SearchTree().ByName(view.Camera).First().(*tetra3d.Camera)
+		}
+
+		if !ok {
+			return nil, fmt.Errorf("golden: camera %s not found in scene %s", view.Camera, scene.Name)
+		}
+
+		camera.SetWorldTransform(sceneCamera.Transform())
+		camera.SetPerspective(sceneCamera.Perspective())
+		camera.SetFieldOfView(sceneCamera.FieldOfView())
+		camera.SetOrthoScale(sceneCamera.OrthoScale())
+		camera.SetNear(sceneCamera.Near())
+		camera.SetFar(sceneCamera.Far())
+
+	} else {
+
+		camera.SetPerspective(!view.Orthographic)
+
+		if view.FieldOfView > 0 {
+			camera.SetFieldOfView(view.FieldOfView)
+		}
+
+		if view.OrthoScale > 0 {
+			camera.SetOrthoScale(view.OrthoScale)
+		}
+
+		// Cameras look down -Z, so the camera's +Z axis should point from the target towards the camera.
+		camera.SetWorldPositionVec(view.Position)
+		camera.SetWorldRotation(tetra3d.NewLookAtMatrix(view.Target, view.Position, tetra3d.WorldUp))
+
+	}
+
+	renderer := tetra3d.NewSoftwareRenderer(camera)
+	renderer.Clear()
+	renderer.RenderScene(scene)
+
+	return renderer.ColorBuffer(), nil
+
+}
+
+// Compare compares the rendered image against the reference image, returning the number of pixels that differ by more
+// than the tolerance in any color channel, along with a diff image. In the diff image, mismatched pixels are red, while
+// the rest of the reference image is drawn faded out for context. Images of different sizes mismatch on every pixel.
+func Compare(rendered, reference image.Image, tolerance uint8) (int, *image.RGBA) {
+
+	bounds := rendered.Bounds()
+	diff := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+
+	if bounds.Dx() != reference.Bounds().Dx() || bounds.Dy() != reference.Bounds().Dy() {
+		for i := 0; i < len(diff.Pix); i += 4 {
+			diff.Pix[i], diff.Pix[i+3] = 255, 255
+		}
+		return bounds.Dx() * bounds.Dy(), diff
+	}
+
+	refMin := reference.Bounds().Min
+	mismatched := 0
+
+	for y := 0; y < bounds.Dy(); y++ {
+
+		for x := 0; x < bounds.Dx(); x++ {
+
+			got := color.RGBAModel.Convert(rendered.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.RGBA)
+			want := color.RGBAModel.Convert(reference.At(refMin.X+x, refMin.Y+y)).(color.RGBA)
+
+			if channelDiff(got.R, want.R) > tolerance || channelDiff(got.G, want.G) > tolerance ||
+				channelDiff(got.B, want.B) > tolerance || channelDiff(got.A, want.A) > tolerance {
+				mismatched++
+				diff.SetRGBA(x, y, color.RGBA{255, 0, 0, 255})
+				continue
+			}
+
+			gray := uint8((uint16(want.R) + uint16(want.G) + uint16(want.B)) / 3 / 4)
+			diff.SetRGBA(x, y, color.RGBA{gray, gray, gray, 255})
+
+		}
+
+	}
+
+	return mismatched, diff
+
+}
+
+func channelDiff(a, b uint8) uint8 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
+
+// Check compares the image given against the reference image of the same name, failing the test if too many pixels mismatch.
+// On failure, the rendered image and a diff image are written to the Options' DiffDir as "<name>.png" and "<name>_diff.png".
+// Passing nil for options uses DefaultOptions().
+func Check(t testing.TB, name string, img image.Image, options *Options) {
+
+	t.Helper()
+
+	if options == nil {
+		options = DefaultOptions()
+	}
+
+	referencePath := filepath.Join(options.ReferenceDir, name+".png")
+
+	if options.Update {
+		if err := writePNG(referencePath, img); err != nil {
+			t.Fatalf("golden: couldn't write reference image %s: %s", referencePath, err)
+		}
+		return
+	}
+
+	reference, err := readPNG(referencePath)
+	if err != nil {
+		t.Fatalf("golden: couldn't read reference image %s: %s", referencePath, err)
+	}
+
+	mismatched, diff := Compare(img, reference, options.Tolerance)
+
+	if mismatched <= options.MaxMismatchedPixels {
+		return
+	}
+
+	if err := writePNG(filepath.Join(options.DiffDir, name+".png"), img); err != nil {
+		t.Errorf("golden: couldn't write rendered image: %s", err)
+	}
+
+	if err := writePNG(filepath.Join(options.DiffDir, name+"_diff.png"), diff); err != nil {
+		t.Errorf("golden: couldn't write diff image: %s", err)
+	}
+
+	t.Errorf("golden: %s has %d mismatched pixels (%d allowed); rendered and diff images written to %s", name, mismatched, options.MaxMismatchedPixels, options.DiffDir)
+
+}
+
+// CheckScene renders the Scene from each of the Views given and checks the results against their reference images.
+// Passing nil for options uses DefaultOptions().
+func CheckScene(t testing.TB, scene *tetra3d.Scene, views []View, options *Options) {
+
+	t.Helper()
+
+	for _, view := range views {
+
+		img, err := Render(scene, view)
+		if err != nil {
+			t.Errorf("golden: couldn't render view %s: %s", view.Name, err)
+			continue
+		}
+
+		Check(t, view.Name, img, options)
+
+	}
+
+}
+
+func readPNG(path string) (image.Image, error) {
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	return png.Decode(file)
+
+}
+
+func writePNG(path string, img image.Image) error {
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := png.Encode(file, img); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+
+}
@@ -0,0 +1,54 @@
+package golden
+
+import (
+	"flag"
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/solarlune/tetra3d"
+)
+
+var update = flag.Bool("update", false, "update the reference images instead of comparing against them")
+
+func TestExampleScenes(t *testing.T) {
+
+	library, err := tetra3d.LoadGLTFFile("../examples/test.gltf", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	options := DefaultOptions()
+	options.Update = *update
+
+	CheckScene(t, library.ExportedScene, []View{
+		{Name: "test_camera", Camera: "Camera", Width: 160, Height: 90},
+		{Name: "test_front", Position: tetra3d.NewVector(0, 1, 6)},
+		{Name: "test_top_ortho", Position: tetra3d.NewVector(0, 10, 0), Orthographic: true, OrthoScale: 6},
+	}, options)
+
+}
+
+func TestCompare(t *testing.T) {
+
+	reference := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	rendered := image.NewRGBA(image.Rect(0, 0, 4, 4))
+
+	rendered.SetRGBA(0, 0, color.RGBA{2, 0, 0, 0})
+	rendered.SetRGBA(1, 1, color.RGBA{0, 0, 255, 255})
+
+	mismatched, diff := Compare(rendered, reference, 2)
+
+	if mismatched != 1 {
+		t.Fatalf("expected 1 mismatched pixel, got %d", mismatched)
+	}
+
+	if c := diff.RGBAAt(1, 1); c != (color.RGBA{255, 0, 0, 255}) {
+		t.Fatalf("mismatched pixel isn't marked in the diff image; got %v", c)
+	}
+
+	if mismatched, _ := Compare(image.NewRGBA(image.Rect(0, 0, 2, 2)), reference, 255); mismatched != 4 {
+		t.Fatalf("images of different sizes should mismatch entirely; got %d mismatched pixels", mismatched)
+	}
+
+}
@@ -0,0 +1,373 @@
+package tetra3d
+
+import (
+	"math"
+)
+
+const (
+	IKSolverTwoBone = iota // Solves a chain of exactly three joints (like a hip, knee, and ankle, or a shoulder, elbow, and wrist) analytically.
+	IKSolverFABRIK         // Solves a chain of any length iteratively using Forward And Backward Reaching Inverse Kinematics, which bends the chain smoothly.
+	IKSolverCCD            // Solves a chain of any length iteratively using Cyclic Coordinate Descent, which bends the joints nearest the end of the chain most.
+)
+
+// IKJoint is a joint of an IKChain.
+type IKJoint struct {
+	Node INode
+
+	// MinAngle and MaxAngle limit how far the joint can bend, in radians - that is, the angle between the bone leading into the joint and the
+	// bone leading out of it. For the first joint of a chain, the angle is measured from the direction of its bone before the chain is solved.
+	// By default, MinAngle is 0 and MaxAngle is pi, so the joint can bend freely. Setting a MinAngle on a knee or elbow, for example, keeps
+	// the limb from straightening completely. The two-bone solver only limits the first and middle joints.
+	MinAngle, MaxAngle float64
+}
+
+// limit returns the direction given, limited to bending between the joint's MinAngle and MaxAngle away from the previous direction. If the
+// directions are parallel, the joint bends around the hint axis.
+func (joint *IKJoint) limit(prev, dir, hint Vector) Vector {
+
+	angle := prev.Angle(dir)
+	limited := clamp(angle, joint.MinAngle, joint.MaxAngle)
+
+	if limited == angle {
+		return dir
+	}
+
+	axis := prev.Cross(dir)
+	if axis.MagnitudeSquared() < 1e-12 {
+		axis = hint
+	}
+	if axis.MagnitudeSquared() < 1e-12 {
+		return dir
+	}
+
+	return prev.Unit().RotateVec(axis, limited).Scale(dir.Magnitude())
+
+}
+
+// IKChain is a chain of joints (usually bones, with Node.IsBone() returning true) that's posed using inverse kinematics, so that the end of
+// the chain reaches for a target - for example, to plant a foot on a slope, or to have a hand reach for an object. An IKChain rotates
+// its joints rather than moving them, so bones keep their lengths.
+//
+// IKChains are solved after the bones are posed by animation, so call IKChain.Solve() after AnimationPlayer.Update() each frame. The
+// AnimationPlayer poses the bones again on its next update, so the chain doesn't build on the last frame's solution.
+type IKChain struct {
+	Solver int        // The solver used to pose the chain (i.e. IKSolverTwoBone). Defaults to IKSolverFABRIK.
+	Joints []*IKJoint // The joints of the chain, from the start of the chain to its end.
+
+	Target         INode  // The node the end of the chain reaches for.
+	TargetPosition Vector // The position in world space the end of the chain reaches for if the Target is nil.
+
+	// Pole is an optional node the chain bends towards, like the direction a knee or elbow points in. Without a Pole, the chain bends the way
+	// it was already bent.
+	Pole INode
+
+	// Weight is how much the chain is influenced by the solver, ranging from 0 (where the chain stays as it was posed) to 1 (where the chain
+	// is fully solved). Defaults to 1.
+	Weight float64
+
+	// If MatchTargetRotation is true, the node at the end of the chain is rotated to match the Target's rotation (like a foot matching the
+	// angle of the ground it's planted on).
+	MatchTargetRotation bool
+
+	Iterations int     // The maximum number of iterations the FABRIK and CCD solvers run each solve. Defaults to 10.
+	Tolerance  float64 // How close the end of the chain has to be to the target for the FABRIK and CCD solvers to stop early. Defaults to 0.001.
+
+	positions []Vector
+	lengths   []float64
+	original  []Matrix4
+}
+
+// NewIKChain creates a new IKChain solved with the solver given (i.e. IKSolverFABRIK) from the start node to the end node, which should be
+// underneath the start node in the scenegraph. For the two-bone solver, the end node should be the start node's grandchild.
+func NewIKChain(solver int, start, end INode) *IKChain {
+
+	nodes := []INode{}
+
+	for node := end; node != start; node = node.Parent() {
+		if node == nil {
+			panic("Error: NewIKChain() given an end node {" + end.Name() + "} that isn't underneath the start node {" + start.Name() + "}.")
+		}
+		nodes = append([]INode{node}, nodes...)
+	}
+
+	nodes = append([]INode{start}, nodes...)
+
+	chain := &IKChain{
+		Solver:     solver,
+		Joints:     make([]*IKJoint, 0, len(nodes)),
+		Weight:     1,
+		Iterations: 10,
+		Tolerance:  0.001,
+	}
+
+	for _, node := range nodes {
+		chain.Joints = append(chain.Joints, &IKJoint{Node: node, MaxAngle: math.Pi})
+	}
+
+	return chain
+
+}
+
+// Joint returns the joint for the node with the given name, or nil if the chain has no joint by that name.
+func (chain *IKChain) Joint(name string) *IKJoint {
+	for _, joint := range chain.Joints {
+		if joint.Node.Name() == name {
+			return joint
+		}
+	}
+	return nil
+}
+
+// Solve poses the chain's joints so the end of the chain reaches for its target.
+func (chain *IKChain) Solve() {
+
+	count := len(chain.Joints)
+
+	if chain.Weight <= 0 || count < 2 {
+		return
+	}
+
+	if chain.Solver == IKSolverTwoBone && count != 3 {
+		panic("Error: IKChain.Solve() can't use the two-bone solver on a chain of more or less than three joints.")
+	}
+
+	chain.positions = chain.positions[:0]
+	chain.lengths = chain.lengths[:0]
+	chain.original = chain.original[:0]
+
+	for i, joint := range chain.Joints {
+		chain.positions = append(chain.positions, joint.Node.WorldPosition())
+		chain.original = append(chain.original, joint.Node.LocalRotation())
+		if i > 0 {
+			chain.lengths = append(chain.lengths, chain.positions[i].Distance(chain.positions[i-1]))
+		}
+	}
+
+	p := chain.positions
+
+	target := chain.TargetPosition
+	if chain.Target != nil {
+		target = chain.Target.WorldPosition()
+	}
+
+	var pole Vector
+	hasPole := chain.Pole != nil
+	if hasPole {
+		pole = chain.Pole.WorldPosition()
+	}
+
+	// The direction of the first bone before solving, which the first joint's limits are relative to
+	startDir := p[1].Sub(p[0])
+
+	// The axis joints bend around when they have to bend from being straight
+	hint := Vector{}
+	if hasPole {
+		hint = target.Sub(p[0]).Cross(pole.Sub(p[0]))
+	}
+	if hint.MagnitudeSquared() < 1e-12 && count > 2 {
+		hint = startDir.Cross(p[2].Sub(p[1]))
+	}
+
+	switch chain.Solver {
+	case IKSolverTwoBone:
+		chain.solveTwoBone(target, pole, hasPole, startDir, hint)
+	case IKSolverCCD:
+		chain.solveCCD(target, pole, hasPole, startDir, hint)
+	default:
+		chain.solveFABRIK(target, pole, hasPole, startDir, hint)
+	}
+
+	// Rotate each joint so its bone points to where the next joint was solved to be; as each joint's rotated, the joints after it move along.
+	for i := 0; i < count-1; i++ {
+
+		node := chain.Joints[i].Node
+		current := chain.Joints[i+1].Node.WorldPosition().Sub(node.WorldPosition())
+		solved := p[i+1].Sub(p[i])
+
+		axis := current.Cross(solved)
+		if axis.MagnitudeSquared() < 1e-12 {
+			continue
+		}
+
+		node.SetWorldRotation(node.WorldRotation().Mult(NewMatrix4Rotate(axis.X, axis.Y, axis.Z, current.Angle(solved))))
+
+	}
+
+	if chain.MatchTargetRotation && chain.Target != nil {
+		chain.Joints[count-1].Node.SetWorldRotation(chain.Target.WorldRotation())
+	}
+
+	if chain.Weight < 1 {
+		for i, joint := range chain.Joints {
+			blended := chain.original[i].ToQuaternion().Lerp(joint.Node.LocalRotation().ToQuaternion(), chain.Weight)
+			joint.Node.SetLocalRotation(blended.Normalized().ToMatrix4())
+		}
+	}
+
+}
+
+func (chain *IKChain) solveTwoBone(target, pole Vector, hasPole bool, startDir, hint Vector) {
+
+	p := chain.positions
+	upper, lower := chain.lengths[0], chain.lengths[1]
+
+	if upper <= 0 || lower <= 0 {
+		return
+	}
+
+	root := p[0]
+
+	toTarget := target.Sub(root)
+	if toTarget.MagnitudeSquared() < 1e-12 {
+		toTarget = p[2].Sub(root)
+	}
+	dist := toTarget.Magnitude()
+	toTarget = toTarget.Unit()
+
+	// Find how far the middle joint has to bend for the end of the chain to reach the target (or to get as close as it can), by the
+	// law of cosines, and limit it.
+	interior := math.Acos(clamp((upper*upper+lower*lower-dist*dist)/(2*upper*lower), -1, 1))
+	bend := clamp(math.Pi-interior, chain.Joints[1].MinAngle, chain.Joints[1].MaxAngle)
+	dist = math.Sqrt(math.Max(upper*upper+lower*lower+2*upper*lower*math.Cos(bend), 0))
+
+	rootAngle := 0.0
+	if dist > 1e-8 {
+		rootAngle = math.Acos(clamp((upper*upper+dist*dist-lower*lower)/(2*upper*dist), -1, 1))
+	}
+
+	// The chain bends towards the pole, or the way it's already bent.
+	bendTowards := p[1].Sub(root)
+	if hasPole {
+		bendTowards = pole.Sub(root)
+	}
+	bendDir := bendTowards.Sub(toTarget.Scale(bendTowards.Dot(toTarget)))
+
+	if bendDir.MagnitudeSquared() < 1e-12 {
+		bendDir = toTarget.Cross(hint)
+	}
+	if bendDir.MagnitudeSquared() < 1e-12 {
+		bendDir = toTarget.Cross(WorldRight)
+	}
+	if bendDir.MagnitudeSquared() < 1e-12 {
+		bendDir = toTarget.Cross(WorldUp)
+	}
+	bendDir = bendDir.Unit()
+
+	p[1] = root.Add(toTarget.Scale(math.Cos(rootAngle) * upper)).Add(bendDir.Scale(math.Sin(rootAngle) * upper))
+	p[2] = root.Add(toTarget.Scale(dist))
+
+	// Limit the first joint by rotating the whole chain.
+	upperDir := p[1].Sub(root)
+	rotateIKPoints(p[1:], root, upperDir, chain.Joints[0].limit(startDir, upperDir, hint))
+
+}
+
+func (chain *IKChain) solveFABRIK(target, pole Vector, hasPole bool, startDir, hint Vector) {
+
+	p := chain.positions
+	root := p[0]
+	last := len(p) - 1
+
+	for iteration := 0; iteration < chain.Iterations; iteration++ {
+
+		if p[last].Distance(target) <= chain.Tolerance {
+			break
+		}
+
+		// Backward; reach from the target to the root
+		p[last] = target
+		for i := last - 1; i >= 0; i-- {
+			p[i] = p[i+1].Add(p[i].Sub(p[i+1]).Unit().Scale(chain.lengths[i]))
+		}
+
+		if hasPole {
+			chain.bendTowardsPole(pole)
+		}
+
+		// Forward; reach from the root back to the target, limiting the joints along the way
+		p[0] = root
+		prev := startDir
+		for i := 0; i < last; i++ {
+			dir := chain.Joints[i].limit(prev, p[i+1].Sub(p[i]), hint).Unit()
+			p[i+1] = p[i].Add(dir.Scale(chain.lengths[i]))
+			prev = dir
+		}
+
+	}
+
+}
+
+func (chain *IKChain) solveCCD(target, pole Vector, hasPole bool, startDir, hint Vector) {
+
+	p := chain.positions
+	last := len(p) - 1
+
+	for iteration := 0; iteration < chain.Iterations; iteration++ {
+
+		if p[last].Distance(target) <= chain.Tolerance {
+			break
+		}
+
+		// Rotate each joint, from the end of the chain to its start, so the end of the chain points at the target.
+		for i := last - 1; i >= 0; i-- {
+
+			rotateIKPoints(p[i+1:], p[i], p[last].Sub(p[i]), target.Sub(p[i]))
+
+			prev := startDir
+			if i > 0 {
+				prev = p[i].Sub(p[i-1])
+			}
+
+			dir := p[i+1].Sub(p[i])
+			rotateIKPoints(p[i+1:], p[i], dir, chain.Joints[i].limit(prev, dir, hint))
+
+		}
+
+		if hasPole {
+			chain.bendTowardsPole(pole)
+		}
+
+	}
+
+}
+
+// bendTowardsPole rotates each joint between the start and end of the chain around the line between its neighbors, so that it points
+// towards the pole; this doesn't move any other joints.
+func (chain *IKChain) bendTowardsPole(pole Vector) {
+
+	p := chain.positions
+
+	for i := 1; i < len(p)-1; i++ {
+
+		axis := p[i+1].Sub(p[i-1]).Unit()
+		if axis.MagnitudeSquared() < 1e-12 {
+			continue
+		}
+
+		joint := p[i].Sub(p[i-1])
+		joint = joint.Sub(axis.Scale(joint.Dot(axis)))
+
+		towards := pole.Sub(p[i-1])
+		towards = towards.Sub(axis.Scale(towards.Dot(axis)))
+
+		rotateIKPoints(p[i:i+1], p[i-1], joint, towards)
+
+	}
+
+}
+
+// rotateIKPoints rotates the points around the pivot by the rotation from one direction to another.
+func rotateIKPoints(points []Vector, pivot, from, to Vector) {
+
+	axis := from.Cross(to)
+	if axis.MagnitudeSquared() < 1e-12 {
+		return
+	}
+
+	angle := from.Angle(to)
+
+	for i := range points {
+		points[i] = pivot.Add(points[i].Sub(pivot).RotateVec(axis, angle))
+	}
+
+}
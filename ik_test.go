@@ -0,0 +1,143 @@
+package tetra3d
+
+import (
+	"math"
+	"testing"
+)
+
+// newTestLeg creates a chain of nodes, each one unit below the last.
+func newTestLeg(count int) []INode {
+	nodes := []INode{NewNode("Joint0")}
+	for i := 1; i < count; i++ {
+		node := NewNode("Joint" + string(rune('0'+i)))
+		node.SetLocalPosition(0, -1, 0)
+		nodes[i-1].AddChildren(node)
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
+func checkIKLengths(t *testing.T, nodes []INode) {
+	for i := 1; i < len(nodes); i++ {
+		if length := nodes[i].WorldPosition().Distance(nodes[i-1].WorldPosition()); math.Abs(length-1) > 0.0001 {
+			t.Fatalf("bone %d should have a length of 1, but has a length of %f", i, length)
+		}
+	}
+}
+
+func TestIKTwoBone(t *testing.T) {
+
+	leg := newTestLeg(3)
+
+	pole := NewNode("Pole")
+	pole.SetLocalPosition(0, -1, 5)
+
+	chain := NewIKChain(IKSolverTwoBone, leg[0], leg[2])
+	chain.TargetPosition = Vector{0, -1.5, 0.5, 0}
+	chain.Pole = pole
+	chain.Solve()
+
+	checkIKLengths(t, leg)
+
+	if end := leg[2].WorldPosition(); end.Distance(chain.TargetPosition) > 0.0001 {
+		t.Fatalf("end of chain at %s should reach the target at %s", end, chain.TargetPosition)
+	}
+
+	if knee := leg[1].WorldPosition(); knee.Z <= 0 {
+		t.Fatalf("knee at %s should bend towards the pole", knee)
+	}
+
+	// Out of reach, the chain straightens towards the target, unless the knee's limited to stay bent.
+	chain.TargetPosition = Vector{0, -10, 0, 0}
+	chain.Joints[1].MinAngle = 0.5
+	chain.Solve()
+
+	checkIKLengths(t, leg)
+
+	upper := leg[1].WorldPosition().Sub(leg[0].WorldPosition())
+	lower := leg[2].WorldPosition().Sub(leg[1].WorldPosition())
+
+	if bend := upper.Angle(lower); math.Abs(bend-0.5) > 0.0001 {
+		t.Fatalf("knee should be limited to bending by 0.5 radians, but bends by %f", bend)
+	}
+
+	if end := leg[2].WorldPosition(); end.X > 0.0001 || end.Z > 0.0001 || end.Y > -1.9 {
+		t.Fatalf("end of chain at %s should point down towards the target", end)
+	}
+
+}
+
+func TestIKChainSolvers(t *testing.T) {
+
+	for _, solver := range []int{IKSolverFABRIK, IKSolverCCD} {
+
+		arm := newTestLeg(4)
+
+		chain := NewIKChain(solver, arm[0], arm[3])
+		chain.Iterations = 50
+		chain.TargetPosition = Vector{1.5, -1, 0.5, 0}
+		chain.Solve()
+
+		checkIKLengths(t, arm)
+
+		if end := arm[3].WorldPosition(); end.Distance(chain.TargetPosition) > 0.01 {
+			t.Fatalf("solver %d: end of chain at %s should reach the target at %s", solver, end, chain.TargetPosition)
+		}
+
+		// Limiting every joint to bend a little keeps the chain from reaching the target.
+		arm = newTestLeg(4)
+		chain = NewIKChain(solver, arm[0], arm[3])
+		for _, joint := range chain.Joints {
+			joint.MaxAngle = 0.1
+		}
+		chain.TargetPosition = Vector{1.5, -1, 0.5, 0}
+		chain.Solve()
+
+		checkIKLengths(t, arm)
+
+		for i := 1; i < len(arm)-1; i++ {
+			in := arm[i].WorldPosition().Sub(arm[i-1].WorldPosition())
+			out := arm[i+1].WorldPosition().Sub(arm[i].WorldPosition())
+			if bend := in.Angle(out); bend > 0.1001 {
+				t.Fatalf("solver %d: joint %d should be limited to bending by 0.1 radians, but bends by %f", solver, i, bend)
+			}
+		}
+
+		if dir := arm[1].WorldPosition().Sub(arm[0].WorldPosition()); dir.Angle(WorldDown) > 0.1001 {
+			t.Fatalf("solver %d: first joint should be limited to turning by 0.1 radians, but turns by %f", solver, dir.Angle(WorldDown))
+		}
+
+	}
+
+}
+
+func TestIKWeight(t *testing.T) {
+
+	target := Vector{1, -1, 0, 0}
+
+	full := newTestLeg(3)
+	chain := NewIKChain(IKSolverTwoBone, full[0], full[2])
+	chain.TargetPosition = target
+	chain.Solve()
+
+	leg := newTestLeg(3)
+	chain = NewIKChain(IKSolverTwoBone, leg[0], leg[2])
+	chain.TargetPosition = target
+	chain.Weight = 0
+	chain.Solve()
+
+	if !leg[2].WorldPosition().Equals(Vector{0, -2, 0, 0}) {
+		t.Fatalf("chain with a weight of 0 shouldn't move, but its end is at %s", leg[2].WorldPosition())
+	}
+
+	chain.Weight = 0.5
+	chain.Solve()
+
+	fullAngle := full[1].WorldPosition().Angle(WorldDown)
+	halfAngle := leg[1].WorldPosition().Angle(WorldDown)
+
+	if math.Abs(halfAngle-fullAngle/2) > 0.01 {
+		t.Fatalf("chain with a weight of 0.5 should turn half as far (%f) as the solved chain, but turns %f", fullAngle/2, halfAngle)
+	}
+
+}
@@ -0,0 +1,381 @@
+package tetra3d
+
+import "math"
+
+// Instance is a single copy of an InstancedModel's Mesh, with its own transform and color. Instances aren't Nodes, so they're cheap to
+// create and move around in large numbers; their transforms are relative to the InstancedModel that owns them.
+type Instance struct {
+	// The color of the Instance, multiplied against the InstancedModel's Color. Note that Instances are only drawn as transparent if the
+	// InstancedModel is, and that transparent Instances aren't sorted against each other.
+	Color   *Color
+	Visible bool // Whether the Instance is drawn.
+
+	position   Vector
+	scale      Vector
+	rotation   Matrix4
+	transform  Matrix4
+	translated bool // Whether the Instance is only moved (not rotated or scaled), so it can share vertices with other Instances
+	dirty      bool
+}
+
+// NewInstance creates a new, visible Instance at the origin.
+func NewInstance() *Instance {
+	return &Instance{
+		Color:    NewColor(1, 1, 1, 1),
+		Visible:  true,
+		scale:    Vector{1, 1, 1, 0},
+		rotation: NewMatrix4(),
+		dirty:    true,
+	}
+}
+
+// Clone returns a clone of the Instance.
+func (instance *Instance) Clone() *Instance {
+	clone := NewInstance()
+	clone.Color = instance.Color.Clone()
+	clone.Visible = instance.Visible
+	clone.position = instance.position
+	clone.scale = instance.scale
+	clone.rotation = instance.rotation.Clone()
+	return clone
+}
+
+// Position returns the Instance's position relative to its InstancedModel.
+func (instance *Instance) Position() Vector {
+	return instance.position
+}
+
+// SetPosition sets the Instance's position relative to its InstancedModel.
+func (instance *Instance) SetPosition(x, y, z float64) {
+	instance.position.X = x
+	instance.position.Y = y
+	instance.position.Z = z
+	instance.dirty = true
+}
+
+// SetPositionVec sets the Instance's position relative to its InstancedModel using a Vector.
+func (instance *Instance) SetPositionVec(position Vector) {
+	instance.SetPosition(position.X, position.Y, position.Z)
+}
+
+// Scale returns the Instance's scale relative to its InstancedModel.
+func (instance *Instance) Scale() Vector {
+	return instance.scale
+}
+
+// SetScale sets the Instance's scale relative to its InstancedModel.
+func (instance *Instance) SetScale(x, y, z float64) {
+	instance.scale.X = x
+	instance.scale.Y = y
+	instance.scale.Z = z
+	instance.dirty = true
+}
+
+// Rotation returns the Instance's rotation relative to its InstancedModel.
+func (instance *Instance) Rotation() Matrix4 {
+	return instance.rotation.Clone()
+}
+
+// SetRotation sets the Instance's rotation relative to its InstancedModel.
+func (instance *Instance) SetRotation(rotation Matrix4) {
+	instance.rotation = rotation.Clone()
+	instance.dirty = true
+}
+
+// Transform returns the Instance's transform relative to its InstancedModel. The transform is only recalculated after the Instance's position,
+// scale, or rotation changes.
+func (instance *Instance) Transform() Matrix4 {
+
+	if instance.dirty {
+		transform := NewMatrix4Scale(instance.scale.X, instance.scale.Y, instance.scale.Z)
+		transform = transform.Mult(instance.rotation)
+		transform = transform.Mult(NewMatrix4Translate(instance.position.X, instance.position.Y, instance.position.Z))
+		instance.transform = transform
+		instance.translated = instance.scale.X == 1 && instance.scale.Y == 1 && instance.scale.Z == 1 && instance.rotation.Equals(NewMatrix4())
+		instance.dirty = false
+	}
+
+	return instance.transform
+
+}
+
+// InstancedModel is a Model that draws its Mesh once for each of its Instances, each with its own transform and color.
+// Unlike dynamic batching or static merging, Instances don't copy the Mesh or need a Model each, so drawing thousands of copies of
+// a Mesh (like foliage, crowds, or debris) costs little more than lighting it for each Instance. Instances are drawn together
+// (flushing as few times as the vertex buffers allow), and with FrustumCulling on, they're culled individually.
+// Instances that are only moved (not rotated or scaled) share vertices: the Mesh is transformed once for them (including its
+// VertexTransformFunction and billboarding), and each Instance offsets the result. Billboarded Instances that share vertices all face
+// the Camera as the InstancedModel would from its own position. Rotated or scaled Instances, and Instances of morphed Meshes, are transformed
+// individually, and lit Materials are still lit for each Instance.
+// Note that skinned Meshes and levels of detail aren't supported for InstancedModels.
+type InstancedModel struct {
+	*Model
+	Instances []*Instance
+
+	proxy      *Model                          // The Model rendered in place of each Instance
+	cullSphere *BoundingSphere                 // The bounding sphere used to cull each Instance
+	shared     map[*MeshPart]*instanceVertices // The vertices shared by the Instances that are only moved, by MeshPart
+}
+
+// instanceVertices are the vertices of a MeshPart transformed as though an Instance were at the InstancedModel's origin, shared between
+// the InstancedModel's Instances that are only moved.
+type instanceVertices struct {
+	start      int      // The index of the first vertex of the MeshPart
+	transforms []Vector // The vertices transformed into clip space
+	normals    []Vector // The vertex normals transformed into view space, if the Camera renders normals
+}
+
+// NewInstancedModel creates a new InstancedModel of the Mesh and name provided, without any Instances.
+func NewInstancedModel(mesh *Mesh, name string) *InstancedModel {
+
+	im := &InstancedModel{
+		Model:      NewModel(mesh, name),
+		proxy:      NewModel(mesh, name),
+		cullSphere: NewBoundingSphere("instance bounding sphere", 0),
+		shared:     map[*MeshPart]*instanceVertices{},
+	}
+
+	im.Model.instancer = im
+	im.proxy.renderOwner = im.Model
+
+	return im
+
+}
+
+// Clone returns a clone of the InstancedModel and its Instances.
+func (im *InstancedModel) Clone() INode {
+
+	clone := NewInstancedModel(im.Mesh, im.name)
+	clone.Model = im.Model.Clone().(*Model)
+	clone.Model.instancer = clone
+	clone.proxy.renderOwner = clone.Model
+
+	for _, child := range clone.children {
+		child.setParent(clone)
+	}
+
+	for _, instance := range im.Instances {
+		clone.Instances = append(clone.Instances, instance.Clone())
+	}
+
+	return clone
+
+}
+
+// AddInstances adds the given number of new Instances to the InstancedModel, returning them.
+func (im *InstancedModel) AddInstances(count int) []*Instance {
+	added := make([]*Instance, 0, count)
+	for i := 0; i < count; i++ {
+		added = append(added, NewInstance())
+	}
+	im.Instances = append(im.Instances, added...)
+	return added
+}
+
+// RemoveInstances removes the given Instances from the InstancedModel.
+func (im *InstancedModel) RemoveInstances(instances ...*Instance) {
+
+	for _, instance := range instances {
+
+		for i, existing := range im.Instances {
+			if existing == instance {
+				im.Instances = append(im.Instances[:i], im.Instances[i+1:]...)
+				break
+			}
+		}
+
+	}
+
+}
+
+// shareVertices transforms the MeshPart's vertices once for the Instances that are only moved, returning them, or nil if the vertices
+// can't be shared.
+func (im *InstancedModel) shareVertices(camera *Camera, vpMatrix Matrix4, meshPart *MeshPart) *instanceVertices {
+
+	owner := im.Model
+
+	if im.proxy.updateMorphState(); im.proxy.morphed || owner.skinned {
+		return nil
+	}
+
+	shared, ok := im.shared[meshPart]
+	if !ok {
+		shared = &instanceVertices{}
+		im.shared[meshPart] = shared
+	}
+
+	ownerTransform := owner.Transform()
+	base := ownerTransform
+
+	if meshPart.Material != nil && meshPart.Material.BillboardMode != BillboardModeNone {
+		base = billboardTransform(base, owner.WorldPosition(), camera.WorldPosition(), meshPart.Material.BillboardMode)
+	}
+
+	mvp := base.Mult(vpMatrix)
+
+	mesh := meshPart.Mesh
+	count := meshPart.VertexIndexEnd - meshPart.VertexIndexStart
+
+	shared.start = meshPart.VertexIndexStart
+	shared.transforms = resizeVectors(shared.transforms, count)
+
+	for i := 0; i < count; i++ {
+		v := mesh.VertexPositions[shared.start+i]
+		if owner.VertexTransformFunction != nil {
+			v = owner.VertexTransformFunction(v, shared.start+i)
+		}
+		shared.transforms[i] = mvp.MultVecW(v)
+	}
+
+	if camera.RenderNormals {
+		_, _, mvJustR := ownerTransform.Mult(camera.ViewMatrix()).Decompose()
+		shared.normals = resizeVectors(shared.normals, count)
+		for i := 0; i < count; i++ {
+			shared.normals[i] = mvJustR.MultVecW(mesh.VertexNormals[shared.start+i])
+		}
+	}
+
+	return shared
+
+}
+
+// renderInstances calls render once for each of the InstancedModel's visible Instances that are within the Camera's frustum, passing a Model
+// transformed and colored as the Instance. Instances that are only moved share vertices transformed once for them all.
+func (im *InstancedModel) renderInstances(camera *Camera, vpMatrix Matrix4, meshPart *MeshPart, render func(rp renderPair)) {
+
+	owner := im.Model
+
+	if owner.Mesh == nil {
+		return
+	}
+
+	proxy := im.proxy
+	proxy.Mesh = owner.Mesh
+	proxy.MorphWeights = owner.MorphWeights
+	proxy.LightGroup = owner.LightGroup
+	proxy.ColorBlendingFunc = owner.ColorBlendingFunc
+	proxy.VertexTransformFunction = owner.VertexTransformFunction
+	proxy.VertexClipFunction = owner.VertexClipFunction
+	proxy.layers = owner.layers
+
+	ownerTransform := owner.Transform()
+	ownerPosition := ownerTransform.Row(3)
+
+	var shared *instanceVertices
+	sharing := false
+
+	center := owner.Mesh.Dimensions.Center()
+	radius := owner.Mesh.Dimensions.MaxSpan() / 2
+
+	for _, instance := range im.Instances {
+
+		if !instance.Visible {
+			continue
+		}
+
+		transform := instance.Transform().Mult(ownerTransform)
+
+		if owner.FrustumCulling {
+
+			scale := math.Max(math.Max(transform.Row(0).Magnitude(), transform.Row(1).Magnitude()), transform.Row(2).Magnitude())
+			im.cullSphere.Radius = radius * scale
+			im.cullSphere.SetLocalPositionVec(transform.MultVec(center))
+
+			if !camera.SphereInFrustum(im.cullSphere) {
+				continue
+			}
+
+		}
+
+		// The proxy has no parent, so its transform can be set directly without being recalculated.
+		proxy.cachedTransform = transform
+		proxy.isTransformDirty = false
+
+		proxy.instanceVertices = nil
+
+		if instance.translated {
+
+			// Vertices are only shared once an Instance that can use them is drawn
+			if !sharing {
+				shared = im.shareVertices(camera, vpMatrix, meshPart)
+				sharing = true
+			}
+
+			if shared != nil {
+
+				// Projecting is linear before the perspective divide, so moving vertices in world space moves them by the projected
+				// difference in clip space.
+				offset := transform.Row(3)
+				offset.X -= ownerPosition.X
+				offset.Y -= ownerPosition.Y
+				offset.Z -= ownerPosition.Z
+
+				proxy.instanceOffset = Vector{
+					offset.X*vpMatrix[0][0] + offset.Y*vpMatrix[1][0] + offset.Z*vpMatrix[2][0],
+					offset.X*vpMatrix[0][1] + offset.Y*vpMatrix[1][1] + offset.Z*vpMatrix[2][1],
+					offset.X*vpMatrix[0][2] + offset.Y*vpMatrix[1][2] + offset.Z*vpMatrix[2][2],
+					offset.X*vpMatrix[0][3] + offset.Y*vpMatrix[1][3] + offset.Z*vpMatrix[2][3],
+				}
+
+				proxy.instanceVertices = shared
+
+			}
+
+		}
+
+		proxy.Color.Set(owner.Color.R*instance.Color.R, owner.Color.G*instance.Color.G, owner.Color.B*instance.Color.B, owner.Color.A*instance.Color.A)
+
+		render(renderPair{Model: proxy, MeshPart: meshPart})
+
+	}
+
+	proxy.instanceVertices = nil
+
+}
+
+// nodeModel returns the Model that the Node given renders as - either the Node itself if it's a Model, or the Model of an InstancedModel
+// or Decal.
+func nodeModel(node INode) (*Model, bool) {
+	switch n := node.(type) {
+	case *Model:
+		return n, true
+	case *InstancedModel:
+		return n.Model, true
+	case *Decal:
+		return n.Model, true
+	}
+	return nil, false
+}
+
+/////
+
+// AddChildren parents the provided children Nodes to the passed parent Node, inheriting its transformations and being under it in the scenegraph
+// hierarchy. If the children are already parented to other Nodes, they are unparented before doing so.
+func (im *InstancedModel) AddChildren(children ...INode) {
+	im.addChildren(im, children...)
+}
+
+// Unparent unparents the InstancedModel from its parent, removing it from the scenegraph.
+func (im *InstancedModel) Unparent() {
+	if im.parent != nil {
+		im.parent.RemoveChildren(im)
+	}
+}
+
+// Type returns the NodeType for this object.
+func (im *InstancedModel) Type() NodeType {
+	return NodeTypeInstancedModel
+}
+
+// Index returns the index of the Node in its parent's children list.
+// If the node doesn't have a parent, its index will be -1.
+func (im *InstancedModel) Index() int {
+	if im.parent != nil {
+		for i, c := range im.parent.Children() {
+			if c == im {
+				return i
+			}
+		}
+	}
+	return -1
+}
@@ -0,0 +1,181 @@
+package tetra3d
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestInstancedModel(t *testing.T) {
+
+	scene := NewScene("Test")
+
+	mesh := NewCubeMesh()
+	mesh.MeshParts[0].Material.Shadeless = true
+
+	crowd := NewInstancedModel(mesh, "Crowd")
+	scene.Root.AddChildren(crowd)
+
+	instances := crowd.AddInstances(4)
+	instances[0].SetPosition(-2, 0, 0)
+	instances[0].Color.Set(1, 0, 0, 1)
+	instances[1].SetPosition(2, 0, 0)
+	instances[1].Color.Set(0, 1, 0, 1)
+	instances[2].Visible = false
+	instances[3].SetPosition(100, 0, 0) // Outside of the frustum
+
+	camera := NewCamera(64, 64)
+	camera.SetLocalPosition(0, 0, 10)
+	scene.Root.AddChildren(camera)
+
+	if models := scene.Root.SearchTree().Models(); len(models) != 1 || models[0] != crowd.Model {
+		t.Fatalf("expected the InstancedModel's Model to be found when searching for Models")
+	}
+
+	sr := NewSoftwareRenderer(camera)
+	sr.RenderScene(scene)
+
+	left := camera.WorldToScreen(Vector{-2, 0, 0, 0})
+	right := camera.WorldToScreen(Vector{2, 0, 0, 0})
+
+	if c := sr.ColorBuffer().RGBAAt(int(left.X), int(left.Y)); c != (color.RGBA{255, 0, 0, 255}) {
+		t.Fatalf("left instance is %v, expected red", c)
+	}
+
+	if c := sr.ColorBuffer().RGBAAt(int(right.X), int(right.Y)); c != (color.RGBA{0, 255, 0, 255}) {
+		t.Fatalf("right instance is %v, expected green", c)
+	}
+
+	if c := sr.ColorBuffer().RGBAAt(32, 32); c.A != 0 {
+		t.Fatalf("the invisible instance was drawn")
+	}
+
+	if camera.DebugInfo.DrawnParts != 2 {
+		t.Fatalf("expected 2 instances to be drawn, got %d", camera.DebugInfo.DrawnParts)
+	}
+
+	crowd.RemoveInstances(instances[0])
+
+	clone := crowd.Clone().(*InstancedModel)
+	if len(clone.Instances) != 3 || clone.Instances[0] == instances[1] || !clone.Instances[0].Position().Equals(instances[1].Position()) || clone.Model.instancer != clone {
+		t.Fatalf("expected cloned InstancedModels to clone their instances")
+	}
+
+}
+
+func TestInstancedModelFlush(t *testing.T) {
+
+	scene := NewScene("Test")
+
+	crowd := NewInstancedModel(NewCubeMesh(), "Crowd")
+	crowd.FrustumCulling = false
+	scene.Root.AddChildren(crowd)
+
+	// More vertices than fit in the vertex buffers at once
+	for i, instance := range crowd.AddInstances(MaxTriangleCount / 6) {
+		instance.SetPosition(float64(i%50), float64(i/50), 0)
+	}
+
+	camera := NewCamera(64, 64)
+	camera.SetLocalPosition(25, 25, 60)
+	camera.RenderIDs = true
+	scene.Root.AddChildren(camera)
+
+	camera.Clear()
+	camera.RenderScene(scene)
+
+	if camera.DebugInfo.DrawnParts < 2 {
+		t.Fatalf("expected the instances to be drawn in more than one batch, got %d", camera.DebugInfo.DrawnParts)
+	}
+
+	if len(camera.pickEntries) == 0 || camera.pickEntries[0].Model != crowd.Model {
+		t.Fatalf("expected instances to be picked as their InstancedModel")
+	}
+
+}
+
+func TestInstancedModelSharedVertices(t *testing.T) {
+
+	positions := []Vector{{-3, 0, 0, 0}, {3, 0, 0, 0}, {0, 2, 1, 0}}
+
+	// Renders the Mesh at each of the positions above, either as Instances of an InstancedModel or as Models.
+	render := func(instanced bool, workers int) (*SoftwareRenderer, *InstancedModel) {
+
+		scene := NewScene("Test")
+
+		mesh := NewCubeMesh()
+		mesh.MeshParts[0].Material.Shadeless = false
+
+		light := NewPointLight("Light", 1, 0.5, 0.25, 2)
+		light.SetLocalPosition(0, 4, 4)
+		scene.Root.AddChildren(light)
+
+		parent := NewNode("Parent")
+		parent.SetLocalPosition(0.5, -1, -2)
+		parent.SetLocalRotation(NewMatrix4Rotate(0, 1, 0, 0.5))
+		scene.Root.AddChildren(parent)
+
+		var crowd *InstancedModel
+
+		if instanced {
+			crowd = NewInstancedModel(mesh, "Crowd")
+			parent.AddChildren(crowd)
+			for i, instance := range crowd.AddInstances(len(positions)) {
+				instance.SetPositionVec(positions[i])
+			}
+		} else {
+			for _, position := range positions {
+				model := NewModel(mesh, "Model")
+				model.SetLocalPositionVec(position)
+				parent.AddChildren(model)
+			}
+		}
+
+		camera := NewCamera(64, 64)
+		camera.SetLocalPosition(0, 1, 12)
+		camera.VertexWorkers = workers
+		scene.Root.AddChildren(camera)
+
+		sr := NewSoftwareRenderer(camera)
+		sr.RenderScene(scene)
+
+		return sr, crowd
+
+	}
+
+	for _, workers := range []int{1, 4} {
+
+		expected, _ := render(false, workers)
+		sr, crowd := render(true, workers)
+
+		if len(crowd.shared) != 1 {
+			t.Fatalf("expected moved-only instances to share vertices")
+		}
+
+		drawn := 0
+		different := 0
+
+		for i := 0; i < len(sr.ColorBuffer().Pix); i += 4 {
+			if expected.ColorBuffer().Pix[i+3] != 0 {
+				drawn++
+			}
+			for c := 0; c < 4; c++ {
+				diff := int(sr.ColorBuffer().Pix[i+c]) - int(expected.ColorBuffer().Pix[i+c])
+				if diff > 2 || diff < -2 {
+					different++
+					break
+				}
+			}
+		}
+
+		if drawn == 0 {
+			t.Fatalf("expected the instances to be drawn")
+		}
+
+		// Rounding may differ along the edges of triangles, but not more than that
+		if different > drawn/20 {
+			t.Fatalf("instances sharing vertices differ from Models in %d of %d drawn pixels with %d workers", different, drawn, workers)
+		}
+
+	}
+
+}
@@ -1,5 +1,7 @@
 package tetra3d
 
+import "math"
+
 // ILight represents an interface that is fulfilled by an object that emits light, returning the color a vertex should be given that Vertex and its model matrix.
 type ILight interface {
 	INode
@@ -11,12 +13,23 @@ type ILight interface {
 	// It gets called once before lighting all visible triangles of a given Model.
 	beginModel(model *Model)
 
+	// workingCopy returns a shallow copy of the light. As beginModel() stores working state in the light, Models lit on different goroutines
+	// at the same time are each lit by their own copy.
+	workingCopy() ILight
+
 	Light(meshPart *MeshPart, model *Model, targetColors []*Color, onlyVisible bool) // Light lights the triangles in the MeshPart, storing the result in the targetColors
 	// color buffer. If onlyVisible is true, only the visible vertices will be lit; if it's false, they will all be lit.
 	IsOn() bool    // isOn is simply used tfo tell if a "generic" Light is on or not.
 	SetOn(on bool) // SetOn sets whether the light is on or not
 }
 
+// newLightNode creates the Node for a light. Lights are on every render layer by default, so they light all Models.
+func newLightNode(name string) *Node {
+	node := NewNode(name)
+	node.layers = RenderLayerAll
+	return node
+}
+
 //---------------//
 
 // AmbientLight represents an ambient light that colors the entire Scene.
@@ -34,7 +47,7 @@ type AmbientLight struct {
 // NewAmbientLight returns a new AmbientLight.
 func NewAmbientLight(name string, r, g, b, energy float32) *AmbientLight {
 	return &AmbientLight{
-		Node:   NewNode(name),
+		Node:   newLightNode(name),
 		Color:  NewColor(r, g, b, 1),
 		Energy: energy,
 		On:     true,
@@ -61,6 +74,11 @@ func (amb *AmbientLight) beginRender() {
 
 func (amb *AmbientLight) beginModel(model *Model) {}
 
+func (amb *AmbientLight) workingCopy() ILight {
+	working := *amb
+	return &working
+}
+
 // Light returns the light level for the ambient light. It doesn't use the provided Triangle; it takes it as an argument to simply adhere to the Light interface.
 func (amb *AmbientLight) Light(meshPart *MeshPart, model *Model, targetColors []*Color, onlyVisible bool) {
 	meshPart.ForEachVertexIndex(func(vertIndex int) {
@@ -131,7 +149,7 @@ type PointLight struct {
 // NewPointLight creates a new Point light.
 func NewPointLight(name string, r, g, b, energy float32) *PointLight {
 	return &PointLight{
-		Node:   NewNode(name),
+		Node:   newLightNode(name),
 		Energy: energy,
 		Color:  NewColor(r, g, b, 1),
 		On:     true,
@@ -174,6 +192,11 @@ func (point *PointLight) beginModel(model *Model) {
 
 }
 
+func (point *PointLight) workingCopy() ILight {
+	working := *point
+	return &working
+}
+
 // Light returns the R, G, and B values for the PointLight for all vertices of a given Triangle.
 func (point *PointLight) Light(meshPart *MeshPart, model *Model, targetColors []*Color, onlyVisible bool) {
 
@@ -193,8 +216,7 @@ func (point *PointLight) Light(meshPart *MeshPart, model *Model, targetColors []
 			vertPos = model.Mesh.vertexSkinnedPositions[index]
 			vertNormal = model.Mesh.vertexSkinnedNormals[index]
 		} else {
-			vertPos = model.Mesh.VertexPositions[index]
-			vertNormal = model.Mesh.VertexNormals[index]
+			vertPos, vertNormal = model.localVertex(index)
 		}
 
 		distance = point.workingPosition.DistanceSquared(vertPos)
@@ -299,6 +321,199 @@ func (point *PointLight) Type() NodeType {
 
 //---------------//
 
+// SpotLight represents a light that shines in a cone along its local -Z axis. Vertices inside the inner cone angle are fully lit,
+// while vertices between the inner and outer cone angles are lit progressively less.
+type SpotLight struct {
+	*Node
+	// Distance represents the distance after which the light fully attenuates. If this is 0 (the default),
+	// it falls off using something akin to the inverse square law, like a PointLight.
+	Distance float64
+	// Color is the color of the SpotLight.
+	Color *Color
+	// Energy is the overall energy of the Light, with 1.0 being full brightness. Internally, technically there's no
+	// difference between a brighter color and a higher energy, but this is here for convenience / adherance to the
+	// GLTF spec and 3D modelers.
+	Energy float32
+	// InnerConeAngle is the angle in radians from the center of the cone where the light starts to fall off.
+	InnerConeAngle float64
+	// OuterConeAngle is the angle in radians from the center of the cone where the light fully fades out.
+	OuterConeAngle float64
+	// If the light is on and contributing to the scene.
+	On bool
+
+	distanceSquared float64
+	cosInner        float64
+	cosOuter        float64
+	workingPosition Vector
+	workingForward  Vector
+}
+
+// NewSpotLight creates a new SpotLight with the given RGB color, energy, and cone angles (in radians).
+func NewSpotLight(name string, r, g, b, energy float32, innerConeAngle, outerConeAngle float64) *SpotLight {
+	return &SpotLight{
+		Node:           newLightNode(name),
+		Energy:         energy,
+		Color:          NewColor(r, g, b, 1),
+		InnerConeAngle: innerConeAngle,
+		OuterConeAngle: outerConeAngle,
+		On:             true,
+	}
+}
+
+// Clone returns a new clone of the given SpotLight.
+func (spot *SpotLight) Clone() INode {
+
+	clone := NewSpotLight(spot.name, spot.Color.R, spot.Color.G, spot.Color.B, spot.Energy, spot.InnerConeAngle, spot.OuterConeAngle)
+	clone.On = spot.On
+	clone.Distance = spot.Distance
+
+	clone.Node = spot.Node.Clone().(*Node)
+	for _, child := range clone.children {
+		child.setParent(clone)
+	}
+
+	return clone
+
+}
+
+func (spot *SpotLight) beginRender() {
+	spot.distanceSquared = spot.Distance * spot.Distance
+
+	outer := spot.OuterConeAngle
+	inner := math.Min(spot.InnerConeAngle, outer)
+	spot.cosInner = math.Cos(inner)
+	spot.cosOuter = math.Cos(outer)
+}
+
+func (spot *SpotLight) beginModel(model *Model) {
+
+	// Forward() is +Z, which points back towards the light source, as spot lights shine along -Z.
+	forward := spot.WorldRotation().Forward()
+
+	if model.skinned {
+		spot.workingPosition = spot.WorldPosition()
+		spot.workingForward = forward
+	} else {
+		// As with PointLights, we transform the light into the Model's local space rather than transforming each vertex.
+		p, s, r := model.Transform().Inverted().Decompose()
+		spot.workingPosition = r.MultVec(spot.WorldPosition()).Add(p.Mult(Vector{1 / s.X, 1 / s.Y, 1 / s.Z, s.W}))
+		spot.workingForward = r.MultVec(forward).Unit()
+	}
+
+}
+
+func (spot *SpotLight) workingCopy() ILight {
+	working := *spot
+	return &working
+}
+
+// Light returns the R, G, and B values for the SpotLight for all vertices of a given Triangle.
+func (spot *SpotLight) Light(meshPart *MeshPart, model *Model, targetColors []*Color, onlyVisible bool) {
+
+	meshPart.ForEachVertexIndex(func(index int) {
+
+		var vertPos, vertNormal Vector
+
+		if model.skinned {
+			vertPos = model.Mesh.vertexSkinnedPositions[index]
+			vertNormal = model.Mesh.vertexSkinnedNormals[index]
+		} else {
+			vertPos, vertNormal = model.localVertex(index)
+		}
+
+		distance := spot.workingPosition.DistanceSquared(vertPos)
+
+		if spot.Distance > 0 {
+			if distance > spot.distanceSquared {
+				return
+			}
+		} else if 1/distance*float64(spot.Energy) < 0.001 {
+			return
+		}
+
+		lightVec := spot.workingPosition.Sub(vertPos).Unit()
+
+		// Cone falloff; 1 within the inner cone, 0 outside of the outer cone, and smoothly interpolated in-between.
+		cone := 0.0
+		angle := lightVec.Dot(spot.workingForward)
+
+		if angle >= spot.cosInner {
+			cone = 1
+		} else if angle > spot.cosOuter {
+			cone = clamp((angle-spot.cosOuter)/(spot.cosInner-spot.cosOuter), 0, 1)
+			cone = cone * cone * (3 - 2*cone)
+		} else {
+			return
+		}
+
+		diffuse := vertNormal.Dot(lightVec)
+
+		if diffuse > 0 {
+
+			diffuseFactor := 0.0
+
+			if spot.Distance == 0 {
+				diffuseFactor = diffuse * (1.0 / (1.0 + (0.1 * distance))) * 2
+			} else {
+				diffuseFactor = diffuse * clamp(1.0-(pow((distance/spot.distanceSquared), 4)), 0, 1)
+			}
+
+			diffuseFactor *= cone
+
+			targetColors[index].AddRGBA(
+				spot.Color.R*float32(diffuseFactor)*spot.Energy,
+				spot.Color.G*float32(diffuseFactor)*spot.Energy,
+				spot.Color.B*float32(diffuseFactor)*spot.Energy,
+				0,
+			)
+
+		}
+
+	}, onlyVisible)
+
+}
+
+// AddChildren parents the provided children Nodes to the passed parent Node, inheriting its transformations and being under it in the scenegraph
+// hierarchy. If the children are already parented to other Nodes, they are unparented before doing so.
+func (spot *SpotLight) AddChildren(children ...INode) {
+	spot.addChildren(spot, children...)
+}
+
+// Unparent unparents the SpotLight from its parent, removing it from the scenegraph.
+func (spot *SpotLight) Unparent() {
+	if spot.parent != nil {
+		spot.parent.RemoveChildren(spot)
+	}
+}
+
+func (spot *SpotLight) IsOn() bool {
+	return spot.On && spot.Energy > 0
+}
+
+func (spot *SpotLight) SetOn(on bool) {
+	spot.On = on
+}
+
+// Index returns the index of the Node in its parent's children list.
+// If the node doesn't have a parent, its index will be -1.
+func (spot *SpotLight) Index() int {
+	if spot.parent != nil {
+		for i, c := range spot.parent.Children() {
+			if c == spot {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// Type returns the NodeType for this object.
+func (spot *SpotLight) Type() NodeType {
+	return NodeTypeSpotLight
+}
+
+//---------------//
+
 // DirectionalLight represents a directional light of infinite distance.
 type DirectionalLight struct {
 	*Node
@@ -315,7 +530,7 @@ type DirectionalLight struct {
 // NewDirectionalLight creates a new Directional Light with the specified RGB color and energy (assuming 1.0 energy is standard / "100%" lighting).
 func NewDirectionalLight(name string, r, g, b, energy float32) *DirectionalLight {
 	return &DirectionalLight{
-		Node:   NewNode(name),
+		Node:   newLightNode(name),
 		Color:  NewColor(r, g, b, 1),
 		Energy: energy,
 		On:     true,
@@ -348,6 +563,11 @@ func (sun *DirectionalLight) beginModel(model *Model) {
 	}
 }
 
+func (sun *DirectionalLight) workingCopy() ILight {
+	working := *sun
+	return &working
+}
+
 // Light returns the R, G, and B values for the DirectionalLight for each vertex of the provided Triangle.
 func (sun *DirectionalLight) Light(meshPart *MeshPart, model *Model, targetColors []*Color, onlyVisible bool) {
 
@@ -358,7 +578,8 @@ func (sun *DirectionalLight) Light(meshPart *MeshPart, model *Model, targetColor
 			// If it's skinned, we don't have to calculate the normal, as that's been pre-calc'd for us
 			normal = model.Mesh.vertexSkinnedNormals[index]
 		} else {
-			normal = sun.workingModelRotation.MultVec(model.Mesh.VertexNormals[index])
+			_, localNormal := model.localVertex(index)
+			normal = sun.workingModelRotation.MultVec(localNormal)
 		}
 
 		diffuseFactor := normal.Dot(sun.workingForward)
@@ -438,7 +659,7 @@ type CubeLight struct {
 // NewCubeLight creates a new CubeLight with the given dimensions.
 func NewCubeLight(name string, dimensions Dimensions) *CubeLight {
 	cube := &CubeLight{
-		Node: NewNode(name),
+		Node: newLightNode(name),
 		// Dimensions:    Dimensions{{-w / 2, -h / 2, -d / 2}, {w / 2, h / 2, d / 2}},
 		Dimensions:    dimensions,
 		Distance:      0,
@@ -570,6 +791,11 @@ func (cube *CubeLight) beginModel(model *Model) {
 
 }
 
+func (cube *CubeLight) workingCopy() ILight {
+	working := *cube
+	return &working
+}
+
 // Light returns the R, G, and B values for the PointLight for all vertices of a given Triangle.
 func (cube *CubeLight) Light(meshPart *MeshPart, model *Model, targetColors []*Color, onlyVisible bool) {
 
@@ -610,8 +836,7 @@ func (cube *CubeLight) Light(meshPart *MeshPart, model *Model, targetColors []*C
 			vertPos = model.Mesh.vertexSkinnedPositions[index]
 			vertNormal = model.Mesh.vertexSkinnedNormals[index]
 		} else {
-			vertPos = model.Mesh.VertexPositions[index]
-			vertNormal = model.Mesh.VertexNormals[index]
+			vertPos, vertNormal = model.localVertex(index)
 		}
 
 		var diffuse, diffuseFactor float64
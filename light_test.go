@@ -0,0 +1,175 @@
+package tetra3d
+
+import (
+	"math"
+	"testing"
+)
+
+// newSpotLightTestFloor returns a Model with a vertex on the ground for each of the given angles away from straight below a light 5 units up.
+func newSpotLightTestFloor(angles ...float64) *Model {
+
+	verts := []VertexInfo{}
+	for _, angle := range angles {
+		verts = append(verts, NewVertex(5*math.Tan(angle), 0, 0, 0, 0))
+	}
+
+	mesh := NewMesh("Floor", verts...)
+	mesh.AddMeshPart(NewMaterial("Floor"), 0, 1, 2, 3, 4, 5)
+	for i := range mesh.VertexNormals {
+		mesh.VertexNormals[i] = Vector{0, 1, 0, 0}
+	}
+
+	return NewModel(mesh, "Floor")
+
+}
+
+func TestSpotLightFalloff(t *testing.T) {
+
+	// Inside the inner cone, halfway through the penumbra, at the outer edge, and outside of the cone.
+	angles := []float64{0, 0.2, 0.4, 0.5 - 0.0001, 0.6, 1}
+
+	light := func(spot *SpotLight) []float32 {
+		floor := newSpotLightTestFloor(angles...)
+		spot.SetLocalPosition(0, 5, 0)
+		spot.SetLocalRotation(NewMatrix4Rotate(1, 0, 0, -math.Pi/2))
+		floor.BakeLighting(0, spot)
+		values := []float32{}
+		for _, colors := range floor.Mesh.VertexColors {
+			values = append(values, colors[0].R)
+		}
+		return values
+	}
+
+	// A spot light with a wide, hard cone lights the floor the same way a point light would, so it's used as the baseline.
+	unconed := light(NewSpotLight("Wide", 1, 1, 1, 1, 1.5, 1.5))
+	coned := light(NewSpotLight("Spot", 1, 1, 1, 1, 0.3, 0.5))
+
+	for i, angle := range angles {
+
+		if unconed[i] <= 0 {
+			t.Fatalf("vertex %f radians away should be lit by a wide spot light", angle)
+		}
+
+		expected := float32(0)
+
+		switch {
+		case angle <= 0.3:
+			expected = unconed[i]
+		case angle == 0.4:
+			// Cone falloff is measured by the cosine of the angle and smoothed.
+			f := (math.Cos(angle) - math.Cos(0.5)) / (math.Cos(0.3) - math.Cos(0.5))
+			expected = unconed[i] * float32(f*f*(3-2*f))
+		}
+
+		if math.Abs(float64(coned[i]-expected)) > 0.001 {
+			t.Errorf("vertex %f radians away should be lit by %f, but was lit by %f", angle, expected, coned[i])
+		}
+
+	}
+
+	if coned[3] > 0.001 {
+		t.Errorf("vertex at the edge of the cone should be almost unlit, but was lit by %f", coned[3])
+	}
+
+	// An inner cone angle larger than the outer one is clamped to the outer cone.
+	clamped := light(NewSpotLight("Clamped", 1, 1, 1, 1, 1, 0.5))
+
+	for i, angle := range angles {
+		if angle < 0.5 && clamped[i] != unconed[i] {
+			t.Errorf("vertex %f radians away should be fully lit by a spot light with its inner cone clamped, but was lit by %f", angle, clamped[i])
+		} else if angle >= 0.5 && clamped[i] != 0 {
+			t.Errorf("vertex %f radians away should be outside the clamped cone, but was lit by %f", angle, clamped[i])
+		}
+	}
+
+}
+
+func TestSpotLightClone(t *testing.T) {
+
+	spot := NewSpotLight("Spot", 1, 0.5, 0.25, 2, 0.3, 0.5)
+	spot.Distance = 10
+	spot.On = false
+	spot.SetLocalPosition(1, 2, 3)
+	spot.AddChildren(NewNode("Child"))
+
+	clone := spot.Clone().(*SpotLight)
+
+	if clone == spot || clone.Color == spot.Color {
+		t.Fatalf("cloned spot light should be a separate light with its own color")
+	}
+
+	if clone.Name() != "Spot" || clone.Energy != 2 || clone.Distance != 10 || clone.On ||
+		clone.InnerConeAngle != 0.3 || clone.OuterConeAngle != 0.5 || *clone.Color != *spot.Color {
+		t.Fatalf("cloned spot light didn't keep its settings")
+	}
+
+	if !clone.LocalPosition().Equals(Vector{1, 2, 3, 0}) {
+		t.Fatalf("cloned spot light should keep its position, but is at %s", clone.LocalPosition())
+	}
+
+	if len(clone.Children()) != 1 || clone.Children()[0].Parent() != clone || clone.Children()[0] == spot.Children()[0] {
+		t.Fatalf("cloned spot light should have its own cloned children")
+	}
+
+	if clone.Type() != NodeTypeSpotLight {
+		t.Fatalf("cloned spot light should be a spot light")
+	}
+
+}
+
+func TestLoadGLTFSpotLight(t *testing.T) {
+
+	data := []byte(`{
+		"asset": {"version": "2.0"},
+		"extensionsUsed": ["KHR_lights_punctual"],
+		"extensions": {
+			"KHR_lights_punctual": {
+				"lights": [
+					{"type": "spot", "name": "Spot", "color": [1, 0.5, 0.25], "intensity": 160, "range": 12, "spot": {"innerConeAngle": 0.25, "outerConeAngle": 0.5}},
+					{"type": "spot", "name": "Default", "intensity": 80}
+				]
+			}
+		},
+		"scene": 0,
+		"scenes": [{"name": "Level", "nodes": [0, 1]}],
+		"nodes": [
+			{"name": "Spot", "translation": [1, 2, 3], "extensions": {"KHR_lights_punctual": {"light": 0}}},
+			{"name": "Default", "extensions": {"KHR_lights_punctual": {"light": 1}}}
+		]
+	}`)
+
+	library, err := LoadGLTFData(data, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root := library.ExportedScene.Root
+
+	spot, ok := root.Get("Spot").(*SpotLight)
+	if !ok {
+		t.Fatalf("spot light wasn't loaded as a SpotLight")
+	}
+
+	if math.Abs(spot.InnerConeAngle-0.25) > 0.0001 || math.Abs(spot.OuterConeAngle-0.5) > 0.0001 {
+		t.Fatalf("spot light cone angles weren't loaded, got %f and %f", spot.InnerConeAngle, spot.OuterConeAngle)
+	}
+
+	if spot.Energy != 2 || spot.Distance != 12 || *spot.Color != *NewColor(1, 0.5, 0.25, 1) {
+		t.Fatalf("spot light settings weren't loaded")
+	}
+
+	if !spot.WorldPosition().Equals(Vector{1, 2, 3, 0}) {
+		t.Fatalf("spot light should be at 1, 2, 3, but is at %s", spot.WorldPosition())
+	}
+
+	def, ok := root.Get("Default").(*SpotLight)
+	if !ok {
+		t.Fatalf("spot light without cone angles wasn't loaded as a SpotLight")
+	}
+
+	// The glTF spec's default cone angles are 0 and pi / 4.
+	if def.InnerConeAngle != 0 || math.Abs(def.OuterConeAngle-math.Pi/4) > 0.0001 || def.Distance != 0 {
+		t.Fatalf("spot light without cone angles should use the default angles, but got %f and %f", def.InnerConeAngle, def.OuterConeAngle)
+	}
+
+}
@@ -0,0 +1,295 @@
+package tetra3d
+
+import (
+	"math"
+	"sort"
+)
+
+type LODMode int
+
+const (
+	LODModeDistance   LODMode = iota // Levels of detail are chosen by the distance from the Camera to the Model's bounding sphere, in world units.
+	LODModeScreenSize                // Levels of detail are chosen by the height of the Model's bounding sphere on screen, as a fraction of the Camera's height.
+)
+
+// LODLevel is a single reduced level of detail in an LOD set.
+type LODLevel struct {
+	Mesh *Mesh // The Mesh to render in place of the Model's own Mesh at this level of detail.
+
+	// Threshold is the point at which the level starts to be used. For LODModeDistance, this is a distance, and so it increases with each level;
+	// for LODModeScreenSize, this is a fraction of the screen's height, and so it decreases with each level.
+	Threshold float64
+
+	model *Model // The Model rendered in the LOD owner's place for this level
+}
+
+// LOD is a set of reduced levels of detail for a Model. When a Model has an LOD, Cameras render whichever level fits the Model's distance (or
+// size on screen) each frame in place of the Model's own Mesh, which serves as the most detailed level. This saves on processing vertices for
+// Models that are too far away for their detail to be noticed.
+// Note that LODs are ignored for skinned Models and InstancedModels, as well as for Models that are dynamically or statically batched.
+type LOD struct {
+	Levels []*LODLevel // The reduced levels of detail, ordered from most to least detailed; use LOD.AddLevel() to keep them in order.
+	Mode   LODMode     // How levels are chosen (LODModeDistance or LODModeScreenSize).
+
+	// Hysteresis is how far past a level's Threshold (in the units of the Mode) a Model has to move before switching levels. This keeps Models
+	// lingering around a Threshold from switching back and forth every frame.
+	Hysteresis float64
+
+	// CrossFade is how far (in the units of the Mode) a Model has to move after switching levels for the previous level to dither out as the new
+	// level dithers in, rather than the new level popping in. Moving steadily, this is the range past the point the level switches at (its
+	// Threshold, plus the Hysteresis in the direction of the switch). A CrossFade of 0 disables cross-fading. Cross-fading only works with
+	// Camera.RenderDepth on.
+	CrossFade float64
+
+	current map[*Camera]*lodState // The level last rendered by each Camera, so that Cameras don't interfere with each other's hysteresis
+}
+
+// lodState is the level of detail a Camera last rendered an LOD at, and how far it's cross-faded to it.
+type lodState struct {
+	level    int     // The level of detail
+	previous int     // The level being cross-faded from
+	fade     float64 // How far the level has dithered in over the previous one, ranging from 0 to 1
+	value    float64 // The value the level was last chosen from
+}
+
+// NewLOD creates a new, empty LOD set that chooses levels using the mode given (LODModeDistance or LODModeScreenSize).
+func NewLOD(mode LODMode) *LOD {
+	return &LOD{
+		Mode:    mode,
+		current: map[*Camera]*lodState{},
+	}
+}
+
+// Clone returns a clone of the LOD set. The clone's levels share their Meshes with the original's.
+func (lod *LOD) Clone() *LOD {
+	newLOD := NewLOD(lod.Mode)
+	newLOD.Hysteresis = lod.Hysteresis
+	newLOD.CrossFade = lod.CrossFade
+	for _, level := range lod.Levels {
+		newLOD.Levels = append(newLOD.Levels, &LODLevel{Mesh: level.Mesh, Threshold: level.Threshold})
+	}
+	return newLOD
+}
+
+// AddLevel adds a level of detail rendering the Mesh given once the Threshold is passed, keeping the LOD's Levels ordered from most to least detailed.
+func (lod *LOD) AddLevel(mesh *Mesh, threshold float64) *LODLevel {
+
+	level := &LODLevel{Mesh: mesh, Threshold: threshold}
+	lod.Levels = append(lod.Levels, level)
+
+	sort.SliceStable(lod.Levels, func(i, j int) bool {
+		return lod.past(lod.Levels[j].Threshold, lod.Levels[i].Threshold) && lod.Levels[i].Threshold != lod.Levels[j].Threshold
+	})
+
+	return level
+
+}
+
+// CurrentLevel returns the index of the level of detail last rendered by the given Camera; 0 is the Model's own Mesh, and 1 onwards are the
+// LOD's Levels.
+func (lod *LOD) CurrentLevel(camera *Camera) int {
+	if state, exists := lod.current[camera]; exists {
+		return state.level
+	}
+	return 0
+}
+
+// direction returns 1 if the LOD's thresholds increase as detail decreases, or -1 if they decrease.
+func (lod *LOD) direction() float64 {
+	if lod.Mode == LODModeScreenSize {
+		return -1
+	}
+	return 1
+}
+
+// past returns if the value given is past the threshold given, towards lower levels of detail.
+func (lod *LOD) past(value, threshold float64) bool {
+	if lod.Mode == LODModeScreenSize {
+		return value <= threshold
+	}
+	return value >= threshold
+}
+
+// update chooses the level of detail for the Camera given from the value given (a distance or screen size, depending on the LOD's Mode),
+// returning the level, the level it's cross-fading from, and how far it's faded in over that level.
+func (lod *LOD) update(camera *Camera, value float64) (level, previous int, fade float64) {
+
+	if lod.current == nil {
+		lod.current = map[*Camera]*lodState{}
+	}
+
+	state, exists := lod.current[camera]
+	if !exists {
+		state = &lodState{}
+		lod.current[camera] = state
+	}
+
+	current := state.level
+
+	if current > len(lod.Levels) {
+		current = len(lod.Levels)
+	}
+
+	for i := range lod.Levels {
+		if lod.past(value, lod.switchPoint(i+1, current)) {
+			level = i + 1
+		}
+	}
+
+	if !exists || level != current {
+
+		// A level of detail fades in from the point it was switched to at. A Camera seeing the LOD for the first time (at level 0) fades it in
+		// as though it came from the more detailed levels.
+		state.previous = current
+		state.fade = 1
+
+		if level != current && lod.CrossFade > 0 {
+			switchLevel := level
+			if level < current {
+				switchLevel = level + 1
+			}
+			state.fade = math.Abs(value-lod.switchPoint(switchLevel, current)) / lod.CrossFade
+		}
+
+	} else if lod.CrossFade > 0 {
+		// Moving either way after switching levels continues the cross-fade, so a Model that turns back within the hysteresis keeps showing the
+		// level it's at.
+		state.fade += math.Abs(value-state.value) / lod.CrossFade
+	} else {
+		state.fade = 1
+	}
+
+	state.level = level
+	state.value = value
+	state.fade = math.Min(state.fade, 1)
+
+	return state.level, state.previous, state.fade
+
+}
+
+// switchPoint returns the value at which the given level is switched to (when switching towards lower levels of detail) or away from (when
+// switching towards higher levels of detail), depending on the current level. Dropping to a level takes passing its threshold by the
+// hysteresis, while staying at a level only takes not coming back by as much.
+func (lod *LOD) switchPoint(level, current int) float64 {
+	threshold := lod.Levels[level-1].Threshold
+	if level > current {
+		return threshold + lod.direction()*lod.Hysteresis
+	}
+	return threshold - lod.direction()*lod.Hysteresis
+}
+
+// levelModel returns the Model to render for the given level of detail of the owning Model. Reduced levels are rendered through Models that
+// share the owner's Node (and so its transform and visibility) and bounding sphere.
+func (lod *LOD) levelModel(owner *Model, level int) *Model {
+
+	if level == 0 {
+		return owner
+	}
+
+	l := lod.Levels[level-1]
+
+	if l.model == nil || l.model.Mesh != l.Mesh || l.model.Node != owner.Node {
+		l.model = NewModel(l.Mesh, owner.name)
+		l.model.Node = owner.Node
+		l.model.renderOwner = owner
+	}
+
+	m := l.model
+	m.BoundingSphere = owner.BoundingSphere
+	m.FrustumCulling = owner.FrustumCulling
+	m.Color = owner.Color
+	m.ColorBlendingFunc = owner.ColorBlendingFunc
+	m.LightGroup = owner.LightGroup
+	m.VertexTransformFunction = owner.VertexTransformFunction
+	m.VertexClipFunction = owner.VertexClipFunction
+
+	return m
+
+}
+
+// lodValue returns the value used to choose the level of detail of the Model given from the Camera - either the distance to the Model's
+// bounding sphere, or the height of the bounding sphere on screen.
+func (camera *Camera) lodValue(model *Model) float64 {
+
+	model.Transform()
+
+	distance := camera.WorldPosition().Distance(model.BoundingSphere.WorldPosition())
+
+	if model.LOD.Mode != LODModeScreenSize {
+		return distance
+	}
+
+	var height float64
+
+	if camera.perspective {
+		height = 2 * distance * math.Tan(camera.fieldOfView*math.Pi/360)
+	} else {
+		w, h := camera.Size()
+		height = 2 * camera.orthoScale * float64(h) / float64(w)
+	}
+
+	if height <= 0 {
+		return math.Inf(1)
+	}
+
+	return model.BoundingSphere.WorldRadius() * 2 / height
+
+}
+
+// lodModels returns the Models given, with each Model that has an LOD replaced by the Model (or Models, while cross-fading) to render for its
+// current level of detail.
+func (camera *Camera) lodModels(models []*Model) []*Model {
+
+	camera.lodRenderModels = camera.lodRenderModels[:0]
+
+	for _, model := range models {
+
+		model.lodFade = 0
+
+		lod := model.LOD
+
+		if lod == nil || len(lod.Levels) == 0 || model.skinned || model.dynamicBatcher || model.instancer != nil || model.AutoBatchMode == AutoBatchStatic {
+			camera.lodRenderModels = append(camera.lodRenderModels, model)
+			continue
+		}
+
+		level, previousLevel, fade := lod.update(camera, camera.lodValue(model))
+
+		current := lod.levelModel(model, level)
+		current.lodFade = 0
+
+		if fade < 1 {
+
+			previous := lod.levelModel(model, previousLevel)
+			previous.lodFade = float32(fade)
+			previous.lodFadeOut = true
+			camera.lodRenderModels = append(camera.lodRenderModels, previous)
+
+			if fade <= 0 {
+				continue
+			}
+
+			current.lodFade = float32(fade)
+			current.lodFadeOut = false
+
+		}
+
+		camera.lodRenderModels = append(camera.lodRenderModels, current)
+
+	}
+
+	return camera.lodRenderModels
+
+}
+
+// ditherRange returns the range of Bayer matrix values of the pixels the Model draws to, which is used to dither Models in and out while
+// cross-fading between levels of detail.
+func (model *Model) ditherRange() (float32, float32) {
+	if model.lodFade <= 0 {
+		return 0, 2
+	}
+	if model.lodFadeOut {
+		return model.lodFade, 2
+	}
+	return 0, model.lodFade
+}
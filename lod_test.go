@@ -0,0 +1,215 @@
+package tetra3d
+
+import (
+	"image/color"
+	"math"
+	"testing"
+)
+
+func newLODTestMesh(r, g, b float32) *Mesh {
+	mesh := NewCubeMesh()
+	mesh.MeshParts[0].Material.Shadeless = true
+	mesh.MeshParts[0].Material.Color.Set(r, g, b, 1)
+	return mesh
+}
+
+func TestLODLevels(t *testing.T) {
+
+	scene := NewScene("Test")
+
+	rock := NewModel(newLODTestMesh(1, 0, 0), "Rock")
+	rock.LOD = NewLOD(LODModeDistance)
+	rock.LOD.AddLevel(newLODTestMesh(0, 0, 1), 20)
+	rock.LOD.AddLevel(newLODTestMesh(0, 1, 0), 10)
+	rock.LOD.Hysteresis = 1
+	scene.Root.AddChildren(rock)
+
+	if rock.LOD.Levels[0].Threshold != 10 || rock.LOD.Levels[1].Threshold != 20 {
+		t.Fatalf("expected levels to be ordered by threshold")
+	}
+
+	camera := NewCamera(64, 64)
+	scene.Root.AddChildren(camera)
+
+	sr := NewSoftwareRenderer(camera)
+
+	for _, step := range []struct {
+		distance float64
+		level    int
+		color    color.RGBA
+	}{
+		{5, 0, color.RGBA{255, 0, 0, 255}},
+		{10.5, 0, color.RGBA{255, 0, 0, 255}}, // Within the hysteresis of the first level
+		{11.5, 1, color.RGBA{0, 255, 0, 255}},
+		{9.5, 1, color.RGBA{0, 255, 0, 255}}, // Within the hysteresis coming back
+		{8.5, 0, color.RGBA{255, 0, 0, 255}},
+		{30, 2, color.RGBA{0, 0, 255, 255}},
+	} {
+
+		camera.SetLocalPosition(0, 0, step.distance)
+
+		sr.Clear()
+		sr.RenderScene(scene)
+
+		if rock.LOD.CurrentLevel(camera) != step.level {
+			t.Fatalf("at a distance of %f, expected level %d, got %d", step.distance, step.level, rock.LOD.CurrentLevel(camera))
+		}
+
+		if c := sr.ColorBuffer().RGBAAt(32, 32); c != step.color {
+			t.Fatalf("at a distance of %f, center pixel is %v, expected %v", step.distance, c, step.color)
+		}
+
+	}
+
+	if clone := rock.Clone().(*Model); clone.LOD == rock.LOD || len(clone.LOD.Levels) != 2 || clone.LOD.Levels[0].Mesh != rock.LOD.Levels[0].Mesh {
+		t.Fatalf("expected cloned models to clone their LODs")
+	}
+
+}
+
+// countLODPixels returns how many pixels in the center of the SoftwareRenderer's color buffer are drawn red and green by the levels of an LOD.
+func countLODPixels(t *testing.T, sr *SoftwareRenderer) (red, green int) {
+
+	for y := 28; y < 36; y++ {
+		for x := 28; x < 36; x++ {
+			switch sr.ColorBuffer().RGBAAt(x, y) {
+			case color.RGBA{255, 0, 0, 255}:
+				red++
+			case color.RGBA{0, 255, 0, 255}:
+				green++
+			default:
+				t.Fatalf("pixel %d, %d isn't drawn by either level", x, y)
+			}
+		}
+	}
+
+	return red, green
+
+}
+
+func TestLODCrossFade(t *testing.T) {
+
+	scene := NewScene("Test")
+
+	rock := NewModel(newLODTestMesh(1, 0, 0), "Rock")
+	rock.LOD = NewLOD(LODModeScreenSize)
+	rock.LOD.AddLevel(newLODTestMesh(0, 1, 0), 0.5)
+	rock.LOD.CrossFade = 0.2
+	scene.Root.AddChildren(rock)
+
+	camera := NewCamera(64, 64)
+	camera.SetPerspective(false)
+	camera.SetOrthoScale(math.Sqrt(3) * 2.5) // The cube's bounding sphere spans 0.4 of the screen's height, halfway through the cross-fade.
+	camera.SetLocalPosition(0, 0, 5)
+	scene.Root.AddChildren(camera)
+
+	sr := NewSoftwareRenderer(camera)
+	sr.RenderScene(scene)
+
+	if rock.LOD.CurrentLevel(camera) != 1 {
+		t.Fatalf("expected level 1, got %d", rock.LOD.CurrentLevel(camera))
+	}
+
+	red, green := countLODPixels(t, sr)
+
+	if red != 32 || green != 32 {
+		t.Fatalf("expected the levels to dither evenly, got %d red and %d green pixels", red, green)
+	}
+
+}
+
+func TestLODCrossFadeHysteresis(t *testing.T) {
+
+	scene := NewScene("Test")
+
+	rock := NewModel(newLODTestMesh(1, 0, 0), "Rock")
+	rock.SetLocalScale(3, 3, 3)
+	rock.LOD = NewLOD(LODModeDistance)
+	rock.LOD.AddLevel(newLODTestMesh(0, 1, 0), 10)
+	rock.LOD.Hysteresis = 1
+	rock.LOD.CrossFade = 2
+	scene.Root.AddChildren(rock)
+
+	camera := NewCamera(64, 64)
+	scene.Root.AddChildren(camera)
+
+	sr := NewSoftwareRenderer(camera)
+
+	// Levels fade in from where they're switched to at (the threshold plus the hysteresis, either way), so the level being faded in is the
+	// current level, even when turning back within the hysteresis.
+	for _, step := range []struct {
+		distance float64
+		level    int
+		green    int
+	}{
+		{5, 0, 0},
+		{10.5, 0, 0},
+		{11.5, 1, 16}, // Switched at 11, so a quarter of the way through the cross-fade
+		{10.5, 1, 48}, // Turning back continues the cross-fade
+		{9.5, 1, 64},
+		{8.5, 0, 48}, // Switched back at 9, so the base level is a quarter of the way through fading in
+		{5, 0, 0},
+	} {
+
+		camera.SetLocalPosition(0, 0, step.distance)
+
+		sr.Clear()
+		sr.RenderScene(scene)
+
+		if level := rock.LOD.CurrentLevel(camera); level != step.level {
+			t.Fatalf("at a distance of %f, expected level %d, got %d", step.distance, step.level, level)
+		}
+
+		if red, green := countLODPixels(t, sr); green != step.green || red != 64-step.green {
+			t.Fatalf("at a distance of %f, expected %d green pixels, got %d green and %d red pixels", step.distance, step.green, green, red)
+		}
+
+	}
+
+}
+
+func TestLODCameras(t *testing.T) {
+
+	scene := NewScene("Test")
+
+	rock := NewModel(newLODTestMesh(1, 0, 0), "Rock")
+	rock.LOD = NewLOD(LODModeDistance)
+	rock.LOD.AddLevel(newLODTestMesh(0, 1, 0), 10)
+	rock.LOD.AddLevel(newLODTestMesh(0, 0, 1), 20)
+	rock.LOD.Hysteresis = 1
+	scene.Root.AddChildren(rock)
+
+	near := NewCamera(64, 64)
+	far := NewCamera(64, 64)
+	far.SetLocalPosition(0, 0, 30)
+	scene.Root.AddChildren(near, far)
+
+	nearRenderer := NewSoftwareRenderer(near)
+	farRenderer := NewSoftwareRenderer(far)
+
+	// Each Camera keeps its own level, so the far Camera doesn't pull the near one out of its hysteresis.
+	for _, distance := range []float64{5, 10.5} {
+
+		near.SetLocalPosition(0, 0, distance)
+
+		nearRenderer.Clear()
+		nearRenderer.RenderScene(scene)
+
+		farRenderer.Clear()
+		farRenderer.RenderScene(scene)
+
+	}
+
+	if level := rock.LOD.CurrentLevel(near); level != 0 {
+		t.Fatalf("expected the near camera to stay at level 0, got %d", level)
+	}
+
+	if level := rock.LOD.CurrentLevel(far); level != 2 {
+		t.Fatalf("expected the far camera to be at level 2, got %d", level)
+	}
+
+	if c := nearRenderer.ColorBuffer().RGBAAt(32, 32); c != (color.RGBA{255, 0, 0, 255}) {
+		t.Fatalf("near camera's center pixel is %v, expected the base level", c)
+	}
+
+}
@@ -40,6 +40,9 @@ type Material struct {
 	TexturePath       string               // The path to the texture, if it was not packed into the exporter.
 	TextureFilterMode ebiten.Filter        // Texture filtering mode
 	TextureWrapMode   ebiten.Address       // Texture wrapping mode
+	TextureCamera     *Camera              // A Camera whose render results are used as the Material's Texture, for things like security monitors; see Camera.BindTexture().
+	unboundTexture    *ebiten.Image        // The Material's Texture from before its TextureCamera's render results replaced it
+	textureBound      bool                 // If the Material's Texture is currently replaced by its TextureCamera's render results
 	properties        *Properties          // Properties allows you to specify auxiliary data on the Material. This is loaded from GLTF files or Blender's Custom Properties if the setting is enabled on the export menu.
 	BackfaceCulling   bool                 // If backface culling is enabled (which it is by default), faces turned away from the camera aren't rendered.
 	TriangleSortMode  int                  // TriangleSortMode influences how triangles with this Material are sorted.
@@ -87,6 +90,9 @@ func (material *Material) Clone() *Material {
 	newMat.library = material.library
 	newMat.Color = material.Color.Clone()
 	newMat.Texture = material.Texture
+	newMat.TextureCamera = material.TextureCamera
+	newMat.unboundTexture = material.unboundTexture
+	newMat.textureBound = material.textureBound
 	newMat.properties = material.properties.Clone()
 	newMat.BackfaceCulling = material.BackfaceCulling
 	newMat.TriangleSortMode = material.TriangleSortMode
@@ -1,6 +1,7 @@
 package tetra3d
 
 import (
+	"math"
 	"testing"
 )
 
@@ -48,3 +49,16 @@ func TestMatrixInversion(t *testing.T) {
 // 	}
 
 // }
+
+func TestMatrixToQuaternion(t *testing.T) {
+
+	for _, axis := range []Vector{{1, 0, 0, 0}, {0, 1, 0, 0}, {0, 0, 1, 0}, {1, 1, 0, 0}} {
+		for _, angle := range []float64{0.3, math.Pi / 2, math.Pi, -2.9} {
+			mat := NewMatrix4Rotate(axis.X, axis.Y, axis.Z, angle)
+			if quat := mat.ToQuaternion(); !quat.ToMatrix4().Equals(mat) {
+				t.Errorf("rotation of %f around %s gave incorrect quaternion %v", angle, axis, quat)
+			}
+		}
+	}
+
+}
@@ -180,6 +180,12 @@ type Mesh struct {
 	vertsAddStart int
 	vertsAddEnd   int
 
+	// MorphTargets are the morph targets (shape keys) of the Mesh. A Model using the Mesh blends
+	// between them using its MorphWeights.
+	MorphTargets           []*MorphTarget
+	vertexMorphedPositions []Vector
+	vertexMorphedNormals   []Vector
+
 	VertexColorChannelNames map[string]int
 	Dimensions              Dimensions
 	Properties              *Properties
@@ -209,6 +215,9 @@ func NewMesh(name string, verts ...VertexInfo) *Mesh {
 		VertexActiveColorChannel: []int{},
 		VertexBones:              [][]uint16{},
 		VertexWeights:            [][]float32{},
+		MorphTargets:             []*MorphTarget{},
+		vertexMorphedPositions:   []Vector{},
+		vertexMorphedNormals:     []Vector{},
 	}
 
 	if len(verts) > 0 {
@@ -286,6 +295,13 @@ func (mesh *Mesh) Clone() *Mesh {
 		newMesh.vertexSkinnedPositions = append(newMesh.vertexSkinnedPositions, mesh.vertexSkinnedPositions[v])
 	}
 
+	newMesh.vertexMorphedPositions = append(newMesh.vertexMorphedPositions, mesh.vertexMorphedPositions...)
+	newMesh.vertexMorphedNormals = append(newMesh.vertexMorphedNormals, mesh.vertexMorphedNormals...)
+
+	for _, target := range mesh.MorphTargets {
+		newMesh.MorphTargets = append(newMesh.MorphTargets, target.Clone())
+	}
+
 	newMesh.Triangles = make([]*Triangle, 0, len(mesh.Triangles))
 
 	for _, part := range mesh.MeshParts {
@@ -349,6 +365,49 @@ func (mesh *Mesh) allocateVertexBuffers(vertexCount int) {
 
 	mesh.vertexSkinnedPositions = append(make([]Vector, 0, vertexCount), mesh.vertexSkinnedPositions...)
 
+	mesh.vertexMorphedPositions = append(make([]Vector, 0, vertexCount), mesh.vertexMorphedPositions...)
+
+	mesh.vertexMorphedNormals = append(make([]Vector, 0, vertexCount), mesh.vertexMorphedNormals...)
+
+	for _, target := range mesh.MorphTargets {
+		target.PositionDeltas = append(make([]Vector, 0, vertexCount), target.PositionDeltas...)
+		target.NormalDeltas = append(make([]Vector, 0, vertexCount), target.NormalDeltas...)
+	}
+
+}
+
+// reset removes the Mesh's vertices, triangles, and MeshParts, keeping the capacity of its vertex buffers so that a Mesh that's rebuilt
+// often (like the Mesh of a Shadows instance) doesn't have to reallocate them.
+func (mesh *Mesh) reset() {
+
+	mesh.MeshParts = mesh.MeshParts[:0]
+	mesh.Triangles = mesh.Triangles[:0]
+	mesh.triIndex = 0
+	mesh.vertsAddStart = 0
+	mesh.vertsAddEnd = 0
+
+	mesh.VertexPositions = mesh.VertexPositions[:0]
+	mesh.VertexNormals = mesh.VertexNormals[:0]
+	mesh.vertexLights = mesh.vertexLights[:0]
+	mesh.VertexUVs = mesh.VertexUVs[:0]
+	mesh.VertexColors = mesh.VertexColors[:0]
+	mesh.VertexActiveColorChannel = mesh.VertexActiveColorChannel[:0]
+	mesh.VertexBones = mesh.VertexBones[:0]
+	mesh.VertexWeights = mesh.VertexWeights[:0]
+	mesh.vertexTransforms = mesh.vertexTransforms[:0]
+	mesh.vertexSkinnedNormals = mesh.vertexSkinnedNormals[:0]
+	mesh.vertexTransformedNormals = mesh.vertexTransformedNormals[:0]
+	mesh.vertexSkinnedPositions = mesh.vertexSkinnedPositions[:0]
+	mesh.vertexMorphedPositions = mesh.vertexMorphedPositions[:0]
+	mesh.vertexMorphedNormals = mesh.vertexMorphedNormals[:0]
+
+	for _, target := range mesh.MorphTargets {
+		target.PositionDeltas = target.PositionDeltas[:0]
+		target.NormalDeltas = target.NormalDeltas[:0]
+	}
+
+	mesh.Dimensions = Dimensions{}
+
 }
 
 func (mesh *Mesh) ensureEnoughVertexColorChannels(channelIndex int) {
@@ -470,11 +529,41 @@ func (mesh *Mesh) AddVertices(verts ...VertexInfo) {
 		mesh.vertexSkinnedNormals = append(mesh.vertexSkinnedNormals, Vector{0, 0, 0, 0})
 		mesh.vertexTransformedNormals = append(mesh.vertexTransformedNormals, Vector{0, 0, 0, 0})
 		mesh.vertexSkinnedPositions = append(mesh.vertexSkinnedPositions, Vector{0, 0, 0, 0})
+		mesh.vertexMorphedPositions = append(mesh.vertexMorphedPositions, Vector{0, 0, 0, 0})
+		mesh.vertexMorphedNormals = append(mesh.vertexMorphedNormals, Vector{0, 0, 0, 0})
+
+		// New vertices aren't influenced by any existing morph targets until their deltas are set.
+		for _, target := range mesh.MorphTargets {
+			target.PositionDeltas = append(target.PositionDeltas, Vector{0, 0, 0, 0})
+			target.NormalDeltas = append(target.NormalDeltas, Vector{0, 0, 0, 0})
+		}
 
 	}
 
 }
 
+// AddMorphTarget adds a new MorphTarget of the given name to the Mesh, with zeroed deltas for all existing vertices.
+// Models created from the Mesh afterwards will have a weight for the new MorphTarget.
+func (mesh *Mesh) AddMorphTarget(name string) *MorphTarget {
+	target := &MorphTarget{
+		Name:           name,
+		PositionDeltas: make([]Vector, len(mesh.VertexPositions)),
+		NormalDeltas:   make([]Vector, len(mesh.VertexPositions)),
+	}
+	mesh.MorphTargets = append(mesh.MorphTargets, target)
+	return target
+}
+
+// MorphTargetIndex returns the index of the MorphTarget with the given name, or -1 if no such MorphTarget exists in the Mesh.
+func (mesh *Mesh) MorphTargetIndex(name string) int {
+	for i, target := range mesh.MorphTargets {
+		if target.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
 // Library returns the Library from which this Mesh was loaded. If it was created through code, this function will return nil.
 func (mesh *Mesh) Library() *Library {
 	return mesh.library
@@ -1191,6 +1280,25 @@ func calculateNormal(p1, p2, p3 Vector) Vector {
 
 }
 
+// MorphTarget represents a morph target (or shape key, as it's called in Blender) of a Mesh. It stores offsets for each vertex of the Mesh,
+// which are scaled by a Model's weight for the target and added to the base vertex positions and normals when rendering.
+type MorphTarget struct {
+	Name           string
+	PositionDeltas []Vector // The offset for each vertex's position, indexed by vertex index.
+	NormalDeltas   []Vector // The offset for each vertex's normal, indexed by vertex index.
+	DefaultWeight  float64  // The weight Models start with for this MorphTarget.
+}
+
+// Clone returns a copy of the MorphTarget.
+func (target *MorphTarget) Clone() *MorphTarget {
+	return &MorphTarget{
+		Name:           target.Name,
+		PositionDeltas: append([]Vector{}, target.PositionDeltas...),
+		NormalDeltas:   append([]Vector{}, target.NormalDeltas...),
+		DefaultWeight:  target.DefaultWeight,
+	}
+}
+
 // MeshPart represents a collection of vertices and triangles, which are all rendered at once, as a single part, with a single material.
 // Depth testing is done between mesh parts or objects, so splitting an object up into different materials can be effective to help with depth sorting.
 type MeshPart struct {
@@ -2,6 +2,7 @@ package tetra3d
 
 import (
 	"errors"
+	"math"
 	"sort"
 	"time"
 
@@ -32,22 +33,44 @@ type Model struct {
 	skinMatrix Matrix4
 	bones      [][]*Node // The bones (nodes) of the Model, assuming it has been skinned. A Mesh's bones slice will point to indices indicating bones in the Model.
 
+	// MorphWeights are the weights of the Mesh's MorphTargets for this Model, indexed in the same order as Mesh.MorphTargets.
+	// A weight of 0 means the MorphTarget has no influence, while a weight of 1 means it fully applies.
+	MorphWeights []float64
+	morphed      bool
+
 	// A LightGroup indicates if a Model should be lit by a specific group of Lights. This allows you to control the overall lighting of scenes more accurately.
 	// If a Model has no LightGroup, the Model is lit by the lights present in the Scene.
 	LightGroup *LightGroup
 
+	BlobShadow   bool // If the Model casts a blob shadow onto the ground beneath it when a Shadows instance is updated.
+	PlanarShadow bool // If the Model casts a planar shadow (its flattened triangles) onto the plane of a Shadows instance when it's updated.
+
+	// LOD is the Model's set of reduced levels of detail; if it's nil (the default), the Model always renders its own Mesh.
+	LOD        *LOD
+	lodFade    float32 // How far the Model has dithered in or out while cross-fading between levels of detail
+	lodFadeOut bool
+
+	renderOwner *Model          // The Model this Model renders in place of (for a level of detail or an instance), if any
+	instancer   *InstancedModel // The InstancedModel this Model belongs to, if any
+
+	instanceVertices *instanceVertices // The vertices an instance proxy shares with the InstancedModel's other Instances, if it can
+	instanceOffset   Vector            // How far the Instance rendered by an instance proxy is from the shared vertices, in clip space
+
 	// VertexTransformFunction is a function that runs on the world position of each vertex position rendered with the material.
 	// It accepts the vertex position as an argument, along with the index of the vertex in the mesh.
 	// One can use this to simply transform vertices of the mesh on CPU (note that this is, of course, not as performant as
 	// a traditional GPU vertex shader, but is fine for simple / low-poly mesh transformations).
 	// This function is run after skinning the vertex if the material belongs to a mesh that is skinned by an armature.
 	// Note that the VertexTransformFunction must return the vector passed.
+	// When rendering with a Camera with more than one VertexWorker, the VertexTransformFunction is called from the Camera's vertex worker
+	// goroutines, possibly at the same time as other Models' functions, so it must be safe to call concurrently.
 	VertexTransformFunction func(vertexPosition Vector, vertexIndex int) Vector
 
 	// VertexClipFunction is a function that runs on the clipped result of each vertex position rendered with the material.
 	// The function takes the vertex position along with the vertex index in the mesh.
 	// This program runs after the vertex position is clipped to screen coordinates.
 	// Note that the VertexClipFunction must return the vector passed.
+	// The VertexClipFunction is always called from the goroutine rendering the Model, even when the Camera uses multiple VertexWorkers.
 	VertexClipFunction func(vertexPosition Vector, vertexIndex int) Vector
 
 	// Automatic batching mode; when set and a Model changes parenting, it will be automatically batched as necessary according to
@@ -76,6 +99,11 @@ func NewModel(mesh *Mesh, name string) *Model {
 	radius := 0.0
 	if mesh != nil {
 		radius = mesh.Dimensions.MaxSpan() / 2
+
+		model.MorphWeights = make([]float64, len(mesh.MorphTargets))
+		for i, target := range mesh.MorphTargets {
+			model.MorphWeights[i] = target.DefaultWeight
+		}
 	}
 	model.BoundingSphere = NewBoundingSphere("bounding sphere", radius)
 
@@ -91,6 +119,13 @@ func (model *Model) Clone() INode {
 	newModel.visible = model.visible
 	newModel.Color = model.Color.Clone()
 	newModel.AutoBatchMode = model.AutoBatchMode
+	newModel.MorphWeights = append([]float64{}, model.MorphWeights...)
+	newModel.BlobShadow = model.BlobShadow
+	newModel.PlanarShadow = model.PlanarShadow
+
+	if model.LOD != nil {
+		newModel.LOD = model.LOD.Clone()
+	}
 
 	for k := range model.DynamicBatchModels {
 		newModel.DynamicBatchModels[k] = append([]*Model{}, model.DynamicBatchModels[k]...)
@@ -395,6 +430,87 @@ func (model *Model) ReassignBones(armatureRoot INode) {
 
 }
 
+// SetMorphWeight sets the weight of the Mesh's MorphTarget with the given name for this Model. If the Mesh doesn't have a MorphTarget
+// with the name given, SetMorphWeight does nothing.
+func (model *Model) SetMorphWeight(targetName string, weight float64) {
+	if model.Mesh == nil {
+		return
+	}
+	if index := model.Mesh.MorphTargetIndex(targetName); index >= 0 {
+		for len(model.MorphWeights) <= index {
+			model.MorphWeights = append(model.MorphWeights, 0)
+		}
+		model.MorphWeights[index] = weight
+	}
+}
+
+// MorphWeight returns the weight of the Mesh's MorphTarget with the given name for this Model. If the Mesh doesn't have a MorphTarget
+// with the name given, MorphWeight returns 0.
+func (model *Model) MorphWeight(targetName string) float64 {
+	if model.Mesh == nil {
+		return 0
+	}
+	if index := model.Mesh.MorphTargetIndex(targetName); index >= 0 && index < len(model.MorphWeights) {
+		return model.MorphWeights[index]
+	}
+	return 0
+}
+
+// updateMorphState updates whether the Model needs its vertices morphed (i.e. any of its MorphTargets have a non-zero weight).
+func (model *Model) updateMorphState() {
+	model.morphed = false
+	for i := range model.Mesh.MorphTargets {
+		if i < len(model.MorphWeights) && model.MorphWeights[i] != 0 {
+			model.morphed = true
+			return
+		}
+	}
+}
+
+// morphVertex applies the Model's weighted MorphTargets to the vertex of the given index, storing the result in the Mesh's morphed
+// vertex buffers and returning the morphed position and normal.
+func (model *Model) morphVertex(vertID int) (Vector, Vector) {
+
+	mesh := model.Mesh
+
+	pos := mesh.VertexPositions[vertID]
+	normal := mesh.VertexNormals[vertID]
+
+	for i, target := range mesh.MorphTargets {
+
+		if i >= len(model.MorphWeights) {
+			break
+		}
+
+		weight := model.MorphWeights[i]
+
+		if weight == 0 {
+			continue
+		}
+
+		pos = pos.Add(target.PositionDeltas[vertID].Scale(weight))
+		normal = normal.Add(target.NormalDeltas[vertID].Scale(weight))
+
+	}
+
+	normal = normal.Unit()
+
+	mesh.vertexMorphedPositions[vertID] = pos
+	mesh.vertexMorphedNormals[vertID] = normal
+
+	return pos, normal
+
+}
+
+// localVertex returns the position and normal of the vertex of the given index in the Model's local space, with morph targets applied.
+// Lights use this to light Models that aren't skinned.
+func (model *Model) localVertex(vertID int) (Vector, Vector) {
+	if model.morphed {
+		return model.Mesh.vertexMorphedPositions[vertID], model.Mesh.vertexMorphedNormals[vertID]
+	}
+	return model.Mesh.VertexPositions[vertID], model.Mesh.VertexNormals[vertID]
+}
+
 func (model *Model) skinVertex(vertID int) (Vector, Vector) {
 
 	// Avoid reallocating a new matrix for every vertex; that's wasteful
@@ -421,25 +537,25 @@ func (model *Model) skinVertex(vertID int) (Vector, Vector) {
 
 	}
 
-	vertOut := model.skinMatrix.MultVecW(model.Mesh.VertexPositions[vertID])
+	basePos, baseNormal := model.Mesh.VertexPositions[vertID], model.Mesh.VertexNormals[vertID]
+
+	if model.morphed {
+		basePos, baseNormal = model.morphVertex(vertID)
+	}
+
+	vertOut := model.skinMatrix.MultVecW(basePos)
 
 	model.skinMatrix[3][0] = 0
 	model.skinMatrix[3][1] = 0
 	model.skinMatrix[3][2] = 0
 	model.skinMatrix[3][3] = 1
 
-	normal := model.skinMatrix.MultVecW(model.Mesh.VertexNormals[vertID])
+	normal := model.skinMatrix.MultVecW(baseNormal)
 
 	return vertOut, normal
 
 }
 
-var transformedVertexPositions = [3]Vector{
-	{0, 0, 0, 0},
-	{0, 0, 0, 0},
-	{0, 0, 0, 0},
-}
-
 func (model *Model) refreshVertexVisibility() {
 	for i := range model.Mesh.visibleVertices {
 		model.Mesh.visibleVertices[i] = false
@@ -449,6 +565,17 @@ func (model *Model) refreshVertexVisibility() {
 // ProcessVertices processes the vertices a Model has in preparation for rendering, given a view-projection
 // matrix, a camera, and the MeshPart being rendered.
 func (model *Model) ProcessVertices(vpMatrix Matrix4, camera *Camera, meshPart *MeshPart, scene *Scene) []sortingTriangle {
+	sortingTris, animationTime := model.processVertices(vpMatrix, camera, meshPart, scene)
+	camera.DebugInfo.animationTime += animationTime
+	return sortingTris
+}
+
+// processVertices processes the Model's vertices like ProcessVertices(), returning the time spent skinning them rather than adding it to the
+// Camera's DebugInfo, as it may be called on multiple goroutines at once.
+func (model *Model) processVertices(vpMatrix Matrix4, camera *Camera, meshPart *MeshPart, scene *Scene) ([]sortingTriangle, time.Duration) {
+
+	var transformedVertexPositions [3]Vector
+	var animationTime time.Duration
 
 	var transformFunc func(vertPos Vector, index int) Vector
 
@@ -458,6 +585,8 @@ func (model *Model) ProcessVertices(vpMatrix Matrix4, camera *Camera, meshPart *
 
 	modelTransform := model.Transform()
 
+	model.updateMorphState()
+
 	far := camera.far
 
 	sortingTriIndex := 0
@@ -475,26 +604,11 @@ func (model *Model) ProcessVertices(vpMatrix Matrix4, camera *Camera, meshPart *
 	camPos := camera.WorldPosition()
 	invertedCamPos := modelTransform.Inverted().MultVec(camPos)
 
-	if mat != nil && mat.BillboardMode != BillboardModeNone {
-
-		lookat := NewLookAtMatrix(model.WorldPosition(), camPos, WorldUp)
-
-		if mat.BillboardMode == BillboardModeXZ {
-			lookat.SetRow(1, Vector{0, 1, 0, 0})
-			x := lookat.Row(0)
-			x.Y = 0
-			lookat.SetRow(0, x.Unit())
-
-			z := lookat.Row(2)
-			z.Y = 0
-			lookat.SetRow(2, z.Unit())
-		}
-
-		// This is the slowest part, for sure, but it's necessary to have a billboarded object still be accurate
-		p, s, r := base.Decompose()
-		base = r.Mult(lookat).Mult(NewMatrix4Scale(s.X, s.Y, s.Z))
-		base.SetRow(3, Vector{p.X, p.Y, p.Z, 1})
+	// Instances of an InstancedModel that are only moved share the vertices the InstancedModel processed, offsetting them.
+	shared := model.instanceVertices
 
+	if mat != nil && mat.BillboardMode != BillboardModeNone && shared == nil {
+		base = billboardTransform(base, model.WorldPosition(), camPos, mat.BillboardMode)
 	}
 
 	mvp := base.Mult(vpMatrix)
@@ -503,7 +617,7 @@ func (model *Model) ProcessVertices(vpMatrix Matrix4, camera *Camera, meshPart *
 	meshPart.sortingTriangles = meshPart.sortingTriangles[:cap(meshPart.sortingTriangles)]
 
 	var mvJustRForNormals Matrix4
-	if camera.RenderNormals {
+	if camera.RenderNormals && shared == nil {
 		_, _, mvJustRForNormals = modelTransform.Mult(camera.ViewMatrix()).Decompose()
 	}
 
@@ -537,12 +651,22 @@ func (model *Model) ProcessVertices(vpMatrix Matrix4, camera *Camera, meshPart *
 
 				mesh.vertexTransforms[tri.VertexIndices[i]] = vpMatrix.MultVecW(vertPos)
 
-				camera.DebugInfo.animationTime += time.Since(t)
+				animationTime += time.Since(t)
+
+			} else if shared != nil {
+
+				v := shared.transforms[tri.VertexIndices[i]-shared.start]
+				offset := model.instanceOffset
+				mesh.vertexTransforms[tri.VertexIndices[i]] = Vector{v.X + offset.X, v.Y + offset.Y, v.Z + offset.Z, v.W + offset.W}
 
 			} else {
 
 				v0 := mesh.VertexPositions[tri.VertexIndices[i]]
 
+				if model.morphed {
+					v0, _ = model.morphVertex(tri.VertexIndices[i])
+				}
+
 				if transformFunc != nil {
 					v0 = transformFunc(v0, tri.VertexIndices[i])
 				}
@@ -556,7 +680,12 @@ func (model *Model) ProcessVertices(vpMatrix Matrix4, camera *Camera, meshPart *
 			}
 
 			if camera.RenderNormals {
-				mesh.vertexTransformedNormals[tri.VertexIndices[i]] = mvJustRForNormals.MultVecW(mesh.VertexNormals[tri.VertexIndices[i]])
+				if shared != nil {
+					mesh.vertexTransformedNormals[tri.VertexIndices[i]] = shared.normals[tri.VertexIndices[i]-shared.start]
+				} else {
+					_, normal := model.localVertex(tri.VertexIndices[i])
+					mesh.vertexTransformedNormals[tri.VertexIndices[i]] = mvJustRForNormals.MultVecW(normal)
+				}
 			}
 
 			w := mesh.vertexTransforms[tri.VertexIndices[i]].W
@@ -687,7 +816,32 @@ func (model *Model) ProcessVertices(vpMatrix Matrix4, camera *Camera, meshPart *
 		})
 	}
 
-	return meshPart.sortingTriangles
+	return meshPart.sortingTriangles, animationTime
+
+}
+
+// billboardTransform returns the transform given, turned at the position given to face the camera position given using the BillboardMode given.
+func billboardTransform(base Matrix4, position, camPos Vector, mode int) Matrix4 {
+
+	lookat := NewLookAtMatrix(position, camPos, WorldUp)
+
+	if mode == BillboardModeXZ {
+		lookat.SetRow(1, Vector{0, 1, 0, 0})
+		x := lookat.Row(0)
+		x.Y = 0
+		lookat.SetRow(0, x.Unit())
+
+		z := lookat.Row(2)
+		z.Y = 0
+		lookat.SetRow(2, z.Unit())
+	}
+
+	// This is the slowest part, for sure, but it's necessary to have a billboarded object still be accurate
+	p, s, r := base.Decompose()
+	base = r.Mult(lookat).Mult(NewMatrix4Scale(s.X, s.Y, s.Z))
+	base.SetRow(3, Vector{p.X, p.Y, p.Z, 1})
+
+	return base
 
 }
 
@@ -857,9 +1011,63 @@ func (model *Model) BakeAO(bakeOptions *AOBakeOptions) {
 
 }
 
+// BakeLightingOptions controls how lighting is baked into a Model's vertex colors with Model.BakeLightingWithOptions().
+type BakeLightingOptions struct {
+	TargetChannel int      // The target vertex color channel to bake the lighting to.
+	Lights        []ILight // The lights to bake. If the Model is in a Scene, the Scene's AmbientLight is baked as well.
+
+	// A slice of bounding objects that block light from reaching the Model's vertices when baking. If this is empty, no shadows are baked.
+	// Rays are cast from each vertex to each PointLight, SpotLight, and DirectionalLight; vertices that can't see a light are darkened.
+	// AmbientLights and CubeLights aren't blocked.
+	OcclusionObjects []IBoundingObject
+
+	// How much occluded vertices are darkened, ranging from 0 (not at all) to 1 (the occluded light doesn't contribute at all).
+	OcclusionStrength float64
+
+	// How many rays are cast from each vertex to each light. Above 1, rays are spread across an area around the light, SoftRadius
+	// units in radius, giving shadows soft edges (with vertices being partially lit). DirectionalLights have no position, so for them,
+	// SoftRadius is instead how far rays spread for each unit they travel; this makes it roughly the angular radius of the light, in
+	// radians (so 0.01 spreads rays across about half a degree either way).
+	SoftSamples int
+	SoftRadius  float64
+
+	RayOffset         float64 // How far along its normal a vertex's rays start from, to avoid rays hitting the Model's own surface.
+	DirectionalLength float64 // How long rays cast towards DirectionalLights are, as they have no position.
+}
+
+// NewDefaultBakeLightingOptions creates a new BakeLightingOptions struct with default settings, baking the lights given into the
+// first vertex color channel.
+func NewDefaultBakeLightingOptions(lights ...ILight) *BakeLightingOptions {
+	return &BakeLightingOptions{
+		TargetChannel:     0,
+		Lights:            lights,
+		OcclusionObjects:  []IBoundingObject{},
+		OcclusionStrength: 1,
+		SoftSamples:       1,
+		SoftRadius:        0.5,
+		RayOffset:         0.01,
+		DirectionalLength: 1000,
+	}
+}
+
 // BakeLighting bakes the colors for the provided lights into a Model's Mesh's vertex colors. Note that the baked lighting overwrites whatever vertex colors
 // previously existed in the target channel (as otherwise, the colors could only get brighter with additive mixing, or only get darker with multiplicative mixing).
 func (model *Model) BakeLighting(targetChannel int, lights ...ILight) {
+	options := NewDefaultBakeLightingOptions(lights...)
+	options.TargetChannel = targetChannel
+	model.BakeLightingWithOptions(options)
+}
+
+// BakeLightingWithOptions bakes lighting into a Model's Mesh's vertex colors like Model.BakeLighting(), using the settings in the provided
+// BakeLightingOptions struct; this allows shadows to be baked by casting rays against the OcclusionObjects set in the options.
+// If nil is passed instead of bake options, a default BakeLightingOptions struct will be created and used (which bakes just the Scene's AmbientLight).
+func (model *Model) BakeLightingWithOptions(bakeOptions *BakeLightingOptions) {
+
+	if bakeOptions == nil {
+		bakeOptions = NewDefaultBakeLightingOptions()
+	}
+
+	targetChannel := bakeOptions.TargetChannel
 
 	if model.Mesh == nil || targetChannel < 0 {
 		return
@@ -867,7 +1075,15 @@ func (model *Model) BakeLighting(targetChannel int, lights ...ILight) {
 
 	model.Mesh.ensureEnoughVertexColorChannels(targetChannel)
 
-	allLights := append([]ILight{}, lights...)
+	// Bake the lighting for the Model's current shape, taking its morph targets into account
+	model.updateMorphState()
+	if model.morphed {
+		for i := range model.Mesh.VertexPositions {
+			model.morphVertex(i)
+		}
+	}
+
+	allLights := append([]ILight{}, bakeOptions.Lights...)
 
 	if model.Scene() != nil {
 		allLights = append(allLights, model.Scene().World.AmbientLight)
@@ -893,12 +1109,45 @@ func (model *Model) BakeLighting(targetChannel int, lights ...ILight) {
 		targetColors[i] = NewColor(0, 0, 0, 1)
 	}
 
+	var occlusion *bakeOcclusion
+	var lightColors []*Color
+
+	if len(bakeOptions.OcclusionObjects) > 0 && bakeOptions.OcclusionStrength > 0 {
+		occlusion = newBakeOcclusion(model, bakeOptions)
+		lightColors = make([]*Color, len(targetColors))
+		for i := range lightColors {
+			lightColors[i] = NewColor(0, 0, 0, 1)
+		}
+	}
+
 	for _, light := range allLights {
 
-		if light.IsOn() {
+		if !light.IsOn() {
+			continue
+		}
+
+		if occlusion == nil || !occlusion.blocks(light) {
 			for _, mp := range model.Mesh.MeshParts {
 				light.Light(mp, model, targetColors, false)
 			}
+			continue
+		}
+
+		// Light into a separate buffer so the light's contribution can be darkened for each vertex according to how occluded it is
+		for _, c := range lightColors {
+			c.Set(0, 0, 0, 1)
+		}
+
+		for _, mp := range model.Mesh.MeshParts {
+			light.Light(mp, model, lightColors, false)
+		}
+
+		for i, c := range lightColors {
+			if c.R == 0 && c.G == 0 && c.B == 0 {
+				continue // Unlit vertices don't need to be tested
+			}
+			visibility := float32(1 - bakeOptions.OcclusionStrength*occlusion.occluded(i, light))
+			targetColors[i].AddRGBA(c.R*visibility, c.G*visibility, c.B*visibility, 0)
 		}
 
 	}
@@ -909,6 +1158,89 @@ func (model *Model) BakeLighting(targetChannel int, lights ...ILight) {
 
 }
 
+// bakeOcclusion tests how occluded a Model's vertices are from lights while baking lighting.
+type bakeOcclusion struct {
+	options   *BakeLightingOptions
+	positions []Vector // The world positions of the vertices, pushed out along their normals by the RayOffset
+	offsets   []Vector // The offsets from each light's position for each ray cast from a vertex
+}
+
+func newBakeOcclusion(model *Model, options *BakeLightingOptions) *bakeOcclusion {
+
+	occlusion := &bakeOcclusion{
+		options:   options,
+		positions: make([]Vector, len(model.Mesh.VertexPositions)),
+	}
+
+	transform := model.Transform()
+	rotation := model.WorldRotation()
+
+	for i := range occlusion.positions {
+		pos, normal := model.localVertex(i)
+		occlusion.positions[i] = transform.MultVec(pos).Add(rotation.MultVec(normal).Unit().Scale(options.RayOffset))
+	}
+
+	samples := options.SoftSamples
+	if samples < 1 {
+		samples = 1
+	}
+
+	occlusion.offsets = make([]Vector, samples)
+
+	if samples > 1 {
+
+		// Spread the samples evenly across a sphere around the light with a Fibonacci spiral, so baking gives the same results each time.
+		for i := range occlusion.offsets {
+			y := 1 - (float64(i)+0.5)/float64(samples)*2
+			r := math.Sqrt(1 - y*y)
+			theta := math.Pi * (3 - math.Sqrt(5)) * float64(i)
+			occlusion.offsets[i] = Vector{math.Cos(theta) * r, y, math.Sin(theta) * r, 0}.Scale(options.SoftRadius)
+		}
+
+	}
+
+	return occlusion
+
+}
+
+// blocks returns if the light given can be blocked by occluding objects.
+func (occlusion *bakeOcclusion) blocks(light ILight) bool {
+	switch light.(type) {
+	case *PointLight, *SpotLight, *DirectionalLight:
+		return true
+	}
+	return false
+}
+
+// occluded returns the fraction of rays cast from the vertex of the given index to the light that are blocked, ranging from 0 to 1.
+func (occlusion *bakeOcclusion) occluded(vertID int, light ILight) float64 {
+
+	from := occlusion.positions[vertID]
+
+	var target Vector
+	spread := 1.0
+
+	if sun, ok := light.(*DirectionalLight); ok {
+		// A DirectionalLight's forward vector points back towards the light.
+		target = from.Add(sun.WorldRotation().Forward().Unit().Scale(occlusion.options.DirectionalLength))
+		// The SoftRadius is an angular size for DirectionalLights, so the rays spread the same way however long they are.
+		spread = occlusion.options.DirectionalLength
+	} else {
+		target = light.WorldPosition()
+	}
+
+	blocked := 0
+
+	for _, offset := range occlusion.offsets {
+		if len(RayTest(from, target.Add(offset.Scale(spread)), occlusion.options.OcclusionObjects...)) > 0 {
+			blocked++
+		}
+	}
+
+	return float64(blocked) / float64(len(occlusion.offsets))
+
+}
+
 // isTransparent returns true if the provided MeshPart has a Material with TransparencyModeTransparent, or if it's
 // TransparencyModeAuto with the model or material alpha color being under 0.99. This is a helper function for sorting
 // MeshParts into either transparent or opaque buckets for rendering.
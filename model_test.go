@@ -0,0 +1,103 @@
+package tetra3d
+
+import (
+	"testing"
+)
+
+func TestBakeLightingOcclusion(t *testing.T) {
+
+	ground := NewModel(NewPlaneMesh(), "Ground")
+	ground.SetLocalScale(5, 1, 5)
+
+	light := NewPointLight("Light", 1, 1, 1, 1)
+	light.SetLocalPosition(0, 5, 0)
+
+	// The occluder sits halfway between the light and the ground's +X, +Z corner.
+	occluder := NewBoundingSphere("Occluder", 0.5)
+	occluder.SetLocalPosition(2.5, 2.5, 2.5)
+
+	cornerIndex := func(x, z float64) int {
+		for i, pos := range ground.Mesh.VertexPositions {
+			if pos.X == x && pos.Z == z {
+				return i
+			}
+		}
+		t.Fatalf("no vertex at corner %f, %f", x, z)
+		return -1
+	}
+
+	shadowed := cornerIndex(1, 1)
+	lit := cornerIndex(-1, -1)
+
+	options := NewDefaultBakeLightingOptions(light)
+	options.OcclusionObjects = []IBoundingObject{occluder}
+	ground.BakeLightingWithOptions(options)
+
+	if c := ground.Mesh.VertexColors[shadowed][0]; c.R != 0 || c.G != 0 || c.B != 0 {
+		t.Fatalf("occluded vertex has color %v, expected black", c)
+	}
+
+	litColor := ground.Mesh.VertexColors[lit][0].Clone()
+
+	if litColor.R <= 0 {
+		t.Fatalf("unoccluded vertex has color %v, expected it to be lit", litColor)
+	}
+
+	// With soft samples spread wider than the occluder, the occluded vertex is only partially darkened.
+	options.SoftSamples = 16
+	options.SoftRadius = 2
+	ground.BakeLightingWithOptions(options)
+
+	if c := ground.Mesh.VertexColors[shadowed][0]; c.R <= 0 || c.R >= litColor.R {
+		t.Fatalf("softly occluded vertex has color %v, expected it to be partially lit (fully lit is %v)", c, litColor)
+	}
+
+	if c := ground.Mesh.VertexColors[lit][0]; c.R != litColor.R {
+		t.Fatalf("unoccluded vertex has color %v with soft samples, expected %v", c, litColor)
+	}
+
+	// Without occlusion objects, baking matches BakeLighting().
+	ground.BakeLighting(0, light)
+
+	if c := ground.Mesh.VertexColors[shadowed][0]; c.R != litColor.R {
+		t.Fatalf("vertex has color %v without occlusion, expected %v", c, litColor)
+	}
+
+}
+
+func TestBakeLightingDirectionalOcclusion(t *testing.T) {
+
+	ground := NewModel(NewPlaneMesh(), "Ground")
+
+	// A DirectionalLight faces back towards the light, so this light shines straight down.
+	sun := NewDirectionalLight("Sun", 1, 1, 1, 1)
+	sun.SetLocalRotation(NewLookAtMatrix(Vector{}, WorldUp, WorldForward))
+
+	// The occluder sits right above the ground's +X, +Z corner.
+	occluder := NewBoundingSphere("Occluder", 0.5)
+	occluder.SetLocalPosition(1, 2, 1)
+
+	shadowed := -1
+	for i, pos := range ground.Mesh.VertexPositions {
+		if pos.X == 1 && pos.Z == 1 {
+			shadowed = i
+		}
+	}
+
+	options := NewDefaultBakeLightingOptions(sun)
+	options.OcclusionObjects = []IBoundingObject{occluder}
+	ground.BakeLightingWithOptions(options)
+
+	if c := ground.Mesh.VertexColors[shadowed][0]; c.R != 0 || c.G != 0 || c.B != 0 {
+		t.Fatalf("occluded vertex has color %v, expected black", c)
+	}
+
+	// Spread across about half a radian either way, some of the rays pass by the occluder.
+	options.SoftSamples = 16
+	ground.BakeLightingWithOptions(options)
+
+	if c := ground.Mesh.VertexColors[shadowed][0]; c.R <= 0 || c.R >= 1 {
+		t.Fatalf("softly occluded vertex has color %v, expected it to be partially lit", c)
+	}
+
+}
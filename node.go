@@ -17,9 +17,13 @@ const (
 	NodeTypeCamera NodeType = "NodeCamera" // NodeTypeCamera represents specifically a Camera
 	NodeTypePath   NodeType = "NodePath"   // NodeTypePath represents specifically a Path
 	NodeTypeGrid   NodeType = "NodeGrid"   // NodeTypeGrid represents specifically a Grid
+	NodeTypePortal NodeType = "NodePortal" // NodeTypePortal represents specifically a Portal
 
 	NodeTypeGridPoint NodeType = "Node_GridPoint" // NodeTypeGrid represents specifically a GridPoint (note the extra underscore to ensure !NodeTypeGridPoint.Is(NodeTypeGrid))
 
+	NodeTypeInstancedModel NodeType = "NodeModelInstanced" // NodeTypeInstancedModel represents specifically an InstancedModel (which is also a Model)
+	NodeTypeDecal          NodeType = "NodeModelDecal"     // NodeTypeDecal represents specifically a Decal (which is also a Model)
+
 	NodeTypeBoundingObject    NodeType = "NodeBounding"          // NodeTypeBoundingObject represents any generic bounding object
 	NodeTypeBoundingAABB      NodeType = "NodeBoundingAABB"      // NodeTypeBoundingAABB represents specifically a BoundingAABB
 	NodeTypeBoundingCapsule   NodeType = "NodeBoundingCapsule"   // NodeTypeBoundingCapsule represents specifically a BoundingCapsule
@@ -31,6 +35,7 @@ const (
 	NodeTypePointLight       NodeType = "NodeLightPoint"       // NodeTypePointLight represents specifically a point light
 	NodeTypeDirectionalLight NodeType = "NodeLightDirectional" // NodeTypeDirectionalLight represents specifically a directional (sun) light
 	NodeTypeCubeLight        NodeType = "NodeLightCube"        // NodeTypeCubeLight represents, specifically, a cube light
+	NodeTypeSpotLight        NodeType = "NodeLightSpot"        // NodeTypeSpotLight represents specifically a spot light
 )
 
 // Is returns true if a NodeType satisfies another NodeType category. A specific node type can be said to
@@ -43,6 +48,16 @@ func (nt NodeType) Is(other NodeType) bool {
 	return strings.Contains(string(nt), string(other))
 }
 
+// RenderLayer is a bitmask of render layers. Each Node is on one or more render layers; Cameras only render Models that are on a layer
+// in their CullMask, and lights only light Models that share a layer with them.
+type RenderLayer uint32
+
+const (
+	RenderLayerDefault RenderLayer = 1 << 0     // RenderLayerDefault is the first render layer, which Nodes are on by default.
+	RenderLayerNone    RenderLayer = 0          // RenderLayerNone is no render layers at all.
+	RenderLayerAll     RenderLayer = 0xFFFFFFFF // RenderLayerAll is every render layer; lights are on every layer by default.
+)
+
 // INode represents an object that exists in 3D space and can be positioned relative to an origin point.
 // By default, this origin point is {0, 0, 0} (or world origin), but Nodes can be parented
 // to other Nodes to change this origin (making their movements relative and their transforms
@@ -115,6 +130,7 @@ type INode interface {
 	ResetTransform()
 
 	setOriginalTransform()
+	originalLocalTransform() (position, scale Vector, rotation Matrix4, exists bool)
 
 	// SetWorldTransform sets the Node's global (world) transform to the full 4x4 transformation matrix provided.
 	SetWorldTransform(transform Matrix4)
@@ -181,6 +197,11 @@ type INode interface {
 	// SetVisible sets the object's visibility. If recursive is true, all recursive children of this Node will have their visibility set the same way.
 	SetVisible(visible, recursive bool)
 
+	// Layers returns the render layers the Node is on.
+	Layers() RenderLayer
+	// SetLayers sets the render layers the Node is on. If recursive is true, all recursive children of this Node will have their layers set the same way.
+	SetLayers(layers RenderLayer, recursive bool)
+
 	// Get searches a node's hierarchy using a string to find a specified node. The path is in the format of names of nodes, separated by forward
 	// slashes ('/'), and is relative to the node you use to call Get. As an example of Get, if you had a cup parented to a desk, which was
 	// parented to a room, that was finally parented to the root of the scene, it would be found at "Room/Desk/Cup". Note also that you can use "../" to
@@ -214,6 +235,9 @@ type INode interface {
 	// DistanceTo returns the distance between the given Nodes' centers.
 	// Quick syntactic sugar for Node.WorldPosition().Distance(otherNode.WorldPosition()).
 	DistanceTo(otherNode INode) float64
+
+	// baseNode returns the Node underlying the object, for functionality that only *Node offers (like Constraints).
+	baseNode() *Node
 }
 
 var nodeID uint64 = 0
@@ -228,7 +252,12 @@ type Node struct {
 	scale             Vector
 	rotation          Matrix4
 	originalTransform Matrix4
+	originalPosition  Vector  // The Node's local position when its original transform was set
+	originalScale     Vector  // The Node's local scale when its original transform was set
+	originalRotation  Matrix4 // The Node's local rotation when its original transform was set
+	hasOriginal       bool    // If the Node's original transform has been set
 	visible           bool
+	layers            RenderLayer
 	data              interface{} // A place to store a pointer to something if you need it
 	children          []INode
 	parent            INode
@@ -243,6 +272,8 @@ type Node struct {
 	library           *Library // The Library this Node was instantiated from (nil if it wasn't instantiated with a library at all)
 	scene             *Scene
 	onTransformUpdate func()
+	constraints       []*Constraint // Constraints applied to the Node, in order
+	constraintState   nodeConstraintState
 }
 
 // NewNode returns a new Node.
@@ -256,6 +287,7 @@ func NewNode(name string) *Node {
 		rotation:         NewMatrix4(),
 		children:         []INode{},
 		visible:          true,
+		layers:           RenderLayerDefault,
 		isTransformDirty: true,
 		props:            NewProperties(),
 		// We set this just in case we call a transform property getter before setting it and caching anything
@@ -306,11 +338,16 @@ func (node *Node) Clone() INode {
 	newNode.scale = node.scale
 	newNode.rotation = node.rotation.Clone()
 	newNode.visible = node.visible
+	newNode.layers = node.layers
 	newNode.data = node.data
 	newNode.setOriginalTransform()
 
 	newNode.props = node.props.Clone()
 	newNode.animationPlayer = node.animationPlayer.Clone()
+
+	for _, constraint := range node.constraints {
+		newNode.constraints = append(newNode.constraints, constraint.Clone())
+	}
 	newNode.library = node.library
 
 	if node.animationPlayer.RootNode == node {
@@ -336,6 +373,12 @@ func (node *Node) Clone() INode {
 		newNode.inverseBindMatrix = node.inverseBindMatrix.Clone()
 	}
 
+	// Cloned Constraints target the original nodes, so any that target nodes in the cloned tree need to target their clones instead
+	retargetClonedConstraints(
+		append([]INode{node}, node.SearchTree().INodes()...),
+		append([]INode{newNode}, newNode.SearchTree().INodes()...),
+	)
+
 	return newNode
 }
 
@@ -484,6 +527,16 @@ func (node *Node) ResetTransform() {
 
 func (node *Node) setOriginalTransform() {
 	node.originalTransform = node.Transform()
+	node.originalPosition = node.position
+	node.originalScale = node.scale
+	node.originalRotation = node.rotation.Clone()
+	node.hasOriginal = true
+}
+
+// originalLocalTransform returns the Node's local transform from when its original transform was set (when the Node was instantiated in
+// the Scene or cloned), and whether it's been set at all.
+func (node *Node) originalLocalTransform() (position, scale Vector, rotation Matrix4, exists bool) {
+	return node.originalPosition, node.originalScale, node.originalRotation, node.hasOriginal
 }
 
 // WorldPosition returns a 3D Vector consisting of the object's world position (position relative to the world origin point of {0, 0, 0}).
@@ -829,6 +882,21 @@ func (node *Node) SetVisible(visible bool, recursive bool) {
 	node.visible = visible
 }
 
+// Layers returns the render layers the Node is on.
+func (node *Node) Layers() RenderLayer {
+	return node.layers
+}
+
+// SetLayers sets the render layers the Node is on. If recursive is true, all recursive children of this Node will have their layers set the same way.
+func (node *Node) SetLayers(layers RenderLayer, recursive bool) {
+	if recursive {
+		for _, child := range node.SearchTree().INodes() {
+			child.SetLayers(layers, false)
+		}
+	}
+	node.layers = layers
+}
+
 // Properties represents an unordered set of game properties that can be used to identify this object.
 func (node *Node) Properties() *Properties {
 	return node.props
@@ -867,6 +935,8 @@ func (node *Node) HierarchyAsString() string {
 				prefix = "GRID"
 			} else if nodeType.Is(NodeTypeGridPoint) {
 				prefix = "GPOINT"
+			} else if nodeType.Is(NodeTypePortal) {
+				prefix = "PORTAL"
 			} else if nodeType.Is(NodeTypeAmbientLight) {
 				prefix = "AMB"
 			} else if nodeType.Is(NodeTypeDirectionalLight) {
@@ -875,6 +945,8 @@ func (node *Node) HierarchyAsString() string {
 				prefix = "POINT"
 			} else if nodeType.Is(NodeTypeCubeLight) {
 				prefix = "CUBE"
+			} else if nodeType.Is(NodeTypeSpotLight) {
+				prefix = "SPOT"
 			} else if nodeType.Is(NodeTypeBoundingSphere) {
 				prefix = "BS"
 			} else if nodeType.Is(NodeTypeBoundingAABB) {
@@ -1041,6 +1113,10 @@ func (node *Node) AnimationPlayer() *AnimationPlayer {
 	return node.animationPlayer
 }
 
+func (node *Node) baseNode() *Node {
+	return node
+}
+
 func (node *Node) sectorHierarchy() *Sector {
 
 	if node.parent != nil {
@@ -239,12 +239,12 @@ func (nf NodeFilter) IBoundingObjectsWithProps(props ...string) []IBoundingObjec
 	return nf.ByParentProps(props...).IBoundingObjects()
 }
 
-// Models returns a slice of the Models contained within the NodeFilter.
+// Models returns a slice of the Models contained within the NodeFilter, including the Models of any InstancedModels.
 func (nf NodeFilter) Models() []*Model {
 	out := nf.execute(nf.Start)
 	models := make([]*Model, 0, len(out))
 	for _, n := range out {
-		if m, ok := n.(*Model); ok {
+		if m, ok := nodeModel(n); ok {
 			models = append(models, m)
 		}
 	}
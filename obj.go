@@ -0,0 +1,609 @@
+package tetra3d
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"image"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	_ "image/jpeg"
+	_ "image/png"
+)
+
+// OBJLoadOptions represents options one can use to tweak how .obj files are loaded into Tetra3D.
+type OBJLoadOptions struct {
+	// MTLResolver opens the files that an OBJ file refers to - that is, material libraries (.mtl files) named through "mtllib"
+	// statements, and the texture images that those material libraries name in turn. Material library paths are passed as written
+	// in the OBJ file, while texture paths are joined to the directory of the material library naming them. If MTLResolver is nil, Materials are created with default settings using just the names given in "usemtl" statements.
+	// NewMTLResolverFS can be used to create an MTLResolver that reads files from an fs.FS.
+	MTLResolver func(path string) (io.ReadCloser, error)
+	SceneName   string // The name of the Scene that the OBJ file's objects are placed in. Defaults to "Scene".
+}
+
+// DefaultOBJLoadOptions returns a default instance of OBJLoadOptions.
+func DefaultOBJLoadOptions() *OBJLoadOptions {
+	return &OBJLoadOptions{
+		SceneName: "Scene",
+	}
+}
+
+// NewMTLResolverFS returns a function usable as an OBJLoadOptions.MTLResolver that opens material libraries and textures from the given
+// fs.FS (like an embed.FS, or the result of os.DirFS()). The paths given by the OBJ file are treated as relative to basePath, which
+// should usually be the directory containing the OBJ file within the file system.
+func NewMTLResolverFS(fileSystem fs.FS, basePath string) func(path string) (io.ReadCloser, error) {
+	return func(filePath string) (io.ReadCloser, error) {
+		// OBJ files written on Windows can use backslashes as path separators.
+		filePath = strings.ReplaceAll(filePath, "\\", "/")
+		return fileSystem.Open(path.Join(basePath, filePath))
+	}
+}
+
+// LoadOBJFile takes a filepath to a .obj model file, and returns a *Library populated with the .obj file's objects, meshes, and materials.
+// Each object in the OBJ file becomes a Mesh and a Model in the Library's Scene, with a MeshPart for each material the object uses.
+// If the OBJLoadOptions' MTLResolver is nil, material libraries and textures are loaded from the directory the OBJ file is in.
+// If the call couldn't complete for any reason, like due to a malformed OBJ file, it will return an error.
+func LoadOBJFile(filePath string, options *OBJLoadOptions) (*Library, error) {
+
+	fileData, err := os.ReadFile(filePath)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if options == nil {
+		options = DefaultOBJLoadOptions()
+	}
+
+	if options.MTLResolver == nil {
+		fileOptions := *options
+		fileOptions.MTLResolver = NewMTLResolverFS(os.DirFS(filepath.Dir(filePath)), ".")
+		options = &fileOptions
+	}
+
+	return LoadOBJData(fileData, options)
+
+}
+
+// objVertexKey identifies a unique combination of position, UV, and normal indices for a face corner in an OBJ file.
+type objVertexKey struct {
+	position, uv, normal int
+}
+
+// objGroup is a collection of faces in an OBJ file object that share a material; each becomes a MeshPart.
+type objGroup struct {
+	material    string
+	vertices    []VertexInfo
+	indices     []int
+	vertexIndex map[objVertexKey]int
+	hasNormals  bool
+}
+
+type objObject struct {
+	name   string
+	groups []*objGroup
+}
+
+func (object *objObject) group(materialName string) *objGroup {
+	for _, g := range object.groups {
+		if g.material == materialName {
+			return g
+		}
+	}
+	group := &objGroup{
+		material:    materialName,
+		vertexIndex: map[objVertexKey]int{},
+		hasNormals:  true,
+	}
+	object.groups = append(object.groups, group)
+	return group
+}
+
+// LoadOBJData takes a []byte consisting of the contents of an OBJ file, and returns a *Library populated with the .obj file's objects,
+// meshes, and materials. Each object in the OBJ file becomes a Mesh and a Model in the Library's Scene, with a MeshPart for each
+// material the object uses. Materials are loaded from the material libraries the OBJ file names using the OBJLoadOptions' MTLResolver.
+// If the call couldn't complete for any reason, like due to a malformed OBJ file, it will return an error.
+func LoadOBJData(data []byte, options *OBJLoadOptions) (*Library, error) {
+
+	if options == nil {
+		options = DefaultOBJLoadOptions()
+	}
+
+	sceneName := options.SceneName
+	if sceneName == "" {
+		sceneName = "Scene"
+	}
+
+	library := NewLibrary()
+	scene := library.AddScene(sceneName)
+	library.ExportedScene = scene
+
+	positions := []Vector{}
+	colors := []*Color{}
+	uvs := []Vector{}
+	normals := []Vector{}
+
+	objects := []*objObject{}
+	materialLibraries := []string{}
+
+	// Some exporters separate objects with "o" statements, and others only with "g" statements.
+	objectStatement := "g"
+	if bytes.HasPrefix(data, []byte("o ")) || bytes.Contains(data, []byte("\no ")) {
+		objectStatement = "o"
+	}
+
+	currentObject := &objObject{name: "Object"}
+	objects = append(objects, currentObject)
+	currentMaterial := ""
+
+	// OBJ indices are 1-based, and negative indices count backwards from the most recently defined element.
+	resolveIndex := func(value string, count int) (int, error) {
+		index, err := strconv.Atoi(value)
+		if err != nil {
+			return 0, err
+		}
+		if index < 0 {
+			index += count
+		} else {
+			index--
+		}
+		if index < 0 || index >= count {
+			return 0, fmt.Errorf("index %s out of range", value)
+		}
+		return index, nil
+	}
+
+	parseFloats := func(fields []string) ([]float64, error) {
+		values := make([]float64, 0, len(fields))
+		for _, f := range fields {
+			v, err := strconv.ParseFloat(f, 64)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, v)
+		}
+		return values, nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	lineNumber := 0
+
+	for scanner.Scan() {
+
+		lineNumber++
+
+		line := strings.TrimSpace(scanner.Text())
+
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		fields := strings.Fields(line)
+
+		switch fields[0] {
+
+		case "v":
+
+			values, err := parseFloats(fields[1:])
+			if err != nil || len(values) < 3 {
+				return nil, fmt.Errorf("obj: malformed vertex position on line %d", lineNumber)
+			}
+
+			positions = append(positions, Vector{values[0], values[1], values[2], 0})
+
+			// Vertex colors are an unofficial extension, written as three additional values after the position.
+			if len(values) >= 6 {
+				colors = append(colors, NewColor(float32(values[3]), float32(values[4]), float32(values[5]), 1))
+			} else {
+				colors = append(colors, nil)
+			}
+
+		case "vt":
+
+			values, err := parseFloats(fields[1:])
+			if err != nil || len(values) < 1 {
+				return nil, fmt.Errorf("obj: malformed texture coordinate on line %d", lineNumber)
+			}
+
+			uv := Vector{values[0], 0, 0, 0}
+			if len(values) > 1 {
+				uv.Y = values[1]
+			}
+			uvs = append(uvs, uv)
+
+		case "vn":
+
+			values, err := parseFloats(fields[1:])
+			if err != nil || len(values) < 3 {
+				return nil, fmt.Errorf("obj: malformed vertex normal on line %d", lineNumber)
+			}
+
+			normals = append(normals, Vector{values[0], values[1], values[2], 0})
+
+		case "f":
+
+			if len(fields) < 4 {
+				return nil, fmt.Errorf("obj: face with fewer than three vertices on line %d", lineNumber)
+			}
+
+			group := currentObject.group(currentMaterial)
+
+			corners := make([]int, 0, len(fields)-1)
+
+			for _, corner := range fields[1:] {
+
+				key := objVertexKey{-1, -1, -1}
+				var err error
+
+				parts := strings.Split(corner, "/")
+
+				if key.position, err = resolveIndex(parts[0], len(positions)); err != nil {
+					return nil, fmt.Errorf("obj: malformed face on line %d: %s", lineNumber, err)
+				}
+
+				if len(parts) > 1 && parts[1] != "" {
+					if key.uv, err = resolveIndex(parts[1], len(uvs)); err != nil {
+						return nil, fmt.Errorf("obj: malformed face on line %d: %s", lineNumber, err)
+					}
+				}
+
+				if len(parts) > 2 && parts[2] != "" {
+					if key.normal, err = resolveIndex(parts[2], len(normals)); err != nil {
+						return nil, fmt.Errorf("obj: malformed face on line %d: %s", lineNumber, err)
+					}
+				}
+
+				// Corners without normals aren't shared so that they can be flat-shaded once the Mesh is complete.
+				if index, exists := group.vertexIndex[key]; exists && key.normal >= 0 {
+					corners = append(corners, index)
+					continue
+				}
+
+				p := positions[key.position]
+				vertex := NewVertex(p.X, p.Y, p.Z, 0, 0)
+
+				if key.uv >= 0 {
+					vertex.U = uvs[key.uv].X
+					vertex.V = uvs[key.uv].Y
+				}
+
+				if key.normal >= 0 {
+					n := normals[key.normal]
+					vertex.NormalX = n.X
+					vertex.NormalY = n.Y
+					vertex.NormalZ = n.Z
+				} else {
+					group.hasNormals = false
+				}
+
+				if color := colors[key.position]; color != nil {
+					vertex.Colors = append(vertex.Colors, color.Clone())
+					vertex.ActiveColorChannel = 0
+				}
+
+				group.vertexIndex[key] = len(group.vertices)
+				corners = append(corners, len(group.vertices))
+				group.vertices = append(group.vertices, vertex)
+
+			}
+
+			// Polygons are triangulated as a fan around their first vertex.
+			for i := 1; i < len(corners)-1; i++ {
+				group.indices = append(group.indices, corners[0], corners[i], corners[i+1])
+			}
+
+		case "usemtl":
+			currentMaterial = strings.TrimSpace(strings.TrimPrefix(line, "usemtl"))
+
+		case "mtllib":
+			// Material library filenames can contain spaces, but multiple libraries can also be listed in one statement.
+			libPath := strings.TrimSpace(strings.TrimPrefix(line, "mtllib"))
+			if strings.Count(libPath, ".mtl") > 1 {
+				materialLibraries = append(materialLibraries, fields[1:]...)
+			} else {
+				materialLibraries = append(materialLibraries, libPath)
+			}
+
+		case objectStatement:
+
+			name := strings.TrimSpace(line[len(objectStatement):])
+			if name == "" {
+				name = "Object"
+			}
+
+			currentObject = &objObject{name: name}
+			objects = append(objects, currentObject)
+
+		}
+
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	textures := map[string]*ebiten.Image{}
+
+	for _, libPath := range materialLibraries {
+
+		if options.MTLResolver == nil {
+			break
+		}
+
+		mtlData, err := readOBJResource(options.MTLResolver, libPath)
+
+		if err != nil {
+			log.Println("Warning: couldn't open material library " + libPath + ": " + err.Error())
+			continue
+		}
+
+		// Textures named in a material library are relative to the material library, which may not be next to the OBJ file.
+		mtlDir := path.Dir(strings.ReplaceAll(libPath, "\\", "/"))
+
+		if err := loadMTLData(mtlData, library, options.MTLResolver, mtlDir, textures); err != nil {
+			return nil, err
+		}
+
+	}
+
+	for _, object := range objects {
+
+		if len(object.groups) == 0 {
+			continue
+		}
+
+		mesh := NewMesh(object.name)
+		mesh.library = library
+
+		for _, group := range object.groups {
+
+			if len(group.indices) == 0 {
+				continue
+			}
+
+			var mat *Material
+
+			if group.material != "" {
+				mat = library.Materials[group.material]
+				if mat == nil {
+					mat = NewMaterial(group.material)
+					mat.library = library
+					library.Materials[mat.Name] = mat
+				}
+			}
+
+			mesh.AddVertices(group.vertices...)
+			part := mesh.AddMeshPart(mat, group.indices...)
+
+			if !group.hasNormals {
+				part.ForEachTri(func(tri *Triangle) {
+					for _, index := range tri.VertexIndices {
+						mesh.VertexNormals[index] = tri.Normal
+					}
+				})
+			}
+
+		}
+
+		for _, colors := range mesh.VertexColors {
+			if len(colors) > 0 {
+				mesh.VertexColorChannelNames["Col"] = 0
+				break
+			}
+		}
+
+		mesh.UpdateBounds()
+
+		library.Meshes[mesh.Name] = mesh
+
+		model := NewModel(mesh, object.name)
+		model.setLibrary(library)
+		scene.Root.AddChildren(model)
+
+	}
+
+	return library, nil
+
+}
+
+// mtlTextureOptionArgs is how many arguments each texture map option takes; options that take a variable number of arguments
+// (up to 3 numbers) are marked with -1.
+var mtlTextureOptionArgs = map[string]int{
+	"-blendu":  1,
+	"-blendv":  1,
+	"-boost":   1,
+	"-mm":      2,
+	"-o":       -1,
+	"-s":       -1,
+	"-t":       -1,
+	"-texres":  1,
+	"-clamp":   1,
+	"-bm":      1,
+	"-imfchan": 1,
+	"-type":    1,
+	"-cc":      1,
+}
+
+// mtlTexturePath returns the filename of a texture map statement's fields (not including the statement itself), skipping the options that
+// precede it. Filenames can contain spaces.
+func mtlTexturePath(fields []string) string {
+
+	i := 0
+
+	for i < len(fields) {
+
+		argCount, isOption := mtlTextureOptionArgs[fields[i]]
+		if !isOption {
+			break
+		}
+
+		i++
+
+		if argCount < 0 {
+			for n := 0; n < 3 && i < len(fields); n++ {
+				if _, err := strconv.ParseFloat(fields[i], 64); err != nil {
+					break
+				}
+				i++
+			}
+		} else {
+			i += argCount
+		}
+
+	}
+
+	if i >= len(fields) {
+		return ""
+	}
+
+	return strings.Join(fields[i:], " ")
+
+}
+
+func readOBJResource(resolver func(path string) (io.ReadCloser, error), path string) ([]byte, error) {
+
+	file, err := resolver(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	return io.ReadAll(file)
+
+}
+
+// loadMTLData parses a material library, adding its Materials to the Library given. Textures are loaded through the resolver
+// given relative to mtlDir (the directory of the material library), and are shared between Materials through the textures map.
+func loadMTLData(data []byte, library *Library, resolver func(path string) (io.ReadCloser, error), mtlDir string, textures map[string]*ebiten.Image) error {
+
+	var mat *Material
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	lineNumber := 0
+
+	for scanner.Scan() {
+
+		lineNumber++
+
+		line := strings.TrimSpace(scanner.Text())
+
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		fields := strings.Fields(line)
+
+		if fields[0] == "newmtl" {
+			mat = NewMaterial(strings.TrimSpace(strings.TrimPrefix(line, "newmtl")))
+			mat.library = library
+			library.Materials[mat.Name] = mat
+			continue
+		}
+
+		if mat == nil {
+			continue
+		}
+
+		switch fields[0] {
+
+		case "Kd":
+
+			if len(fields) < 4 {
+				return fmt.Errorf("mtl: malformed diffuse color on line %d", lineNumber)
+			}
+
+			rgb := [3]float64{}
+			for i := range rgb {
+				v, err := strconv.ParseFloat(fields[i+1], 64)
+				if err != nil {
+					return fmt.Errorf("mtl: malformed diffuse color on line %d", lineNumber)
+				}
+				rgb[i] = v
+			}
+
+			mat.Color.R = float32(rgb[0])
+			mat.Color.G = float32(rgb[1])
+			mat.Color.B = float32(rgb[2])
+
+		case "d", "Tr":
+
+			if len(fields) < 2 {
+				return fmt.Errorf("mtl: malformed transparency on line %d", lineNumber)
+			}
+
+			alpha, err := strconv.ParseFloat(fields[len(fields)-1], 64)
+			if err != nil {
+				return fmt.Errorf("mtl: malformed transparency on line %d", lineNumber)
+			}
+
+			if fields[0] == "Tr" {
+				alpha = 1 - alpha
+			}
+
+			mat.Color.A = float32(alpha)
+
+			if alpha < 1 {
+				mat.TransparencyMode = TransparencyModeTransparent
+			}
+
+		case "illum":
+
+			// Illumination model 0 is just a color, without any lighting.
+			if len(fields) > 1 && fields[1] == "0" {
+				mat.Shadeless = true
+			}
+
+		case "map_Kd":
+
+			texturePath := mtlTexturePath(fields[1:])
+
+			if texturePath == "" {
+				return fmt.Errorf("mtl: malformed diffuse texture on line %d", lineNumber)
+			}
+
+			texturePath = strings.ReplaceAll(texturePath, "\\", "/")
+			if !path.IsAbs(texturePath) {
+				texturePath = path.Join(mtlDir, texturePath)
+			}
+
+			mat.TexturePath = texturePath
+
+			if texture, exists := textures[mat.TexturePath]; exists {
+				mat.Texture = texture
+				continue
+			}
+
+			imageData, err := readOBJResource(resolver, mat.TexturePath)
+
+			if err != nil {
+				log.Println("Warning: couldn't open texture " + mat.TexturePath + " for material " + mat.Name + ": " + err.Error())
+				continue
+			}
+
+			img, _, err := image.Decode(bytes.NewReader(imageData))
+
+			if err != nil {
+				return err
+			}
+
+			mat.Texture = ebiten.NewImageFromImage(img)
+			textures[mat.TexturePath] = mat.Texture
+
+		}
+
+	}
+
+	return scanner.Err()
+
+}
@@ -0,0 +1,144 @@
+package tetra3d
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+const testOBJ = `# Two quads using different materials
+mtllib crate.mtl
+o Crate
+v 0 0 0
+v 1 0 0
+v 1 1 0
+v 0 1 0
+vt 0 0
+vt 1 0
+vt 1 1
+vt 0 1
+vn 0 0 1
+usemtl Wood
+f 1/1/1 2/2/1 3/3/1 4/4/1
+usemtl Metal
+f -4/1 -3/2 -2/3
+`
+
+const testMTL = `newmtl Wood
+Kd 0.5 0.25 0
+map_Kd textures/wood.png
+
+newmtl Metal
+Kd 0.8 0.8 0.8
+d 0.5
+`
+
+func TestLoadOBJData(t *testing.T) {
+
+	texture := &bytes.Buffer{}
+	if err := png.Encode(texture, image.NewRGBA(image.Rect(0, 0, 2, 2))); err != nil {
+		t.Fatal(err)
+	}
+
+	files := fstest.MapFS{
+		"models/crate.mtl":         {Data: []byte(testMTL)},
+		"models/textures/wood.png": {Data: texture.Bytes()},
+	}
+
+	options := DefaultOBJLoadOptions()
+	options.MTLResolver = NewMTLResolverFS(files, "models")
+
+	library, err := LoadOBJData([]byte(testOBJ), options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mesh := library.Meshes["Crate"]
+	if mesh == nil {
+		t.Fatal("crate mesh wasn't loaded")
+	}
+
+	if len(mesh.MeshParts) != 2 {
+		t.Fatalf("crate mesh has %d mesh parts, expected 2", len(mesh.MeshParts))
+	}
+
+	// The quad is triangulated, and shares its vertices between its triangles.
+	wood := mesh.MeshParts[0]
+	if wood.TriangleCount() != 2 || wood.VertexIndexCount() != 4 || wood.Material.Name != "Wood" {
+		t.Errorf("wood mesh part has %d triangles and %d vertices", wood.TriangleCount(), wood.VertexIndexCount())
+	}
+
+	if wood.Material.Color.R != 0.5 || wood.Material.TexturePath != "textures/wood.png" || wood.Material.Texture == nil {
+		t.Errorf("wood material wasn't loaded correctly")
+	}
+
+	metal := mesh.MeshParts[1]
+	if metal.TriangleCount() != 1 || metal.Material.TransparencyMode != TransparencyModeTransparent || metal.Material.Color.A != 0.5 {
+		t.Errorf("metal mesh part wasn't loaded correctly")
+	}
+
+	// Faces without normals are flat-shaded.
+	if normal := mesh.VertexNormals[metal.VertexIndexStart]; !normal.Equals(Vector{0, 0, 1, 0}) {
+		t.Errorf("metal normal is %s, expected flat normal facing +Z", normal)
+	}
+
+	if model := library.ExportedScene.Root.Get("Crate"); model == nil || model.(*Model).Mesh != mesh {
+		t.Errorf("crate model wasn't added to the scene")
+	}
+
+}
+
+func TestLoadOBJTexturePaths(t *testing.T) {
+
+	texture := &bytes.Buffer{}
+	if err := png.Encode(texture, image.NewRGBA(image.Rect(0, 0, 2, 2))); err != nil {
+		t.Fatal(err)
+	}
+
+	obj := `mtllib materials/crate.mtl
+o Crate
+v 0 0 0
+v 1 0 0
+v 1 1 0
+usemtl Wood
+f 1 2 3
+usemtl Metal
+f 3 2 1
+`
+
+	// Texture paths are relative to the material library, and can have options before them and spaces in them.
+	mtl := `newmtl Wood
+map_Kd -s 2 2 1 -o 0.5 0.5 -clamp on textures/old wood.png
+
+newmtl Metal
+map_Kd -bm 0.5
+`
+
+	files := fstest.MapFS{
+		"models/materials/crate.mtl":             {Data: []byte(mtl)},
+		"models/materials/textures/old wood.png": {Data: texture.Bytes()},
+	}
+
+	options := DefaultOBJLoadOptions()
+	options.MTLResolver = NewMTLResolverFS(files, "models")
+
+	if _, err := LoadOBJData([]byte(obj), options); err == nil {
+		t.Fatalf("texture statement with only options should return an error")
+	}
+
+	files["models/materials/crate.mtl"] = &fstest.MapFile{Data: []byte(strings.Split(mtl, "\n\n")[0])}
+
+	library, err := LoadOBJData([]byte(obj), options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wood := library.Materials["Wood"]
+	if wood.TexturePath != "materials/textures/old wood.png" || wood.Texture == nil {
+		t.Fatalf("wood texture wasn't loaded relative to its material library, path is %s", wood.TexturePath)
+	}
+
+}
@@ -0,0 +1,97 @@
+package tetra3d
+
+import (
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// PickResult is the result of picking a pixel of a Camera's render with Camera.PickAt().
+type PickResult struct {
+	Model    *Model    // The Model rendered at the pixel.
+	Triangle *Triangle // The triangle of the Model's Mesh rendered at the pixel.
+
+	// The depth of the pixel, ranging from 0 (at the Camera) to 1 (at the Camera's far plane), as in the Camera's depth texture.
+	// If Camera.RenderDepth is off, this is 0.
+	Depth float64
+}
+
+// The ID shader draws triangles with their pick IDs encoded in the vertex colors' RGB channels and their depth in the alpha channel,
+// discarding any fragments that are behind the depth already rendered to the depth texture.
+var idShaderText = []byte(
+	`package main
+
+	var DepthTest float
+
+	func decodeDepth(rgba vec4) float {
+		return rgba.r + (rgba.g / 255) + (rgba.b / 65025)
+	}
+
+	func Fragment(position vec4, texCoord vec2, color vec4) vec4 {
+
+		if DepthTest > 0 {
+			existingDepth := imageSrc0At(position.xy / imageSrcTextureSize())
+			if existingDepth.a > 0 && color.a > decodeDepth(existingDepth) + 0.0005 {
+				discard()
+			}
+		}
+
+		return vec4(color.rgb, 1)
+
+	}
+
+	`,
+)
+
+// idVertex returns a copy of the given vertex for rendering to the ID texture, with the pick ID and depth given encoded into its color.
+func (camera *Camera) idVertex(vertex ebiten.Vertex, pickID int, depth float64) ebiten.Vertex {
+	vertex.ColorR = float32(pickID&0xFF) / 255
+	vertex.ColorG = float32((pickID>>8)&0xFF) / 255
+	vertex.ColorB = float32((pickID>>16)&0xFF) / 255
+	vertex.ColorA = float32(depth)
+	return vertex
+}
+
+// decodePickID returns the pick ID encoded in a pixel of the ID texture; 0 means that nothing was rendered to the pixel.
+func decodePickID(c color.Color) int {
+	rgba := color.RGBAModel.Convert(c).(color.RGBA)
+	if rgba.A == 0 {
+		return 0
+	}
+	return int(rgba.R) | int(rgba.G)<<8 | int(rgba.B)<<16
+}
+
+// PickAt returns the Model and triangle rendered to the given pixel of the Camera's textures, along with the pixel's depth,
+// allowing Models to be selected with pixel precision without needing any bounding objects. Camera.RenderIDs must be on
+// while rendering for picking to work. If nothing was rendered to the pixel, PickAt returns nil.
+// Note that PickAt reads back from the GPU, so it can only be called while the game is running, and is relatively slow.
+// Alpha-clipped pixels of Models can also be picked.
+func (camera *Camera) PickAt(x, y int) *PickResult {
+
+	if !camera.RenderIDs {
+		return nil
+	}
+
+	w, h := camera.Size()
+	if x < 0 || y < 0 || x >= w || y >= h {
+		return nil
+	}
+
+	pickID := decodePickID(camera.resultIDTexture.At(x, y))
+
+	if pickID <= 0 || pickID > len(camera.pickEntries) {
+		return nil
+	}
+
+	result := camera.pickEntries[pickID-1]
+
+	if camera.RenderDepth {
+		depth := color.RGBAModel.Convert(camera.resultDepthTexture.At(x, y)).(color.RGBA)
+		if depth.A > 0 {
+			result.Depth = float64(depth.R)/255 + float64(depth.G)/255/255 + float64(depth.B)/255/65025
+		}
+	}
+
+	return &result
+
+}
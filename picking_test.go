@@ -0,0 +1,69 @@
+package tetra3d
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestPickIDEncoding(t *testing.T) {
+
+	camera := NewCamera(16, 16)
+
+	for _, id := range []int{1, 255, 256, 70000, 0xFFFFFF} {
+
+		v := camera.idVertex(colorVertexList[0], id, 0.5)
+		c := color.RGBA{uint8(v.ColorR*255 + 0.5), uint8(v.ColorG*255 + 0.5), uint8(v.ColorB*255 + 0.5), 255}
+
+		if decoded := decodePickID(c); decoded != id {
+			t.Fatalf("pick ID %d decoded as %d", id, decoded)
+		}
+
+		if v.ColorA != 0.5 {
+			t.Fatalf("pick vertex depth is %f, expected 0.5", v.ColorA)
+		}
+
+	}
+
+	if decodePickID(color.RGBA{}) != 0 {
+		t.Fatalf("empty pixels should decode to no pick ID")
+	}
+
+}
+
+func TestPickEntries(t *testing.T) {
+
+	scene := NewScene("Test")
+
+	cube := NewModel(NewCubeMesh(), "Cube")
+	scene.Root.AddChildren(cube)
+
+	camera := NewCamera(64, 64)
+	camera.SetLocalPosition(0, 0, 5)
+	camera.RenderIDs = true
+	scene.Root.AddChildren(camera)
+
+	camera.Clear()
+	camera.RenderScene(scene)
+
+	// Only the cube's front face is visible, with the rest being backface culled.
+	if len(camera.pickEntries) != 2 {
+		t.Fatalf("expected 2 pickable triangles, got %d", len(camera.pickEntries))
+	}
+
+	for _, entry := range camera.pickEntries {
+		if entry.Model != cube || entry.Triangle.Normal.Z <= 0 {
+			t.Fatalf("expected the cube's front triangles to be pickable, got %v", entry.Triangle.Normal)
+		}
+	}
+
+	if camera.PickAt(-1, 0) != nil || camera.PickAt(64, 0) != nil {
+		t.Fatalf("picking outside of the camera should return nil")
+	}
+
+	camera.Clear()
+
+	if len(camera.pickEntries) != 0 {
+		t.Fatalf("clearing the camera should clear its pickable triangles")
+	}
+
+}
@@ -0,0 +1,378 @@
+package tetra3d
+
+import (
+	"math"
+	"sort"
+)
+
+// maxPortalDepth is the maximum number of Portals that Cameras look through in a row when finding visible Sectors.
+const maxPortalDepth = 32
+
+// Portal is a Node representing an opening (like a doorway or a window) between two Sectors. When a Camera renders with sector rendering
+// enabled and the Sector it's in has Portals, the Camera only renders the Sectors that can actually be seen through the Portals
+// (recursively, through Portals of those Sectors in turn), rather than all neighboring Sectors within Camera.SectorRenderDepth.
+type Portal struct {
+	*Node
+	Points  []Vector   // The corners of the Portal's convex polygon, in order, in the Portal's local space.
+	Sectors [2]*Sector // The Sectors the Portal links; use Portal.Link() to set these.
+
+	// Open indicates if the Portal can be seen through; a closed Portal (for a shut door, for example) hides whatever can only be seen through it.
+	// Portals are open by default.
+	Open bool
+}
+
+// NewPortal returns a new, open Portal with the given name, shaped as a rectangle of the width and height given in the Portal's local X and Y axes.
+func NewPortal(name string, width, height float64) *Portal {
+	return &Portal{
+		Node: NewNode(name),
+		Points: []Vector{
+			{-width / 2, -height / 2, 0, 0},
+			{width / 2, -height / 2, 0, 0},
+			{width / 2, height / 2, 0, 0},
+			{-width / 2, height / 2, 0, 0},
+		},
+		Open: true,
+	}
+}
+
+// newPortalFromMesh returns a new Portal with the name given, shaped as the outline of the vertices of the (planar, convex) Mesh given.
+func newPortalFromMesh(name string, mesh *Mesh) *Portal {
+
+	portal := NewPortal(name, 0, 0)
+	portal.Points = nil
+
+	if len(mesh.Triangles) == 0 {
+		return portal
+	}
+
+	indices := mesh.Triangles[0].VertexIndices
+	normal := calculateNormal(mesh.VertexPositions[indices[0]], mesh.VertexPositions[indices[1]], mesh.VertexPositions[indices[2]])
+	center := mesh.Dimensions.Center()
+
+	for _, pos := range mesh.VertexPositions {
+
+		unique := true
+		for _, point := range portal.Points {
+			if point.Distance(pos) < 0.0001 {
+				unique = false
+				break
+			}
+		}
+
+		if unique {
+			pos.W = 0
+			portal.Points = append(portal.Points, pos)
+		}
+
+	}
+
+	// Order the points around the center of the Mesh.
+	right := portal.Points[0].Sub(center).Unit()
+	up := normal.Cross(right)
+
+	angle := func(point Vector) float64 {
+		diff := point.Sub(center)
+		return math.Atan2(diff.Dot(up), diff.Dot(right))
+	}
+
+	sort.SliceStable(portal.Points, func(i, j int) bool { return angle(portal.Points[i]) < angle(portal.Points[j]) })
+
+	return portal
+
+}
+
+// Clone returns a clone of the Portal, linked to the same Sectors as the original.
+func (portal *Portal) Clone() INode {
+
+	clone := NewPortal(portal.name, 0, 0)
+	clone.Points = append([]Vector{}, portal.Points...)
+	clone.Open = portal.Open
+
+	clone.Node = portal.Node.Clone().(*Node)
+	for _, child := range clone.children {
+		child.setParent(clone)
+	}
+
+	if portal.Sectors[0] != nil && portal.Sectors[1] != nil {
+		clone.Link(portal.Sectors[0], portal.Sectors[1])
+	}
+
+	return clone
+
+}
+
+// Link links the Portal to the two Sectors given, unlinking it from any Sectors it was previously linked to.
+func (portal *Portal) Link(sectorA, sectorB *Sector) {
+
+	portal.Unlink()
+
+	portal.Sectors = [2]*Sector{sectorA, sectorB}
+
+	for _, sector := range portal.Sectors {
+		if sector != nil {
+			sector.Portals = append(sector.Portals, portal)
+		}
+	}
+
+}
+
+// Unlink unlinks the Portal from the Sectors it links.
+func (portal *Portal) Unlink() {
+
+	for _, sector := range portal.Sectors {
+
+		if sector == nil {
+			continue
+		}
+
+		for i, p := range sector.Portals {
+			if p == portal {
+				sector.Portals = append(sector.Portals[:i], sector.Portals[i+1:]...)
+				break
+			}
+		}
+
+	}
+
+	portal.Sectors = [2]*Sector{}
+
+}
+
+// UpdateSectors links the Portal to the first two Sectors of the Models given whose AABBs contain the Portal's center, returning if
+// two such Sectors were found.
+func (portal *Portal) UpdateSectors(sectorModels ...*Model) bool {
+
+	center := portal.WorldCenter()
+	found := []*Sector{}
+
+	for _, model := range sectorModels {
+		if model.sector != nil && model.sector.AABB.PointInside(center) {
+			found = append(found, model.sector)
+			if len(found) == 2 {
+				portal.Link(found[0], found[1])
+				return true
+			}
+		}
+	}
+
+	return false
+
+}
+
+// OtherSector returns the Sector on the other side of the Portal from the one given, or nil if the Portal doesn't link the Sector given.
+func (portal *Portal) OtherSector(sector *Sector) *Sector {
+	if portal.Sectors[0] == sector {
+		return portal.Sectors[1]
+	} else if portal.Sectors[1] == sector {
+		return portal.Sectors[0]
+	}
+	return nil
+}
+
+// WorldPoints returns the corners of the Portal's polygon in world space.
+func (portal *Portal) WorldPoints() []Vector {
+	transform := portal.Transform()
+	points := make([]Vector, 0, len(portal.Points))
+	for _, point := range portal.Points {
+		p := transform.MultVec(point)
+		p.W = 0
+		points = append(points, p)
+	}
+	return points
+}
+
+// WorldCenter returns the center of the Portal's polygon in world space.
+func (portal *Portal) WorldCenter() Vector {
+	center := NewVectorZero()
+	points := portal.WorldPoints()
+	if len(points) == 0 {
+		return portal.WorldPosition()
+	}
+	for _, point := range points {
+		center = center.Add(point)
+	}
+	return center.Divide(float64(len(points)))
+}
+
+/////
+
+// AddChildren parents the provided children Nodes to the passed parent Node, inheriting its transformations and being under it in the scenegraph
+// hierarchy. If the children are already parented to other Nodes, they are unparented before doing so.
+func (portal *Portal) AddChildren(children ...INode) {
+	portal.addChildren(portal, children...)
+}
+
+// Unparent unparents the Portal from its parent, removing it from the scenegraph.
+func (portal *Portal) Unparent() {
+	if portal.parent != nil {
+		portal.parent.RemoveChildren(portal)
+	}
+}
+
+// Type returns the NodeType for this object.
+func (portal *Portal) Type() NodeType {
+	return NodeTypePortal
+}
+
+// Index returns the index of the Node in its parent's children list.
+// If the node doesn't have a parent, its index will be -1.
+func (portal *Portal) Index() int {
+	if portal.parent != nil {
+		for i, c := range portal.parent.Children() {
+			if c == portal {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+/////
+
+// screenRect is a rectangular region of the screen, in pixels.
+type screenRect struct {
+	MinX, MinY, MaxX, MaxY float64
+}
+
+// intersect returns the region where the screenRect overlaps the other one given.
+func (rect screenRect) intersect(other screenRect) screenRect {
+	return screenRect{
+		math.Max(rect.MinX, other.MinX),
+		math.Max(rect.MinY, other.MinY),
+		math.Min(rect.MaxX, other.MaxX),
+		math.Min(rect.MaxY, other.MaxY),
+	}
+}
+
+// union returns the smallest screenRect that covers both the screenRect and the other one given.
+func (rect screenRect) union(other screenRect) screenRect {
+	return screenRect{
+		math.Min(rect.MinX, other.MinX),
+		math.Min(rect.MinY, other.MinY),
+		math.Max(rect.MaxX, other.MaxX),
+		math.Max(rect.MaxY, other.MaxY),
+	}
+}
+
+// empty returns if the screenRect has no area.
+func (rect screenRect) empty() bool {
+	return rect.MaxX <= rect.MinX || rect.MaxY <= rect.MinY
+}
+
+// portalScreenRect returns the screen-space bounds of the Portal from the Camera's point of view, after clipping the Portal against the
+// Camera's near plane. If the Portal is entirely behind the near plane, the returned screenRect is empty.
+func (camera *Camera) portalScreenRect(portal *Portal) screenRect {
+
+	view := camera.ViewMatrix()
+
+	polygon := make([]clipVertex, 0, len(portal.Points))
+	for _, point := range portal.WorldPoints() {
+		p := view.MultVec(point)
+		p.W = 0
+		polygon = append(polygon, clipVertex{Position: p})
+	}
+
+	// The Camera looks down its -Z axis.
+	polygon = clipPolygon(polygon, Vector{0, 0, -1, 0}, camera.near)
+
+	rect := screenRect{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+
+	projection := camera.Projection()
+
+	for _, v := range polygon {
+		p := v.Position
+		p.W = 1
+		screen := camera.ClipToScreen(projection.MultVecW(p))
+		rect.MinX = math.Min(rect.MinX, screen.X)
+		rect.MinY = math.Min(rect.MinY, screen.Y)
+		rect.MaxX = math.Max(rect.MaxX, screen.X)
+		rect.MaxY = math.Max(rect.MaxY, screen.Y)
+	}
+
+	return rect
+
+}
+
+// modelScreenRect returns screen-space bounds that cover the Model's bounding sphere from the Camera's point of view. If the bounding sphere
+// crosses the Camera's near plane, the returned screenRect covers the entire screen.
+func (camera *Camera) modelScreenRect(model *Model) screenRect {
+
+	model.Transform()
+
+	center := camera.ViewMatrix().MultVec(model.BoundingSphere.WorldPosition())
+	radius := model.BoundingSphere.WorldRadius()
+
+	// The Camera looks down its -Z axis.
+	if -center.Z-radius < camera.near {
+		return screenRect{math.Inf(-1), math.Inf(-1), math.Inf(1), math.Inf(1)}
+	}
+
+	rect := screenRect{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+
+	projection := camera.Projection()
+
+	// The corners of a box around the bounding sphere cover the sphere on screen.
+	for _, corner := range []Vector{
+		{-1, -1, -1, 0}, {1, -1, -1, 0}, {-1, 1, -1, 0}, {1, 1, -1, 0},
+		{-1, -1, 1, 0}, {1, -1, 1, 0}, {-1, 1, 1, 0}, {1, 1, 1, 0},
+	} {
+		p := center.Add(corner.Scale(radius))
+		p.W = 1
+		screen := camera.ClipToScreen(projection.MultVecW(p))
+		rect.MinX = math.Min(rect.MinX, screen.X)
+		rect.MinY = math.Min(rect.MinY, screen.Y)
+		rect.MaxX = math.Max(rect.MaxX, screen.X)
+		rect.MaxY = math.Max(rect.MaxY, screen.Y)
+	}
+
+	return rect
+
+}
+
+// markPortalSectors marks the Sectors visible through the open Portals of the Sector given as visible, narrowing the visible region of the screen
+// to each Portal's bounds and recursing through the Portals of the Sectors found. Each Sector found records the region of the screen it's seen
+// through, so that the objects in it can be tested against that region. path holds the Portals already looked through.
+func (camera *Camera) markPortalSectors(sector *Sector, visibleRect screenRect, path []*Portal) {
+
+	if len(path) >= maxPortalDepth {
+		return
+	}
+
+	for _, portal := range sector.Portals {
+
+		other := portal.OtherSector(sector)
+
+		if !portal.Open || !portal.visible || other == nil {
+			continue
+		}
+
+		lookedThrough := false
+		for _, p := range path {
+			if p == portal {
+				lookedThrough = true
+				break
+			}
+		}
+
+		if lookedThrough {
+			continue
+		}
+
+		rect := camera.portalScreenRect(portal).intersect(visibleRect)
+
+		if rect.empty() {
+			continue
+		}
+
+		if other.sectorVisible {
+			other.visibleRect = other.visibleRect.union(rect)
+		} else {
+			other.sectorVisible = true
+			other.visibleRect = rect
+		}
+
+		camera.markPortalSectors(other, rect, append(path, portal))
+
+	}
+
+}
@@ -0,0 +1,153 @@
+package tetra3d
+
+import (
+	"testing"
+)
+
+// newPortalTestLevel returns a Scene with three sector rooms in a row along the -Z axis, A, B, and C, along with the Portals between them.
+func newPortalTestLevel() (scene *Scene, rooms []*Model, portals []*Portal) {
+
+	scene = NewScene("Test")
+
+	for i, name := range []string{"A", "B", "C"} {
+		room := NewModel(NewCubeMesh(), name)
+		room.SetLocalPosition(0, 0, float64(i)*-10)
+		NewVertexSelection(room.Mesh).SelectAll().ApplyMatrix(NewMatrix4Scale(5, 5, 5))
+		room.Mesh.UpdateBounds()
+		room.sector = NewSector(room)
+		scene.Root.AddChildren(room)
+		rooms = append(rooms, room)
+	}
+
+	for i := range rooms {
+		rooms[i].sector.UpdateNeighbors(rooms...)
+	}
+
+	portalAB := NewPortal("AB", 2, 2)
+	portalAB.SetLocalPosition(0, 0, -5)
+
+	// The Portal from B to C is off to the side, so it can't be seen through the Portal from A to B from the Camera in A.
+	portalBC := NewPortal("BC", 2, 2)
+	portalBC.SetLocalPosition(4, 0, -15)
+
+	scene.Root.AddChildren(portalAB, portalBC)
+
+	if !portalAB.UpdateSectors(rooms...) || !portalBC.UpdateSectors(rooms...) {
+		panic("portals couldn't be linked to sectors")
+	}
+
+	return scene, rooms, []*Portal{portalAB, portalBC}
+
+}
+
+func renderedSectors(camera *Camera, scene *Scene, rooms []*Model) map[string]bool {
+	rendered := map[string]bool{}
+	models, _ := camera.renderables(scene.Root)
+	for _, model := range models {
+		for _, room := range rooms {
+			if model == room {
+				rendered[room.Name()] = true
+			}
+		}
+	}
+	return rendered
+}
+
+func TestPortalVisibility(t *testing.T) {
+
+	scene, rooms, portals := newPortalTestLevel()
+
+	if portals[0].OtherSector(rooms[0].sector) != rooms[1].sector || len(rooms[1].sector.Portals) != 2 {
+		t.Fatalf("portals weren't linked to the expected sectors")
+	}
+
+	camera := NewCamera(64, 64)
+	camera.SectorRendering = true
+	camera.SetLocalPosition(0, 0, 4)
+	scene.Root.AddChildren(camera)
+
+	if rendered := renderedSectors(camera, scene, rooms); !rendered["A"] || !rendered["B"] || rendered["C"] {
+		t.Fatalf("expected A and B to be visible through the portal, got %v", rendered)
+	}
+
+	portals[1].SetLocalPosition(0, 0, -15)
+
+	if rendered := renderedSectors(camera, scene, rooms); !rendered["A"] || !rendered["B"] || !rendered["C"] {
+		t.Fatalf("expected C to be visible through both portals, got %v", rendered)
+	}
+
+	portals[0].Open = false
+
+	if rendered := renderedSectors(camera, scene, rooms); !rendered["A"] || rendered["B"] || rendered["C"] {
+		t.Fatalf("expected only A to be visible with its portal closed, got %v", rendered)
+	}
+
+	portals[0].Open = true
+	camera.SetLocalRotation(NewMatrix4Rotate(0, 1, 0, 3.1415))
+
+	if rendered := renderedSectors(camera, scene, rooms); !rendered["A"] || rendered["B"] || rendered["C"] {
+		t.Fatalf("expected only A to be visible while facing away from its portal, got %v", rendered)
+	}
+
+}
+
+func TestPortalSceneClone(t *testing.T) {
+
+	scene, rooms, _ := newPortalTestLevel()
+
+	clone := scene.Clone()
+
+	clonedAB := clone.Root.Get("AB").(*Portal)
+	clonedA := clone.Root.Get("A").(*Model)
+	clonedB := clone.Root.Get("B").(*Model)
+
+	if clonedAB.Sectors[0] != clonedA.sector || clonedAB.Sectors[1] != clonedB.sector {
+		t.Fatalf("cloned portals should link cloned sectors")
+	}
+
+	if len(rooms[0].sector.Portals) != 1 || len(rooms[1].sector.Portals) != 2 {
+		t.Fatalf("cloning shouldn't link portals to the original sectors")
+	}
+
+}
+
+func TestPortalModelCulling(t *testing.T) {
+
+	scene, rooms, _ := newPortalTestLevel()
+
+	seen := NewModel(NewCubeMesh(), "Seen")
+	seen.SetLocalScale(0.5, 0.5, 0.5)
+
+	// Off to the side, so it's on screen, but can't be seen through the Portal from A to B.
+	hidden := NewModel(NewCubeMesh(), "Hidden")
+	hidden.SetLocalScale(0.5, 0.5, 0.5)
+	hidden.SetLocalPosition(4, 0, 0)
+
+	rooms[1].AddChildren(seen, hidden)
+
+	camera := NewCamera(64, 64)
+	camera.SectorRendering = true
+	camera.SetLocalPosition(0, 0, 4)
+	scene.Root.AddChildren(camera)
+
+	rendered := func() map[string]bool {
+		rendered := map[string]bool{}
+		models, _ := camera.renderables(scene.Root)
+		for _, model := range models {
+			rendered[model.Name()] = true
+		}
+		return rendered
+	}
+
+	if r := rendered(); !r["B"] || !r["Seen"] || r["Hidden"] {
+		t.Fatalf("expected only models in B that can be seen through the portal to be rendered, got %v", r)
+	}
+
+	// From inside of B, everything in it is visible.
+	camera.SetLocalPosition(0, 0, -6)
+
+	if r := rendered(); !r["Seen"] || !r["Hidden"] {
+		t.Fatalf("expected all models in the sector the camera is in to be rendered, got %v", r)
+	}
+
+}
@@ -0,0 +1,523 @@
+package tetra3d
+
+import (
+	"image"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// PostProcessPass is a single full-screen pass of a PostProcessStack, drawn with a Kage shader. When the pass is drawn, the shader's
+// source images are:
+//
+// imageSrc0 - The result of the previous pass (or the Camera's ColorTexture for the first pass).
+//
+// imageSrc1 - The Camera's depth texture, with depth encoded across the RGB channels (see the decodeDepth() function in the built-in passes).
+// Pixels that nothing was rendered to have an alpha of 0.
+//
+// imageSrc2 - The Camera's normal texture (if Camera.RenderNormals is on), with normals mapped from -1 to 1 onto 0 to 1 in the RGB channels.
+//
+// imageSrc3 - The Camera's ColorTexture, untouched by any passes.
+//
+// All of the images are the same size as the Camera, and colors are premultiplied by alpha, as usual for ebiten.
+type PostProcessPass struct {
+	Name     string                 // The name of the pass.
+	Active   bool                   // Whether the pass is drawn when applying its PostProcessStack. Defaults to true.
+	Shader   *ebiten.Shader         // The Kage shader used to draw the pass.
+	Uniforms map[string]interface{} // The uniforms passed to the shader.
+
+	// Iterations is how many times in a row the pass is drawn, with each iteration reading the result of the previous one; this can be
+	// used to strengthen blurs, for example. Values below 1 are treated as 1.
+	Iterations int
+}
+
+// NewPostProcessPass creates a new PostProcessPass with the given name, compiling the Kage shader source provided.
+func NewPostProcessPass(name string, shaderSrc []byte) (*PostProcessPass, error) {
+
+	shader, err := ebiten.NewShader(shaderSrc)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PostProcessPass{
+		Name:       name,
+		Active:     true,
+		Shader:     shader,
+		Uniforms:   map[string]interface{}{},
+		Iterations: 1,
+	}, nil
+
+}
+
+// newBuiltinPostProcessPass creates a PostProcessPass using the shader source for one of the built-in passes, panicking if it doesn't compile.
+func newBuiltinPostProcessPass(name string, shaderSrc []byte, uniforms map[string]interface{}) *PostProcessPass {
+
+	pass, err := NewPostProcessPass(name, shaderSrc)
+	if err != nil {
+		panic(err)
+	}
+
+	pass.Uniforms = uniforms
+
+	return pass
+
+}
+
+// Clone creates a clone of the PostProcessPass. The clone shares the original's Shader, while its Uniforms are copied.
+func (pass *PostProcessPass) Clone() *PostProcessPass {
+
+	clone := &PostProcessPass{
+		Name:       pass.Name,
+		Active:     pass.Active,
+		Shader:     pass.Shader,
+		Uniforms:   make(map[string]interface{}, len(pass.Uniforms)),
+		Iterations: pass.Iterations,
+	}
+
+	for k, v := range pass.Uniforms {
+		if floats, ok := v.([]float32); ok {
+			v = append([]float32{}, floats...)
+		}
+		clone.Uniforms[k] = v
+	}
+
+	return clone
+
+}
+
+// PostProcessStack is an ordered list of PostProcessPasses applied to a Camera's rendered results. Each active pass is drawn in order,
+// reading the result of the previous pass, with the stack ping-ponging between two internal buffers as it goes.
+type PostProcessStack struct {
+	Passes []*PostProcessPass // The passes of the stack, in the order they're drawn.
+
+	buffers     [2]*ebiten.Image
+	result      *ebiten.Image
+	drawOptions *ebiten.DrawRectShaderOptions
+}
+
+// NewPostProcessStack creates a new, empty PostProcessStack.
+func NewPostProcessStack() *PostProcessStack {
+	return &PostProcessStack{
+		Passes:      []*PostProcessPass{},
+		drawOptions: &ebiten.DrawRectShaderOptions{},
+	}
+}
+
+// Clone creates a clone of the PostProcessStack, cloning its passes.
+func (stack *PostProcessStack) Clone() *PostProcessStack {
+	clone := NewPostProcessStack()
+	for _, pass := range stack.Passes {
+		clone.Passes = append(clone.Passes, pass.Clone())
+	}
+	return clone
+}
+
+// Add adds the passes given to the end of the PostProcessStack.
+func (stack *PostProcessStack) Add(passes ...*PostProcessPass) {
+	stack.Passes = append(stack.Passes, passes...)
+}
+
+// Remove removes the passes given from the PostProcessStack.
+func (stack *PostProcessStack) Remove(passes ...*PostProcessPass) {
+	for _, toRemove := range passes {
+		for i, pass := range stack.Passes {
+			if pass == toRemove {
+				stack.Passes = append(stack.Passes[:i], stack.Passes[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// Get returns the first pass in the PostProcessStack with the given name, or nil if no pass has that name.
+func (stack *PostProcessStack) Get(name string) *PostProcessPass {
+	for _, pass := range stack.Passes {
+		if pass.Name == name {
+			return pass
+		}
+	}
+	return nil
+}
+
+// Apply draws the active passes of the PostProcessStack over the results of the given Camera's previous Render() calls, returning the
+// final result. If no passes are active, this is the Camera's ColorTexture. Note that the returned image is reused by the stack,
+// so it will change when Apply() is next called.
+func (stack *PostProcessStack) Apply(camera *Camera) *ebiten.Image {
+
+	w, h := camera.Size()
+	stack.resize(w, h)
+
+	src := camera.resultColorTexture
+	bufferIndex := 0
+
+	for _, pass := range stack.Passes {
+
+		if !pass.Active || pass.Shader == nil {
+			continue
+		}
+
+		iterations := pass.Iterations
+		if iterations < 1 {
+			iterations = 1
+		}
+
+		for i := 0; i < iterations; i++ {
+
+			dst := stack.buffers[bufferIndex]
+			bufferIndex = 1 - bufferIndex
+
+			stack.drawOptions.Uniforms = pass.Uniforms
+			stack.drawOptions.Images = [4]*ebiten.Image{src, camera.resultDepthTexture, camera.resultNormalTexture, camera.resultColorTexture}
+
+			dst.Clear()
+			dst.DrawRectShader(w, h, pass.Shader, stack.drawOptions)
+
+			src = dst
+
+		}
+
+	}
+
+	stack.result = src
+
+	return src
+
+}
+
+// Result returns the final result of the last Apply() call, or nil if the PostProcessStack hasn't been applied yet.
+func (stack *PostProcessStack) Result() *ebiten.Image {
+	return stack.result
+}
+
+func (stack *PostProcessStack) resize(w, h int) {
+
+	if stack.buffers[0] != nil {
+
+		bw, bh := stack.buffers[0].Size()
+		if bw == w && bh == h {
+			return
+		}
+
+		stack.buffers[0].Dispose()
+		stack.buffers[1].Dispose()
+
+	}
+
+	bounds := image.Rect(0, 0, w, h)
+	opt := &ebiten.NewImageOptions{
+		Unmanaged: true,
+	}
+
+	stack.buffers[0] = ebiten.NewImageWithOptions(bounds, opt)
+	stack.buffers[1] = ebiten.NewImageWithOptions(bounds, opt)
+
+}
+
+// NewBloomPass creates a PostProcessPass that makes bright areas of the screen glow. Color channels brighter than the threshold
+// (ranging from 0 to 1) bleed into their surroundings, multiplied by the strength given. spread is the distance between blur
+// samples in pixels; larger values give a wider, but coarser glow.
+// Uniforms: Threshold, Strength, Spread (float32).
+func NewBloomPass(threshold, strength, spread float64) *PostProcessPass {
+
+	return newBuiltinPostProcessPass("Bloom", []byte(
+		`package main
+
+		var Threshold float
+		var Strength float
+		var Spread float
+
+		func Fragment(position vec4, texCoord vec2, color vec4) vec4 {
+
+			texel := 1 / imageSrcTextureSize()
+			base := imageSrc0UnsafeAt(texCoord)
+
+			glow := vec3(0)
+			total := 0.0
+
+			for y := -3; y <= 3; y++ {
+				for x := -3; x <= 3; x++ {
+					offset := vec2(float(x), float(y))
+					weight := exp(-dot(offset, offset) / 8)
+					sample := imageSrc0At(texCoord + (offset * Spread * texel))
+					glow += max(sample.rgb - vec3(Threshold), vec3(0)) * weight
+					total += weight
+				}
+			}
+
+			glow = glow / total * Strength
+
+			result := clamp(base.rgb + glow, vec3(0), vec3(1))
+			alpha := max(base.a, max(result.r, max(result.g, result.b)))
+
+			return vec4(result, alpha)
+
+		}
+		`),
+		map[string]interface{}{
+			"Threshold": float32(threshold),
+			"Strength":  float32(strength),
+			"Spread":    float32(spread),
+		},
+	)
+
+}
+
+// NewDitherPass creates a PostProcessPass that reduces each color channel to the given number of levels, using an ordered
+// (Bayer matrix) dither to smooth out the banding. ditherSize is the size of each dither cell in pixels.
+// Uniforms: Levels, DitherSize (float32), BayerMatrix ([]float32).
+func NewDitherPass(levels int, ditherSize float64) *PostProcessPass {
+
+	return newBuiltinPostProcessPass("Dither", []byte(
+		`package main
+
+		var Levels float
+		var DitherSize float
+		var BayerMatrix [16]float
+
+		func Fragment(position vec4, texCoord vec2, color vec4) vec4 {
+
+			c := imageSrc0UnsafeAt(texCoord)
+
+			if c.a == 0 || Levels < 2 {
+				return c
+			}
+
+			yc := int(position.y / DitherSize) % 4
+			xc := int(position.x / DitherSize) % 4
+			threshold := BayerMatrix[(yc*4)+xc] - 0.5
+
+			steps := Levels - 1
+			rgb := floor((c.rgb / c.a * steps) + threshold + 0.5) / steps
+
+			return vec4(clamp(rgb, vec3(0), vec3(1)) * c.a, c.a)
+
+		}
+		`),
+		map[string]interface{}{
+			"Levels":      float32(levels),
+			"DitherSize":  float32(ditherSize),
+			"BayerMatrix": append([]float32{}, bayerMatrix...),
+		},
+	)
+
+}
+
+// MaxPaletteColors is the maximum number of colors a palette quantization pass can use.
+const MaxPaletteColors = 64
+
+// NewPaletteQuantizePass creates a PostProcessPass that snaps each pixel to the closest color in the palette given. The palette can
+// have up to MaxPaletteColors colors; passing more panics.
+// Uniforms: Palette ([]float32, holding the RGB values of each color one after another), PaletteSize (float32).
+func NewPaletteQuantizePass(palette ...*Color) *PostProcessPass {
+
+	if len(palette) > MaxPaletteColors {
+		panic("Error: NewPaletteQuantizePass() was given more than MaxPaletteColors colors.")
+	}
+
+	colors := make([]float32, MaxPaletteColors*3)
+	for i, c := range palette {
+		colors[i*3] = c.R
+		colors[i*3+1] = c.G
+		colors[i*3+2] = c.B
+	}
+
+	return newBuiltinPostProcessPass("Palette Quantize", []byte(
+		`package main
+
+		var Palette [192]float
+		var PaletteSize float
+
+		func Fragment(position vec4, texCoord vec2, color vec4) vec4 {
+
+			c := imageSrc0UnsafeAt(texCoord)
+
+			if c.a == 0 || PaletteSize == 0 {
+				return c
+			}
+
+			rgb := c.rgb / c.a
+			closest := rgb
+			closestDist := 1000.0
+
+			for i := 0; i < 64; i++ {
+				if float(i) < PaletteSize {
+					p := vec3(Palette[i*3], Palette[(i*3)+1], Palette[(i*3)+2])
+					diff := rgb - p
+					if dot(diff, diff) < closestDist {
+						closestDist = dot(diff, diff)
+						closest = p
+					}
+				}
+			}
+
+			return vec4(closest * c.a, c.a)
+
+		}
+		`),
+		map[string]interface{}{
+			"Palette":     colors,
+			"PaletteSize": float32(len(palette)),
+		},
+	)
+
+}
+
+// NewScanlinesPass creates a PostProcessPass that darkens every other row of lines to imitate a CRT display. strength is how much
+// the lines are darkened (ranging from 0 to 1), while lineHeight is how tall each line is in pixels.
+// Uniforms: Strength, LineHeight (float32).
+func NewScanlinesPass(strength, lineHeight float64) *PostProcessPass {
+
+	return newBuiltinPostProcessPass("Scanlines", []byte(
+		`package main
+
+		var Strength float
+		var LineHeight float
+
+		func Fragment(position vec4, texCoord vec2, color vec4) vec4 {
+
+			c := imageSrc0UnsafeAt(texCoord)
+			line := mod(floor(position.y / max(LineHeight, 1)), 2)
+
+			return vec4(c.rgb * (1 - (Strength * line)), c.a)
+
+		}
+		`),
+		map[string]interface{}{
+			"Strength":   float32(strength),
+			"LineHeight": float32(lineHeight),
+		},
+	)
+
+}
+
+// NewDepthOfFieldPass creates a PostProcessPass that blurs the screen according to depth, keeping pixels in focus around the focus
+// depth. Depths range from 0 (the Camera's near plane) to 1 (its far plane); focusRange is how far from the focus depth pixels can
+// be before they're fully blurred, and blurRadius is how wide the blur is at its strongest, in pixels. This requires Camera.RenderDepth.
+// Uniforms: FocusDepth, FocusRange, BlurRadius (float32).
+func NewDepthOfFieldPass(focusDepth, focusRange, blurRadius float64) *PostProcessPass {
+
+	return newBuiltinPostProcessPass("Depth of Field", []byte(
+		`package main
+
+		var FocusDepth float
+		var FocusRange float
+		var BlurRadius float
+
+		func decodeDepth(rgba vec4) float {
+			return rgba.r + (rgba.g / 255) + (rgba.b / 65025)
+		}
+
+		func Fragment(position vec4, texCoord vec2, color vec4) vec4 {
+
+			depth := 1.0
+			depthValue := imageSrc1UnsafeAt(texCoord)
+			if depthValue.a > 0 {
+				depth = decodeDepth(depthValue)
+			}
+
+			blur := clamp(abs(depth - FocusDepth) / max(FocusRange, 0.0001), 0, 1) * BlurRadius
+
+			if blur < 0.5 {
+				return imageSrc0UnsafeAt(texCoord)
+			}
+
+			texel := 1 / imageSrcTextureSize()
+			sum := vec4(0)
+
+			for y := -2; y <= 2; y++ {
+				for x := -2; x <= 2; x++ {
+					sum += imageSrc0At(texCoord + (vec2(float(x), float(y)) / 2 * blur * texel))
+				}
+			}
+
+			return sum / 25
+
+		}
+		`),
+		map[string]interface{}{
+			"FocusDepth": float32(focusDepth),
+			"FocusRange": float32(focusRange),
+			"BlurRadius": float32(blurRadius),
+		},
+	)
+
+}
+
+// NewOutlinePass creates a PostProcessPass that draws outlines of the given color and thickness (in pixels) along edges found
+// in the Camera's normal texture, so it requires Camera.RenderNormals. Edges are where neighboring normals differ by more than
+// the normal threshold (ranging from 0, where any difference makes an edge, to 2, where none do), or where the depth differs by
+// more than the depth threshold (if it's above 0 and Camera.RenderDepth is on); the outsides of objects are always outlined.
+// Uniforms: OutlineColor ([]float32, RGBA), Thickness, NormalThreshold, DepthThreshold (float32).
+func NewOutlinePass(color *Color, thickness, normalThreshold, depthThreshold float64) *PostProcessPass {
+
+	return newBuiltinPostProcessPass("Outline", []byte(
+		`package main
+
+		var OutlineColor vec4
+		var Thickness float
+		var NormalThreshold float
+		var DepthThreshold float
+
+		func decodeDepth(rgba vec4) float {
+			return rgba.r + (rgba.g / 255) + (rgba.b / 65025)
+		}
+
+		func depthAt(texCoord vec2) float {
+			depthValue := imageSrc1At(texCoord)
+			if depthValue.a == 0 {
+				return 1
+			}
+			return decodeDepth(depthValue)
+		}
+
+		func edgeAt(texCoord vec2, offset vec2, normal vec4, depth float) float {
+
+			other := imageSrc2At(texCoord + offset)
+
+			if (other.a > 0) != (normal.a > 0) {
+				return 1
+			}
+
+			if normal.a == 0 {
+				return 0
+			}
+
+			if 1 - dot((normal.rgb * 2) - 1, (other.rgb * 2) - 1) > NormalThreshold {
+				return 1
+			}
+
+			if DepthThreshold > 0 && abs(depthAt(texCoord + offset) - depth) > DepthThreshold {
+				return 1
+			}
+
+			return 0
+
+		}
+
+		func Fragment(position vec4, texCoord vec2, color vec4) vec4 {
+
+			texel := Thickness / imageSrcTextureSize()
+
+			c := imageSrc0UnsafeAt(texCoord)
+			normal := imageSrc2UnsafeAt(texCoord)
+			depth := depthAt(texCoord)
+
+			edge := max(
+				max(edgeAt(texCoord, vec2(texel.x, 0), normal, depth), edgeAt(texCoord, vec2(-texel.x, 0), normal, depth)),
+				max(edgeAt(texCoord, vec2(0, texel.y), normal, depth), edgeAt(texCoord, vec2(0, -texel.y), normal, depth)),
+			)
+
+			outline := vec4(OutlineColor.rgb * OutlineColor.a, OutlineColor.a)
+
+			return (c * (1 - (edge * OutlineColor.a))) + (outline * edge)
+
+		}
+		`),
+		map[string]interface{}{
+			"OutlineColor":    []float32{color.R, color.G, color.B, color.A},
+			"Thickness":       float32(thickness),
+			"NormalThreshold": float32(normalThreshold),
+			"DepthThreshold":  float32(depthThreshold),
+		},
+	)
+
+}
@@ -0,0 +1,58 @@
+package tetra3d
+
+import (
+	"testing"
+)
+
+func TestPostProcessStack(t *testing.T) {
+
+	camera := NewCamera(32, 32)
+
+	if result := camera.PostProcess(); result != camera.ColorTexture() {
+		t.Fatalf("an empty stack should return the Camera's ColorTexture")
+	}
+
+	// Creating the built-in passes compiles their shaders, panicking if they're invalid.
+	bloom := NewBloomPass(0.8, 1, 2)
+	dither := NewDitherPass(4, 1)
+	palette := NewPaletteQuantizePass(NewColor(0, 0, 0, 1), NewColor(1, 1, 1, 1))
+	scanlines := NewScanlinesPass(0.5, 1)
+	dof := NewDepthOfFieldPass(0.5, 0.25, 4)
+	outline := NewOutlinePass(NewColor(0, 0, 0, 1), 1, 0.5, 0)
+
+	stack := camera.PostProcessing
+	stack.Add(bloom, dither, palette, scanlines, dof, outline)
+
+	if stack.Get("Scanlines") != scanlines {
+		t.Fatalf("couldn't get the scanlines pass by name")
+	}
+
+	// Each active pass draws to the buffer the previous pass didn't.
+	if result := camera.PostProcess(); result != stack.buffers[1] {
+		t.Fatalf("expected 6 passes to end in the second buffer")
+	}
+
+	scanlines.Active = false
+
+	if result := camera.PostProcess(); result != stack.buffers[0] {
+		t.Fatalf("expected 5 active passes to end in the first buffer")
+	}
+
+	bloom.Iterations = 2
+
+	if result := camera.PostProcess(); result != stack.buffers[1] || stack.Result() != result {
+		t.Fatalf("expected a pass with two iterations to draw twice")
+	}
+
+	stack.Remove(bloom, dither, palette, dof, outline)
+
+	if len(stack.Passes) != 1 || camera.PostProcess() != camera.ColorTexture() {
+		t.Fatalf("expected a stack with only an inactive pass to return the Camera's ColorTexture")
+	}
+
+	clone := camera.Clone().(*Camera)
+	if len(clone.PostProcessing.Passes) != 1 || clone.PostProcessing.Passes[0] == scanlines || clone.PostProcessing.Passes[0].Shader != scanlines.Shader {
+		t.Fatalf("expected cloned cameras to clone their post-processing passes")
+	}
+
+}
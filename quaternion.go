@@ -104,6 +104,11 @@ func (quat Quaternion) Negated() Quaternion {
 	return NewQuaternion(-quat.X, -quat.Y, -quat.Z, -quat.W)
 }
 
+// Inverted returns the inverse of the Quaternion, which undoes its rotation (assuming it's normalized).
+func (quat Quaternion) Inverted() Quaternion {
+	return NewQuaternion(-quat.X, -quat.Y, -quat.Z, quat.W)
+}
+
 func (q1 Quaternion) Mult(q2 Quaternion) Quaternion {
 	// Cribbed from euclidean space: http://www.euclideanspace.com/maths/algebra/realNormedAlgebra/quaternions/code/index.htm#mul
 	return NewQuaternion(
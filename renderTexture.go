@@ -0,0 +1,128 @@
+package tetra3d
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// renderFrame counts the frames Cameras have started, so that Cameras bound to Materials' textures can tell whether they've been rendered
+// since a Camera's frame started.
+var renderFrame uint64 = 0
+
+// BindTexture binds the Camera to the textures of the Materials given, so that they display what the Camera sees - this is useful for things
+// like security monitors, mirrors, or portals. Whenever another Camera renders a Model using one of the Materials, the bound Camera renders
+// its Scene first (or the Scene being rendered, if it isn't in one), and the Material's Texture is set to a copy of the bound Camera's
+// post-processed render results. Setting a Material's TextureCamera back to nil gives it back its original Texture the next time it's rendered.
+// Bound Cameras are rendered at most once per frame of the Camera rendering them, where a frame starts when the Camera is cleared, or when it
+// renders again without being cleared. So, when rendering split-screen, clearing every Camera before rendering any of them renders each bound Camera
+// just once. When bound Cameras see each other's textures (or their own), they see what was
+// last rendered, rather than rendering endlessly.
+func (camera *Camera) BindTexture(materials ...*Material) {
+	for _, mat := range materials {
+		mat.TextureCamera = camera
+	}
+}
+
+// renderTextureCameras renders the TextureCameras of the Materials in the render pairs given, and updates the Materials' textures.
+func (camera *Camera) renderTextureCameras(scene *Scene, pairs ...[]renderPair) {
+
+	// A Camera that renders again without being cleared (or that's never been cleared) starts a new frame, so the Cameras it sees keep updating.
+	if !camera.renderingTexture {
+		if camera.frame == 0 || camera.frameRendered {
+			camera.startFrame()
+		}
+		camera.frameRendered = true
+	}
+
+	camera.renderingBound = true
+	defer func() { camera.renderingBound = false }()
+
+	for _, list := range pairs {
+
+		for _, pair := range list {
+
+			mat := pair.MeshPart.Material
+
+			if mat == nil {
+				continue
+			}
+
+			if mat.TextureCamera == nil {
+				// The Material was unbound from its Camera, so it gets its own Texture back.
+				if mat.textureBound {
+					mat.Texture = mat.unboundTexture
+					mat.unboundTexture = nil
+					mat.textureBound = false
+				}
+				continue
+			}
+
+			bound := mat.TextureCamera
+
+			// Cameras that are partway through rendering aren't rendered again, as they'd clear what they're rendering. Otherwise, bound
+			// Cameras are rendered if they haven't been since this Camera's frame started.
+			if !bound.renderingBound && bound.textureFrame < camera.frame {
+				bound.renderTexture(scene)
+			}
+
+			if !mat.textureBound {
+				mat.unboundTexture = mat.Texture
+				mat.textureBound = true
+			}
+
+			// Bound Cameras that are rendering give the texture they were last rendered to.
+			bound.allocateBoundTexture()
+			mat.Texture = bound.boundTexture
+
+		}
+
+	}
+
+}
+
+// startFrame starts a new frame for the Camera, after which the Cameras bound to the textures it sees are rendered again.
+func (camera *Camera) startFrame() {
+	renderFrame++
+	camera.frame = renderFrame
+	camera.frameRendered = false
+}
+
+// renderTexture renders the Camera for Materials' textures, copying the results to the Camera's bound texture. The Camera renders its own
+// Scene, if it's in one, or otherwise the Scene given. The render is for the latest frame, so that any Cameras bound to textures this
+// Camera sees are rendered once for it, too.
+func (camera *Camera) renderTexture(scene *Scene) {
+
+	camera.renderingTexture = true
+	camera.frame = renderFrame
+	camera.textureFrame = renderFrame
+
+	if own := camera.Scene(); own != nil {
+		scene = own
+	}
+
+	camera.Clear()
+	camera.RenderScene(scene)
+
+	// The results are copied, so the Camera can draw Models using its own texture.
+	camera.allocateBoundTexture()
+	camera.boundTexture.Clear()
+	camera.boundTexture.DrawImage(camera.PostProcess(), nil)
+
+	camera.renderingTexture = false
+
+}
+
+// allocateBoundTexture creates the texture the Camera's render results are copied to for Materials' textures, if it doesn't exist or is the wrong size.
+func (camera *Camera) allocateBoundTexture() {
+
+	w, h := camera.Size()
+
+	if camera.boundTexture != nil {
+		if bw, bh := camera.boundTexture.Size(); bw == w && bh == h {
+			return
+		}
+		camera.boundTexture.Dispose()
+	}
+
+	camera.boundTexture = ebiten.NewImage(w, h)
+
+}
@@ -0,0 +1,172 @@
+package tetra3d
+
+import (
+	"math"
+	"sort"
+)
+
+// RootMotionDelta returns how far the root bone moved in the last update while the AnimationPlayer's ExtractRootMotion is true. The delta
+// is relative to the direction the character faces (taking any turning from previous updates into account), in the space of the root bone's
+// parent (usually the armature). See AnimationPlayer.ApplyRootMotion() to move a node using the delta.
+func (ap *AnimationPlayer) RootMotionDelta() Vector {
+	return ap.rootMotionDelta
+}
+
+// RootMotionYaw returns how far the root bone turned around the Y axis (in radians) in the last update while the AnimationPlayer's
+// ExtractRootMotion is true.
+func (ap *AnimationPlayer) RootMotionYaw() float64 {
+	return ap.rootMotionYaw
+}
+
+// ApplyRootMotion moves and turns the node given (like a character's collider, or the node the armature is parented to) by the root
+// motion extracted in the last update. The node should face the same way as the armature.
+func (ap *AnimationPlayer) ApplyRootMotion(node INode) {
+	node.MoveVec(node.LocalRotation().MultVec(ap.rootMotionDelta))
+	if ap.rootMotionYaw != 0 {
+		node.Rotate(0, 1, 0, ap.rootMotionYaw)
+	}
+}
+
+// extractRootMotion records the motion of the root bone since the last update, sampled at the time given in the AnimationPlayer's
+// Animation, and removes the motion from the pose.
+func (ap *AnimationPlayer) extractRootMotion(animate bool, time float64, pose map[INode]AnimationValues) {
+
+	ap.rootMotionDelta = Vector{}
+	ap.rootMotionYaw = 0
+
+	if !ap.ExtractRootMotion || !animate {
+		ap.rootMotionAnim = nil
+		return
+	}
+
+	anim := ap.Animation
+	channel := ap.rootMotionChannel()
+
+	if channel == nil {
+		ap.rootMotionAnim = nil
+		return
+	}
+
+	if ap.rootMotionAnim == anim {
+
+		prev := ap.rootMotionTime
+
+		var delta Vector
+		var yaw float64
+
+		// A looping Animation's motion continues from the end of the Animation to the start, rather than jumping back.
+		if ap.FinishMode == FinishModeLoop && ap.PlaySpeed > 0 && time < prev {
+			delta, yaw = rootMotionBetween(channel, prev, anim.Length)
+			nextDelta, nextYaw := rootMotionBetween(channel, 0, time)
+			delta = delta.Add(nextDelta.RotateVec(WorldUp, yaw))
+			yaw += nextYaw
+		} else if ap.FinishMode == FinishModeLoop && ap.PlaySpeed < 0 && time > prev {
+			delta, yaw = rootMotionBetween(channel, prev, 0)
+			nextDelta, nextYaw := rootMotionBetween(channel, anim.Length, time)
+			delta = delta.Add(nextDelta.RotateVec(WorldUp, yaw))
+			yaw += nextYaw
+		} else {
+			delta, yaw = rootMotionBetween(channel, prev, time)
+		}
+
+		if !ap.RootMotionVertical {
+			delta.Y = 0
+		}
+
+		ap.rootMotionDelta = delta
+		ap.rootMotionYaw = yaw
+
+	}
+
+	ap.rootMotionAnim = anim
+	ap.rootMotionTime = time
+
+	// Keep the root bone where it started in the Animation, facing the way it started.
+
+	node := ap.ChannelsToNodes[channel]
+
+	values, exists := pose[node]
+	if !exists {
+		return
+	}
+
+	startPos, startYaw := rootMotionSample(channel, 0)
+	pos, yaw := rootMotionSample(channel, time)
+
+	if values.PositionExists {
+		offset := pos.Sub(startPos)
+		if !ap.RootMotionVertical {
+			offset.Y = 0
+		}
+		values.Position = values.Position.Sub(offset)
+	}
+
+	if values.RotationExists {
+		values.Rotation = NewQuaternionFromAxisAngle(WorldUp, -wrapRootMotionAngle(yaw-startYaw)).Mult(values.Rotation).Normalized()
+	}
+
+	pose[node] = values
+
+}
+
+// rootMotionChannel returns the channel of the AnimationPlayer's Animation that animates the root bone - the channel named by
+// RootMotionBone, or otherwise the channel for a bone that isn't parented to another bone.
+func (ap *AnimationPlayer) rootMotionChannel() *AnimationChannel {
+
+	if ap.RootMotionBone != "" {
+		return ap.Animation.Channels[ap.RootMotionBone]
+	}
+
+	names := make([]string, 0, len(ap.Animation.Channels))
+	for name := range ap.Animation.Channels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		channel := ap.Animation.Channels[name]
+		if node := ap.ChannelsToNodes[channel]; node != nil && node.IsBone() && (node.Parent() == nil || !node.Parent().IsBone()) {
+			return channel
+		}
+	}
+
+	return nil
+
+}
+
+// rootMotionSample returns the position of the channel at the given time, and how far it's turned around the Y axis.
+func rootMotionSample(channel *AnimationChannel, time float64) (Vector, float64) {
+
+	pos := Vector{}
+	yaw := 0.0
+
+	if track, exists := channel.Tracks[TrackTypePosition]; exists {
+		if vec, exists := track.ValueAsVector(time); exists {
+			pos = vec
+		}
+	}
+
+	if track, exists := channel.Tracks[TrackTypeRotation]; exists {
+		if quat, exists := track.ValueAsQuaternion(time); exists {
+			forward := quat.Normalized().RotateVec(Vector{0, 0, 1, 0})
+			yaw = math.Atan2(forward.X, forward.Z)
+		}
+	}
+
+	return pos, yaw
+
+}
+
+// rootMotionBetween returns the motion of the channel from one time to another, relative to the direction it faced at the first time
+// (compared to the start of the Animation), and how far it turned around the Y axis.
+func rootMotionBetween(channel *AnimationChannel, from, to float64) (Vector, float64) {
+	_, startYaw := rootMotionSample(channel, 0)
+	fromPos, fromYaw := rootMotionSample(channel, from)
+	toPos, toYaw := rootMotionSample(channel, to)
+	return toPos.Sub(fromPos).RotateVec(WorldUp, -wrapRootMotionAngle(fromYaw-startYaw)), wrapRootMotionAngle(toYaw - fromYaw)
+}
+
+// wrapRootMotionAngle wraps the angle given to range from -pi to pi.
+func wrapRootMotionAngle(angle float64) float64 {
+	return math.Remainder(angle, 2*math.Pi)
+}
@@ -61,6 +61,21 @@ func (scene *Scene) Clone() *Scene {
 		n.sector.UpdateNeighbors(models...)
 	}
 
+	// Cloned Portals link the original Scene's Sectors, so they need to be linked to their clones instead
+	sectorClones := map[*Sector]*Sector{}
+	for i, n := range scene.Root.SearchTree().bySectors().Models() {
+		if i < len(models) {
+			sectorClones[n.sector] = models[i].sector
+		}
+	}
+
+	for _, n := range newScene.Root.SearchTree().ByType(NodeTypePortal).INodes() {
+		portal := n.(*Portal)
+		if a, b := sectorClones[portal.Sectors[0]], sectorClones[portal.Sectors[1]]; a != nil && b != nil {
+			portal.Link(a, b)
+		}
+	}
+
 	return newScene
 
 }
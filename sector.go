@@ -33,11 +33,14 @@ func (np NeighborSet) Clear() {
 // only the objects within the current sector and any neighboring sectors, up to a customizeable
 // depth.
 // A Sector is logically an AABB, which sits next to other Sectors (AABBs).
+// If the Sector the Camera is in has Portals, the Camera renders only the Sectors visible through them instead.
 type Sector struct {
 	Model         *Model
 	AABB          *BoundingAABB
 	Neighbors     NeighborSet
+	Portals       []*Portal // The Portals linking the Sector to other Sectors; use Portal.Link() to add to these.
 	sectorVisible bool
+	visibleRect   screenRect // The region of the screen the Sector can be seen through when rendering through Portals
 }
 
 func NewSector(model *Model) *Sector {
@@ -55,7 +58,7 @@ func NewSector(model *Model) *Sector {
 
 }
 
-// Clone clones a Sector.
+// Clone clones a Sector. The clone isn't linked to the original's Portals.
 func (sector *Sector) Clone() *Sector {
 
 	newSector := &Sector{
@@ -0,0 +1,428 @@
+package tetra3d
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"sort"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+var defaultBlobShadowImage = newBlobShadowImage(32)
+
+// newBlobShadowImage creates a soft, round blob shadow image of the given size, with an alpha falloff towards its edges.
+func newBlobShadowImage(size int) *image.NRGBA {
+
+	img := image.NewNRGBA(image.Rect(0, 0, size, size))
+	half := float64(size) / 2
+
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			dist := math.Hypot(float64(x)+0.5-half, float64(y)+0.5-half) / half
+			alpha := clamp(1-smoothstep(0.5, 1, dist), 0, 1)
+			img.SetNRGBA(x, y, color.NRGBA{255, 255, 255, uint8(alpha * 255)})
+		}
+	}
+
+	return img
+
+}
+
+// Shadows is a simple shadow system, fitting for the low-poly look Tetra3D targets. Rather than rendering depth maps, Shadows
+// generates shadow geometry for Models that opt into it, according to a DirectionalLight or PointLight:
+//
+// Blob shadows (Model.BlobShadow) are textured decals projected from a Model down onto the triangles of any BoundingTriangles
+// nodes in the scene, fading out the further the ground is from the Model.
+//
+// Planar shadows (Model.PlanarShadow) are the Model's triangles flattened onto a single plane (i.e. the ground) along the direction
+// of the light.
+//
+// The generated geometry is held by Shadows.Model, which should be added to the scene to be rendered; call Shadows.Update() each frame
+// after moving or animating shadow-casting Models to rebuild it.
+type Shadows struct {
+	Model *Model // The Model that displays the generated shadows; add this to the scene to render them.
+
+	// Light is the light that casts shadows. With a DirectionalLight, shadows are cast in the direction the light shines; with a
+	// PointLight, shadows are cast away from the light's position. If Light is nil or another type of light, shadows are cast straight down.
+	// If the Light is off, no shadows are generated.
+	Light ILight
+
+	Color *Color // The color of the shadows; defaults to translucent black.
+
+	BlobTexture  *ebiten.Image // The texture used for blob shadows; defaults to a soft circle.
+	BlobScale    float64       // The size of blob shadows relative to the radius of the casting Model's BoundingSphere; defaults to 1.
+	BlobDistance float64       // The maximum distance blob shadows are cast; shadows fade out as the ground gets further from the Model. Defaults to 10.
+
+	PlaneOrigin Vector // A point on the plane that planar shadows are projected onto; defaults to the origin.
+	PlaneNormal Vector // The normal of the plane that planar shadows are projected onto; defaults to WorldUp.
+
+	Offset float64 // How far shadows are pushed off of the surface they're cast onto to avoid z-fighting; defaults to 0.01.
+
+	blobMaterial   *Material
+	planarMaterial *Material
+	aabb           *BoundingAABB
+}
+
+// NewShadows creates a new Shadows instance that casts shadows according to the given light (which can be nil to cast shadows straight down).
+func NewShadows(light ILight) *Shadows {
+
+	shadows := &Shadows{
+		Model:        NewModel(NewMesh("shadows"), "shadows"),
+		Light:        light,
+		Color:        NewColor(0, 0, 0, 0.5),
+		BlobTexture:  ebiten.NewImageFromImage(defaultBlobShadowImage),
+		BlobScale:    1,
+		BlobDistance: 10,
+		PlaneNormal:  WorldUp,
+		Offset:       0.01,
+		aabb:         NewBoundingAABB("shadow aabb", 1, 1, 1),
+	}
+
+	shadows.Model.FrustumCulling = false
+
+	shadows.blobMaterial = newShadowMaterial("blob shadow")
+	shadows.blobMaterial.TextureFilterMode = ebiten.FilterLinear
+	shadows.blobMaterial.TextureWrapMode = ebiten.AddressClampToZero
+	shadows.planarMaterial = newShadowMaterial("planar shadow")
+
+	return shadows
+
+}
+
+func newShadowMaterial(name string) *Material {
+	mat := NewMaterial(name)
+	mat.Shadeless = true
+	mat.BackfaceCulling = false
+	mat.TransparencyMode = TransparencyModeTransparent
+	return mat
+}
+
+// direction returns the direction that shadows are cast in from the given world position.
+func (shadows *Shadows) direction(point Vector) Vector {
+
+	switch light := shadows.Light.(type) {
+	case *DirectionalLight:
+		// A DirectionalLight's forward vector points back towards the light.
+		return light.WorldRotation().Forward().Invert().Unit()
+	case *PointLight:
+		if dir := point.Sub(light.WorldPosition()); !dir.IsZero() {
+			return dir.Unit()
+		}
+	}
+
+	return WorldDown
+
+}
+
+// Update rebuilds the shadows for the Models underneath the given root node (i.e. a Scene's Root). Blob shadows are cast onto
+// the BoundingTriangles nodes underneath the root node.
+func (shadows *Shadows) Update(root INode) {
+
+	// The Mesh is rebuilt every update, so it's reset rather than recreated to reuse its vertex buffers.
+	shadows.Model.Mesh.reset()
+
+	if shadows.Light != nil && !shadows.Light.IsOn() {
+		return
+	}
+
+	var grounds []*BoundingTriangles
+
+	for _, node := range root.SearchTree().ByType(NodeTypeBoundingTriangles).INodes() {
+		grounds = append(grounds, node.(*BoundingTriangles))
+	}
+
+	blobVerts := []VertexInfo{}
+	planarVerts := []VertexInfo{}
+
+	for _, model := range root.SearchTree().Models() {
+
+		if model == shadows.Model || !model.Visible() || model.Mesh == nil {
+			continue
+		}
+
+		if model.BlobShadow {
+			blobVerts = shadows.addBlobShadow(blobVerts, model, grounds)
+		}
+
+		if model.PlanarShadow {
+			planarVerts = shadows.addPlanarShadow(planarVerts, model)
+		}
+
+	}
+
+	mesh := shadows.Model.Mesh
+
+	addVertexMeshParts(mesh, shadows.blobMaterial, blobVerts)
+	addVertexMeshParts(mesh, shadows.planarMaterial, planarVerts)
+
+	shadows.blobMaterial.Texture = shadows.BlobTexture
+
+	mesh.UpdateBounds()
+	mesh.AutoNormal()
+
+	shadows.Model.BoundingSphere.Radius = mesh.Dimensions.MaxSpan() / 2
+
+}
+
+// addVertexMeshParts adds the vertices given to the Mesh, with MeshParts using the Material given rendering them as a list of triangles.
+// MeshParts can only render so many triangles, so the vertices are split across as many MeshParts as they need (see MaxTriangleCount).
+func addVertexMeshParts(mesh *Mesh, material *Material, verts []VertexInfo) {
+
+	partVerts := (MaxTriangleCount - 1) * 3
+
+	for start := 0; start < len(verts); start += partVerts {
+		end := start + partVerts
+		if end > len(verts) {
+			end = len(verts)
+		}
+		mesh.AddVertices(verts[start:end]...)
+		mesh.AddMeshPart(material, sequentialIndices(end-start)...)
+	}
+
+}
+
+func sequentialIndices(count int) []int {
+	indices := make([]int, count)
+	for i := range indices {
+		indices[i] = i
+	}
+	return indices
+}
+
+func (shadows *Shadows) shadowVertex(position, uv Vector, alpha float64) VertexInfo {
+	vert := NewVertex(position.X, position.Y, position.Z, uv.X, uv.Y)
+	shadowColor := shadows.Color.Clone()
+	shadowColor.A *= float32(clamp(alpha, 0, 1))
+	vert.Colors = []*Color{shadowColor}
+	vert.ActiveColorChannel = 0
+	return vert
+}
+
+// addBlobShadow projects a square blob shadow from the center of the Model's BoundingSphere along the shadow direction onto the
+// ground triangles, clipping each triangle to the square and the shadow's distance.
+func (shadows *Shadows) addBlobShadow(verts []VertexInfo, model *Model, grounds []*BoundingTriangles) []VertexInfo {
+
+	model.Transform() // Make sure the BoundingSphere is in place
+
+	center := model.BoundingSphere.WorldPosition()
+	radius := model.BoundingSphere.WorldRadius() * shadows.BlobScale
+	dir := shadows.direction(center)
+
+	if radius <= 0 || shadows.BlobDistance <= 0 {
+		return verts
+	}
+
+	projector := newClipBox(center, dir, radius, shadows.BlobDistance)
+
+	// The AABB contains the entire projected box, whichever way it's facing.
+	size := radius * 2 * math.Sqrt2
+	shadows.aabb.SetDimensions(size+math.Abs(dir.X)*shadows.BlobDistance, size+math.Abs(dir.Y)*shadows.BlobDistance, size+math.Abs(dir.Z)*shadows.BlobDistance)
+	shadows.aabb.SetWorldPositionVec(center.Add(dir.Scale(shadows.BlobDistance / 2)))
+
+	for _, ground := range grounds {
+
+		transform := ground.Transform() // This also updates the ground's BoundingAABB
+
+		if !shadows.aabb.Colliding(ground.BoundingAABB) {
+			continue
+		}
+
+		// Triangles are added in order so that the shadows are generated the same way each time.
+		triIDs := make([]int, 0, 16)
+		for triID := range ground.Broadphase.TrianglesFromBounding(shadows.aabb) {
+			triIDs = append(triIDs, int(triID))
+		}
+		sort.Ints(triIDs)
+
+		for _, triID := range triIDs {
+
+			tri := ground.Mesh.Triangles[triID]
+
+			polygon := make([]clipVertex, 3)
+			for i, index := range tri.VertexIndices {
+				polygon[i].Position = transform.MultVec(ground.Mesh.VertexPositions[index])
+			}
+
+			normal := calculateNormal(polygon[0].Position, polygon[1].Position, polygon[2].Position)
+
+			// Only triangles facing against the shadow's direction receive the shadow.
+			if normal.Dot(dir) >= 0 {
+				continue
+			}
+
+			polygon = projector.clip(polygon)
+
+			for i := 1; i < len(polygon)-1; i++ {
+				for _, v := range []clipVertex{polygon[0], polygon[i], polygon[i+1]} {
+					uv, depth := projector.project(v.Position)
+					verts = append(verts, shadows.shadowVertex(v.Position.Add(normal.Scale(shadows.Offset)), uv, 1-depth))
+				}
+			}
+
+		}
+
+	}
+
+	return verts
+
+}
+
+// addPlanarShadow flattens the light-facing triangles of the Model onto the shadow plane along the shadow direction.
+func (shadows *Shadows) addPlanarShadow(verts []VertexInfo, model *Model) []VertexInfo {
+
+	planeNormal := shadows.PlaneNormal.Unit()
+	planeDistance := planeNormal.Dot(shadows.PlaneOrigin)
+	offset := planeNormal.Scale(shadows.Offset)
+
+	model.updateMorphState()
+	transform := model.Transform()
+	mesh := model.Mesh
+
+	positions := make([]Vector, len(mesh.VertexPositions))
+	for i := range positions {
+		if model.skinned {
+			positions[i], _ = model.skinVertex(i)
+		} else if model.morphed {
+			pos, _ := model.morphVertex(i)
+			positions[i] = transform.MultVec(pos)
+		} else {
+			positions[i] = transform.MultVec(mesh.VertexPositions[i])
+		}
+	}
+
+	projected := [3]Vector{}
+
+	for _, tri := range mesh.Triangles {
+
+		p0, p1, p2 := positions[tri.VertexIndices[0]], positions[tri.VertexIndices[1]], positions[tri.VertexIndices[2]]
+		dir := shadows.direction(p0.Add(p1).Add(p2).Divide(3))
+
+		// Only the triangles facing the light cast shadows, so that each part of the Model only shades the plane once.
+		if calculateNormal(p0, p1, p2).Dot(dir) >= 0 {
+			continue
+		}
+
+		denominator := dir.Dot(planeNormal)
+		if denominator >= 0 {
+			continue
+		}
+
+		valid := true
+
+		for i, p := range []Vector{p0, p1, p2} {
+			t := (planeDistance - p.Dot(planeNormal)) / denominator
+			if t < 0 { // The vertex is below the plane
+				valid = false
+				break
+			}
+			projected[i] = p.Add(dir.Scale(t)).Add(offset)
+		}
+
+		if !valid {
+			continue
+		}
+
+		for _, p := range projected {
+			verts = append(verts, shadows.shadowVertex(p, Vector{}, 1))
+		}
+
+	}
+
+	return verts
+
+}
+
+// clipVertex is a vertex of a polygon being clipped.
+type clipVertex struct {
+	Position Vector
+}
+
+// clipPolygon clips the convex polygon given against a plane, keeping the part of the polygon where dot(normal, position) >= distance.
+func clipPolygon(polygon []clipVertex, normal Vector, distance float64) []clipVertex {
+
+	if len(polygon) == 0 {
+		return polygon
+	}
+
+	clipped := make([]clipVertex, 0, len(polygon)+1)
+
+	for i := range polygon {
+
+		current := polygon[i]
+		next := polygon[(i+1)%len(polygon)]
+
+		currentDist := normal.Dot(current.Position) - distance
+		nextDist := normal.Dot(next.Position) - distance
+
+		if currentDist >= 0 {
+			clipped = append(clipped, current)
+		}
+
+		if (currentDist >= 0) != (nextDist >= 0) {
+			t := currentDist / (currentDist - nextDist)
+			clipped = append(clipped, clipVertex{
+				Position: current.Position.Add(next.Position.Sub(current.Position).Scale(t)),
+			})
+		}
+
+	}
+
+	if len(clipped) < 3 {
+		return nil
+	}
+
+	return clipped
+
+}
+
+// clipBox is an oriented box that projects along its forward direction from a starting point; it's used to clip and texture
+// triangles that fall within it.
+type clipBox struct {
+	Origin         Vector
+	Right, Up, Dir Vector
+	HalfWidth      float64
+	Depth          float64
+}
+
+func newClipBox(origin, dir Vector, halfWidth, depth float64) clipBox {
+
+	axis := WorldUp
+	if math.Abs(dir.Dot(axis)) > 0.99 {
+		axis = NewVector(0, 0, 1)
+	}
+
+	right := axis.Cross(dir).Unit()
+
+	return clipBox{
+		Origin:    origin,
+		Right:     right,
+		Up:        dir.Cross(right).Unit(),
+		Dir:       dir,
+		HalfWidth: halfWidth,
+		Depth:     depth,
+	}
+
+}
+
+// clip clips the polygon given to the inside of the box.
+func (box clipBox) clip(polygon []clipVertex) []clipVertex {
+
+	polygon = clipPolygon(polygon, box.Right, box.Right.Dot(box.Origin)-box.HalfWidth)
+	polygon = clipPolygon(polygon, box.Right.Invert(), -box.Right.Dot(box.Origin)-box.HalfWidth)
+	polygon = clipPolygon(polygon, box.Up, box.Up.Dot(box.Origin)-box.HalfWidth)
+	polygon = clipPolygon(polygon, box.Up.Invert(), -box.Up.Dot(box.Origin)-box.HalfWidth)
+	polygon = clipPolygon(polygon, box.Dir, box.Dir.Dot(box.Origin))
+	polygon = clipPolygon(polygon, box.Dir.Invert(), -box.Dir.Dot(box.Origin)-box.Depth)
+	return polygon
+
+}
+
+// project returns the UV coordinates of the given world position across the face of the box, along with how deep the
+// position is into the box (from 0 at the origin to 1 at the box's depth).
+func (box clipBox) project(position Vector) (Vector, float64) {
+	diff := position.Sub(box.Origin)
+	u := diff.Dot(box.Right)/(box.HalfWidth*2) + 0.5
+	v := diff.Dot(box.Up)/(box.HalfWidth*2) + 0.5
+	return Vector{u, v, 0, 0}, diff.Dot(box.Dir) / box.Depth
+}
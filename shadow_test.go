@@ -0,0 +1,200 @@
+package tetra3d
+
+import (
+	"math"
+	"testing"
+)
+
+func TestShadowsBlob(t *testing.T) {
+
+	scene := NewScene("Test")
+
+	ground := NewBoundingTriangles("Ground", NewPlaneMesh(), 1)
+	ground.SetLocalScale(10, 1, 10)
+
+	caster := NewModel(NewCubeMesh(), "Caster")
+	caster.SetLocalPosition(0, 3, 0)
+	caster.BlobShadow = true
+
+	scene.Root.AddChildren(ground, caster)
+
+	shadows := NewShadows(nil)
+	shadows.Update(scene.Root)
+
+	mesh := shadows.Model.Mesh
+
+	if len(mesh.MeshParts) != 1 || mesh.MeshParts[0].Material != shadows.blobMaterial {
+		t.Fatalf("expected a single blob shadow mesh part, got %d parts", len(mesh.MeshParts))
+	}
+
+	radius := caster.BoundingSphere.WorldRadius()
+
+	for i, pos := range mesh.VertexPositions {
+
+		if math.Abs(pos.Y-shadows.Offset) > 0.0001 {
+			t.Fatalf("blob shadow vertex %d at %s isn't on the ground", i, pos)
+		}
+
+		if math.Abs(pos.X) > radius+0.0001 || math.Abs(pos.Z) > radius+0.0001 {
+			t.Fatalf("blob shadow vertex %d at %s is outside of the blob's radius %f", i, pos, radius)
+		}
+
+		uv := mesh.VertexUVs[i]
+		if uv.X < -0.0001 || uv.X > 1.0001 || uv.Y < -0.0001 || uv.Y > 1.0001 {
+			t.Fatalf("blob shadow vertex %d has UV %s outside of the blob texture", i, uv)
+		}
+
+		// The ground is 3 units below the caster, so the shadow fades by 3/10ths of its opacity.
+		if alpha := mesh.VertexColors[i][0].A; math.Abs(float64(alpha)-0.35) > 0.0001 {
+			t.Fatalf("blob shadow vertex %d has alpha %f, expected 0.35", i, alpha)
+		}
+
+	}
+
+	// Updating again rebuilds the same Mesh rather than adding to it or creating a new one.
+	vertexCount := len(mesh.VertexPositions)
+	triangleCount := len(mesh.Triangles)
+	shadows.Update(scene.Root)
+
+	if shadows.Model.Mesh != mesh || len(mesh.VertexPositions) != vertexCount || len(mesh.Triangles) != triangleCount || len(mesh.MeshParts) != 1 {
+		t.Fatalf("updating shadows again should rebuild the same mesh with %d vertices, got %d vertices and %d parts", vertexCount, len(mesh.VertexPositions), len(mesh.MeshParts))
+	}
+
+	if part := mesh.MeshParts[0]; part.VertexIndexStart != 0 || part.TriangleStart != 0 || part.TriangleCount() != triangleCount {
+		t.Fatalf("rebuilt shadow mesh part should start from the first vertex and triangle")
+	}
+
+	// Moving the caster beyond the blob distance removes the shadow.
+	caster.SetLocalPosition(0, 20, 0)
+	shadows.Update(scene.Root)
+
+	if len(shadows.Model.Mesh.VertexPositions) != 0 {
+		t.Fatalf("expected no shadow past the blob distance, got %d vertices", len(shadows.Model.Mesh.VertexPositions))
+	}
+
+}
+
+// newTestGridMesh creates a 2x2 floor facing up, divided into the given number of cells along each side. The floor's split into two MeshParts,
+// so that it can be rendered even when it has more triangles than a single MeshPart can render.
+func newTestGridMesh(name string, cells int) *Mesh {
+
+	size := 2.0 / float64(cells)
+
+	mesh := NewMesh(name)
+
+	rows := []int{0, cells / 2, cells}
+
+	for half := 0; half < 2; half++ {
+
+		verts := []VertexInfo{}
+
+		for z := rows[half]; z < rows[half+1]; z++ {
+			for x := 0; x < cells; x++ {
+				x0, z0 := -1+float64(x)*size, -1+float64(z)*size
+				x1, z1 := x0+size, z0+size
+				verts = append(verts,
+					NewVertex(x1, 0, z0, 0, 0), NewVertex(x0, 0, z0, 0, 0), NewVertex(x1, 0, z1, 0, 0),
+					NewVertex(x0, 0, z0, 0, 0), NewVertex(x0, 0, z1, 0, 0), NewVertex(x1, 0, z1, 0, 0),
+				)
+			}
+		}
+
+		mesh.AddVertices(verts...)
+		mesh.AddMeshPart(NewMaterial(name), sequentialIndices(len(verts))...)
+
+	}
+
+	mesh.UpdateBounds()
+
+	return mesh
+
+}
+
+func TestShadowsPlanar(t *testing.T) {
+
+	scene := NewScene("Test")
+
+	sun := NewDirectionalLight("Sun", 1, 1, 1, 1)
+	// A DirectionalLight faces back towards the light, so this light shines down and towards -X at 45 degrees.
+	sun.SetLocalRotation(NewLookAtMatrix(Vector{}, NewVector(1, 1, 0), WorldUp))
+
+	caster := NewModel(NewCubeMesh(), "Caster")
+	caster.SetLocalPosition(0, 2, 0)
+	caster.PlanarShadow = true
+
+	scene.Root.AddChildren(sun, caster)
+
+	shadows := NewShadows(sun)
+	shadows.Update(scene.Root)
+
+	mesh := shadows.Model.Mesh
+
+	// Only the top and +X faces of the cube face the light.
+	if len(mesh.Triangles) != 4 {
+		t.Fatalf("expected 4 shadow triangles, got %d", len(mesh.Triangles))
+	}
+
+	for i, pos := range mesh.VertexPositions {
+
+		if math.Abs(pos.Y-shadows.Offset) > 0.0001 {
+			t.Fatalf("planar shadow vertex %d at %s isn't on the plane", i, pos)
+		}
+
+		// The cube spans 1 to 3 units up, so its shadow is pushed 1 to 3 units towards -X.
+		if pos.X < -4.0001 || pos.X > 0.0001 || math.Abs(pos.Z) > 1.0001 {
+			t.Fatalf("planar shadow vertex %d at %s is outside of the expected shadow", i, pos)
+		}
+
+	}
+
+	sun.SetOn(false)
+	shadows.Update(scene.Root)
+
+	if len(shadows.Model.Mesh.Triangles) != 0 {
+		t.Fatalf("expected no shadows from a light that's off")
+	}
+
+}
+
+func TestShadowsTriangleLimit(t *testing.T) {
+
+	scene := NewScene("Test")
+
+	// A detailed caster, with a few more triangles facing the light than a single MeshPart can render.
+	cells := 105
+	caster := NewModel(newTestGridMesh("Caster", cells), "Caster")
+	caster.SetLocalPosition(0, 2, 0)
+	caster.PlanarShadow = true
+
+	scene.Root.AddChildren(caster)
+
+	shadows := NewShadows(nil)
+	shadows.Update(scene.Root)
+
+	mesh := shadows.Model.Mesh
+
+	if len(mesh.Triangles) != cells*cells*2 {
+		t.Fatalf("expected %d shadow triangles, got %d", cells*cells*2, len(mesh.Triangles))
+	}
+
+	if len(mesh.MeshParts) < 2 {
+		t.Fatalf("shadows with more triangles than a MeshPart can render should be split across MeshParts")
+	}
+
+	for _, part := range mesh.MeshParts {
+		if part.TriangleCount() >= MaxTriangleCount {
+			t.Fatalf("shadow mesh part has %d triangles, which is more than can be rendered", part.TriangleCount())
+		}
+	}
+
+	// Rendering the shadows shouldn't panic.
+	camera := NewCamera(32, 32)
+	camera.SetLocalPosition(0, 5, 0)
+	camera.SetLocalRotation(NewMatrix4Rotate(1, 0, 0, -math.Pi/2))
+	scene.Root.AddChildren(camera, shadows.Model)
+
+	sr := NewSoftwareRenderer(camera)
+	sr.Clear()
+	sr.RenderScene(scene)
+
+}
@@ -0,0 +1,475 @@
+package tetra3d
+
+import (
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+const (
+	SkyModeGradient = iota // The Sky is a vertical gradient from its GroundColor below the horizon, through its HorizonColor, up to its ZenithColor.
+	SkyModeCubemap         // The Sky is a box with an image on each of its six Faces.
+	SkyModeDome            // The Sky is a Mesh (like a dome or sphere) surrounding the Camera.
+)
+
+// The faces of a cubemap Sky, in the order they're stored in Sky.Faces.
+const (
+	SkyFaceRight  = iota // The face on the +X side of the Sky.
+	SkyFaceLeft          // The face on the -X side of the Sky.
+	SkyFaceTop           // The face on the +Y side of the Sky.
+	SkyFaceBottom        // The face on the -Y side of the Sky.
+	SkyFaceBack          // The face on the +Z side of the Sky.
+	SkyFaceFront         // The face on the -Z side of the Sky, which Cameras face by default.
+)
+
+const (
+	skySphereRings    = 16  // The number of rings of latitude of a gradient Sky's sphere; this should be even, so a ring lies on the horizon
+	skySphereSegments = 32  // The number of segments of longitude of a gradient Sky's sphere
+	skyCubeDivisions  = 8   // How many times each face of a cubemap Sky is divided along each side
+	skyDistance       = 0.9 // How far out a Sky is drawn, as a fraction of the Camera's far plane
+)
+
+// skyCubeCorners are the positions of the top-left, top-right, and bottom-left corners of each face of a cubemap Sky, as seen from inside.
+var skyCubeCorners = [6][3]Vector{
+	SkyFaceRight:  {{1, 1, -1, 0}, {1, 1, 1, 0}, {1, -1, -1, 0}},
+	SkyFaceLeft:   {{-1, 1, 1, 0}, {-1, 1, -1, 0}, {-1, -1, 1, 0}},
+	SkyFaceTop:    {{-1, 1, 1, 0}, {1, 1, 1, 0}, {-1, 1, -1, 0}},
+	SkyFaceBottom: {{-1, -1, -1, 0}, {1, -1, -1, 0}, {-1, -1, 1, 0}},
+	SkyFaceBack:   {{1, 1, 1, 0}, {-1, 1, 1, 0}, {1, -1, 1, 0}},
+	SkyFaceFront:  {{-1, 1, -1, 0}, {1, 1, -1, 0}, {-1, -1, -1, 0}},
+}
+
+// Sky is a background drawn around the Camera, behind everything else in a Scene. To use a Sky, set it as a World's Sky.
+// Skies follow the Camera's position (but not its rotation), so they appear to be infinitely far away. They're drawn at the start of each
+// render of a Scene, unlit, unaffected by fog, and without writing depth, so anything else drawn in the Scene is drawn over them.
+// Note that if Camera.RenderDepth is off, a Sky is drawn over whatever previous renders drew to the Camera since it was last cleared.
+// Skies are only drawn by perspective Cameras.
+type Sky struct {
+	Mode int // The Sky's mode (SkyModeGradient, SkyModeCubemap, or SkyModeDome).
+
+	HorizonColor *Color // The color of a gradient Sky at the horizon.
+	ZenithColor  *Color // The color of a gradient Sky directly overhead.
+	GroundColor  *Color // The color of a gradient Sky directly below.
+
+	// Faces are the images on each face of a cubemap Sky, indexed by the SkyFace constants (SkyFaceRight, SkyFaceLeft, etc). Each face is drawn
+	// as it appears from inside the box, laid out as though the faces were folded up from a horizontal cross: the side faces (right, left, back, and front)
+	// are upright, the bottom edge of the top face meets the front face, and the top edge of the bottom face meets the front face.
+	Faces     [6]*ebiten.Image
+	FacePaths [6]string // The paths to the images for the Faces, if the Sky was loaded from a GLTF file with its images left unpacked.
+
+	// Dome is the Mesh drawn for a dome Sky. It's scaled up around its origin to surround the Camera, and its Materials are drawn shadeless,
+	// fogless, and transparent (regardless of their settings). The Dome should be finely divided, as large triangles passing behind the Camera
+	// can distort. If the Dome's vertices or MeshParts are changed after the Sky is drawn, call Sky.UpdateDome() so the Sky picks up the changes.
+	Dome *Mesh
+
+	gradient       *Model
+	gradientColors [3]Color // The colors the gradient Sky's vertices were last colored with
+	gradientRadius float64
+	cube           *Model
+	cubeFaceSizes  [6]int // The widths and heights of the faces the cubemap Sky's UVs were last inset for
+	cubeRadius     float64
+	dome           *Model
+	domeParts      []*MeshPart
+	domeSource     *Mesh // The Dome the dome Sky's Model was last set up for
+	domeRadius     float64
+}
+
+// NewSkyGradient creates a new gradient Sky, fading from the ground color given below the horizon, through the horizon color, to the zenith color overhead.
+func NewSkyGradient(horizon, zenith, ground *Color) *Sky {
+	return &Sky{
+		Mode:         SkyModeGradient,
+		HorizonColor: horizon.Clone(),
+		ZenithColor:  zenith.Clone(),
+		GroundColor:  ground.Clone(),
+	}
+}
+
+// newSky creates a new Sky of the mode given, with default gradient colors.
+func newSky(mode int) *Sky {
+	sky := NewSkyGradient(NewColor(0.6, 0.7, 0.8, 1), NewColor(0.2, 0.4, 0.8, 1), NewColor(0.2, 0.2, 0.2, 1))
+	sky.Mode = mode
+	return sky
+}
+
+// NewSkyCubemap creates a new cubemap Sky with the images given as its faces, indexed by the SkyFace constants (SkyFaceRight, SkyFaceLeft, etc).
+func NewSkyCubemap(faces [6]*ebiten.Image) *Sky {
+	sky := newSky(SkyModeCubemap)
+	sky.Faces = faces
+	return sky
+}
+
+// NewSkyCubemapCross creates a new cubemap Sky from a single image of its faces laid out in a horizontal cross, four faces wide and three tall.
+// The middle row holds the left, front, right, and back faces, with the top face above the front face and the bottom face below it.
+func NewSkyCubemapCross(cross *ebiten.Image) *Sky {
+
+	bounds := cross.Bounds()
+	size := bounds.Dx() / 4
+
+	if size <= 0 || bounds.Dy()/3 != size {
+		panic("Error: NewSkyCubemapCross() was given an image that isn't laid out as a horizontal cross (four faces wide and three faces tall).")
+	}
+
+	// The cells of the cross that each face is in, indexed by the SkyFace constants
+	cells := [6][2]int{
+		SkyFaceRight:  {2, 1},
+		SkyFaceLeft:   {0, 1},
+		SkyFaceTop:    {1, 0},
+		SkyFaceBottom: {1, 2},
+		SkyFaceBack:   {3, 1},
+		SkyFaceFront:  {1, 1},
+	}
+
+	faces := [6]*ebiten.Image{}
+
+	for i, cell := range cells {
+		x := bounds.Min.X + cell[0]*size
+		y := bounds.Min.Y + cell[1]*size
+		faces[i] = ebiten.NewImage(size, size)
+		opt := &ebiten.DrawImageOptions{}
+		opt.GeoM.Translate(float64(-x), float64(-y))
+		faces[i].DrawImage(cross, opt)
+	}
+
+	return NewSkyCubemap(faces)
+
+}
+
+// NewSkyDome creates a new dome Sky that draws the Mesh given around the Camera.
+func NewSkyDome(mesh *Mesh) *Sky {
+	sky := newSky(SkyModeDome)
+	sky.Dome = mesh
+	return sky
+}
+
+// Clone returns a clone of the Sky. The clone shares its Faces and Dome with the original.
+func (sky *Sky) Clone() *Sky {
+	clone := NewSkyGradient(sky.HorizonColor, sky.ZenithColor, sky.GroundColor)
+	clone.Mode = sky.Mode
+	clone.Faces = sky.Faces
+	clone.FacePaths = sky.FacePaths
+	clone.Dome = sky.Dome
+	return clone
+}
+
+// model returns the Model to draw for the Sky around the Camera given, or nil if there's nothing to draw.
+func (sky *Sky) model(camera *Camera) *Model {
+
+	if !camera.perspective {
+		return nil
+	}
+
+	var model *Model
+	var radius float64
+
+	switch sky.Mode {
+	case SkyModeGradient:
+		model, radius = sky.gradientModel(), sky.gradientRadius
+	case SkyModeCubemap:
+		model, radius = sky.cubeModel(), sky.cubeRadius
+	case SkyModeDome:
+		model, radius = sky.domeModel(), sky.domeRadius
+	}
+
+	if model == nil || radius <= 0 {
+		return nil
+	}
+
+	// The Sky is sized to fit just within the Camera's far plane.
+	scale := camera.far * skyDistance / radius
+	model.SetLocalScaleVec(Vector{scale, scale, scale, 0})
+	model.SetLocalPositionVec(camera.WorldPosition())
+
+	return model
+
+}
+
+// gradientModel returns the gradient Sky's sphere, recoloring it if its colors have changed.
+func (sky *Sky) gradientModel() *Model {
+
+	if sky.gradient == nil {
+		sky.gradient = newSkyModel(newSkySphereMesh())
+		sky.gradientColors[0].A = -1 // Ensures the sphere is colored the first time
+		sky.gradientRadius = skyMeshRadius(sky.gradient.Mesh)
+	}
+
+	colors := [3]Color{*sky.GroundColor, *sky.HorizonColor, *sky.ZenithColor}
+
+	if colors == sky.gradientColors {
+		return sky.gradient
+	}
+
+	sky.gradientColors = colors
+
+	mesh := sky.gradient.Mesh
+
+	for i, pos := range mesh.VertexPositions {
+		color := mesh.VertexColors[i][0]
+		if pos.Y >= 0 {
+			color.Set(sky.HorizonColor.ToFloat32s())
+			color.Mix(sky.ZenithColor, float32(pos.Y))
+		} else {
+			color.Set(sky.HorizonColor.ToFloat32s())
+			color.Mix(sky.GroundColor, float32(-pos.Y))
+		}
+	}
+
+	return sky.gradient
+
+}
+
+// cubeModel returns the cubemap Sky's box, with each of its faces textured by the Sky's Faces.
+func (sky *Sky) cubeModel() *Model {
+
+	if sky.cube == nil {
+		sky.cube = newSkyModel(newSkyCubeMesh())
+		sky.cubeRadius = skyMeshRadius(sky.cube.Mesh)
+	}
+
+	mesh := sky.cube.Mesh
+	perFace := (skyCubeDivisions + 1) * (skyCubeDivisions + 1)
+
+	for f, part := range mesh.MeshParts {
+
+		face := sky.Faces[f]
+		part.Material.Texture = face
+
+		size := 0
+		if face != nil {
+			size = face.Bounds().Dx()
+		}
+
+		if size == sky.cubeFaceSizes[f] {
+			continue
+		}
+
+		sky.cubeFaceSizes[f] = size
+
+		// UVs are inset by half a texel so that the edges of each face don't sample from the opposite side of the image.
+		inset := 0.0
+		if size > 0 {
+			inset = 0.5 / float64(size)
+		}
+
+		for v := 0; v < perFace; v++ {
+			s := float64(v%(skyCubeDivisions+1)) / skyCubeDivisions
+			t := float64(v/(skyCubeDivisions+1)) / skyCubeDivisions
+			mesh.VertexUVs[f*perFace+v] = Vector{inset + s*(1-inset*2), 1 - inset - t*(1-inset*2), 0, 0}
+		}
+
+	}
+
+	return sky.cube
+
+}
+
+// UpdateDome updates the dome Sky to draw its Dome Mesh as it is now; call this after changing the Dome's vertices or MeshParts. Setting
+// the Sky's Dome to a different Mesh updates the Sky automatically.
+func (sky *Sky) UpdateDome() {
+	sky.domeSource = nil
+}
+
+// domeModel returns a Model drawing the dome Sky's Mesh. The Model draws a copy of the Mesh that shares its vertices, but has its own
+// MeshParts, so its Materials can be drawn as the Sky's without changing the originals. The copy is only set up again when the Dome changes
+// (or Sky.UpdateDome() is called), though its Materials are refreshed from the Dome's each time.
+func (sky *Sky) domeModel() *Model {
+
+	if sky.Dome == nil {
+		return nil
+	}
+
+	if sky.dome == nil {
+		sky.dome = newSkyModel(&Mesh{})
+	}
+
+	if sky.domeSource != sky.Dome {
+		sky.setupDome()
+	}
+
+	for i, original := range sky.Dome.MeshParts {
+
+		if i >= len(sky.domeParts) {
+			break
+		}
+
+		part := sky.domeParts[i]
+
+		if original.Material != nil {
+			*part.Material = *original.Material
+			part.Material.Shadeless = true
+			part.Material.Fogless = true
+			part.Material.TransparencyMode = TransparencyModeTransparent
+		} else {
+			*part.Material = *newSkyMaterial()
+		}
+
+	}
+
+	return sky.dome
+
+}
+
+// setupDome sets the dome Sky's Model up to draw a copy of the Sky's Dome, sharing its vertices.
+func (sky *Sky) setupDome() {
+
+	sky.domeSource = sky.Dome
+	sky.domeRadius = skyMeshRadius(sky.Dome)
+
+	mesh := sky.dome.Mesh
+	*mesh = *sky.Dome
+	mesh.MeshParts = nil
+
+	for i, original := range sky.Dome.MeshParts {
+
+		if i >= len(sky.domeParts) {
+			sky.domeParts = append(sky.domeParts, &MeshPart{Material: newSkyMaterial()})
+		}
+
+		part := sky.domeParts[i]
+		part.Mesh = mesh
+		part.VertexIndexStart = original.VertexIndexStart
+		part.VertexIndexEnd = original.VertexIndexEnd
+		part.TriangleStart = original.TriangleStart
+		part.TriangleEnd = original.TriangleEnd
+
+		// The MeshPart needs room to sort the Dome's triangles when it's drawn.
+		part.sortingTriangles = part.sortingTriangles[:0]
+		for ti := original.TriangleStart; ti <= original.TriangleEnd; ti++ {
+			part.sortingTriangles = append(part.sortingTriangles, sortingTriangle{Triangle: mesh.Triangles[ti]})
+		}
+
+		mesh.MeshParts = append(mesh.MeshParts, part)
+
+	}
+
+	sky.dome.MorphWeights = sky.dome.MorphWeights[:0]
+	for _, target := range mesh.MorphTargets {
+		sky.dome.MorphWeights = append(sky.dome.MorphWeights, target.DefaultWeight)
+	}
+
+}
+
+// skyMeshRadius returns the distance from the origin of the Mesh given to its furthest vertex.
+func skyMeshRadius(mesh *Mesh) float64 {
+	radius := 0.0
+	for _, pos := range mesh.VertexPositions {
+		radius = math.Max(radius, pos.Magnitude())
+	}
+	return radius
+}
+
+// newSkyModel returns a new Model to draw a Sky's Mesh with.
+func newSkyModel(mesh *Mesh) *Model {
+	model := NewModel(mesh, "Sky")
+	model.FrustumCulling = false
+	return model
+}
+
+// newSkyMaterial returns a new Material for drawing a Sky with. Skies are seen from the inside, so they aren't backface culled.
+func newSkyMaterial() *Material {
+	mat := NewMaterial("Sky")
+	mat.Shadeless = true
+	mat.Fogless = true
+	mat.BackfaceCulling = false
+	mat.TransparencyMode = TransparencyModeTransparent
+	return mat
+}
+
+// newSkySphereMesh returns a unit sphere for a gradient Sky, with a vertex color for each vertex.
+func newSkySphereMesh() *Mesh {
+
+	mesh := NewMesh("Sky")
+
+	verts := []VertexInfo{}
+
+	for ring := 0; ring <= skySphereRings; ring++ {
+
+		pitch := math.Pi/2 - math.Pi*float64(ring)/skySphereRings
+
+		for seg := 0; seg < skySphereSegments; seg++ {
+			yaw := math.Pi * 2 * float64(seg) / skySphereSegments
+			vert := NewVertex(math.Cos(pitch)*math.Cos(yaw), math.Sin(pitch), math.Cos(pitch)*math.Sin(yaw), 0, 0)
+			vert.Colors = append(vert.Colors, NewColor(1, 1, 1, 1))
+			vert.ActiveColorChannel = 0
+			verts = append(verts, vert)
+		}
+
+	}
+
+	mesh.AddVertices(verts...)
+
+	indices := []int{}
+
+	for ring := 0; ring < skySphereRings; ring++ {
+		for seg := 0; seg < skySphereSegments; seg++ {
+			a := ring*skySphereSegments + seg
+			b := ring*skySphereSegments + (seg+1)%skySphereSegments
+			c := a + skySphereSegments
+			d := b + skySphereSegments
+			// The triangles at the poles would have no area
+			if ring > 0 {
+				indices = append(indices, a, c, b)
+			}
+			if ring < skySphereRings-1 {
+				indices = append(indices, b, c, d)
+			}
+		}
+	}
+
+	mesh.AddMeshPart(newSkyMaterial(), indices...)
+	mesh.UpdateBounds()
+
+	return mesh
+
+}
+
+// newSkyCubeMesh returns a box for a cubemap Sky, with a MeshPart for each face (in the order of the SkyFace constants). Each face is
+// divided into a grid so that triangles passing behind the Camera are small enough not to distort noticeably.
+func newSkyCubeMesh() *Mesh {
+
+	mesh := NewMesh("Sky")
+
+	verts := []VertexInfo{}
+
+	for _, corners := range skyCubeCorners {
+
+		right := corners[1].Sub(corners[0])
+		down := corners[2].Sub(corners[0])
+
+		for y := 0; y <= skyCubeDivisions; y++ {
+			for x := 0; x <= skyCubeDivisions; x++ {
+				s := float64(x) / skyCubeDivisions
+				t := float64(y) / skyCubeDivisions
+				pos := corners[0].Add(right.Scale(s)).Add(down.Scale(t))
+				verts = append(verts, NewVertex(pos.X, pos.Y, pos.Z, s, 1-t))
+			}
+		}
+
+	}
+
+	mesh.AddVertices(verts...)
+
+	perFace := (skyCubeDivisions + 1) * (skyCubeDivisions + 1)
+
+	for f := range skyCubeCorners {
+
+		indices := []int{}
+
+		for y := 0; y < skyCubeDivisions; y++ {
+			for x := 0; x < skyCubeDivisions; x++ {
+				a := f*perFace + y*(skyCubeDivisions+1) + x
+				b := a + 1
+				c := a + skyCubeDivisions + 1
+				d := c + 1
+				indices = append(indices, a, c, b, b, c, d)
+			}
+		}
+
+		mesh.AddMeshPart(newSkyMaterial(), indices...)
+
+	}
+
+	mesh.UpdateBounds()
+
+	return mesh
+
+}
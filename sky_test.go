@@ -0,0 +1,222 @@
+package tetra3d
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+func TestSkyGradient(t *testing.T) {
+
+	scene := NewScene("Test")
+	scene.World.Sky = NewSkyGradient(NewColor(0, 1, 0, 1), NewColor(0, 0, 1, 1), NewColor(1, 0, 0, 1))
+
+	cube := NewModel(NewCubeMesh(), "Cube")
+	cube.Mesh.MeshParts[0].Material.Shadeless = true
+	cube.SetLocalScaleVec(Vector{0.5, 0.5, 0.5, 0})
+	scene.Root.AddChildren(cube)
+
+	camera := NewCamera(64, 64)
+	camera.SetLocalPosition(0, 0, 5)
+	scene.Root.AddChildren(camera)
+
+	sr := NewSoftwareRenderer(camera)
+	sr.Clear()
+	sr.RenderScene(scene)
+
+	if c := sr.ColorBuffer().RGBAAt(32, 32); c != (color.RGBA{255, 255, 255, 255}) {
+		t.Fatalf("center pixel is %v, expected the cube to be drawn over the sky", c)
+	}
+
+	if c := sr.ColorBuffer().RGBAAt(0, 32); c.G < 200 || c.R > 50 || c.B > 50 || c.A != 255 {
+		t.Fatalf("pixel on the horizon is %v, expected the horizon color", c)
+	}
+
+	if depth := sr.DepthAt(0, 32); !math.IsInf(depth, 1) {
+		t.Fatalf("the sky shouldn't write depth, but wrote a depth of %f", depth)
+	}
+
+	for _, view := range []struct {
+		pitch    float64
+		expected color.RGBA
+	}{
+		{math.Pi / 2, color.RGBA{0, 0, 255, 255}},
+		{-math.Pi / 2, color.RGBA{255, 0, 0, 255}},
+	} {
+
+		camera.SetLocalRotation(NewMatrix4Rotate(1, 0, 0, view.pitch))
+		sr.Clear()
+		sr.RenderScene(scene)
+
+		if c := sr.ColorBuffer().RGBAAt(32, 32); c != view.expected {
+			t.Fatalf("center pixel looking at a pitch of %f is %v, expected %v", view.pitch, c, view.expected)
+		}
+
+	}
+
+	// The sky follows the Camera, so it looks the same from anywhere.
+	camera.SetLocalPosition(1000, 500, -2000)
+	sr.Clear()
+	sr.RenderScene(scene)
+
+	if c := sr.ColorBuffer().RGBAAt(32, 32); c != (color.RGBA{255, 0, 0, 255}) {
+		t.Fatalf("center pixel after moving the camera is %v, expected the ground color", c)
+	}
+
+	camera.SetLocalPosition(0, 0, 5)
+	camera.SetLocalRotation(NewMatrix4())
+	camera.RenderIDs = true
+	camera.VertexWorkers = 2 // The sky's vertices are processed before the Camera goes back to rendering IDs
+	camera.Clear()
+	camera.RenderScene(scene)
+
+	if len(camera.pickEntries) == 0 {
+		t.Fatalf("expected the cube to be pickable")
+	}
+
+	for _, entry := range camera.pickEntries {
+		if entry.Model != cube {
+			t.Fatalf("the sky shouldn't be pickable")
+		}
+	}
+
+}
+
+func TestSkyCubemap(t *testing.T) {
+
+	colors := [6]color.RGBA{
+		SkyFaceRight:  {255, 0, 0, 255},
+		SkyFaceLeft:   {0, 255, 0, 255},
+		SkyFaceTop:    {0, 0, 255, 255},
+		SkyFaceBottom: {255, 255, 0, 255},
+		SkyFaceBack:   {0, 255, 255, 255},
+		SkyFaceFront:  {255, 0, 255, 255},
+	}
+
+	scene := NewScene("Test")
+
+	camera := NewCamera(64, 64)
+	scene.Root.AddChildren(camera)
+
+	sr := NewSoftwareRenderer(camera)
+
+	faces := [6]*ebiten.Image{}
+
+	for i, c := range colors {
+		faces[i] = ebiten.NewImage(2, 2)
+		img := image.NewRGBA(image.Rect(0, 0, 2, 2))
+		for p := 0; p < 4; p++ {
+			img.SetRGBA(p%2, p/2, c)
+		}
+		sr.TextureImages[faces[i]] = img
+	}
+
+	// The top row of the front face is white, so its orientation can be checked.
+	front := sr.TextureImages[faces[SkyFaceFront]].(*image.RGBA)
+	front.SetRGBA(0, 0, color.RGBA{255, 255, 255, 255})
+	front.SetRGBA(1, 0, color.RGBA{255, 255, 255, 255})
+
+	scene.World.Sky = NewSkyCubemap(faces)
+
+	views := []struct {
+		rotation Matrix4
+		face     int
+	}{
+		{NewMatrix4Rotate(0, 1, 0, -math.Pi/2), SkyFaceRight},
+		{NewMatrix4Rotate(0, 1, 0, math.Pi/2), SkyFaceLeft},
+		{NewMatrix4Rotate(1, 0, 0, math.Pi/2), SkyFaceTop},
+		{NewMatrix4Rotate(1, 0, 0, -math.Pi/2), SkyFaceBottom},
+		{NewMatrix4Rotate(0, 1, 0, math.Pi), SkyFaceBack},
+	}
+
+	for _, view := range views {
+
+		camera.SetLocalRotation(view.rotation)
+		sr.Clear()
+		sr.RenderScene(scene)
+
+		if c := sr.ColorBuffer().RGBAAt(32, 32); c != colors[view.face] {
+			t.Fatalf("center pixel facing sky face %d is %v, expected %v", view.face, c, colors[view.face])
+		}
+
+	}
+
+	camera.SetLocalRotation(NewMatrix4())
+	sr.Clear()
+	sr.RenderScene(scene)
+
+	if c := sr.ColorBuffer().RGBAAt(32, 40); c != colors[SkyFaceFront] {
+		t.Fatalf("pixel below the center of the front face is %v, expected %v", c, colors[SkyFaceFront])
+	}
+
+	if c := sr.ColorBuffer().RGBAAt(32, 24); c != (color.RGBA{255, 255, 255, 255}) {
+		t.Fatalf("pixel above the center of the front face is %v, expected the top of the face to be white", c)
+	}
+
+}
+
+func TestSkyDome(t *testing.T) {
+
+	scene := NewScene("Test")
+
+	camera := NewCamera(64, 64)
+	scene.Root.AddChildren(camera)
+
+	sr := NewSoftwareRenderer(camera)
+
+	// The dome's seen from the inside.
+	dome := NewCubeMesh()
+	dome.MeshParts[0].Material.Color.Set(1, 0, 0, 1)
+	dome.MeshParts[0].Material.BackfaceCulling = false
+
+	sky := NewSkyDome(dome)
+	scene.World.Sky = sky
+
+	render := func() color.RGBA {
+		sr.Clear()
+		sr.RenderScene(scene)
+		return sr.ColorBuffer().RGBAAt(32, 32)
+	}
+
+	if c := render(); c != (color.RGBA{255, 0, 0, 255}) {
+		t.Fatalf("center pixel is %v, expected the dome's color", c)
+	}
+
+	parts := sky.dome.Mesh.MeshParts
+	radius := sky.domeRadius
+
+	// Changes to the Dome's Materials show up straight away, while the rest of the dome is only set up again when the Dome changes.
+	dome.MeshParts[0].Material.Color.Set(0, 1, 0, 1)
+
+	if c := render(); c != (color.RGBA{0, 255, 0, 255}) {
+		t.Fatalf("center pixel is %v, expected the dome's new color", c)
+	}
+
+	if &sky.dome.Mesh.MeshParts[0] != &parts[0] || sky.domeSource != dome {
+		t.Fatalf("the dome shouldn't be set up again every frame")
+	}
+
+	NewVertexSelection(dome).SelectAll().ApplyMatrix(NewMatrix4Scale(2, 2, 2))
+	sky.UpdateDome()
+
+	if c := render(); c != (color.RGBA{0, 255, 0, 255}) {
+		t.Fatalf("center pixel is %v after resizing the dome, expected it to still be drawn", c)
+	}
+
+	if math.Abs(sky.domeRadius-radius*2) > 0.0001 {
+		t.Fatalf("expected the dome's radius to be %f after updating it, got %f", radius*2, sky.domeRadius)
+	}
+
+	other := NewCubeMesh()
+	other.MeshParts[0].Material.Color.Set(0, 0, 1, 1)
+	other.MeshParts[0].Material.BackfaceCulling = false
+	sky.Dome = other
+
+	if c := render(); c != (color.RGBA{0, 0, 255, 255}) {
+		t.Fatalf("center pixel is %v, expected the new dome's color", c)
+	}
+
+}
@@ -0,0 +1,644 @@
+package tetra3d
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"log"
+	"math"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// SoftwareRenderer renders Scenes from a Camera's point of view entirely on the CPU into an *image.RGBA, with a depth buffer of its own
+// instead of the Camera's textures. Rendering goes through the same vertex processing, lighting, fog, sorting, depth testing, and alpha clipping
+// as Camera.Render(), so the results should be close to what's drawn through Ebitengine. This makes it useful to render where there's no GPU
+// to draw with - for example, for making thumbnails on a server or for tests.
+// Materials' custom fragment shaders aren't supported (Materials are drawn as though they had none), and neither are the Camera's normal and
+// accumulation textures.
+type SoftwareRenderer struct {
+	Camera *Camera // The Camera to render from. The SoftwareRenderer's buffers are resized to match the Camera's size when cleared.
+
+	// TextureImages maps textures used by Materials to images to sample from when rendering. Textures that aren't in TextureImages are
+	// read back from the GPU, which Ebitengine only allows once the game has started; so when rendering without a running game,
+	// textures should be added here, or they'll be drawn as though the Material had no texture.
+	TextureImages map[*ebiten.Image]image.Image
+
+	colorBuffer *image.RGBA
+	depthBuffer []float32
+	textures    map[*ebiten.Image]*image.RGBA
+	vertices    []softwareVertex
+}
+
+// softwareVertex is a vertex that has been processed and projected to the SoftwareRenderer's buffers.
+type softwareVertex struct {
+	X, Y, Depth float64
+	U, V        float64
+	R, G, B, A  float32
+}
+
+// softwareDrawState holds the settings used to draw the fragments of a MeshPart.
+type softwareDrawState struct {
+	texture       *image.RGBA
+	filter        ebiten.Filter
+	address       ebiten.Address
+	alphaClip     bool
+	ditherMin     float32
+	ditherMax     float32
+	writeDepth    bool
+	fog           bool
+	compositeMode ebiten.CompositeMode
+	colorM        *ebiten.ColorM
+}
+
+// NewSoftwareRenderer creates a new SoftwareRenderer that renders from the point of view of the Camera given.
+func NewSoftwareRenderer(camera *Camera) *SoftwareRenderer {
+	sr := &SoftwareRenderer{
+		Camera:        camera,
+		TextureImages: map[*ebiten.Image]image.Image{},
+		textures:      map[*ebiten.Image]*image.RGBA{},
+	}
+	sr.Clear()
+	return sr
+}
+
+// Clear should be called at the beginning of a single rendered frame and clears the SoftwareRenderer's color and depth buffers, resizing
+// them to the Camera's size if necessary. Like Camera.Clear(), it also resets the Camera's debug values.
+func (sr *SoftwareRenderer) Clear() {
+
+	w, h := sr.Camera.Size()
+
+	if sr.colorBuffer == nil || sr.colorBuffer.Bounds().Dx() != w || sr.colorBuffer.Bounds().Dy() != h {
+		sr.colorBuffer = image.NewRGBA(image.Rect(0, 0, w, h))
+		sr.depthBuffer = make([]float32, w*h)
+	}
+
+	for i := range sr.colorBuffer.Pix {
+		sr.colorBuffer.Pix[i] = 0
+	}
+
+	empty := float32(math.Inf(1))
+	for i := range sr.depthBuffer {
+		sr.depthBuffer[i] = empty
+	}
+
+	sr.Camera.beginFrame()
+
+}
+
+// ColorBuffer returns the *image.RGBA that the SoftwareRenderer renders into. Its pixels use premultiplied alpha, like image.RGBA does in general.
+func (sr *SoftwareRenderer) ColorBuffer() *image.RGBA {
+	return sr.colorBuffer
+}
+
+// DepthAt returns the depth rendered at the given pixel in the SoftwareRenderer's depth buffer, ranging from 0 (at the Camera) to 1 (at the
+// Camera's far plane). Pixels that nothing has been rendered to (as well as pixels outside of the buffer) have a depth of +Inf.
+func (sr *SoftwareRenderer) DepthAt(x, y int) float64 {
+	w, h := sr.Camera.Size()
+	if x < 0 || y < 0 || x >= w || y >= h || len(sr.depthBuffer) != w*h {
+		return math.Inf(1)
+	}
+	return float64(sr.depthBuffer[y*w+x])
+}
+
+// RenderScene renders the provided Scene into the SoftwareRenderer's buffers.
+func (sr *SoftwareRenderer) RenderScene(scene *Scene) {
+	sr.RenderNodes(scene, scene.Root)
+}
+
+// RenderNodes renders all nodes starting with the provided rootNode into the SoftwareRenderer's buffers, using the Scene's properties
+// (fog, for example). Like Camera.RenderNodes(), this takes the Camera's sector rendering settings into account.
+func (sr *SoftwareRenderer) RenderNodes(scene *Scene, rootNode INode) {
+	models, lights := sr.Camera.renderables(rootNode)
+	sr.Render(scene, lights, models...)
+}
+
+// Render renders all of the models passed into the SoftwareRenderer's buffers, lit by the lights given and using the provided Scene's
+// properties (fog, for example).
+func (sr *SoftwareRenderer) Render(scene *Scene, lights []ILight, models ...*Model) {
+
+	camera := sr.Camera
+
+	scene.HandleAutobatch()
+
+	frametimeStart := time.Now()
+
+	sceneLights := camera.beginLights(scene, lights)
+
+	vpMatrix := camera.ViewMatrix().Mult(camera.Projection())
+
+	solids, transparents := camera.sortRenderPairs(camera.lodModels(models))
+
+	// drawPart rasterizes the processed vertices of the MeshPart. mesh holds the processed vertices, and color is the Model's color as of
+	// when they were processed.
+	drawPart := func(model *Model, meshPart *MeshPart, mesh *Mesh, color *Color, sortingTris []sortingTriangle, lighting bool) {
+
+		mat := meshPart.Material
+
+		camera.DebugInfo.TotalTris += meshPart.TriangleCount()
+
+		if model.DynamicBatchOwner != nil {
+			camera.DebugInfo.BatchedParts++
+		}
+
+		if len(sortingTris) == 0 {
+			return
+		}
+
+		// Models rendered in place of others (like Instances) are only as transparent as the Model they render for.
+		owner := model
+		if model.renderOwner != nil {
+			owner = model.renderOwner
+		}
+
+		state := softwareDrawState{
+			writeDepth:    !owner.isTransparent(meshPart),
+			fog:           camera.RenderDepth && scene.World != nil && scene.World.FogOn,
+			compositeMode: ebiten.CompositeModeSourceOver,
+		}
+
+		state.ditherMin, state.ditherMax = model.ditherRange()
+
+		if model.ColorBlendingFunc != nil {
+			colorM := model.ColorBlendingFunc(model, meshPart)
+			state.colorM = &colorM
+		}
+
+		mpColor := color.Clone()
+
+		if mat != nil {
+			mpColor.MultiplyRGBA(mat.Color.ToFloat32s())
+			state.texture = sr.textureImage(mat.Texture)
+			state.filter = mat.TextureFilterMode
+			state.address = mat.TextureWrapMode
+			state.alphaClip = mat.TransparencyMode == TransparencyModeAlphaClip
+			state.compositeMode = mat.CompositeMode
+			if mat.Fogless {
+				state.fog = false
+			}
+		}
+
+		w, h := camera.Size()
+
+		if cap(sr.vertices) < meshPart.VertexIndexCount() {
+			sr.vertices = make([]softwareVertex, meshPart.VertexIndexCount())
+		}
+		sr.vertices = sr.vertices[:meshPart.VertexIndexCount()]
+
+		meshPart.ForEachVertexIndex(func(vertIndex int) {
+
+			clipped := camera.clipToScreen(mesh.vertexTransforms[vertIndex], vertIndex, model, float64(w), float64(h))
+
+			vert := &sr.vertices[vertIndex-meshPart.VertexIndexStart]
+			vert.X = clipped.X
+			vert.Y = clipped.Y
+			vert.Depth = camera.vertexDepth(mesh, vertIndex)
+			vert.U = mesh.VertexUVs[vertIndex].X
+			vert.V = mesh.VertexUVs[vertIndex].Y
+			vert.R, vert.G, vert.B, vert.A = camera.vertexColor(scene, mesh, vertIndex, mpColor, lighting)
+
+		}, false)
+
+		for _, sortingTri := range sortingTris {
+			indices := sortingTri.Triangle.VertexIndices
+			sr.rasterize(
+				&sr.vertices[indices[0]-meshPart.VertexIndexStart],
+				&sr.vertices[indices[1]-meshPart.VertexIndexStart],
+				&sr.vertices[indices[2]-meshPart.VertexIndexStart],
+				&state,
+				scene.World,
+			)
+		}
+
+		camera.DebugInfo.DrawnTris += len(sortingTris)
+		camera.DebugInfo.DrawnParts++
+
+	}
+
+	render := func(rp renderPair) {
+
+		model := rp.Model
+
+		if model.Mesh == nil {
+			return
+		}
+
+		meshPart := rp.MeshPart
+
+		lighting := camera.lightingOn(scene, meshPart.Material)
+
+		if camera.VertexWorkers > 1 {
+			sceneLights = camera.queueVertexJob(rp, rp, sceneLights, lighting)
+			return
+		}
+
+		sortingTris := model.ProcessVertices(vpMatrix, camera, meshPart, scene)
+
+		if lighting {
+			sceneLights = camera.lightMeshPart(model, meshPart, sceneLights, len(sortingTris) > 0)
+		}
+
+		drawPart(model, meshPart, model.Mesh, model.Color, sortingTris, lighting)
+
+	}
+
+	// The SoftwareRenderer draws triangles as they come, so it has nothing to flush.
+	camera.vertexJobs.begin(vpMatrix, scene, func(job *vertexJob) {
+		drawPart(job.pair.Model, job.pair.MeshPart, &job.mesh, &job.color, job.sortingTris, job.lighting)
+	}, func(rp renderPair) {})
+
+	// Dynamically batched Models don't need to be flushed together here, but they still render their batched Models instead of themselves.
+	renderBatch := func(pair renderPair) {
+
+		if pair.Model.instancer != nil {
+			pair.Model.instancer.renderInstances(camera, vpMatrix, pair.MeshPart, render)
+			return
+		}
+
+		if !pair.Model.dynamicBatcher {
+			render(pair)
+			return
+		}
+
+		for _, merged := range pair.Model.DynamicBatchModels[pair.MeshPart] {
+
+			if !merged.visible || !camera.onCullMask(merged) {
+				continue
+			}
+
+			if merged.FrustumCulling {
+				merged.Transform()
+				if !camera.SphereInFrustum(merged.BoundingSphere) {
+					continue
+				}
+			}
+
+			for _, part := range merged.Mesh.MeshParts {
+				render(renderPair{Model: merged, MeshPart: part})
+			}
+
+		}
+
+	}
+
+	if scene.World != nil && scene.World.Sky != nil {
+		if sky := scene.World.Sky.model(camera); sky != nil {
+			for _, part := range sky.Mesh.MeshParts {
+				render(renderPair{Model: sky, MeshPart: part})
+			}
+		}
+	}
+
+	for _, pair := range solids {
+		// Automatically statically batched models can't render
+		if pair.Model.visible && pair.Model.AutoBatchMode != AutoBatchStatic {
+			renderBatch(pair)
+		}
+	}
+
+	for _, pair := range transparents {
+		if pair.Model.visible {
+			renderBatch(pair)
+		}
+	}
+
+	camera.runVertexJobs()
+
+	camera.DebugInfo.frameTime += time.Since(frametimeStart)
+
+	camera.DebugInfo.frameCount++
+
+}
+
+// textureImage returns a CPU-side copy of the texture given to sample from, or nil if the texture is nil.
+func (sr *SoftwareRenderer) textureImage(texture *ebiten.Image) *image.RGBA {
+
+	if texture == nil {
+		return nil
+	}
+
+	if img, exists := sr.textures[texture]; exists {
+		return img
+	}
+
+	bounds := texture.Bounds()
+	img := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+
+	if src, exists := sr.TextureImages[texture]; exists {
+		draw.Draw(img, img.Bounds(), src, src.Bounds().Min, draw.Src)
+	} else if !readTexturePixels(texture, img.Pix) {
+		log.Println("Warning: SoftwareRenderer couldn't read a texture from the GPU, as the game isn't running; add it to SoftwareRenderer.TextureImages to render it. It will be drawn white instead.")
+		img = nil
+	}
+
+	sr.textures[texture] = img
+
+	return img
+
+}
+
+// readTexturePixels reads the pixels of the texture given into the pixels slice, returning false if the texture couldn't be read
+// (because the game hasn't started yet, for example).
+func readTexturePixels(texture *ebiten.Image, pixels []byte) (success bool) {
+	defer func() {
+		if recover() != nil {
+			success = false
+		}
+	}()
+	texture.ReadPixels(pixels)
+	return true
+}
+
+// edgeFunction returns twice the signed area of the triangle formed by the edge from a to b and the point (x, y).
+func edgeFunction(a, b *softwareVertex, x, y float64) float64 {
+	return (b.X-a.X)*(y-a.Y) - (b.Y-a.Y)*(x-a.X)
+}
+
+// topLeftEdge returns if the edge from a to b is a top or left edge of a triangle with a positive area; pixel centers that lie exactly on
+// these edges are drawn, while those on the other edges aren't, so that triangles sharing an edge don't draw the same pixels twice.
+func topLeftEdge(a, b *softwareVertex) bool {
+	return (a.Y == b.Y && b.X > a.X) || b.Y < a.Y
+}
+
+// rasterize draws a triangle into the SoftwareRenderer's buffers. Vertex attributes are interpolated linearly in screen space, as they
+// are when Ebitengine draws triangles.
+func (sr *SoftwareRenderer) rasterize(v0, v1, v2 *softwareVertex, state *softwareDrawState, world *World) {
+
+	area := edgeFunction(v0, v1, v2.X, v2.Y)
+
+	if area == 0 {
+		return
+	}
+
+	if area < 0 {
+		v1, v2 = v2, v1
+		area = -area
+	}
+
+	w, h := sr.Camera.Size()
+
+	minX := int(math.Max(math.Floor(math.Min(v0.X, math.Min(v1.X, v2.X))), 0))
+	maxX := int(math.Min(math.Ceil(math.Max(v0.X, math.Max(v1.X, v2.X))), float64(w-1)))
+	minY := int(math.Max(math.Floor(math.Min(v0.Y, math.Min(v1.Y, v2.Y))), 0))
+	maxY := int(math.Min(math.Ceil(math.Max(v0.Y, math.Max(v1.Y, v2.Y))), float64(h-1)))
+
+	topLeft0 := topLeftEdge(v1, v2)
+	topLeft1 := topLeftEdge(v2, v0)
+	topLeft2 := topLeftEdge(v0, v1)
+
+	for y := minY; y <= maxY; y++ {
+
+		py := float64(y) + 0.5
+
+		for x := minX; x <= maxX; x++ {
+
+			px := float64(x) + 0.5
+
+			w0 := edgeFunction(v1, v2, px, py)
+			w1 := edgeFunction(v2, v0, px, py)
+			w2 := edgeFunction(v0, v1, px, py)
+
+			if w0 < 0 || w1 < 0 || w2 < 0 || (w0 == 0 && !topLeft0) || (w1 == 0 && !topLeft1) || (w2 == 0 && !topLeft2) {
+				continue
+			}
+
+			w0 /= area
+			w1 /= area
+			w2 /= area
+
+			depth := v0.Depth*w0 + v1.Depth*w1 + v2.Depth*w2
+
+			index := y*w + x
+
+			if dither := bayerMatrix[(y%4)*4+x%4]; dither < state.ditherMin || dither >= state.ditherMax {
+				continue
+			}
+
+			if sr.Camera.RenderDepth && float32(depth) >= sr.depthBuffer[index] {
+				continue
+			}
+
+			// Texture colors are premultiplied.
+			tr, tg, tb, ta := float32(1), float32(1), float32(1), float32(1)
+
+			if state.texture != nil {
+				u := v0.U*w0 + v1.U*w1 + v2.U*w2
+				v := v0.V*w0 + v1.V*w1 + v2.V*w2
+				tr, tg, tb, ta = sampleTexture(state.texture, u, v, state.filter, state.address)
+			}
+
+			if state.alphaClip && ta == 0 {
+				continue
+			}
+
+			if state.colorM != nil {
+				tr, tg, tb, ta = applyColorM(state.colorM, tr, tg, tb, ta)
+			}
+
+			// Vertex colors are straight alpha, so they're premultiplied before being multiplied against the texture.
+			va := float32(float64(v0.A)*w0 + float64(v1.A)*w1 + float64(v2.A)*w2)
+			r := clampFloat32(tr * float32(float64(v0.R)*w0+float64(v1.R)*w1+float64(v2.R)*w2) * va)
+			g := clampFloat32(tg * float32(float64(v0.G)*w0+float64(v1.G)*w1+float64(v2.G)*w2) * va)
+			b := clampFloat32(tb * float32(float64(v0.B)*w0+float64(v1.B)*w1+float64(v2.B)*w2) * va)
+			a := clampFloat32(ta * va)
+
+			if state.fog {
+				r, g, b, a = applyFog(world, x, y, depth, r, g, b, a)
+			}
+
+			sr.blend(index*4, r, g, b, a, state.compositeMode)
+
+			if state.writeDepth {
+				sr.depthBuffer[index] = float32(depth)
+			}
+
+		}
+
+	}
+
+}
+
+// blend composites a premultiplied color onto the color buffer at the pixel offset given using the composite mode provided. Modes other than
+// source-over, lighter, destination-out, copy, and clear are drawn as source-over.
+func (sr *SoftwareRenderer) blend(offset int, r, g, b, a float32, mode ebiten.CompositeMode) {
+
+	pix := sr.colorBuffer.Pix[offset : offset+4 : offset+4]
+
+	dr := float32(pix[0]) / 255
+	dg := float32(pix[1]) / 255
+	db := float32(pix[2]) / 255
+	da := float32(pix[3]) / 255
+
+	switch mode {
+	case ebiten.CompositeModeLighter:
+		dr, dg, db, da = dr+r, dg+g, db+b, da+a
+	case ebiten.CompositeModeDestinationOut:
+		dr, dg, db, da = dr*(1-a), dg*(1-a), db*(1-a), da*(1-a)
+	case ebiten.CompositeModeCopy:
+		dr, dg, db, da = r, g, b, a
+	case ebiten.CompositeModeClear:
+		dr, dg, db, da = 0, 0, 0, 0
+	default:
+		dr, dg, db, da = r+dr*(1-a), g+dg*(1-a), b+db*(1-a), a+da*(1-a)
+	}
+
+	pix[0] = uint8(clampFloat32(dr)*255 + 0.5)
+	pix[1] = uint8(clampFloat32(dg)*255 + 0.5)
+	pix[2] = uint8(clampFloat32(db)*255 + 0.5)
+	pix[3] = uint8(clampFloat32(da)*255 + 0.5)
+
+}
+
+// sampleTexture samples the texture given at the UV coordinates provided, returning a premultiplied color.
+func sampleTexture(texture *image.RGBA, u, v float64, filter ebiten.Filter, address ebiten.Address) (r, g, b, a float32) {
+
+	w := texture.Bounds().Dx()
+	h := texture.Bounds().Dy()
+
+	// As when rendering through Ebitengine, 1 - v is used because the top of a texture is 1 in UV coordinates, but 0 in pixel coordinates.
+	srcX := u * float64(w)
+	srcY := (1 - v) * float64(h)
+
+	if filter != ebiten.FilterLinear {
+		return texel(texture, int(math.Floor(srcX)), int(math.Floor(srcY)), address)
+	}
+
+	srcX -= 0.5
+	srcY -= 0.5
+
+	x0 := int(math.Floor(srcX))
+	y0 := int(math.Floor(srcY))
+	fx := float32(srcX - math.Floor(srcX))
+	fy := float32(srcY - math.Floor(srcY))
+
+	r00, g00, b00, a00 := texel(texture, x0, y0, address)
+	r10, g10, b10, a10 := texel(texture, x0+1, y0, address)
+	r01, g01, b01, a01 := texel(texture, x0, y0+1, address)
+	r11, g11, b11, a11 := texel(texture, x0+1, y0+1, address)
+
+	mix := func(c00, c10, c01, c11 float32) float32 {
+		top := c00 + (c10-c00)*fx
+		bottom := c01 + (c11-c01)*fx
+		return top + (bottom-top)*fy
+	}
+
+	return mix(r00, r10, r01, r11), mix(g00, g10, g01, g11), mix(b00, b10, b01, b11), mix(a00, a10, a01, a11)
+
+}
+
+// texel returns the premultiplied color of the texture's pixel at the coordinates given, handling coordinates outside of the texture using
+// the address mode provided.
+func texel(texture *image.RGBA, x, y int, address ebiten.Address) (r, g, b, a float32) {
+
+	w := texture.Bounds().Dx()
+	h := texture.Bounds().Dy()
+
+	switch address {
+	case ebiten.AddressRepeat:
+		x = ((x % w) + w) % w
+		y = ((y % h) + h) % h
+	case ebiten.AddressClampToZero:
+		if x < 0 || y < 0 || x >= w || y >= h {
+			return 0, 0, 0, 0
+		}
+	default:
+		x = int(clamp(float64(x), 0, float64(w-1)))
+		y = int(clamp(float64(y), 0, float64(h-1)))
+	}
+
+	offset := texture.PixOffset(x, y)
+	pix := texture.Pix[offset : offset+4 : offset+4]
+
+	return float32(pix[0]) / 255, float32(pix[1]) / 255, float32(pix[2]) / 255, float32(pix[3]) / 255
+
+}
+
+// applyColorM applies a color matrix to a premultiplied color. Like in Ebitengine, the matrix itself works on straight alpha colors.
+func applyColorM(colorM *ebiten.ColorM, r, g, b, a float32) (float32, float32, float32, float32) {
+
+	premultiplied := color.RGBA64{
+		R: uint16(clampFloat32(r) * 0xffff),
+		G: uint16(clampFloat32(g) * 0xffff),
+		B: uint16(clampFloat32(b) * 0xffff),
+		A: uint16(clampFloat32(a) * 0xffff),
+	}
+
+	// Apply() takes care of converting between premultiplied and straight alpha.
+	cr, cg, cb, ca := colorM.Apply(premultiplied).RGBA()
+
+	return float32(cr) / 0xffff, float32(cg) / 0xffff, float32(cb) / 0xffff, float32(ca) / 0xffff
+
+}
+
+// applyFog applies the World's fog to a premultiplied color rendered at the pixel and depth given, the same way the Camera's color shader does.
+func applyFog(world *World, x, y int, depth float64, r, g, b, a float32) (float32, float32, float32, float32) {
+
+	switch world.FogCurve {
+	case FogCurveOutCirc:
+		depth = math.Sqrt(1 - math.Pow(depth-1, 2))
+	case FogCurveInCirc:
+		depth = 1 - math.Sqrt(1-math.Pow(depth, 2))
+	}
+
+	d := float32(smoothstep(float64(world.FogRange[0]), float64(world.FogRange[1]), depth))
+	fogMult := d
+	alphaMult := float32(math.Abs(float64(1 - d)))
+
+	if world.DitheredFogSize > 0 {
+
+		yc := int((float32(y)+0.5)/world.DitheredFogSize) % 4
+		xc := int((float32(x)+0.5)/world.DitheredFogSize) % 4
+		threshold := bayerMatrix[(yc*4)+xc]
+
+		fogMult = 0
+		if d-threshold >= 0 {
+			fogMult = 1
+		}
+
+		if alphaMult-threshold < 0 {
+			alphaMult = 0
+		}
+
+	}
+
+	fr, fg, fb := world.FogColor.R, world.FogColor.G, world.FogColor.B
+
+	switch world.FogMode {
+	case FogAdd:
+		r, g, b = r+fr*fogMult*a, g+fg*fogMult*a, b+fb*fogMult*a
+	case FogSub:
+		r, g, b = r-fr*fogMult*a, g-fg*fogMult*a, b-fb*fogMult*a
+	case FogOverwrite:
+		// The color is premultiplied, so it's mixed towards the fog color premultiplied by the color's alpha.
+		r = r + (fr*a-r)*fogMult
+		g = g + (fg*a-g)*fogMult
+		b = b + (fb*a-b)*fogMult
+	case FogTransparent:
+		r, g, b, a = r*alphaMult, g*alphaMult, b*alphaMult, a*alphaMult
+	}
+
+	return clampFloat32(r), clampFloat32(g), clampFloat32(b), clampFloat32(a)
+
+}
+
+// smoothstep performs smooth Hermite interpolation of x between edge0 and edge1, like the shader function of the same name.
+func smoothstep(edge0, edge1, x float64) float64 {
+	if edge0 == edge1 {
+		if x < edge0 {
+			return 0
+		}
+		return 1
+	}
+	t := clamp((x-edge0)/(edge1-edge0), 0, 1)
+	return t * t * (3 - 2*t)
+}
+
+func clampFloat32(value float32) float32 {
+	if value < 0 {
+		return 0
+	} else if value > 1 {
+		return 1
+	}
+	return value
+}
@@ -0,0 +1,157 @@
+package tetra3d
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+func TestSoftwareRendererDepth(t *testing.T) {
+
+	scene := NewScene("Test")
+
+	near := NewModel(NewCubeMesh(), "Near")
+	near.Mesh.MeshParts[0].Material.Shadeless = true
+	near.Color.Set(0, 1, 0, 1)
+	near.SetLocalPosition(0, 0, 1)
+	near.SetLocalScaleVec(Vector{0.5, 0.5, 0.5, 0})
+
+	far := NewModel(NewCubeMesh(), "Far")
+	far.Mesh.MeshParts[0].Material.Shadeless = true
+	far.Color.Set(1, 0, 0, 1)
+
+	scene.Root.AddChildren(near, far)
+
+	camera := NewCamera(64, 64)
+	camera.SetLocalPosition(0, 0, 5)
+	scene.Root.AddChildren(camera)
+
+	sr := NewSoftwareRenderer(camera)
+	sr.Clear()
+	// The near cube is rendered first, so it has to be depth tested against to show up in front.
+	sr.Render(scene, nil, near, far)
+
+	if c := sr.ColorBuffer().RGBAAt(32, 32); c != (color.RGBA{0, 255, 0, 255}) {
+		t.Fatalf("center pixel is %v, expected the near cube's green", c)
+	}
+
+	if c := sr.ColorBuffer().RGBAAt(20, 32); c != (color.RGBA{255, 0, 0, 255}) {
+		t.Fatalf("pixel beside the near cube is %v, expected the far cube's red", c)
+	}
+
+	if c := sr.ColorBuffer().RGBAAt(0, 0); c.A != 0 {
+		t.Fatalf("corner pixel is %v, expected it to be empty", c)
+	}
+
+	if depth := sr.DepthAt(32, 32); depth <= 0 || depth >= sr.DepthAt(20, 32) {
+		t.Fatalf("near cube's depth %f should be closer than the far cube's depth %f", depth, sr.DepthAt(20, 32))
+	}
+
+	if depth := sr.DepthAt(0, 0); !math.IsInf(depth, 1) {
+		t.Fatalf("empty pixel has a depth of %f", depth)
+	}
+
+}
+
+func TestSoftwareRendererAlphaClip(t *testing.T) {
+
+	scene := NewScene("Test")
+
+	// The left half of the texture is blue, while the right half is transparent.
+	img := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	img.SetRGBA(0, 0, color.RGBA{0, 0, 255, 255})
+
+	plane := NewModel(NewPlaneMesh(), "Plane")
+	mat := plane.Mesh.MeshParts[0].Material
+	mat.Shadeless = true
+	mat.TransparencyMode = TransparencyModeAlphaClip
+	mat.Texture = ebiten.NewImage(2, 1)
+	scene.Root.AddChildren(plane)
+
+	camera := NewCamera(32, 32)
+	camera.SetPerspective(false)
+	camera.SetOrthoScale(2)
+	camera.SetLocalPosition(0, 5, 0)
+	camera.SetLocalRotation(NewMatrix4Rotate(1, 0, 0, -math.Pi/2))
+	scene.Root.AddChildren(camera)
+
+	sr := NewSoftwareRenderer(camera)
+	sr.TextureImages[mat.Texture] = img
+	sr.Clear()
+	sr.RenderScene(scene)
+
+	if c := sr.ColorBuffer().RGBAAt(8, 16); c != (color.RGBA{0, 0, 255, 255}) {
+		t.Fatalf("left pixel is %v, expected blue", c)
+	}
+
+	if c := sr.ColorBuffer().RGBAAt(24, 16); c.A != 0 {
+		t.Fatalf("right pixel is %v, expected it to be clipped", c)
+	}
+
+	if depth := sr.DepthAt(24, 16); !math.IsInf(depth, 1) {
+		t.Fatalf("clipped pixel wrote a depth of %f", depth)
+	}
+
+}
+
+func TestSoftwareRendererFogAlpha(t *testing.T) {
+
+	// A half-transparent white color, with its straight-alpha fogged result for each fog mode halfway into the fog.
+	cr, cg, cb, ca := float32(1), float32(0.5), float32(0), float32(0.5)
+	fog := NewColor(0, 0, 1, 1)
+
+	expected := map[FogMode][4]float32{
+		FogAdd:         {1, 0.5, 0.5, 0.5},
+		FogSub:         {1, 0.5, 0, 0.5},
+		FogOverwrite:   {0.5, 0.25, 0.5, 0.5},
+		FogTransparent: {1, 0.5, 0, 0.25},
+	}
+
+	for mode, straight := range expected {
+
+		world := NewWorld("Test")
+		world.FogMode = mode
+		world.FogColor = fog
+
+		// The color given to applyFog is premultiplied, and so is the result.
+		r, g, b, a := applyFog(world, 0, 0, 0.5, cr*ca, cg*ca, cb*ca, ca)
+
+		want := [4]float32{straight[0] * straight[3], straight[1] * straight[3], straight[2] * straight[3], straight[3]}
+
+		for i, v := range [4]float32{r, g, b, a} {
+			if math.Abs(float64(v-want[i])) > 0.0001 {
+				t.Fatalf("fog mode %d turned premultiplied color %v into %v, expected %v", mode, [4]float32{cr * ca, cg * ca, cb * ca, ca}, [4]float32{r, g, b, a}, want)
+			}
+		}
+
+	}
+
+	// Rendering a half-transparent plane into overwriting fog gives a properly premultiplied color.
+	scene := NewScene("Test")
+	scene.World.FogMode = FogOverwrite
+	scene.World.FogColor = fog
+
+	plane := NewModel(NewPlaneMesh(), "Plane")
+	mat := plane.Mesh.MeshParts[0].Material
+	mat.Shadeless = true
+	mat.TransparencyMode = TransparencyModeTransparent
+	plane.Color.Set(1, 0, 0, 0.5)
+	scene.Root.AddChildren(plane)
+
+	camera := NewCamera(16, 16)
+	camera.SetLocalPosition(0, 5, 0)
+	camera.SetLocalRotation(NewMatrix4Rotate(1, 0, 0, -math.Pi/2))
+	scene.Root.AddChildren(camera)
+
+	sr := NewSoftwareRenderer(camera)
+	sr.Clear()
+	sr.RenderScene(scene)
+
+	if c := sr.ColorBuffer().RGBAAt(8, 8); c.A < 126 || c.A > 129 || c.B == 0 || c.R > c.A || c.B > c.A || int(c.R)+int(c.B) < 126 || int(c.R)+int(c.B) > 129 {
+		t.Fatalf("half-transparent fogged pixel is %v, expected a premultiplied mix of red and blue with an alpha of 128", c)
+	}
+
+}
@@ -14,6 +14,8 @@ var colorVertexList = make([]ebiten.Vertex, ebiten.MaxIndicesCount)
 var normalVertexList = make([]ebiten.Vertex, ebiten.MaxIndicesCount)
 var depthVertexList = make([]ebiten.Vertex, ebiten.MaxIndicesCount)
 var indexList = make([]uint16, ebiten.MaxIndicesCount)
+var idVertexList = make([]ebiten.Vertex, ebiten.MaxIndicesCount)
+var idIndexList = make([]uint16, ebiten.MaxIndicesCount)
 var vertexListIndex = 0
 var indexListIndex = 0
 var indexListStart = 0
@@ -22,4 +24,9 @@ const MaxTriangleCount = 21845
 
 func init() {
 	defaultImg.Fill(color.White)
+
+	// ID triangles each have their own vertices, so their indices are just sequential.
+	for i := range idIndexList {
+		idIndexList[i] = uint16(i)
+	}
 }
@@ -1,6 +1,6 @@
 # Add-on for Tetra3D > Blender exporting
 
-import bpy, os, bmesh, math
+import bpy, os, bmesh, math, mathutils
 from bpy.app.handlers import persistent
 
 bl_info = {
@@ -61,6 +61,22 @@ worldFogCurveTypes = [
     ("INCIRC", "Light", "Light fog (Ease: In Circ); fog will increase aggressively towards the far range, ramping up to 100% at the far range", "SHARPCURVE", 2),
 ]
 
+worldSkyModes = [
+    ("NONE", "None", "No sky is drawn", 0, 0),
+    ("GRADIENT", "Gradient", "The sky is a gradient from the ground color below the horizon, through the horizon color, up to the zenith color overhead", 0, 1),
+    ("CUBEMAP", "Cubemap", "The sky is a box with an image on each of its six faces", 0, 2),
+    ("DOME", "Dome", "The sky is a mesh (like a dome or sphere) drawn around the camera. The mesh's object must be exported (in any scene) for the mesh to be available", 0, 3),
+]
+
+worldSkyFaces = [
+    ("t3dSkyRight__", "Right (+X)"),
+    ("t3dSkyLeft__", "Left (-X)"),
+    ("t3dSkyTop__", "Top (+Y)"),
+    ("t3dSkyBottom__", "Bottom (-Y)"),
+    ("t3dSkyBack__", "Back (+Z)"),
+    ("t3dSkyFront__", "Front (-Z)"),
+]
+
 gamePropTypes = [
     ("bool", "Bool", "Boolean data type", 0, 0),
     ("int", "Int", "Int data type", 0, 1),
@@ -690,6 +706,19 @@ class WORLD_PT_tetra3d(bpy.types.Panel):
             box.prop(context.world, "t3dFogDithered__")            
             box.prop(context.world, "t3dFogRangeStart__", slider=True)
             box.prop(context.world, "t3dFogRangeEnd__", slider=True)
+
+        box = self.layout.box()
+        box.prop(context.world, "t3dSkyMode__")
+
+        if context.world.t3dSkyMode__ == "GRADIENT":
+            box.prop(context.world, "t3dSkyZenithColor__")
+            box.prop(context.world, "t3dSkyHorizonColor__")
+            box.prop(context.world, "t3dSkyGroundColor__")
+        elif context.world.t3dSkyMode__ == "CUBEMAP":
+            for propName, label in worldSkyFaces:
+                box.prop(context.world, propName, text=label)
+        elif context.world.t3dSkyMode__ == "DOME":
+            box.prop(context.world, "t3dSkyDome__")
         
 # The idea behind "globalget and set" is that we're setting properties on the first scene (which must exist), and getting any property just returns the first one from that scene
 def globalGet(propName):
@@ -702,6 +731,73 @@ def globalSet(propName, value):
 def globalDel(propName):
     del bpy.data.scenes[0][propName]
 
+# constraintAxis converts a Blender constraint axis (i.e. "TRACK_NEGATIVE_Z" or "UP_Y") to a Tetra3D axis (i.e. "-y"); Blender's +Z is Tetra3D's +Y, and Blender's +Y is
+# Tetra3D's -Z. Cameras and lights keep their axes, as the GLTF exporter corrects their rotations.
+def constraintAxis(obj, axis):
+    negative = "NEGATIVE" in axis
+    letter = axis[-1].lower()
+    if obj.type not in ("CAMERA", "LIGHT"):
+        if letter == "y":
+            letter = "z"
+            negative = not negative
+        elif letter == "z":
+            letter = "y"
+    return ("-" if negative else "") + letter
+
+# constraintAxes converts the axes a Blender copy constraint uses to Tetra3D's axes, with 1 for an axis that's copied, -1 for an axis that's copied inverted, and 0 for an
+# axis that isn't copied.
+def constraintAxes(constraint):
+    axes = []
+    for axis in ("x", "z", "y"):
+        value = 0
+        if getattr(constraint, "use_" + axis, True):
+            value = -1 if getattr(constraint, "invert_" + axis, False) else 1
+        axes.append(value)
+    return axes
+
+# exportConstraints returns the constraints on the object that Tetra3D supports as a list of dictionaries to store on the object for export.
+def exportConstraints(obj):
+
+    constraints = []
+
+    for c in obj.constraints:
+
+        if c.mute or c.type not in ("TRACK_TO", "DAMPED_TRACK", "COPY_LOCATION", "COPY_ROTATION", "COPY_SCALE", "LIMIT_DISTANCE", "CHILD_OF") or c.target is None:
+            continue
+
+        target = c.target.name
+        if getattr(c, "subtarget", ""):
+            target = c.subtarget
+
+        data = {
+            "type": c.type,
+            "target": target,
+            "influence": c.influence,
+        }
+
+        if c.type == "TRACK_TO":
+            data["track"] = constraintAxis(obj, c.track_axis)
+            data["up"] = constraintAxis(obj, c.up_axis)
+        elif c.type == "DAMPED_TRACK":
+            data["track"] = constraintAxis(obj, c.track_axis)
+        elif c.type in ("COPY_LOCATION", "COPY_ROTATION", "COPY_SCALE"):
+            data["axes"] = constraintAxes(c)
+            # Copy Rotation constraints in newer versions of Blender mix rotations rather than offsetting them
+            data["offset"] = getattr(c, "use_offset", False) or getattr(c, "mix_mode", "REPLACE") != "REPLACE"
+        elif c.type == "LIMIT_DISTANCE":
+            data["distance"] = c.distance
+            data["mode"] = c.limit_mode.replace("LIMITDIST_", "")
+        elif c.type == "CHILD_OF":
+            # Blender's Z-up matrices transform column vectors, while Tetra3D's are Y-up and transform row vectors, so the inverse matrix is
+            # converted to Y-up and then written out a column at a time.
+            toYUp = mathutils.Matrix(((1, 0, 0, 0), (0, 0, 1, 0), (0, -1, 0, 0), (0, 0, 0, 1)))
+            inverse = toYUp @ c.inverse_matrix @ toYUp.inverted()
+            data["inverse"] = [v for col in inverse.col for v in col]
+
+        constraints.append(data)
+
+    return constraints
+
 class RENDER_PT_tetra3d(bpy.types.Panel):
     bl_idname = "RENDER_PT_tetra3d"
     bl_label = "Tetra3D Render Properties"
@@ -825,6 +921,26 @@ def export():
         if "t3dFogRangeEnd__" in world:
             worldData["fog range end"] = world.t3dFogRangeEnd__
 
+        if world.t3dSkyMode__ != "NONE":
+
+            worldData["sky mode"] = world.t3dSkyMode__
+            worldData["sky horizon color"] = list(world.t3dSkyHorizonColor__)
+            worldData["sky zenith color"] = list(world.t3dSkyZenithColor__)
+            worldData["sky ground color"] = list(world.t3dSkyGroundColor__)
+
+            # Sky face images are exported as paths relative to the exported file, just like unpacked textures.
+            faces = []
+            for propName, _ in worldSkyFaces:
+                image = getattr(world, propName)
+                if image is not None and image.filepath != "":
+                    faces.append(os.path.relpath(bpy.path.abspath(image.filepath), os.path.dirname(newPath)).replace("\\", "/"))
+                else:
+                    faces.append("")
+            worldData["sky faces"] = faces
+
+            if world.t3dSkyDome__ is not None:
+                worldData["sky dome"] = world.t3dSkyDome__.data.name
+
         worlds[world.name] = worldData
 
     globalSet("t3dWorlds__", worlds)
@@ -905,6 +1021,10 @@ def export():
                         obj["t3dPathPoints__"] = points
                         obj["t3dPathCyclic__"] = spline.use_cyclic_u or spline.use_cyclic_v
 
+                    constraints = exportConstraints(obj)
+                    if len(constraints) > 0:
+                        obj["t3dConstraints__"] = constraints
+
                     if obj.instance_type == "COLLECTION":
                         obj["t3dInstanceCollection__"] = obj.instance_collection.name
                         ogCollections[obj] = obj.instance_collection
@@ -1153,6 +1273,8 @@ def export():
                         del(obj["t3dPathPoints__"])
                     if "t3dPathCyclic__" in obj:
                         del(obj["t3dPathCyclic__"])
+                    if "t3dConstraints__" in obj:
+                        del(obj["t3dConstraints__"])
                     if obj.type == "MESH":
                         if "t3dVertexColorNames__" in obj.data:
                             del(obj.data["t3dVertexColorNames__"])
@@ -1494,6 +1616,14 @@ def register():
     bpy.types.World.t3dFogRangeStart__ = bpy.props.FloatProperty(name="Fog Range Start", description="With 0 being the near plane and 1 being the far plane of the camera, how far in should the fog start to appear", min=0.0, max=1.0, default=0, get=fogRangeStartGet, set=fogRangeStartSet)
     bpy.types.World.t3dFogRangeEnd__ = bpy.props.FloatProperty(name="Fog Range End", description="With 0 being the near plane and 1 being the far plane of the camera, how far out should the fog be at maximum opacity", min=0.0, max=1.0, default=1, get=fogRangeEndGet, set=fogRangeEndSet)
 
+    bpy.types.World.t3dSkyMode__ = bpy.props.EnumProperty(items=worldSkyModes, name="Sky Mode", description="What kind of sky is drawn behind everything else in scenes using this world", default="NONE")
+    bpy.types.World.t3dSkyZenithColor__ = bpy.props.FloatVectorProperty(name="Zenith Color", description="The color of the sky directly overhead", default=[0.03, 0.13, 0.6, 1], subtype="COLOR", size=4, step=1, min=0, max=1)
+    bpy.types.World.t3dSkyHorizonColor__ = bpy.props.FloatVectorProperty(name="Horizon Color", description="The color of the sky at the horizon", default=[0.3, 0.45, 0.6, 1], subtype="COLOR", size=4, step=1, min=0, max=1)
+    bpy.types.World.t3dSkyGroundColor__ = bpy.props.FloatVectorProperty(name="Ground Color", description="The color of the sky directly below", default=[0.03, 0.03, 0.03, 1], subtype="COLOR", size=4, step=1, min=0, max=1)
+    for propName, label in worldSkyFaces:
+        setattr(bpy.types.World, propName, bpy.props.PointerProperty(name=label, type=bpy.types.Image, description="The image on the " + label + " face of the sky, as seen from inside; faces are laid out as though folded up from a horizontal cross"))
+    bpy.types.World.t3dSkyDome__ = bpy.props.PointerProperty(name="Dome", type=bpy.types.Object, description="The mesh object drawn as the sky around the camera", poll=lambda self, obj: obj.type == "MESH")
+
     if not exportOnSave in bpy.app.handlers.save_post:
         bpy.app.handlers.save_post.append(exportOnSave)
     
@@ -1557,6 +1687,13 @@ def unregister():
     del bpy.types.World.t3dFogRangeEnd__
     del bpy.types.World.t3dFogDithered__
     del bpy.types.World.t3dFogCurve__
+    del bpy.types.World.t3dSkyMode__
+    del bpy.types.World.t3dSkyZenithColor__
+    del bpy.types.World.t3dSkyHorizonColor__
+    del bpy.types.World.t3dSkyGroundColor__
+    for propName, _ in worldSkyFaces:
+        delattr(bpy.types.World, propName)
+    del bpy.types.World.t3dSkyDome__
 
     del bpy.types.Camera.t3dFOV__
 
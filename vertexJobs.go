@@ -0,0 +1,316 @@
+package tetra3d
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// maxQueuedJobVertices is roughly how many vertices a Camera queues up for its vertex workers before processing them and drawing the results.
+const maxQueuedJobVertices = 65536
+
+// vertexJob is either a MeshPart of a Model queued to have its vertices processed (transformed, skinned, and lit) by a vertex worker, or a
+// queued flush. As Models can share Meshes (and so the buffers that vertices are processed into), each job processes its vertices into its
+// own copies of the Model, Mesh, and MeshPart.
+type vertexJob struct {
+	pair  renderPair // The Model and MeshPart queued
+	batch renderPair // The pair flushed if the job's vertices don't fit in the vertex buffers (or the pair to flush for a flush job)
+	flush bool
+
+	lighting      bool
+	lights        []ILight
+	workingLights []ILight
+
+	model Model
+	node  Node  // A snapshot of the Model's Node, as Instances change their Model's transform between jobs
+	color Color // A snapshot of the Model's Color
+	mesh  Mesh
+	part  MeshPart
+
+	sortingTris   []sortingTriangle
+	animationTime time.Duration
+	lightTime     time.Duration
+
+	// The job's vertex buffers, which are reused from frame to frame
+	transforms         []Vector
+	transformedNormals []Vector
+	skinnedPositions   []Vector
+	skinnedNormals     []Vector
+	morphedPositions   []Vector
+	morphedNormals     []Vector
+	visible            []bool
+	lightColors        []Color
+	lightPointers      []*Color
+}
+
+// vertexJobQueue is the queue of jobs for a Camera's vertex workers.
+type vertexJobQueue struct {
+	jobs     []*vertexJob
+	count    int
+	vertices int
+
+	vpMatrix Matrix4
+	scene    *Scene
+	draw     func(job *vertexJob)
+	flush    func(rp renderPair)
+}
+
+// begin readies the queue for rendering a Scene. Once processed, jobs are drawn using draw, and flushed using flush.
+func (queue *vertexJobQueue) begin(vpMatrix Matrix4, scene *Scene, draw func(job *vertexJob), flush func(rp renderPair)) {
+	queue.count = 0
+	queue.vertices = 0
+	queue.vpMatrix = vpMatrix
+	queue.scene = scene
+	queue.draw = draw
+	queue.flush = flush
+}
+
+// next returns the next free job in the queue.
+func (queue *vertexJobQueue) next() *vertexJob {
+	if queue.count == len(queue.jobs) {
+		queue.jobs = append(queue.jobs, &vertexJob{})
+	}
+	job := queue.jobs[queue.count]
+	queue.count++
+	return job
+}
+
+// queueVertexJob queues the render pair to have its vertices processed by the Camera's vertex workers, processing the queue first if it's full.
+// batch is the pair to flush if the pair's vertices don't fit in the vertex buffers when drawn. Like Camera.lightMeshPart(), this returns
+// the lights used for the pair if lighting is true.
+func (camera *Camera) queueVertexJob(rp renderPair, batch renderPair, sceneLights []ILight, lighting bool) []ILight {
+
+	queue := &camera.vertexJobs
+	model := rp.Model
+	mesh := model.Mesh
+	vertexCount := len(mesh.VertexPositions)
+
+	if queue.count > 0 && queue.vertices+vertexCount > maxQueuedJobVertices {
+		camera.runVertexJobs()
+	}
+
+	queue.vertices += vertexCount
+
+	// Nodes cache their transforms when they're first requested, so we request them here to ensure the workers only read them.
+	camera.Transform()
+	model.Transform()
+
+	if model.skinned && model.SkinRoot != nil {
+		model.SkinRoot.Transform()
+		for _, bone := range model.SkinRoot.SearchTree().INodes() {
+			bone.Transform()
+		}
+	}
+
+	if lighting {
+		sceneLights = modelLights(model, sceneLights)
+		for _, light := range sceneLights {
+			light.Transform()
+		}
+	}
+
+	job := queue.next()
+	job.pair = rp
+	job.batch = batch
+	job.flush = false
+	job.lighting = lighting
+	job.lights = sceneLights
+
+	job.node = *model.Node
+	job.color = *model.Color
+	job.mesh = *mesh
+	job.part = *rp.MeshPart
+	job.part.Mesh = &job.mesh
+
+	job.model = *model
+	job.model.Node = &job.node
+	job.model.Color = &job.color
+	job.model.Mesh = &job.mesh
+	job.model.updateMorphState()
+
+	job.allocate(camera.RenderNormals)
+
+	return sceneLights
+
+}
+
+// queueVertexFlush queues a flush of the vertices drawn so far, using the render pair given.
+func (camera *Camera) queueVertexFlush(rp renderPair) {
+	job := camera.vertexJobs.next()
+	job.batch = rp
+	job.flush = true
+}
+
+// allocate points the job's Mesh copy to the job's own vertex buffers, resizing them as necessary. Buffers the job doesn't need are left nil.
+func (job *vertexJob) allocate(renderNormals bool) {
+
+	mesh := &job.mesh
+	count := len(mesh.VertexPositions)
+
+	job.transforms = resizeVectors(job.transforms, count)
+	mesh.vertexTransforms = job.transforms
+
+	if cap(job.visible) < count {
+		job.visible = make([]bool, count)
+	}
+	job.visible = job.visible[:count]
+	mesh.visibleVertices = job.visible
+
+	mesh.vertexTransformedNormals = nil
+	if renderNormals {
+		job.transformedNormals = resizeVectors(job.transformedNormals, count)
+		mesh.vertexTransformedNormals = job.transformedNormals
+	}
+
+	mesh.vertexSkinnedPositions = nil
+	mesh.vertexSkinnedNormals = nil
+	if job.model.skinned {
+		job.skinnedPositions = resizeVectors(job.skinnedPositions, count)
+		job.skinnedNormals = resizeVectors(job.skinnedNormals, count)
+		mesh.vertexSkinnedPositions = job.skinnedPositions
+		mesh.vertexSkinnedNormals = job.skinnedNormals
+	}
+
+	mesh.vertexMorphedPositions = nil
+	mesh.vertexMorphedNormals = nil
+	if job.model.morphed {
+		job.morphedPositions = resizeVectors(job.morphedPositions, count)
+		job.morphedNormals = resizeVectors(job.morphedNormals, count)
+		mesh.vertexMorphedPositions = job.morphedPositions
+		mesh.vertexMorphedNormals = job.morphedNormals
+	}
+
+	mesh.vertexLights = nil
+	if job.lighting {
+		if cap(job.lightColors) < count {
+			job.lightColors = make([]Color, count)
+			job.lightPointers = make([]*Color, count)
+			for i := range job.lightColors {
+				job.lightPointers[i] = &job.lightColors[i]
+			}
+		}
+		mesh.vertexLights = job.lightPointers[:count]
+	}
+
+	if triCount := job.part.TriangleCount(); cap(job.sortingTris) < triCount {
+		job.sortingTris = make([]sortingTriangle, triCount)
+	}
+	job.part.sortingTriangles = job.sortingTris[:0]
+
+}
+
+// resizeVectors returns the buffer given resized to the size given, reallocating it if it's too small.
+func resizeVectors(buffer []Vector, size int) []Vector {
+	if cap(buffer) < size {
+		return make([]Vector, size)
+	}
+	return buffer[:size]
+}
+
+// process processes the job's vertices. lights maps the Scene's lights to the worker's working copies of them.
+func (job *vertexJob) process(camera *Camera, queue *vertexJobQueue, lights map[ILight]ILight) {
+
+	part := &job.part
+
+	for i := part.VertexIndexStart; i < part.VertexIndexEnd; i++ {
+		job.mesh.visibleVertices[i] = false
+	}
+
+	job.sortingTris, job.animationTime = job.model.processVertices(queue.vpMatrix, camera, part, queue.scene)
+	job.lightTime = 0
+
+	if job.lighting {
+
+		t := time.Now()
+
+		job.workingLights = job.workingLights[:0]
+
+		for _, light := range job.lights {
+			working, exists := lights[light]
+			if !exists {
+				working = light.workingCopy()
+				lights[light] = working
+			}
+			job.workingLights = append(job.workingLights, working)
+		}
+
+		lightVertices(&job.model, part, job.workingLights, len(job.sortingTris) > 0)
+
+		job.lightTime = time.Since(t)
+
+	}
+
+}
+
+// runVertexJobs processes the queued jobs across the Camera's vertex workers, and then draws or flushes each job in the order they were queued,
+// emptying the queue.
+func (camera *Camera) runVertexJobs() {
+
+	queue := &camera.vertexJobs
+
+	if queue.count == 0 {
+		return
+	}
+
+	jobs := queue.jobs[:queue.count]
+
+	workers := camera.VertexWorkers
+	if workers > len(jobs) {
+		workers = len(jobs)
+	}
+
+	next := int32(-1)
+
+	wg := sync.WaitGroup{}
+	wg.Add(workers)
+
+	for w := 0; w < workers; w++ {
+
+		go func() {
+
+			defer wg.Done()
+
+			lights := map[ILight]ILight{}
+
+			for {
+				i := int(atomic.AddInt32(&next, 1))
+				if i >= len(jobs) {
+					return
+				}
+				if !jobs[i].flush {
+					jobs[i].process(camera, queue, lights)
+				}
+			}
+
+		}()
+
+	}
+
+	wg.Wait()
+
+	for _, job := range jobs {
+
+		if job.flush {
+			queue.flush(job.batch)
+			continue
+		}
+
+		camera.DebugInfo.animationTime += job.animationTime
+		camera.DebugInfo.lightTime += job.lightTime
+
+		// Skinned vertex positions are also used to spawn particles, so they're copied back to the original Mesh.
+		if job.model.skinned {
+			start, end := job.part.VertexIndexStart, job.part.VertexIndexEnd
+			original := job.pair.Model.Mesh
+			copy(original.vertexSkinnedPositions[start:end], job.mesh.vertexSkinnedPositions[start:end])
+			copy(original.vertexSkinnedNormals[start:end], job.mesh.vertexSkinnedNormals[start:end])
+		}
+
+		queue.draw(job)
+
+	}
+
+	queue.count = 0
+	queue.vertices = 0
+
+}
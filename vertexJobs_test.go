@@ -0,0 +1,143 @@
+package tetra3d
+
+import (
+	"bytes"
+	"testing"
+)
+
+// newVertexWorkerTestScene returns a lit Scene of many Models sharing Meshes, along with a morphed Model, a Model in a LightGroup, and an InstancedModel.
+func newVertexWorkerTestScene() *Scene {
+
+	scene := NewScene("Test")
+
+	cubeMesh := NewCubeMesh()
+
+	for i := 0; i < 64; i++ {
+		cube := NewModel(cubeMesh, "Cube")
+		cube.SetLocalPosition(float64(i%8)*2-7, float64(i/8)*2-7, -float64(i%3)*2)
+		cube.SetLocalRotation(NewMatrix4Rotate(0, 1, 0, float64(i)*0.3))
+		scene.Root.AddChildren(cube)
+	}
+
+	morphMesh := NewIcosphereMesh(2)
+	target := morphMesh.AddMorphTarget("Bulge")
+	for i := range target.PositionDeltas {
+		target.PositionDeltas[i] = morphMesh.VertexPositions[i].Scale(0.5)
+	}
+	morphed := NewModel(morphMesh, "Morphed")
+	morphed.MorphWeights = []float64{1}
+	morphed.SetLocalPosition(0, 0, 3)
+	scene.Root.AddChildren(morphed)
+
+	grouped := NewModel(cubeMesh, "Grouped")
+	grouped.SetLocalPosition(3, 3, 3)
+	grouped.LightGroup = NewLightGroup(NewPointLight("group light", 0, 0, 1, 2))
+	scene.Root.AddChildren(grouped)
+
+	crowd := NewInstancedModel(cubeMesh, "Crowd")
+	for i, instance := range crowd.AddInstances(16) {
+		instance.SetPosition(float64(i)-8, -9, 0)
+		instance.Color.Set(1, float32(i)/16, 0, 1)
+	}
+	scene.Root.AddChildren(crowd)
+
+	point := NewPointLight("point", 1, 0.5, 0.25, 3)
+	point.SetLocalPosition(2, 2, 6)
+
+	sun := NewDirectionalLight("sun", 0.25, 0.5, 1, 0.5)
+	sun.SetLocalRotation(NewMatrix4Rotate(1, 0, 0, -0.5))
+
+	scene.Root.AddChildren(point, sun)
+
+	scene.World.AmbientLight.Energy = 0.2
+
+	return scene
+
+}
+
+func TestVertexWorkersDeterministic(t *testing.T) {
+
+	render := func(workers int) (*SoftwareRenderer, DebugInfo) {
+
+		scene := newVertexWorkerTestScene()
+
+		camera := NewCamera(96, 96)
+		camera.VertexWorkers = workers
+		camera.SetLocalPosition(0, 0, 20)
+		scene.Root.AddChildren(camera)
+
+		sr := NewSoftwareRenderer(camera)
+		sr.RenderScene(scene)
+
+		return sr, camera.DebugInfo
+
+	}
+
+	single, singleInfo := render(1)
+
+	for _, workers := range []int{2, 4, 7} {
+
+		multi, multiInfo := render(workers)
+
+		if !bytes.Equal(single.ColorBuffer().Pix, multi.ColorBuffer().Pix) {
+			t.Fatalf("rendering with %d vertex workers gave a different result than rendering with 1", workers)
+		}
+
+		if singleInfo.DrawnTris != multiInfo.DrawnTris || singleInfo.DrawnParts != multiInfo.DrawnParts || singleInfo.TotalTris != multiInfo.TotalTris {
+			t.Fatalf("rendering with %d vertex workers drew %d triangles in %d parts, expected %d in %d", workers, multiInfo.DrawnTris, multiInfo.DrawnParts, singleInfo.DrawnTris, singleInfo.DrawnParts)
+		}
+
+	}
+
+}
+
+func TestVertexWorkersDrawOrder(t *testing.T) {
+
+	scene := NewScene("Test")
+	cubeMesh := NewCubeMesh()
+
+	// More vertices than are queued for the workers at once
+	for i := 0; i < maxQueuedJobVertices/len(cubeMesh.VertexPositions)+100; i++ {
+		cube := NewModel(cubeMesh, "Cube")
+		cube.SetLocalPosition(float64(i%60), float64(i/60), 0)
+		scene.Root.AddChildren(cube)
+	}
+
+	// More instances than fit in the vertex buffers at once
+	crowd := NewInstancedModel(cubeMesh, "Crowd")
+	crowd.FrustumCulling = false
+	for i, instance := range crowd.AddInstances(MaxTriangleCount / 6) {
+		instance.SetPosition(float64(i%50), float64(i/50), -5)
+	}
+	scene.Root.AddChildren(crowd)
+
+	camera := NewCamera(64, 64)
+	camera.RenderIDs = true
+	camera.SetLocalPosition(30, 25, 80)
+	scene.Root.AddChildren(camera)
+
+	camera.Clear()
+	camera.RenderScene(scene)
+
+	single := append([]PickResult{}, camera.pickEntries...)
+	singleParts := camera.DebugInfo.DrawnParts
+
+	camera.VertexWorkers = 4
+	camera.Clear()
+	camera.RenderScene(scene)
+
+	if len(single) == 0 || len(single) != len(camera.pickEntries) {
+		t.Fatalf("expected the same number of triangles to be drawn with multiple vertex workers, got %d and %d", len(single), len(camera.pickEntries))
+	}
+
+	for i := range single {
+		if single[i] != camera.pickEntries[i] {
+			t.Fatalf("triangle %d was drawn out of order with multiple vertex workers", i)
+		}
+	}
+
+	if singleParts != camera.DebugInfo.DrawnParts {
+		t.Fatalf("expected the same number of draw calls with multiple vertex workers, got %d and %d", singleParts, camera.DebugInfo.DrawnParts)
+	}
+
+}
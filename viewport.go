@@ -0,0 +1,84 @@
+package tetra3d
+
+import (
+	"image"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// SetViewport sets the Camera's viewport - the region of a render target (like the screen) that the Camera draws to using Camera.DrawViewport().
+// This resizes the Camera to the size of the viewport, so several Cameras can split a render target between them (see SplitViewports()).
+func (camera *Camera) SetViewport(viewport image.Rectangle) {
+	viewport = viewport.Canon()
+	camera.viewportX = viewport.Min.X
+	camera.viewportY = viewport.Min.Y
+	camera.Resize(viewport.Dx(), viewport.Dy())
+}
+
+// Viewport returns the Camera's viewport on its render target. Unless it's been set using Camera.SetViewport(), the viewport is at the
+// origin. The viewport is always the size of the Camera.
+func (camera *Camera) Viewport() image.Rectangle {
+	w, h := camera.Size()
+	return image.Rect(camera.viewportX, camera.viewportY, camera.viewportX+w, camera.viewportY+h)
+}
+
+// DrawViewport draws the Camera's post-processed render results (see Camera.PostProcess()) to its viewport on the target image given.
+func (camera *Camera) DrawViewport(target *ebiten.Image) {
+	opt := &ebiten.DrawImageOptions{}
+	opt.GeoM.Translate(float64(camera.viewportX), float64(camera.viewportY))
+	target.DrawImage(camera.PostProcess(), opt)
+}
+
+// ToViewport converts a position on the Camera's render target (like the mouse cursor's position on the screen) to a position on the
+// Camera's textures, for use with functions like Camera.PickAt() or Camera.ScreenToWorld(). The boolean returned is whether the position
+// is within the Camera's viewport.
+func (camera *Camera) ToViewport(x, y int) (int, int, bool) {
+	vx := x - camera.viewportX
+	vy := y - camera.viewportY
+	return vx, vy, image.Pt(x, y).In(camera.Viewport())
+}
+
+// SplitViewports divides the area given into count viewports of (about) the same size, for split-screen rendering. The viewports are laid out
+// in a grid, ordered left to right and then top to bottom. A wide area has at least as many columns as rows, and a tall area at least as many
+// rows as columns, so two viewports are side by side in a wide area and stacked in a tall one. If the last row isn't full, its viewports are
+// widened to fill it. SplitViewports returns nil if count is less than 1.
+func SplitViewports(area image.Rectangle, count int) []image.Rectangle {
+
+	if count < 1 {
+		return nil
+	}
+
+	area = area.Canon()
+
+	long := int(math.Ceil(math.Sqrt(float64(count))))
+	short := (count + long - 1) / long
+
+	cols, rows := long, short
+	if area.Dy() > area.Dx() {
+		cols, rows = short, long
+	}
+
+	viewports := make([]image.Rectangle, 0, count)
+
+	for row := 0; row < rows; row++ {
+
+		rowCount := cols
+		if remaining := count - row*cols; remaining < cols {
+			rowCount = remaining
+		}
+
+		y0 := area.Min.Y + area.Dy()*row/rows
+		y1 := area.Min.Y + area.Dy()*(row+1)/rows
+
+		for col := 0; col < rowCount; col++ {
+			x0 := area.Min.X + area.Dx()*col/rowCount
+			x1 := area.Min.X + area.Dx()*(col+1)/rowCount
+			viewports = append(viewports, image.Rect(x0, y0, x1, y1))
+		}
+
+	}
+
+	return viewports
+
+}
@@ -24,6 +24,7 @@ type World struct {
 	ClearColor *Color // The clear color of the screen; note that this doesn't clear the color of the camera buffer or screen automatically;
 	// this is just what the color is if the scene was exported using the Tetra3D addon from Blender. It's up to you as to how you'd like to
 	// use it.
+	Sky      *Sky    // The Sky drawn behind everything else in the Scene; if Sky is nil (the default), no Sky is drawn.
 	FogColor *Color  // The Color of any fog present in the Scene.
 	FogMode  FogMode // The FogMode, indicating how the fog color is blended if it's on (not FogOff).
 	// FogRange is the depth range at which the fog is active. FogRange consists of two numbers,
@@ -67,6 +68,9 @@ func (world *World) Clone() *World {
 	newWorld.FogCurve = world.FogCurve
 	newWorld.AmbientLight = world.AmbientLight.Clone().(*AmbientLight)
 	newWorld.DitheredFogSize = world.DitheredFogSize
+	if world.Sky != nil {
+		newWorld.Sky = world.Sky.Clone()
+	}
 
 	return newWorld
 